@@ -25,6 +25,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/kubelet/cm/containermap"
 	"k8s.io/kubernetes/pkg/kubelet/lifecycle"
+	"k8s.io/kubernetes/pkg/kubelet/metrics"
 )
 
 const (
@@ -152,6 +153,7 @@ func (s *scope) allocateAlignedResources(pod *v1.Pod, container *v1.Container) e
 }
 
 func topologyAffinityError() lifecycle.PodAdmitResult {
+	metrics.TopologyManagerAdmissionErrorsTotal.Inc()
 	return lifecycle.PodAdmitResult{
 		Message: "Resources cannot be allocated with Topology locality",
 		Reason:  "TopologyAffinityError",
@@ -38,6 +38,22 @@ type ResourceConfiguration struct {
 	// providers is a list of transformers to be used for reading and writing the resources to disk.
 	// eg: aesgcm, aescbc, secretbox, identity.
 	Providers []ProviderConfiguration
+	// compressionCodec enables transparent compression of the resources' values before
+	// they are handed to the first provider, naming a registered codec. "gzip" is built
+	// in; "snappy" and "zstd" are reserved for builds that vendor and register those
+	// codecs. Empty disables compression.
+	// +optional
+	CompressionCodec string
+	// compressionMinSize is the size in bytes below which values are stored
+	// uncompressed. Defaults to 1024 when compression is enabled.
+	// +optional
+	CompressionMinSize int32
+	// allowSensitiveResourceCompression acknowledges that compressing values before
+	// encrypting them leaks information about the plaintext through ciphertext
+	// lengths, and enables compression for resources, such as secrets, that are
+	// protected from that by default.
+	// +optional
+	AllowSensitiveResourceCompression bool
 }
 
 // ProviderConfiguration stores the provided configuration for an encryption provider.
@@ -46,6 +62,8 @@ type ProviderConfiguration struct {
 	AESGCM *AESConfiguration
 	// aescbc is the configuration for the AES-CBC transformer.
 	AESCBC *AESConfiguration
+	// aessiv is the configuration for the deterministic AES-SIV transformer.
+	AESSIV *AESSIVConfiguration
 	// secretbox is the configuration for the Secretbox based transformer.
 	Secretbox *SecretboxConfiguration
 	// identity is the (empty) configuration for the identity transformer.
@@ -61,6 +79,17 @@ type AESConfiguration struct {
 	Keys []Key
 }
 
+// AESSIVConfiguration contains the API configuration for a deterministic AES-SIV transformer.
+type AESSIVConfiguration struct {
+	// keys is a list of keys to be used for creating the AES-SIV transformer.
+	// Each key has to be 32 or 64 bytes long.
+	Keys []Key
+	// allowDeterministicEncryption must be set to true to acknowledge that this
+	// transformer produces identical ciphertexts for identical values, which
+	// reveals equality of the encrypted values to anyone with access to storage.
+	AllowDeterministicEncryption bool
+}
+
 // SecretboxConfiguration contains the API configuration for an Secretbox transformer.
 type SecretboxConfiguration struct {
 	// keys is a list of keys to be used for creating the Secretbox transformer.
@@ -97,4 +126,25 @@ type KMSConfiguration struct {
 	// timeout for gRPC calls to kms-plugin (ex. 5s). The default is 3 seconds.
 	// +optional
 	Timeout *metav1.Duration
+	// maxInFlight is the maximum number of in-flight transformations allowed through the
+	// kms-plugin before further transformations are rejected (fail fast) with a retriable
+	// overloaded error instead of queueing behind a degraded plugin. Zero disables the limit.
+	// +optional
+	MaxInFlight int32
+	// p99LatencyBound rejects transformations while the observed p99 latency of the
+	// kms-plugin exceeds this bound (ex. 100ms), so that a degraded plugin sheds load
+	// instead of slowing every request down. Unset disables the latency check.
+	// +optional
+	P99LatencyBound *metav1.Duration
+	// maxRetries is the number of times a transformation is retried with jittered
+	// exponential backoff after a transient kms-plugin failure (gRPC Unavailable or
+	// ResourceExhausted) before the error is returned to the caller. Zero disables
+	// retries.
+	// +optional
+	MaxRetries int32
+	// failureThreshold is the number of consecutive kms-plugin failures after which
+	// further transformations fail fast with a retriable error, until the plugin has
+	// proven itself healthy again. Zero disables the circuit breaker.
+	// +optional
+	FailureThreshold int32
 }
@@ -46,6 +46,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*AESSIVConfiguration)(nil), (*config.AESSIVConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1_AESSIVConfiguration_To_config_AESSIVConfiguration(a.(*AESSIVConfiguration), b.(*config.AESSIVConfiguration), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.AESSIVConfiguration)(nil), (*AESSIVConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_AESSIVConfiguration_To_v1_AESSIVConfiguration(a.(*config.AESSIVConfiguration), b.(*AESSIVConfiguration), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*EncryptionConfiguration)(nil), (*config.EncryptionConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1_EncryptionConfiguration_To_config_EncryptionConfiguration(a.(*EncryptionConfiguration), b.(*config.EncryptionConfiguration), scope)
 	}); err != nil {
@@ -139,6 +149,28 @@ func Convert_config_AESConfiguration_To_v1_AESConfiguration(in *config.AESConfig
 	return autoConvert_config_AESConfiguration_To_v1_AESConfiguration(in, out, s)
 }
 
+func autoConvert_v1_AESSIVConfiguration_To_config_AESSIVConfiguration(in *AESSIVConfiguration, out *config.AESSIVConfiguration, s conversion.Scope) error {
+	out.Keys = *(*[]config.Key)(unsafe.Pointer(&in.Keys))
+	out.AllowDeterministicEncryption = in.AllowDeterministicEncryption
+	return nil
+}
+
+// Convert_v1_AESSIVConfiguration_To_config_AESSIVConfiguration is an autogenerated conversion function.
+func Convert_v1_AESSIVConfiguration_To_config_AESSIVConfiguration(in *AESSIVConfiguration, out *config.AESSIVConfiguration, s conversion.Scope) error {
+	return autoConvert_v1_AESSIVConfiguration_To_config_AESSIVConfiguration(in, out, s)
+}
+
+func autoConvert_config_AESSIVConfiguration_To_v1_AESSIVConfiguration(in *config.AESSIVConfiguration, out *AESSIVConfiguration, s conversion.Scope) error {
+	out.Keys = *(*[]Key)(unsafe.Pointer(&in.Keys))
+	out.AllowDeterministicEncryption = in.AllowDeterministicEncryption
+	return nil
+}
+
+// Convert_config_AESSIVConfiguration_To_v1_AESSIVConfiguration is an autogenerated conversion function.
+func Convert_config_AESSIVConfiguration_To_v1_AESSIVConfiguration(in *config.AESSIVConfiguration, out *AESSIVConfiguration, s conversion.Scope) error {
+	return autoConvert_config_AESSIVConfiguration_To_v1_AESSIVConfiguration(in, out, s)
+}
+
 func autoConvert_v1_EncryptionConfiguration_To_config_EncryptionConfiguration(in *EncryptionConfiguration, out *config.EncryptionConfiguration, s conversion.Scope) error {
 	out.Resources = *(*[]config.ResourceConfiguration)(unsafe.Pointer(&in.Resources))
 	return nil
@@ -182,6 +214,10 @@ func autoConvert_v1_KMSConfiguration_To_config_KMSConfiguration(in *KMSConfigura
 	out.CacheSize = (*int32)(unsafe.Pointer(in.CacheSize))
 	out.Endpoint = in.Endpoint
 	out.Timeout = (*metav1.Duration)(unsafe.Pointer(in.Timeout))
+	out.MaxInFlight = in.MaxInFlight
+	out.P99LatencyBound = (*metav1.Duration)(unsafe.Pointer(in.P99LatencyBound))
+	out.MaxRetries = in.MaxRetries
+	out.FailureThreshold = in.FailureThreshold
 	return nil
 }
 
@@ -195,6 +231,10 @@ func autoConvert_config_KMSConfiguration_To_v1_KMSConfiguration(in *config.KMSCo
 	out.CacheSize = (*int32)(unsafe.Pointer(in.CacheSize))
 	out.Endpoint = in.Endpoint
 	out.Timeout = (*metav1.Duration)(unsafe.Pointer(in.Timeout))
+	out.MaxInFlight = in.MaxInFlight
+	out.P99LatencyBound = (*metav1.Duration)(unsafe.Pointer(in.P99LatencyBound))
+	out.MaxRetries = in.MaxRetries
+	out.FailureThreshold = in.FailureThreshold
 	return nil
 }
 
@@ -228,6 +268,7 @@ func Convert_config_Key_To_v1_Key(in *config.Key, out *Key, s conversion.Scope)
 func autoConvert_v1_ProviderConfiguration_To_config_ProviderConfiguration(in *ProviderConfiguration, out *config.ProviderConfiguration, s conversion.Scope) error {
 	out.AESGCM = (*config.AESConfiguration)(unsafe.Pointer(in.AESGCM))
 	out.AESCBC = (*config.AESConfiguration)(unsafe.Pointer(in.AESCBC))
+	out.AESSIV = (*config.AESSIVConfiguration)(unsafe.Pointer(in.AESSIV))
 	out.Secretbox = (*config.SecretboxConfiguration)(unsafe.Pointer(in.Secretbox))
 	out.Identity = (*config.IdentityConfiguration)(unsafe.Pointer(in.Identity))
 	out.KMS = (*config.KMSConfiguration)(unsafe.Pointer(in.KMS))
@@ -242,6 +283,7 @@ func Convert_v1_ProviderConfiguration_To_config_ProviderConfiguration(in *Provid
 func autoConvert_config_ProviderConfiguration_To_v1_ProviderConfiguration(in *config.ProviderConfiguration, out *ProviderConfiguration, s conversion.Scope) error {
 	out.AESGCM = (*AESConfiguration)(unsafe.Pointer(in.AESGCM))
 	out.AESCBC = (*AESConfiguration)(unsafe.Pointer(in.AESCBC))
+	out.AESSIV = (*AESSIVConfiguration)(unsafe.Pointer(in.AESSIV))
 	out.Secretbox = (*SecretboxConfiguration)(unsafe.Pointer(in.Secretbox))
 	out.Identity = (*IdentityConfiguration)(unsafe.Pointer(in.Identity))
 	out.KMS = (*KMSConfiguration)(unsafe.Pointer(in.KMS))
@@ -256,6 +298,9 @@ func Convert_config_ProviderConfiguration_To_v1_ProviderConfiguration(in *config
 func autoConvert_v1_ResourceConfiguration_To_config_ResourceConfiguration(in *ResourceConfiguration, out *config.ResourceConfiguration, s conversion.Scope) error {
 	out.Resources = *(*[]string)(unsafe.Pointer(&in.Resources))
 	out.Providers = *(*[]config.ProviderConfiguration)(unsafe.Pointer(&in.Providers))
+	out.CompressionCodec = in.CompressionCodec
+	out.CompressionMinSize = in.CompressionMinSize
+	out.AllowSensitiveResourceCompression = in.AllowSensitiveResourceCompression
 	return nil
 }
 
@@ -267,6 +312,9 @@ func Convert_v1_ResourceConfiguration_To_config_ResourceConfiguration(in *Resour
 func autoConvert_config_ResourceConfiguration_To_v1_ResourceConfiguration(in *config.ResourceConfiguration, out *ResourceConfiguration, s conversion.Scope) error {
 	out.Resources = *(*[]string)(unsafe.Pointer(&in.Resources))
 	out.Providers = *(*[]ProviderConfiguration)(unsafe.Pointer(&in.Providers))
+	out.CompressionCodec = in.CompressionCodec
+	out.CompressionMinSize = in.CompressionMinSize
+	out.AllowSensitiveResourceCompression = in.AllowSensitiveResourceCompression
 	return nil
 }
 
@@ -46,6 +46,27 @@ func (in *AESConfiguration) DeepCopy() *AESConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AESSIVConfiguration) DeepCopyInto(out *AESSIVConfiguration) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]Key, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AESSIVConfiguration.
+func (in *AESSIVConfiguration) DeepCopy() *AESSIVConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AESSIVConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EncryptionConfiguration) DeepCopyInto(out *EncryptionConfiguration) {
 	*out = *in
@@ -107,6 +128,11 @@ func (in *KMSConfiguration) DeepCopyInto(out *KMSConfiguration) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.P99LatencyBound != nil {
+		in, out := &in.P99LatencyBound, &out.P99LatencyBound
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -149,6 +175,11 @@ func (in *ProviderConfiguration) DeepCopyInto(out *ProviderConfiguration) {
 		*out = new(AESConfiguration)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AESSIV != nil {
+		in, out := &in.AESSIV, &out.AESSIV
+		*out = new(AESSIVConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Secretbox != nil {
 		in, out := &in.Secretbox, &out.Secretbox
 		*out = new(SecretboxConfiguration)
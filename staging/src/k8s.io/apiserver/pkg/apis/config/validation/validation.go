@@ -36,12 +36,17 @@ const (
 	base64EncodingErr        = "secrets must be base64 encoded"
 	zeroOrNegativeErrFmt     = "%s should be a positive value"
 	nonZeroErrFmt            = "%s should be a positive value, or negative to disable"
+	negativeValueErrFmt      = "%s must not be negative"
+	compressionCodecRequiredErr = "may only be set together with compressionCodec"
 	encryptionConfigNilErr   = "EncryptionConfiguration can't be nil"
+	deterministicOptInErr    = "must be true to use the deterministic aessiv provider, acknowledging that equal values produce equal ciphertexts in storage"
 )
 
 var (
 	aesKeySizes = []int{16, 24, 32}
 	// See https://golang.org/pkg/crypto/aes/#NewCipher for details on supported key sizes for AES.
+	aesSIVKeySizes = []int{32, 64}
+	// See https://tools.ietf.org/html/rfc5297#section-2.2 for details on the supported key sizes for AES-SIV.
 	secretBoxKeySizes = []int{32}
 	// See https://godoc.org/golang.org/x/crypto/nacl/secretbox#Open for details on the supported key sizes for Secretbox.
 	root = field.NewPath("resources")
@@ -73,6 +78,8 @@ func ValidateEncryptionConfiguration(c *config.EncryptionConfiguration) field.Er
 			allErrs = append(allErrs, field.Required(p, fmt.Sprintf(atLeastOneRequiredErrFmt, p)))
 		}
 
+		allErrs = append(allErrs, validateCompression(&conf, root.Index(i))...)
+
 		for j, provider := range conf.Providers {
 			path := p.Index(j)
 			allErrs = append(allErrs, validateSingleProvider(provider, path)...)
@@ -84,6 +91,8 @@ func ValidateEncryptionConfiguration(c *config.EncryptionConfiguration) field.Er
 				allErrs = append(allErrs, validateKeys(provider.AESGCM.Keys, path.Child("aesgcm").Child("keys"), aesKeySizes)...)
 			case provider.AESCBC != nil:
 				allErrs = append(allErrs, validateKeys(provider.AESCBC.Keys, path.Child("aescbc").Child("keys"), aesKeySizes)...)
+			case provider.AESSIV != nil:
+				allErrs = append(allErrs, validateAESSIVConfiguration(provider.AESSIV, path.Child("aessiv"))...)
 			case provider.Secretbox != nil:
 				allErrs = append(allErrs, validateKeys(provider.Secretbox.Keys, path.Child("secretbox").Child("keys"), secretBoxKeySizes)...)
 			}
@@ -93,6 +102,23 @@ func ValidateEncryptionConfiguration(c *config.EncryptionConfiguration) field.Er
 	return allErrs
 }
 
+func validateCompression(conf *config.ResourceConfiguration, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if conf.CompressionMinSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("compressionMinSize"), conf.CompressionMinSize, fmt.Sprintf(negativeValueErrFmt, "compressionMinSize")))
+	}
+	if conf.CompressionCodec == "" {
+		if conf.CompressionMinSize != 0 {
+			allErrs = append(allErrs, field.Invalid(fieldPath.Child("compressionMinSize"), conf.CompressionMinSize, compressionCodecRequiredErr))
+		}
+		if conf.AllowSensitiveResourceCompression {
+			allErrs = append(allErrs, field.Invalid(fieldPath.Child("allowSensitiveResourceCompression"), conf.AllowSensitiveResourceCompression, compressionCodecRequiredErr))
+		}
+	}
+
+	return allErrs
+}
+
 func validateSingleProvider(provider config.ProviderConfiguration, filedPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	found := 0
@@ -106,6 +132,9 @@ func validateSingleProvider(provider config.ProviderConfiguration, filedPath *fi
 	if provider.AESCBC != nil {
 		found++
 	}
+	if provider.AESSIV != nil {
+		found++
+	}
 	if provider.Secretbox != nil {
 		found++
 	}
@@ -114,7 +143,7 @@ func validateSingleProvider(provider config.ProviderConfiguration, filedPath *fi
 	}
 
 	if found == 0 {
-		return append(allErrs, field.Invalid(filedPath, provider, "provider does not contain any of the expected providers: KMS, AESGCM, AESCBC, Secretbox, Identity"))
+		return append(allErrs, field.Invalid(filedPath, provider, "provider does not contain any of the expected providers: KMS, AESGCM, AESCBC, AESSIV, Secretbox, Identity"))
 	}
 
 	if found > 1 {
@@ -124,6 +153,19 @@ func validateSingleProvider(provider config.ProviderConfiguration, filedPath *fi
 	return allErrs
 }
 
+func validateAESSIVConfiguration(c *config.AESSIVConfiguration, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	// Deterministic encryption reveals equality of the encrypted values, so it
+	// has to be acknowledged explicitly instead of being picked by accident.
+	if !c.AllowDeterministicEncryption {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("allowDeterministicEncryption"), c.AllowDeterministicEncryption, deterministicOptInErr))
+	}
+	allErrs = append(allErrs, validateKeys(c.Keys, fieldPath.Child("keys"), aesSIVKeySizes)...)
+
+	return allErrs
+}
+
 func validateKeys(keys []config.Key, fieldPath *field.Path, expectedLen []int) field.ErrorList {
 	allErrs := field.ErrorList{}
 
@@ -180,6 +222,39 @@ func validateKMSConfiguration(c *config.KMSConfiguration, fieldPath *field.Path)
 	allErrs = append(allErrs, validateKMSTimeout(c, fieldPath.Child("timeout"))...)
 	allErrs = append(allErrs, validateKMSEndpoint(c, fieldPath.Child("endpoint"))...)
 	allErrs = append(allErrs, validateKMSCacheSize(c, fieldPath.Child("cachesize"))...)
+	allErrs = append(allErrs, validateKMSLoadShedding(c, fieldPath)...)
+	allErrs = append(allErrs, validateKMSMaxRetries(c, fieldPath.Child("maxRetries"))...)
+	allErrs = append(allErrs, validateKMSFailureThreshold(c, fieldPath.Child("failureThreshold"))...)
+	return allErrs
+}
+
+func validateKMSMaxRetries(c *config.KMSConfiguration, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if c.MaxRetries < 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath, c.MaxRetries, fmt.Sprintf(negativeValueErrFmt, "maxRetries")))
+	}
+
+	return allErrs
+}
+
+func validateKMSFailureThreshold(c *config.KMSConfiguration, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if c.FailureThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath, c.FailureThreshold, fmt.Sprintf(negativeValueErrFmt, "failureThreshold")))
+	}
+
+	return allErrs
+}
+
+func validateKMSLoadShedding(c *config.KMSConfiguration, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if c.MaxInFlight < 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("maxInFlight"), c.MaxInFlight, fmt.Sprintf(negativeValueErrFmt, "maxInFlight")))
+	}
+	if c.P99LatencyBound != nil && c.P99LatencyBound.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("p99LatencyBound"), c.P99LatencyBound, fmt.Sprintf(zeroOrNegativeErrFmt, "p99LatencyBound")))
+	}
+
 	return allErrs
 }
 
@@ -225,6 +225,58 @@ func TestKey(t *testing.T) {
 	}
 }
 
+func TestAESSIVConfiguration(t *testing.T) {
+	path := root.Index(0).Child("provider").Index(0).Child("aessiv")
+	testCases := []struct {
+		desc string
+		in   *config.AESSIVConfiguration
+		want field.ErrorList
+	}{
+		{
+			desc: "valid configuration",
+			in: &config.AESSIVConfiguration{
+				Keys: []config.Key{
+					{Name: "foo", Secret: "A/j5CnrWGB83ylcPkuUhm/6TSyrQtsNJtDPwPHNOj4Q="},
+				},
+				AllowDeterministicEncryption: true,
+			},
+			want: field.ErrorList{},
+		},
+		{
+			desc: "missing deterministic encryption opt-in",
+			in: &config.AESSIVConfiguration{
+				Keys: []config.Key{
+					{Name: "foo", Secret: "A/j5CnrWGB83ylcPkuUhm/6TSyrQtsNJtDPwPHNOj4Q="},
+				},
+			},
+			want: field.ErrorList{
+				field.Invalid(path.Child("allowDeterministicEncryption"), false, deterministicOptInErr),
+			},
+		},
+		{
+			desc: "key is not of expected length",
+			in: &config.AESSIVConfiguration{
+				Keys: []config.Key{
+					{Name: "foo", Secret: "cGFzc3dvcmQK"},
+				},
+				AllowDeterministicEncryption: true,
+			},
+			want: field.ErrorList{
+				field.Invalid(path.Child("keys").Index(0).Child("secret"), "REDACTED", fmt.Sprintf(keyLenErrFmt, 9, aesSIVKeySizes)),
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := validateAESSIVConfiguration(tt.in, path)
+			if d := cmp.Diff(tt.want, got); d != "" {
+				t.Fatalf("AESSIV Provider validation mismatch (-want +got):\n%s", d)
+			}
+		})
+	}
+}
+
 func TestKMSProviderTimeout(t *testing.T) {
 	timeoutField := field.NewPath("Resource").Index(0).Child("Provider").Index(0).Child("KMS").Child("Timeout")
 	negativeTimeout := &metav1.Duration{Duration: -1 * time.Minute}
@@ -46,6 +46,7 @@ import (
 const (
 	aesCBCTransformerPrefixV1    = "k8s:enc:aescbc:v1:"
 	aesGCMTransformerPrefixV1    = "k8s:enc:aesgcm:v1:"
+	aesSIVTransformerPrefixV1    = "k8s:enc:aessiv:v1:"
 	secretboxTransformerPrefixV1 = "k8s:enc:secretbox:v1:"
 	kmsTransformerPrefixV1       = "k8s:enc:kms:v1:"
 	kmsPluginHealthzNegativeTTL  = 3 * time.Second
@@ -180,6 +181,7 @@ func parseEncryptionConfiguration(f io.Reader) (map[schema.GroupResource]value.T
 	}
 
 	resourceToPrefixTransformer := map[schema.GroupResource][]value.PrefixTransformer{}
+	resourceToCompression := map[schema.GroupResource]compressionSpec{}
 
 	// For each entry in the configuration
 	for _, resourceConfig := range config.Resources {
@@ -193,17 +195,45 @@ func parseEncryptionConfiguration(f io.Reader) (map[schema.GroupResource]value.T
 			gr := schema.ParseGroupResource(resource)
 			resourceToPrefixTransformer[gr] = append(
 				resourceToPrefixTransformer[gr], transformers...)
+			if resourceConfig.CompressionCodec != "" {
+				resourceToCompression[gr] = compressionSpec{
+					codec:          resourceConfig.CompressionCodec,
+					minSize:        int(resourceConfig.CompressionMinSize),
+					allowSensitive: resourceConfig.AllowSensitiveResourceCompression,
+				}
+			}
 		}
 	}
 
 	result := map[schema.GroupResource]value.Transformer{}
 	for gr, transList := range resourceToPrefixTransformer {
-		result[gr] = value.NewMutableTransformer(value.NewPrefixTransformers(fmt.Errorf("no matching prefix found"), transList...))
+		transformer := value.Transformer(value.NewPrefixTransformers(fmt.Errorf("no matching prefix found"), transList...))
+		if spec, found := resourceToCompression[gr]; found {
+			newCompressionTransformer := value.NewCompressionTransformer
+			if spec.allowSensitive {
+				newCompressionTransformer = value.NewCompressionTransformerForSensitiveResource
+			}
+			var err error
+			transformer, err = newCompressionTransformer(spec.codec, spec.minSize, gr.Resource, transformer)
+			if err != nil {
+				return nil, fmt.Errorf("could not configure compression for resource %q, error: %v", gr.String(), err)
+			}
+		}
+		result[gr] = value.NewMutableTransformer(transformer)
 	}
 	return result, nil
 
 }
 
+// compressionSpec is the per-resource compression configuration, applied
+// around the resources' provider chain so that values are compressed before
+// they are encrypted.
+type compressionSpec struct {
+	codec          string
+	minSize        int
+	allowSensitive bool
+}
+
 func loadConfig(data []byte) (*apiserverconfig.EncryptionConfiguration, error) {
 	scheme := runtime.NewScheme()
 	codecs := serializer.NewCodecFactory(scheme)
@@ -238,6 +268,8 @@ func prefixTransformers(config *apiserverconfig.ResourceConfiguration) ([]value.
 			transformer, err = aesPrefixTransformer(provider.AESGCM, aestransformer.NewGCMTransformer, aesGCMTransformerPrefixV1)
 		case provider.AESCBC != nil:
 			transformer, err = aesPrefixTransformer(provider.AESCBC, aestransformer.NewCBCTransformer, aesCBCTransformerPrefixV1)
+		case provider.AESSIV != nil:
+			transformer, err = aesSIVPrefixTransformer(provider.AESSIV)
 		case provider.Secretbox != nil:
 			transformer, err = secretboxPrefixTransformer(provider.Secretbox)
 		case provider.KMS != nil:
@@ -254,7 +286,7 @@ func prefixTransformers(config *apiserverconfig.ResourceConfiguration) ([]value.
 				Prefix:      []byte{},
 			}
 		default:
-			return nil, errors.New("provider does not contain any of the expected providers: KMS, AESGCM, AESCBC, Secretbox, Identity")
+			return nil, errors.New("provider does not contain any of the expected providers: KMS, AESGCM, AESCBC, AESSIV, Secretbox, Identity")
 		}
 
 		if err != nil {
@@ -314,6 +346,56 @@ func aesPrefixTransformer(config *apiserverconfig.AESConfiguration, fn blockTran
 	return result, nil
 }
 
+func aesSIVPrefixTransformer(config *apiserverconfig.AESSIVConfiguration) (value.PrefixTransformer, error) {
+	var result value.PrefixTransformer
+
+	if !config.AllowDeterministicEncryption {
+		return result, fmt.Errorf("aessiv provider requires allowDeterministicEncryption to be true, acknowledging that equal values produce equal ciphertexts in storage")
+	}
+	if len(config.Keys) == 0 {
+		return result, fmt.Errorf("aessiv provider has no valid keys")
+	}
+	for _, key := range config.Keys {
+		if key.Name == "" {
+			return result, fmt.Errorf("key with invalid name provided")
+		}
+		if key.Secret == "" {
+			return result, fmt.Errorf("key %v has no provided secret", key.Name)
+		}
+	}
+
+	keyTransformers := []value.PrefixTransformer{}
+
+	for _, keyData := range config.Keys {
+		key, err := base64.StdEncoding.DecodeString(keyData.Secret)
+		if err != nil {
+			return result, fmt.Errorf("could not obtain secret for named key %s: %s", keyData.Name, err)
+		}
+		transformer, err := aestransformer.NewSIVTransformer(key)
+		if err != nil {
+			return result, fmt.Errorf("error while creating transformer for named key %s: %s", keyData.Name, err)
+		}
+
+		// Create a new PrefixTransformer for this key
+		keyTransformers = append(keyTransformers,
+			value.PrefixTransformer{
+				Transformer: transformer,
+				Prefix:      []byte(keyData.Name + ":"),
+			})
+	}
+
+	// Create a prefixTransformer which can choose between these keys
+	keyTransformer := value.NewPrefixTransformers(
+		fmt.Errorf("no matching key was found for the provided AES-SIV transformer"), keyTransformers...)
+
+	// Create a PrefixTransformer which shall later be put in a list with other providers
+	result = value.PrefixTransformer{
+		Transformer: keyTransformer,
+		Prefix:      []byte(aesSIVTransformerPrefixV1),
+	}
+	return result, nil
+}
+
 func secretboxPrefixTransformer(config *apiserverconfig.SecretboxConfiguration) (value.PrefixTransformer, error) {
 	var result value.PrefixTransformer
 
@@ -369,8 +451,40 @@ func envelopePrefixTransformer(config *apiserverconfig.KMSConfiguration, envelop
 	if err != nil {
 		return value.PrefixTransformer{}, err
 	}
+
+	transformer := value.Transformer(envelopeTransformer)
+
+	// When retries are configured, transient kms-plugin failures are retried
+	// with backoff instead of failing the client request that triggered the
+	// transformation.
+	transformer = value.NewRetryTransformer(value.RetryConfig{
+		ProviderName: config.Name,
+		MaxRetries:   int(config.MaxRetries),
+	}, transformer)
+
+	// When a failure threshold is configured, consecutive kms-plugin failures
+	// open a circuit breaker so that transformations fail fast until the
+	// plugin has proven itself healthy again.
+	if config.FailureThreshold > 0 {
+		transformer = value.NewCircuitBreakerTransformer(value.CircuitBreakerConfig{
+			ProviderName:     config.Name,
+			FailureThreshold: int(config.FailureThreshold),
+		}, transformer)
+	}
+
+	// When load shedding bounds are configured, fail fast with a retriable
+	// error instead of queueing behind a degraded kms-plugin.
+	loadShedding := value.LoadSheddingConfig{
+		ProviderName:  config.Name,
+		MaxConcurrent: int(config.MaxInFlight),
+	}
+	if config.P99LatencyBound != nil {
+		loadShedding.P99LatencyBound = config.P99LatencyBound.Duration
+	}
+	transformer = value.NewLoadSheddingTransformer(loadShedding, transformer)
+
 	return value.PrefixTransformer{
-		Transformer: envelopeTransformer,
+		Transformer: transformer,
 		Prefix:      []byte(prefix + config.Name + ":"),
 	}, nil
 }
@@ -219,6 +219,97 @@ func TestEncryptionProviderConfigCorrect(t *testing.T) {
 	}
 }
 
+func TestEncryptionProviderConfigAESSIV(t *testing.T) {
+	correctConfigWithAesSivFirst := "testdata/valid-configs/aes-siv-first.yaml"
+	aesSivFirstTransformerOverrides, err := parseEncryptionConfiguration(mustConfigReader(t, correctConfigWithAesSivFirst))
+	if err != nil {
+		t.Fatalf("error while parsing configuration file: %s.\nThe file was:\n%s", err, correctConfigWithAesSivFirst)
+	}
+
+	transformer := aesSivFirstTransformerOverrides[schema.ParseGroupResource("secrets")]
+	context := value.DefaultContext([]byte(sampleContextText))
+	originalText := []byte(sampleText)
+
+	first, err := transformer.TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("error while transforming data to storage: %s", err)
+	}
+	if !bytes.HasPrefix(first, []byte(aesSIVTransformerPrefixV1)) {
+		t.Fatalf("unexpected prefix: %q", first)
+	}
+
+	// The first provider is deterministic, so writing the same value twice
+	// must yield the same stored data.
+	second, err := transformer.TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("error while transforming data to storage: %s", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected identical stored data, got %q and %q", first, second)
+	}
+
+	untransformedData, stale, err := transformer.TransformFromStorage(first, context)
+	if err != nil {
+		t.Fatalf("error while reading data from storage: %s", err)
+	}
+	if stale {
+		t.Fatalf("unexpected stale data on reading with the writing transformer")
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Fatalf("transformer transformed data incorrectly. Expected: %v, got %v", originalText, untransformedData)
+	}
+}
+
+func TestEncryptionProviderConfigCompression(t *testing.T) {
+	configWithCompression := "testdata/valid-configs/compression.yaml"
+	transformerOverrides, err := parseEncryptionConfiguration(mustConfigReader(t, configWithCompression))
+	if err != nil {
+		t.Fatalf("error while parsing configuration file: %s.\nThe file was:\n%s", err, configWithCompression)
+	}
+
+	transformer := transformerOverrides[schema.ParseGroupResource("configmaps")]
+	context := value.DefaultContext([]byte(sampleContextText))
+	// Repetitive content well above compressionMinSize, so it must shrink.
+	originalText := bytes.Repeat([]byte(sampleText), 100)
+
+	stored, err := transformer.TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("error while transforming data to storage: %s", err)
+	}
+	if len(stored) >= len(originalText) {
+		t.Fatalf("expected compressed stored data to be smaller than the original %d bytes, got %d", len(originalText), len(stored))
+	}
+
+	untransformedData, stale, err := transformer.TransformFromStorage(stored, context)
+	if err != nil {
+		t.Fatalf("error while reading data from storage: %s", err)
+	}
+	if stale {
+		t.Fatalf("unexpected stale data on reading with the writing transformer")
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Fatalf("transformer transformed data incorrectly. Expected %d bytes, got %d", len(originalText), len(untransformedData))
+	}
+
+	// Values written before compression was enabled are read back unchanged.
+	identityConfig := "testdata/valid-configs/aes-cbc-first.yaml"
+	uncompressedOverrides, err := parseEncryptionConfiguration(mustConfigReader(t, identityConfig))
+	if err != nil {
+		t.Fatalf("error while parsing configuration file: %s.\nThe file was:\n%s", err, identityConfig)
+	}
+	storedUncompressed, err := uncompressedOverrides[schema.ParseGroupResource("secrets")].TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("error while transforming data to storage: %s", err)
+	}
+	untransformedData, _, err = transformer.TransformFromStorage(storedUncompressed, context)
+	if err != nil {
+		t.Fatalf("error while reading pre-compression data from storage: %s", err)
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Fatalf("transformer transformed pre-compression data incorrectly. Expected %d bytes, got %d", len(originalText), len(untransformedData))
+	}
+}
+
 func TestKMSPluginHealthz(t *testing.T) {
 	service, err := envelope.NewGRPCService("unix:///tmp/testprovider.sock", 3*time.Second)
 	if err != nil {
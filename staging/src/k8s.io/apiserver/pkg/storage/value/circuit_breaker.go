@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Circuit breaker states reported in logs and metrics.
+const (
+	circuitStateClosed   = "closed"
+	circuitStateOpen     = "open"
+	circuitStateHalfOpen = "half-open"
+
+	defaultCircuitFailureThreshold = 5
+	defaultCircuitOpenDuration     = 10 * time.Second
+	defaultCircuitHalfOpenProbes   = 1
+)
+
+// CircuitOpenError is returned when a transformation is rejected without
+// reaching the provider because the provider's circuit breaker is open. The
+// error is retriable: the client request that triggered the transformation
+// can safely be retried once the provider has recovered.
+type CircuitOpenError struct {
+	// Provider identifies the provider whose circuit is open.
+	Provider string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("transformation provider %q is unavailable (circuit breaker open), the request may be retried", e.Provider)
+}
+
+// IsCircuitOpenError returns true if the error indicates the transformation
+// was rejected by an open circuit breaker and the operation may be retried.
+func IsCircuitOpenError(err error) bool {
+	var open *CircuitOpenError
+	return stderrors.As(err, &open)
+}
+
+// CircuitBreakerConfig bounds how long a single transformer provider keeps
+// absorbing transformations while it is failing.
+type CircuitBreakerConfig struct {
+	// ProviderName identifies the provider in logs, errors and metrics.
+	ProviderName string
+	// FailureThreshold is the number of consecutive provider failures after
+	// which the circuit opens. Defaults to 5 when zero; negative disables the
+	// circuit breaker.
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before recovery is
+	// probed. Defaults to 10s.
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of transformations let through in
+	// half-open state to probe recovery. Defaults to 1.
+	HalfOpenProbes int
+}
+
+type circuitBreakerTransformer struct {
+	delegate Transformer
+	config   CircuitBreakerConfig
+
+	mu                  sync.Mutex
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	probesInFlight      int
+}
+
+var _ Transformer = &circuitBreakerTransformer{}
+
+// NewCircuitBreakerTransformer wraps a transformer so that after a run of
+// consecutive provider failures further transformations fail fast with a
+// CircuitOpenError instead of each waiting out the provider's timeout, which
+// protects overall request latency while an envelope (KMS) provider is down.
+// After OpenDuration the circuit half-opens and lets a bounded number of
+// probe transformations through; one success closes it again.
+func NewCircuitBreakerTransformer(config CircuitBreakerConfig, delegate Transformer) Transformer {
+	if config.FailureThreshold < 0 {
+		return delegate
+	}
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultCircuitFailureThreshold
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = defaultCircuitOpenDuration
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = defaultCircuitHalfOpenProbes
+	}
+	return &circuitBreakerTransformer{
+		delegate: delegate,
+		config:   config,
+		state:    circuitStateClosed,
+	}
+}
+
+func (t *circuitBreakerTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	probe, err := t.admit()
+	if err != nil {
+		return nil, false, err
+	}
+	out, stale, err := t.delegate.TransformFromStorage(data, context)
+	t.observe(probe, err)
+	return out, stale, err
+}
+
+func (t *circuitBreakerTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	probe, err := t.admit()
+	if err != nil {
+		return nil, err
+	}
+	out, err := t.delegate.TransformToStorage(data, context)
+	t.observe(probe, err)
+	return out, err
+}
+
+// admit decides whether the transformation may reach the provider. It reports
+// whether the call was admitted as a half-open probe, so that observe applies
+// the half-open bookkeeping only to probes and not to calls that were admitted
+// while the circuit was still closed and merely complete after it half-opened.
+func (t *circuitBreakerTransformer) admit() (probe bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.state {
+	case circuitStateClosed:
+		return false, nil
+	case circuitStateOpen:
+		if time.Since(t.openedAt) < t.config.OpenDuration {
+			RecordCircuitBreakerRejection(t.config.ProviderName)
+			return false, &CircuitOpenError{Provider: t.config.ProviderName}
+		}
+		t.transitionLocked(circuitStateHalfOpen)
+		t.probesInFlight = 0
+		fallthrough
+	case circuitStateHalfOpen:
+		if t.probesInFlight >= t.config.HalfOpenProbes {
+			RecordCircuitBreakerRejection(t.config.ProviderName)
+			return false, &CircuitOpenError{Provider: t.config.ProviderName}
+		}
+		t.probesInFlight++
+		return true, nil
+	}
+	return false, nil
+}
+
+// observe feeds the outcome of one provider call back into the breaker.
+func (t *circuitBreakerTransformer) observe(probe bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if probe {
+		if t.state != circuitStateHalfOpen {
+			// The breaker left half-open while the probe was in flight; the
+			// probe's outcome has already been superseded.
+			return
+		}
+		t.probesInFlight--
+		if err != nil {
+			// The probe failed, the provider has not recovered yet.
+			t.openedAt = time.Now()
+			t.transitionLocked(circuitStateOpen)
+			return
+		}
+		t.consecutiveFailures = 0
+		t.transitionLocked(circuitStateClosed)
+		return
+	}
+	if t.state != circuitStateClosed {
+		// A call admitted while the circuit was still closed completed after
+		// the breaker opened or half-opened; its outcome must not influence
+		// the probing.
+		return
+	}
+
+	if err == nil {
+		t.consecutiveFailures = 0
+		return
+	}
+	t.consecutiveFailures++
+	if t.state == circuitStateClosed && t.consecutiveFailures >= t.config.FailureThreshold {
+		t.openedAt = time.Now()
+		t.transitionLocked(circuitStateOpen)
+	}
+}
+
+// transitionLocked records a state change. The caller must hold t.mu.
+func (t *circuitBreakerTransformer) transitionLocked(state string) {
+	klog.V(2).Infof("Circuit breaker for transformation provider %q transitioned from %s to %s", t.config.ProviderName, t.state, state)
+	t.state = state
+	RecordCircuitBreakerStateChange(t.config.ProviderName, state)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// toggleTransformer fails while err is set and counts delegate calls.
+type toggleTransformer struct {
+	err   error
+	calls int
+}
+
+func (t *toggleTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	t.calls++
+	return data, false, t.err
+}
+
+func (t *toggleTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	t.calls++
+	return data, t.err
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	toggle := &toggleTransformer{err: errors.New("provider unavailable")}
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{
+		ProviderName:     "kms-test",
+		FailureThreshold: 2,
+		OpenDuration:     time.Hour,
+	}, toggle)
+
+	for i := 0; i < 2; i++ {
+		if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !errors.Is(err, toggle.err) {
+			t.Fatalf("expected the provider error while the circuit is closed, got %v", err)
+		}
+	}
+
+	_, err := transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	if !IsCircuitOpenError(err) {
+		t.Errorf("expected CircuitOpenError after the failure threshold, got %v", err)
+	}
+	if _, _, err := transformer.TransformFromStorage([]byte("data"), DefaultContext("")); !IsCircuitOpenError(err) {
+		t.Errorf("expected CircuitOpenError on reads as well, got %v", err)
+	}
+	if toggle.calls != 2 {
+		t.Errorf("expected the provider to not be reached while the circuit is open, got %d calls", toggle.calls)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailureCount(t *testing.T) {
+	toggle := &toggleTransformer{err: errors.New("provider unavailable")}
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{
+		ProviderName:     "kms-test",
+		FailureThreshold: 2,
+		OpenDuration:     time.Hour,
+	}, toggle)
+
+	transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	toggle.err = nil
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The failures were not consecutive, so one more must reach the provider.
+	toggle.err = errors.New("provider unavailable")
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !errors.Is(err, toggle.err) {
+		t.Errorf("expected the provider error while the circuit is still closed, got %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenRecovery(t *testing.T) {
+	toggle := &toggleTransformer{err: errors.New("provider unavailable")}
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{
+		ProviderName:     "kms-test",
+		FailureThreshold: 1,
+		OpenDuration:     time.Millisecond,
+	}, toggle)
+
+	transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !IsCircuitOpenError(err) {
+		t.Fatalf("expected CircuitOpenError while the circuit is open, got %v", err)
+	}
+
+	// After OpenDuration a probe is let through; its success closes the circuit.
+	toggle.err = nil
+	time.Sleep(5 * time.Millisecond)
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+			t.Errorf("unexpected error after the circuit closed: %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	toggle := &toggleTransformer{err: errors.New("provider unavailable")}
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{
+		ProviderName:     "kms-test",
+		FailureThreshold: 1,
+		OpenDuration:     time.Millisecond,
+	}, toggle)
+
+	transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	time.Sleep(5 * time.Millisecond)
+
+	// The probe fails, so the circuit opens again for a full OpenDuration.
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !errors.Is(err, toggle.err) {
+		t.Fatalf("expected the probe to reach the provider, got %v", err)
+	}
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !IsCircuitOpenError(err) {
+		t.Errorf("expected CircuitOpenError after a failed probe, got %v", err)
+	}
+}
+
+// TestCircuitBreakerStaleCallDoesNotCountAsProbe verifies that a call
+// admitted while the circuit was still closed, but completing only after the
+// circuit half-opened, neither frees a probe slot nor closes the circuit.
+func TestCircuitBreakerStaleCallDoesNotCountAsProbe(t *testing.T) {
+	toggle := &toggleTransformer{err: errors.New("provider unavailable")}
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{
+		ProviderName:     "kms-test",
+		FailureThreshold: 1,
+		OpenDuration:     time.Millisecond,
+	}, toggle).(*circuitBreakerTransformer)
+
+	// A slow call is admitted while the circuit is still closed.
+	staleProbe, err := transformer.admit()
+	if err != nil {
+		t.Fatalf("unexpected error while the circuit is closed: %v", err)
+	}
+	if staleProbe {
+		t.Fatalf("a call admitted while the circuit is closed must not be a probe")
+	}
+
+	// The provider starts failing, the circuit opens and then half-opens.
+	transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	time.Sleep(5 * time.Millisecond)
+
+	// A probe is admitted and is still in flight when the slow call finishes.
+	probe, err := transformer.admit()
+	if err != nil || !probe {
+		t.Fatalf("expected a probe to be admitted in half-open, got probe=%v err=%v", probe, err)
+	}
+
+	// The slow call completes successfully; it must not close the circuit or
+	// free the probe slot.
+	transformer.observe(staleProbe, nil)
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !IsCircuitOpenError(err) {
+		t.Errorf("expected CircuitOpenError while the real probe is in flight, got %v", err)
+	}
+
+	// Only the real probe's success closes the circuit.
+	transformer.observe(probe, nil)
+	toggle.err = nil
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+		t.Errorf("unexpected error after the probe closed the circuit: %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	transformer := NewCircuitBreakerTransformer(CircuitBreakerConfig{ProviderName: "kms-test", FailureThreshold: -1}, IdentityTransformer)
+	if transformer != IdentityTransformer {
+		t.Errorf("expected the delegate to be returned unwrapped when the circuit breaker is disabled")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// CompressionCodec compresses values before they are handed to an encrypting
+// transformer. Implementations must be safe for concurrent use.
+type CompressionCodec interface {
+	// Name identifies the codec. It is stored alongside the value, so it must
+	// stay stable across releases.
+	Name() string
+	// Compress returns the compressed representation of data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress reverses Compress.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// Codec names reserved for the common codecs. The gzip codec is built in;
+// snappy and zstd implementations can be plugged in through
+// RegisterCompressionCodec by builds that vendor the libraries.
+const (
+	GzipCodecName   = "gzip"
+	SnappyCodecName = "snappy"
+	ZstdCodecName   = "zstd"
+)
+
+var (
+	codecsLock sync.RWMutex
+	codecs     = map[string]CompressionCodec{}
+)
+
+// RegisterCompressionCodec makes a codec available to compression
+// transformers and to reads of values written with it. Registering two codecs
+// with the same name is a programmer error.
+func RegisterCompressionCodec(codec CompressionCodec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	if _, found := codecs[codec.Name()]; found {
+		panic(fmt.Sprintf("compression codec %q registered twice", codec.Name()))
+	}
+	codecs[codec.Name()] = codec
+}
+
+func getCompressionCodec(name string) (CompressionCodec, bool) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	codec, found := codecs[name]
+	return codec, found
+}
+
+func init() {
+	RegisterCompressionCodec(gzipCodec{})
+}
+
+// gzipCodec is the built-in codec, backed by the standard library.
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return GzipCodecName }
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// DefaultMinCompressSize is the size below which values are stored
+// uncompressed. Small values do not compress well and the codec overhead
+// would dominate.
+const DefaultMinCompressSize = 1024
+
+// compressedValuePrefix marks compressed plaintext. The codec name and a
+// trailing colon follow. Uncompressed values are stored without a prefix;
+// the prefix cannot collide with stored objects because both the protobuf
+// storage magic ("k8s\x00") and JSON diverge from it within the first four
+// bytes.
+const compressedValuePrefix = "k8s:cmp:"
+
+// sensitiveResources must not be compressed together with encryption unless
+// the operator explicitly opts in, see NewCompressionTransformer.
+var sensitiveResources = map[string]struct{}{
+	"secrets": {},
+}
+
+// compressionTransformer compresses plaintext before delegating to an
+// encrypting transformer and decompresses after the delegate has decrypted.
+type compressionTransformer struct {
+	codec    CompressionCodec
+	minSize  int
+	resource string
+	delegate Transformer
+}
+
+// NewCompressionTransformer wraps delegate, typically an AEAD transformer,
+// with transparent compression of values of at least minSize bytes (pass 0
+// for DefaultMinCompressSize) using the named registered codec. Values that
+// do not shrink are stored uncompressed, and values written before
+// compression was enabled for the resource are read back unchanged, so the
+// transformer can be introduced and removed without migration.
+//
+// Compressing attacker-influenced plaintext before encrypting it leaks
+// information about the plaintext through the ciphertext length, the same
+// class of weakness as the CRIME and BREACH attacks on TLS. For most
+// resources the content is readable by anybody who can list them anyway, but
+// for Secrets an adversary who can watch etcd value sizes while repeatedly
+// updating adjacent data could learn secret content. Compression of the
+// resources in sensitiveResources is therefore rejected here; operators who
+// understand the trade-off can opt in with
+// NewCompressionTransformerForSensitiveResource.
+func NewCompressionTransformer(codecName string, minSize int, resource string, delegate Transformer) (Transformer, error) {
+	if _, sensitive := sensitiveResources[resource]; sensitive {
+		return nil, fmt.Errorf("compression of %q is disabled by default because compressing before encryption can leak plaintext through ciphertext lengths", resource)
+	}
+	return newCompressionTransformer(codecName, minSize, resource, delegate)
+}
+
+// NewCompressionTransformerForSensitiveResource is NewCompressionTransformer
+// without the guard against compressing sensitive resources. See there for
+// the compression-oracle concerns this acknowledges.
+func NewCompressionTransformerForSensitiveResource(codecName string, minSize int, resource string, delegate Transformer) (Transformer, error) {
+	return newCompressionTransformer(codecName, minSize, resource, delegate)
+}
+
+func newCompressionTransformer(codecName string, minSize int, resource string, delegate Transformer) (Transformer, error) {
+	codec, found := getCompressionCodec(codecName)
+	if !found {
+		return nil, fmt.Errorf("compression codec %q is not registered", codecName)
+	}
+	if minSize <= 0 {
+		minSize = DefaultMinCompressSize
+	}
+	return &compressionTransformer{
+		codec:    codec,
+		minSize:  minSize,
+		resource: resource,
+		delegate: delegate,
+	}, nil
+}
+
+func (t *compressionTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	data, stale, err := t.delegate.TransformFromStorage(data, context)
+	if err != nil {
+		return nil, false, err
+	}
+	rest := bytes.TrimPrefix(data, []byte(compressedValuePrefix))
+	if len(rest) == len(data) {
+		// Written uncompressed, either below minSize or before compression
+		// was enabled for the resource.
+		return data, stale, nil
+	}
+	sep := bytes.IndexByte(rest, ':')
+	if sep < 0 {
+		return nil, false, fmt.Errorf("compressed value has no codec name")
+	}
+	codecName := string(rest[:sep])
+	codec := t.codec
+	if codecName != codec.Name() {
+		// Read with whatever codec wrote the value, and let the caller
+		// rewrite it with the current one.
+		var found bool
+		if codec, found = getCompressionCodec(codecName); !found {
+			return nil, false, fmt.Errorf("compression codec %q is not registered", codecName)
+		}
+		stale = true
+	}
+	out, err := codec.Decompress(rest[sep+1:])
+	if err != nil {
+		return nil, false, fmt.Errorf("decompressing value with codec %q: %v", codecName, err)
+	}
+	RecordCompression(codec.Name(), t.resource, "decompress", len(out), len(rest)-sep-1)
+	return out, stale, nil
+}
+
+func (t *compressionTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	if len(data) < t.minSize {
+		RecordCompression(t.codec.Name(), t.resource, "skipped_small", len(data), len(data))
+		return t.delegate.TransformToStorage(data, context)
+	}
+	compressed, err := t.codec.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("compressing value with codec %q: %v", t.codec.Name(), err)
+	}
+	prefix := compressedValuePrefix + t.codec.Name() + ":"
+	if len(prefix)+len(compressed) >= len(data) {
+		// Incompressible data, e.g. already compressed content; storing it
+		// raw keeps reads cheap and the value small.
+		RecordCompression(t.codec.Name(), t.resource, "skipped_incompressible", len(data), len(data))
+		return t.delegate.TransformToStorage(data, context)
+	}
+	RecordCompression(t.codec.Name(), t.resource, "compress", len(data), len(compressed))
+	out := make([]byte, 0, len(prefix)+len(compressed))
+	out = append(out, prefix...)
+	out = append(out, compressed...)
+	return t.delegate.TransformToStorage(out, context)
+}
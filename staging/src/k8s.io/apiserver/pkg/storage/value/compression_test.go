@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func compressibleValue(size int) []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	transformer, err := NewCompressionTransformer(GzipCodecName, 0, "configmaps", IdentityTransformer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := compressibleValue(4096)
+
+	stored, err := transformer.TransformToStorage(original, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(stored, []byte(compressedValuePrefix+GzipCodecName+":")) {
+		t.Errorf("expected the stored value to carry the codec prefix, got %q", stored[:16])
+	}
+	if len(stored) >= len(original) {
+		t.Errorf("expected the stored value to shrink, got %d >= %d bytes", len(stored), len(original))
+	}
+
+	out, stale, err := transformer.TransformFromStorage(stored, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale {
+		t.Error("expected the value not to be stale")
+	}
+	if !bytes.Equal(out, original) {
+		t.Error("round-tripped value differs from the original")
+	}
+}
+
+func TestCompressionSkipsSmallValues(t *testing.T) {
+	transformer, err := NewCompressionTransformer(GzipCodecName, 0, "configmaps", IdentityTransformer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := compressibleValue(128)
+
+	stored, err := transformer.TransformToStorage(original, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, original) {
+		t.Error("expected a value below the minimum size to be stored unchanged")
+	}
+	out, _, err := transformer.TransformFromStorage(stored, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, original) {
+		t.Error("round-tripped value differs from the original")
+	}
+}
+
+func TestCompressionSkipsIncompressibleValues(t *testing.T) {
+	transformer, err := NewCompressionTransformer(GzipCodecName, 0, "configmaps", IdentityTransformer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := make([]byte, 4096)
+	if _, err := rand.Read(original); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := transformer.TransformToStorage(original, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, original) {
+		t.Error("expected an incompressible value to be stored unchanged")
+	}
+}
+
+func TestCompressionReadsLegacyValues(t *testing.T) {
+	transformer, err := NewCompressionTransformer(GzipCodecName, 0, "configmaps", IdentityTransformer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := compressibleValue(4096)
+
+	// Written before compression was enabled for the resource.
+	out, stale, err := transformer.TransformFromStorage(original, DefaultContext(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale {
+		t.Error("expected legacy values not to be reported stale")
+	}
+	if !bytes.Equal(out, original) {
+		t.Error("legacy value differs from the original")
+	}
+}
+
+func TestCompressionRejectsSecretsByDefault(t *testing.T) {
+	if _, err := NewCompressionTransformer(GzipCodecName, 0, "secrets", IdentityTransformer); err == nil {
+		t.Error("expected compression of secrets to be rejected")
+	} else if !strings.Contains(err.Error(), "disabled by default") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewCompressionTransformerForSensitiveResource(GzipCodecName, 0, "secrets", IdentityTransformer); err != nil {
+		t.Errorf("expected the explicit opt-in to be accepted, got %v", err)
+	}
+}
+
+func TestCompressionRejectsUnknownCodec(t *testing.T) {
+	if _, err := NewCompressionTransformer(ZstdCodecName, 0, "configmaps", IdentityTransformer); err == nil {
+		t.Error("expected an unregistered codec to be rejected")
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+
+	"k8s.io/apiserver/pkg/storage/value"
+)
+
+// siv implements deterministic authenticated encryption with AES-SIV
+// (RFC 5297, https://tools.ietf.org/html/rfc5297). The synthetic IV is
+// derived from the plaintext and the authenticated data instead of being
+// generated randomly, so encrypting the same value with the same
+// authenticated data always yields the same output.
+//
+// Determinism is a deliberate trade-off: it keeps encrypted values
+// equality-comparable in storage, but it also reveals to anyone with access
+// to the stored data which values are equal. This mode must only be chosen
+// for resources where that leak is acceptable; callers are expected to gate
+// its use behind an explicit opt-in. Unlike AES-GCM, nonce reuse cannot
+// compromise the key, which makes key rotation a matter of hygiene rather
+// than of safety.
+//
+// The first half of the key is used to derive the synthetic IV (S2V with
+// AES-CMAC) and the second half to encrypt with AES-CTR. The authenticated
+// data provided as part of the value.Context method is bound as the single
+// associated data string and must match when the same value is set to and
+// loaded from storage.
+type siv struct {
+	macBlock cipher.Block
+	ctrBlock cipher.Block
+	// cmacK1 and cmacK2 are the AES-CMAC subkeys derived from macBlock.
+	cmacK1 []byte
+	cmacK2 []byte
+}
+
+// NewSIVTransformer takes a key of 32 or 64 bytes and performs deterministic
+// encryption and decryption on the given data with AES-SIV. The first half of
+// the key is used for authentication, the second half for encryption.
+func NewSIVTransformer(key []byte) (value.Transformer, error) {
+	switch len(key) {
+	case 32, 64:
+	default:
+		return nil, fmt.Errorf("expected AES-SIV key size 32 or 64, got %d", len(key))
+	}
+	macBlock, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctrBlock, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	cmacK1, cmacK2 := cmacSubkeys(macBlock)
+	return &siv{macBlock: macBlock, ctrBlock: ctrBlock, cmacK1: cmacK1, cmacK2: cmacK2}, nil
+}
+
+func (t *siv) TransformFromStorage(data []byte, context value.Context) ([]byte, bool, error) {
+	if len(data) < aes.BlockSize {
+		return nil, false, fmt.Errorf("the stored data was shorter than the required size")
+	}
+	iv := data[:aes.BlockSize]
+	out := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCTR(t.ctrBlock, counterBlock(iv)).XORKeyStream(out, data[aes.BlockSize:])
+	expected := t.s2v(context.AuthenticatedData(), out)
+	if subtle.ConstantTimeCompare(iv, expected) != 1 {
+		return nil, false, fmt.Errorf("the stored data failed authentication")
+	}
+	return out, false, nil
+}
+
+func (t *siv) TransformToStorage(data []byte, context value.Context) ([]byte, error) {
+	iv := t.s2v(context.AuthenticatedData(), data)
+	result := make([]byte, aes.BlockSize+len(data))
+	copy(result, iv)
+	cipher.NewCTR(t.ctrBlock, counterBlock(iv)).XORKeyStream(result[aes.BlockSize:], data)
+	return result, nil
+}
+
+// s2v derives the synthetic IV from the additional data and the plaintext,
+// as defined in RFC 5297 section 2.4 with the additional data as the single
+// associated data string.
+func (t *siv) s2v(additionalData, plaintext []byte) []byte {
+	d := t.cmac(make([]byte, aes.BlockSize))
+	d = xorBlock(double(d), t.cmac(additionalData))
+	if len(plaintext) >= aes.BlockSize {
+		return t.cmac(xorEnd(plaintext, d))
+	}
+	return t.cmac(xorBlock(double(d), padBlock(plaintext)))
+}
+
+// cmac computes AES-CMAC (RFC 4493) of data under the authentication key.
+func (t *siv) cmac(data []byte) []byte {
+	x := make([]byte, aes.BlockSize)
+	last := make([]byte, aes.BlockSize)
+	if l := len(data); l > 0 && l%aes.BlockSize == 0 {
+		for len(data) > aes.BlockSize {
+			t.macBlock.Encrypt(x, xorBlock(x, data[:aes.BlockSize]))
+			data = data[aes.BlockSize:]
+		}
+		copy(last, xorBlock(data, t.cmacK1))
+	} else {
+		for len(data) >= aes.BlockSize {
+			t.macBlock.Encrypt(x, xorBlock(x, data[:aes.BlockSize]))
+			data = data[aes.BlockSize:]
+		}
+		copy(last, xorBlock(padBlock(data), t.cmacK2))
+	}
+	t.macBlock.Encrypt(x, xorBlock(x, last))
+	return x
+}
+
+// cmacSubkeys derives the two AES-CMAC subkeys from the cipher.
+func cmacSubkeys(block cipher.Block) ([]byte, []byte) {
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 := double(l)
+	k2 := double(k1)
+	return k1, k2
+}
+
+// double multiplies the block by x in GF(2^128), as used by both CMAC subkey
+// derivation and S2V.
+func double(block []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	carry := byte(0)
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		out[i] = block[i]<<1 | carry
+		carry = block[i] >> 7
+	}
+	if carry != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// xorBlock returns the XOR of two full blocks.
+func xorBlock(a, b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	for i := range out {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// xorEnd returns a copy of data with the block XORed into its last 16 bytes.
+func xorEnd(data, block []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	offset := len(data) - aes.BlockSize
+	for i := 0; i < aes.BlockSize; i++ {
+		out[offset+i] ^= block[i]
+	}
+	return out
+}
+
+// padBlock pads data shorter than a block with a single 0x80 byte followed by
+// zeros, as defined in RFC 4493.
+func padBlock(data []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, data)
+	out[len(data)] = 0x80
+	return out
+}
+
+// counterBlock returns the initial CTR counter block for the synthetic IV,
+// with the 31st and 63rd bits cleared as required by RFC 5297 section 2.5.
+func counterBlock(iv []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, iv)
+	out[8] &= 0x7f
+	out[12] &= 0x7f
+	return out
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"k8s.io/apiserver/pkg/storage/value"
+)
+
+func TestSIVTestVector(t *testing.T) {
+	// Deterministic authenticated encryption example from RFC 5297 appendix A.1.
+	key, err := hex.DecodeString("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad, err := hex.DecodeString("101112131415161718191a1b1c1d1e1f2021222324252627")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := hex.DecodeString("112233445566778899aabbccddee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := hex.DecodeString("85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transformer, err := NewSIVTransformer(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := transformer.TransformToStorage(plaintext, value.DefaultContext(ad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, out) {
+		t.Fatalf("unexpected output: got %x, want %x", out, expected)
+	}
+}
+
+func TestSIVDeterministic(t *testing.T) {
+	transformer, err := NewSIVTransformer([]byte("abcdefghijklmnopqrstuvwxyz123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	// Values from 0 to beyond a block in length, to exercise both S2V paths.
+	for i := 0; i < 40; i++ {
+		data := bytes.Repeat([]byte("a"), i)
+		first, err := transformer.TransformToStorage(data, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := transformer.TransformToStorage(data, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Fatalf("length %d: expected identical ciphertexts, got %x and %x", i, first, second)
+		}
+		from, stale, err := transformer.TransformFromStorage(first, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stale || !bytes.Equal(data, from) {
+			t.Fatalf("length %d: unexpected data: %t %q", i, stale, from)
+		}
+	}
+}
+
+func TestSIVAuthentication(t *testing.T) {
+	transformer, err := NewSIVTransformer([]byte("abcdefghijklmnopqrstuvwxyz123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	out, err := transformer.TransformToStorage([]byte("firstvalue"), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify changing the context fails storage
+	if _, _, err := transformer.TransformFromStorage(out, value.DefaultContext([]byte("incorrect_context"))); err == nil {
+		t.Fatalf("expected unauthenticated data")
+	}
+
+	// verify modifying the stored data fails storage
+	for i := range out {
+		tampered := make([]byte, len(out))
+		copy(tampered, out)
+		tampered[i] ^= 0x01
+		if _, _, err := transformer.TransformFromStorage(tampered, context); err == nil {
+			t.Fatalf("expected modification of byte %d to fail authentication", i)
+		}
+	}
+
+	// verify truncated data fails storage
+	if _, _, err := transformer.TransformFromStorage(out[:8], context); err == nil {
+		t.Fatalf("expected truncated data to fail")
+	}
+}
+
+func TestSIVKeyRotation(t *testing.T) {
+	testErr := fmt.Errorf("test error")
+	transformer1, err := NewSIVTransformer([]byte("abcdefghijklmnopqrstuvwxyz123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformer2, err := NewSIVTransformer([]byte("6543210zyxwvutsrqponmlkjihgfedcb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	p := value.NewPrefixTransformers(testErr,
+		value.PrefixTransformer{Prefix: []byte("first:"), Transformer: transformer1},
+		value.PrefixTransformer{Prefix: []byte("second:"), Transformer: transformer2},
+	)
+	out, err := p.TransformToStorage([]byte("firstvalue"), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("first:")) {
+		t.Fatalf("unexpected prefix: %q", out)
+	}
+	from, stale, err := p.TransformFromStorage(out, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale || !bytes.Equal([]byte("firstvalue"), from) {
+		t.Fatalf("unexpected data: %t %q", stale, from)
+	}
+
+	// reverse the order, use the second key
+	p = value.NewPrefixTransformers(testErr,
+		value.PrefixTransformer{Prefix: []byte("second:"), Transformer: transformer2},
+		value.PrefixTransformer{Prefix: []byte("first:"), Transformer: transformer1},
+	)
+	from, stale, err = p.TransformFromStorage(out, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stale || !bytes.Equal([]byte("firstvalue"), from) {
+		t.Fatalf("unexpected data: %t %q", stale, from)
+	}
+}
+
+func TestSIVKeySizes(t *testing.T) {
+	for _, size := range []int{32, 64} {
+		if _, err := NewSIVTransformer(bytes.Repeat([]byte("a"), size)); err != nil {
+			t.Errorf("expected key size %d to be accepted: %v", size, err)
+		}
+	}
+	for _, size := range []int{0, 16, 31, 48, 65} {
+		if _, err := NewSIVTransformer(bytes.Repeat([]byte("a"), size)); err == nil {
+			t.Errorf("expected key size %d to be rejected", size)
+		}
+	}
+}
@@ -331,6 +331,31 @@ func TestGRPCService(t *testing.T) {
 	}
 }
 
+// TestGRPCServiceConformance runs the Service conformance suite shared with
+// the other envelope services against the gRPC service.
+func TestGRPCServiceConformance(t *testing.T) {
+	t.Parallel()
+	// Start a test gRPC server.
+	endpoint := newEndpoint()
+	f, err := mock.NewBase64Plugin(endpoint.path)
+	if err != nil {
+		t.Fatalf("failed to construct test KMS provider server, error: %v", err)
+	}
+	if err := f.Start(); err != nil {
+		t.Fatalf("Failed to start kms-plugin, err: %v", err)
+	}
+	defer f.CleanUp()
+
+	// Create the gRPC client service.
+	service, err := NewGRPCService(endpoint.endpoint, 1*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create envelope service, error: %v", err)
+	}
+	defer destroyService(service)
+
+	testEnvelopeServiceConformance(t, service)
+}
+
 // Normal encryption and decryption operation by multiple go-routines.
 func TestGRPCServiceConcurrentAccess(t *testing.T) {
 	t.Parallel()
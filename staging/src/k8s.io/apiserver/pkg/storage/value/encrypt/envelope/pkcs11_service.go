@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"fmt"
+	"time"
+)
+
+// PKCS11Module is the subset of a PKCS#11 (Cryptoki) implementation needed
+// for envelope key operations. A binding to a real module, for example one
+// built on github.com/miekg/pkcs11, is provided by the caller so that this
+// package does not link against cgo-based PKCS#11 wrappers; tests use an
+// in-memory fake.
+type PKCS11Module interface {
+	// OpenSession logs into the token and returns a session ready for key
+	// operations with the configured key.
+	OpenSession() (PKCS11Session, error)
+}
+
+// PKCS11Session is a single logged-in session with a PKCS#11 token.
+// Implementations do not need to be thread-safe; the service guarantees that
+// a session is only used by one operation at a time.
+type PKCS11Session interface {
+	// Encrypt encrypts a DEK with the key encryption key held by the token.
+	Encrypt(data []byte) ([]byte, error)
+	// Decrypt decrypts a DEK with the key encryption key held by the token.
+	Decrypt(data []byte) ([]byte, error)
+	// Healthy reports whether the session, and the token behind it, is
+	// still usable. It is allowed to perform a cheap operation against the
+	// token.
+	Healthy() bool
+	// Close logs out and releases the session.
+	Close() error
+}
+
+const (
+	// defaultPKCS11PoolSize is the number of sessions kept open with the
+	// token when the caller does not specify one.
+	defaultPKCS11PoolSize = 4
+	// defaultPKCS11HealthInterval is how long a pooled session is trusted
+	// before its health is re-verified on acquisition.
+	defaultPKCS11HealthInterval = 1 * time.Minute
+)
+
+// pooledSession decorates a session with the time its health was last
+// verified so acquisition does not have to hit the token on every operation.
+type pooledSession struct {
+	PKCS11Session
+	lastChecked time.Time
+}
+
+// pkcs11Service implements the envelope Service by performing key operations
+// through sessions with a local PKCS#11 token, for environments that prohibit
+// network KMS services. Sessions are pooled up to a fixed size and their
+// health is re-verified periodically; sessions that fail an operation or a
+// health check are discarded and replaced.
+type pkcs11Service struct {
+	module         PKCS11Module
+	idle           chan *pooledSession
+	healthInterval time.Duration
+
+	// slots is a capacity semaphore bounding the number of open sessions. A
+	// slot is consumed when a session is opened and returned when a session
+	// is discarded, so callers blocked at the pool limit are woken when a
+	// broken session frees its slot, not only when a session is released.
+	slots chan struct{}
+}
+
+// NewPKCS11Service returns an envelope Service which performs key operations
+// through the given PKCS#11 module. poolSize bounds the number of
+// concurrently open sessions and healthInterval how long a pooled session is
+// trusted between health checks; zero values select defaults.
+func NewPKCS11Service(module PKCS11Module, poolSize int, healthInterval time.Duration) (Service, error) {
+	if module == nil {
+		return nil, fmt.Errorf("PKCS#11 module is nil")
+	}
+	if poolSize < 0 {
+		return nil, fmt.Errorf("PKCS#11 session pool size %d must not be negative", poolSize)
+	}
+	if poolSize == 0 {
+		poolSize = defaultPKCS11PoolSize
+	}
+	if healthInterval == 0 {
+		healthInterval = defaultPKCS11HealthInterval
+	}
+	s := &pkcs11Service{
+		module:         module,
+		idle:           make(chan *pooledSession, poolSize),
+		healthInterval: healthInterval,
+		slots:          make(chan struct{}, poolSize),
+	}
+	for i := 0; i < poolSize; i++ {
+		s.slots <- struct{}{}
+	}
+	// Open one session eagerly so that misconfigured modules are caught at
+	// startup rather than on the first write.
+	sess, err := s.acquire()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open initial PKCS#11 session: %v", err)
+	}
+	s.release(sess)
+	return s, nil
+}
+
+// Encrypt encrypts a DEK through a pooled token session.
+func (s *pkcs11Service) Encrypt(data []byte) ([]byte, error) {
+	sess, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	out, err := sess.Encrypt(data)
+	if err != nil {
+		s.discard(sess)
+		return nil, err
+	}
+	s.release(sess)
+	return out, nil
+}
+
+// Decrypt decrypts a DEK through a pooled token session.
+func (s *pkcs11Service) Decrypt(data []byte) ([]byte, error) {
+	sess, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	out, err := sess.Decrypt(data)
+	if err != nil {
+		s.discard(sess)
+		return nil, err
+	}
+	s.release(sess)
+	return out, nil
+}
+
+// acquire returns a healthy session, reusing an idle one when possible,
+// opening a new one while under the pool limit, and otherwise blocking until
+// a session is released or a discarded session frees its slot.
+func (s *pkcs11Service) acquire() (*pooledSession, error) {
+	for {
+		select {
+		case sess := <-s.idle:
+			if s.healthy(sess) {
+				return sess, nil
+			}
+			s.discard(sess)
+			continue
+		default:
+		}
+
+		// No idle session right now; wait for one to be released, or for a
+		// free slot to open a new one.
+		select {
+		case sess := <-s.idle:
+			if s.healthy(sess) {
+				return sess, nil
+			}
+			s.discard(sess)
+		case <-s.slots:
+			sess, err := s.module.OpenSession()
+			if err != nil {
+				s.slots <- struct{}{}
+				return nil, fmt.Errorf("failed to open PKCS#11 session: %v", err)
+			}
+			return &pooledSession{PKCS11Session: sess, lastChecked: time.Now()}, nil
+		}
+	}
+}
+
+// release puts a session back into the idle pool.
+func (s *pkcs11Service) release(sess *pooledSession) {
+	s.idle <- sess
+}
+
+// discard closes a broken session and returns its slot to the pool, waking
+// any caller blocked at the pool limit.
+func (s *pkcs11Service) discard(sess *pooledSession) {
+	sess.Close()
+	s.slots <- struct{}{}
+}
+
+// healthy re-verifies the session against the token when its last check is
+// older than the health interval.
+func (s *pkcs11Service) healthy(sess *pooledSession) bool {
+	if time.Since(sess.lastChecked) < s.healthInterval {
+		return true
+	}
+	if !sess.Healthy() {
+		return false
+	}
+	sess.lastChecked = time.Now()
+	return true
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apiserver/pkg/storage/value"
+	aestransformer "k8s.io/apiserver/pkg/storage/value/encrypt/aes"
+)
+
+// fakePKCS11Module simulates a local PKCS#11 token for testing the session
+// pooling and health checking of the service.
+type fakePKCS11Module struct {
+	mu           sync.Mutex
+	opened       int
+	closed       int
+	healthChecks int
+	openErr      error
+}
+
+func (m *fakePKCS11Module) OpenSession() (PKCS11Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.openErr != nil {
+		return nil, m.openErr
+	}
+	m.opened++
+	return &fakePKCS11Session{module: m, healthy: true}, nil
+}
+
+type fakePKCS11Session struct {
+	module  *fakePKCS11Module
+	healthy bool
+	failOps bool
+	closed  bool
+}
+
+func (s *fakePKCS11Session) Encrypt(data []byte) ([]byte, error) {
+	if s.failOps {
+		return nil, fmt.Errorf("token removed")
+	}
+	return []byte("kek:" + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (s *fakePKCS11Session) Decrypt(data []byte) ([]byte, error) {
+	if s.failOps {
+		return nil, fmt.Errorf("token removed")
+	}
+	if !strings.HasPrefix(string(data), "kek:") {
+		return nil, fmt.Errorf("invalid ciphertext: %q", data)
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimPrefix(string(data), "kek:"))
+}
+
+func (s *fakePKCS11Session) Healthy() bool {
+	s.module.mu.Lock()
+	defer s.module.mu.Unlock()
+	s.module.healthChecks++
+	return s.healthy
+}
+
+func (s *fakePKCS11Session) Close() error {
+	s.closed = true
+	s.module.mu.Lock()
+	defer s.module.mu.Unlock()
+	s.module.closed++
+	return nil
+}
+
+func TestPKCS11ServiceRoundTrip(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	plain := []byte(testText)
+	cipher, err := service.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	got, err := service.Decrypt(cipher)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("round trip mismatch: got %q, want %q", got, plain)
+	}
+}
+
+// TestPKCS11ServiceConformance runs the Service conformance suite shared
+// with the other envelope services against the PKCS#11 service.
+func TestPKCS11ServiceConformance(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	testEnvelopeServiceConformance(t, service)
+}
+
+func TestPKCS11SessionPooling(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	// Sequential operations must reuse the initial session.
+	for i := 0; i < 10; i++ {
+		if _, err := service.Encrypt([]byte(testText)); err != nil {
+			t.Fatalf("encrypt failed: %v", err)
+		}
+	}
+	if module.opened != 1 {
+		t.Errorf("sequential operations opened %d sessions, want 1", module.opened)
+	}
+
+	// Concurrent operations may open more sessions, but never more than the
+	// pool size.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.Encrypt([]byte(testText)); err != nil {
+				t.Errorf("concurrent encrypt failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if module.opened > 2 {
+		t.Errorf("concurrent operations opened %d sessions, want at most 2", module.opened)
+	}
+}
+
+func TestPKCS11HealthChecks(t *testing.T) {
+	module := &fakePKCS11Module{}
+	// A tiny health interval forces a check on every acquisition.
+	service, err := NewPKCS11Service(module, 1, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	if _, err := service.Encrypt([]byte(testText)); err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if module.healthChecks == 0 {
+		t.Errorf("expected the pooled session to be health checked on acquisition")
+	}
+
+	// Mark the pooled session unhealthy; the next operation must discard it,
+	// open a fresh session and still succeed.
+	svc := service.(*pkcs11Service)
+	sess := <-svc.idle
+	sess.PKCS11Session.(*fakePKCS11Session).healthy = false
+	svc.idle <- sess
+
+	if _, err := service.Encrypt([]byte(testText)); err != nil {
+		t.Fatalf("encrypt after token failure failed: %v", err)
+	}
+	if module.closed != 1 {
+		t.Errorf("unhealthy session was not closed, closed %d sessions", module.closed)
+	}
+	if module.opened != 2 {
+		t.Errorf("opened %d sessions, want 2 (initial plus replacement)", module.opened)
+	}
+}
+
+func TestPKCS11FailedOperationDiscardsSession(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	svc := service.(*pkcs11Service)
+	sess := <-svc.idle
+	sess.PKCS11Session.(*fakePKCS11Session).failOps = true
+	svc.idle <- sess
+
+	if _, err := service.Encrypt([]byte(testText)); err == nil {
+		t.Fatalf("expected encrypt to fail on a broken session")
+	}
+	if module.closed != 1 {
+		t.Errorf("broken session was not closed, closed %d sessions", module.closed)
+	}
+
+	// The replacement session must make the service usable again.
+	if _, err := service.Encrypt([]byte(testText)); err != nil {
+		t.Fatalf("encrypt after session replacement failed: %v", err)
+	}
+}
+
+// TestPKCS11DiscardWakesWaiters verifies that a caller blocked at the pool
+// limit is woken when another caller discards a broken session rather than
+// releasing it.
+func TestPKCS11DiscardWakesWaiters(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+
+	// Hold the only session so the pool is exhausted.
+	svc := service.(*pkcs11Service)
+	sess := <-svc.idle
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Encrypt([]byte(testText))
+		done <- err
+	}()
+
+	// Give the waiter a chance to block, then discard the held session as
+	// broken instead of releasing it.
+	time.Sleep(10 * time.Millisecond)
+	svc.discard(sess)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("encrypt after discard failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("encrypt still blocked after the broken session was discarded")
+	}
+}
+
+func TestPKCS11ServiceErrors(t *testing.T) {
+	if _, err := NewPKCS11Service(nil, 0, 0); err == nil {
+		t.Errorf("expected an error for a nil module")
+	}
+	if _, err := NewPKCS11Service(&fakePKCS11Module{}, -1, 0); err == nil {
+		t.Errorf("expected an error for a negative pool size")
+	}
+	module := &fakePKCS11Module{openErr: fmt.Errorf("no token present")}
+	if _, err := NewPKCS11Service(module, 0, 0); err == nil {
+		t.Errorf("expected an error when the initial session cannot be opened")
+	}
+}
+
+// TestPKCS11EnvelopeTransformer runs the PKCS#11 service underneath the
+// standard envelope transformer, mirroring the conformance checks the other
+// envelope services go through.
+func TestPKCS11EnvelopeTransformer(t *testing.T) {
+	module := &fakePKCS11Module{}
+	service, err := NewPKCS11Service(module, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to create PKCS#11 service: %v", err)
+	}
+	envelopeTransformer, err := NewEnvelopeTransformer(service, testEnvelopeCacheSize, aestransformer.NewGCMTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	context := value.DefaultContext([]byte(testContextText))
+	originalText := []byte(testText)
+
+	transformedData, err := envelopeTransformer.TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("error while transforming data to storage: %v", err)
+	}
+	untransformedData, _, err := envelopeTransformer.TransformFromStorage(transformedData, context)
+	if err != nil {
+		t.Fatalf("error while transforming data from storage: %v", err)
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Errorf("transformed data mismatch. Expected: %v, got %v", originalText, untransformedData)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// testEnvelopeServiceConformance verifies the parts of the Service contract
+// that hold for every envelope service, independent of the backing KMS:
+// lossless round trips, ciphertexts that differ from the plaintext, safety
+// under concurrent use and no silent acceptance of tampered ciphertexts.
+// Tests of a Service implementation should run it against a fully
+// initialized service.
+func testEnvelopeServiceConformance(t *testing.T, service Service) {
+	plaintexts := map[string][]byte{
+		"short":  []byte("a"),
+		"text":   []byte(testText),
+		"binary": {0x00, 0x10, 0xFF, 0x00, 0x7F, 0x80},
+		"dek":    bytes.Repeat([]byte{0x42}, 32),
+		"large":  bytes.Repeat([]byte("0123456789abcdef"), 256),
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		for name, plaintext := range plaintexts {
+			ciphertext, err := service.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("encrypt failed for %s plaintext: %v", name, err)
+			}
+			decrypted, err := service.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("decrypt failed for %s plaintext: %v", name, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("round trip mismatch for %s plaintext: got %q, want %q", name, decrypted, plaintext)
+			}
+		}
+	})
+
+	t.Run("ciphertext differs from plaintext", func(t *testing.T) {
+		plaintext := []byte(testText)
+		ciphertext, err := service.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("encrypt failed: %v", err)
+		}
+		if bytes.Equal(ciphertext, plaintext) {
+			t.Errorf("ciphertext must not equal the plaintext")
+		}
+	})
+
+	t.Run("tampered ciphertext is not silently accepted", func(t *testing.T) {
+		plaintext := []byte(testText)
+		ciphertext, err := service.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("encrypt failed: %v", err)
+		}
+		tampered := make([]byte, len(ciphertext))
+		copy(tampered, ciphertext)
+		tampered[len(tampered)-1] ^= 0x01
+		// Not every KMS authenticates its ciphertexts, so an error is not
+		// required, but a tampered ciphertext must never decrypt back to the
+		// original plaintext as if nothing had happened.
+		if decrypted, err := service.Decrypt(tampered); err == nil && bytes.Equal(decrypted, plaintext) {
+			t.Errorf("tampered ciphertext decrypted to the original plaintext")
+		}
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		plaintext := []byte(testText)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 25; j++ {
+					ciphertext, err := service.Encrypt(plaintext)
+					if err != nil {
+						t.Errorf("concurrent encrypt failed: %v", err)
+						return
+					}
+					decrypted, err := service.Decrypt(ciphertext)
+					if err != nil {
+						t.Errorf("concurrent decrypt failed: %v", err)
+						return
+					}
+					if !bytes.Equal(decrypted, plaintext) {
+						t.Errorf("concurrent round trip mismatch: got %q, want %q", decrypted, plaintext)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
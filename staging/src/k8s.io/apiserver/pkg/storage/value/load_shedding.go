@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Load shedding reasons reported in errors and metrics.
+const (
+	loadShedReasonConcurrency = "concurrency"
+	loadShedReasonLatency     = "latency"
+
+	defaultLatencySampleWindowSize = 128
+	defaultLatencySampleMaxAge     = time.Minute
+)
+
+// OverloadedError is returned when a transformation is rejected because the
+// underlying provider is considered overloaded. The error is retriable: the
+// client request that triggered the transformation can safely be retried
+// once the provider has recovered.
+type OverloadedError struct {
+	// Provider identifies the overloaded transformer provider.
+	Provider string
+	// Reason is the load shedding reason, either "concurrency" or "latency".
+	Reason string
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("transformation provider %q is overloaded (%s), the request may be retried", e.Provider, e.Reason)
+}
+
+// IsOverloadedError returns true if the error indicates load shedding by a
+// transformer and the operation may be retried.
+func IsOverloadedError(err error) bool {
+	var overloaded *OverloadedError
+	return stderrors.As(err, &overloaded)
+}
+
+// LoadSheddingConfig bounds the load a single transformer provider may absorb
+// before transformations are rejected instead of queued.
+type LoadSheddingConfig struct {
+	// ProviderName identifies the provider in errors and metrics.
+	ProviderName string
+	// MaxConcurrent is the maximum number of in-flight transformations
+	// allowed through the provider. Zero disables the concurrency limit.
+	MaxConcurrent int
+	// P99LatencyBound rejects transformations while the observed p99
+	// latency over the sample window exceeds this bound. Zero disables the
+	// latency check.
+	P99LatencyBound time.Duration
+	// SampleWindowSize is the number of recent latency samples the p99 is
+	// computed over. Defaults to 128 when zero.
+	SampleWindowSize int
+	// SampleMaxAge is the age beyond which latency samples are discarded.
+	// Expiry is the recovery path out of latency shedding: rejected
+	// transformations record no samples, so without it a window full of slow
+	// samples would shed load forever even after the provider recovered.
+	// Defaults to 1 minute when zero.
+	SampleMaxAge time.Duration
+}
+
+// latencySample is one observed transformation latency, timestamped so that
+// stale samples can be expired.
+type latencySample struct {
+	latency time.Duration
+	at      time.Time
+}
+
+type loadSheddingTransformer struct {
+	delegate Transformer
+	config   LoadSheddingConfig
+
+	// sem limits in-flight transformations, nil when MaxConcurrent is zero.
+	sem chan struct{}
+
+	// maxAge is the age beyond which samples no longer count towards the p99.
+	maxAge time.Duration
+
+	// mu guards the latency sample ring buffer.
+	mu      sync.Mutex
+	samples []latencySample
+	next    int
+}
+
+var _ Transformer = &loadSheddingTransformer{}
+
+// NewLoadSheddingTransformer wraps a transformer so that transformations are
+// rejected with an OverloadedError instead of piling up behind a degraded
+// provider, either when too many transformations are already in flight or
+// when the provider's recent p99 latency exceeds the configured bound. This
+// is primarily intended for envelope (KMS) providers whose latency depends on
+// an external service.
+func NewLoadSheddingTransformer(config LoadSheddingConfig, delegate Transformer) Transformer {
+	if config.MaxConcurrent <= 0 && config.P99LatencyBound <= 0 {
+		return delegate
+	}
+	windowSize := config.SampleWindowSize
+	if windowSize <= 0 {
+		windowSize = defaultLatencySampleWindowSize
+	}
+	maxAge := config.SampleMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultLatencySampleMaxAge
+	}
+	t := &loadSheddingTransformer{
+		delegate: delegate,
+		config:   config,
+		maxAge:   maxAge,
+		samples:  make([]latencySample, windowSize),
+	}
+	if config.MaxConcurrent > 0 {
+		t.sem = make(chan struct{}, config.MaxConcurrent)
+	}
+	return t
+}
+
+func (t *loadSheddingTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	release, err := t.admit()
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
+	start := time.Now()
+	out, stale, err := t.delegate.TransformFromStorage(data, context)
+	t.observe(time.Since(start))
+	return out, stale, err
+}
+
+func (t *loadSheddingTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	release, err := t.admit()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	start := time.Now()
+	out, err := t.delegate.TransformToStorage(data, context)
+	t.observe(time.Since(start))
+	return out, err
+}
+
+// admit decides whether another transformation may proceed. On success it
+// returns the function releasing the concurrency slot.
+func (t *loadSheddingTransformer) admit() (func(), error) {
+	release := func() {}
+	if t.sem != nil {
+		select {
+		case t.sem <- struct{}{}:
+			release = func() { <-t.sem }
+		default:
+			RecordLoadShed(t.config.ProviderName, loadShedReasonConcurrency)
+			return nil, &OverloadedError{Provider: t.config.ProviderName, Reason: loadShedReasonConcurrency}
+		}
+	}
+	if t.config.P99LatencyBound > 0 && t.p99() > t.config.P99LatencyBound {
+		release()
+		RecordLoadShed(t.config.ProviderName, loadShedReasonLatency)
+		return nil, &OverloadedError{Provider: t.config.ProviderName, Reason: loadShedReasonLatency}
+	}
+	return release, nil
+}
+
+// observe adds one latency sample to the ring buffer.
+func (t *loadSheddingTransformer) observe(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.next] = latencySample{latency: latency, at: time.Now()}
+	t.next++
+	if t.next == len(t.samples) {
+		t.next = 0
+	}
+}
+
+// p99 computes the 99th percentile latency over the fresh samples in the
+// window. It returns zero unless the window holds a full set of fresh
+// samples: either the provider has seen little traffic yet and should not be
+// considered degraded based on a few samples, or shedding itself has starved
+// the window and the provider deserves to be probed again.
+func (t *loadSheddingTransformer) p99() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cutoff := time.Now().Add(-t.maxAge)
+	sorted := make([]time.Duration, 0, len(t.samples))
+	for _, sample := range t.samples {
+		if sample.at.After(cutoff) {
+			sorted = append(sorted, sample.latency)
+		}
+	}
+	if len(sorted) < len(t.samples) {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*99/100]
+}
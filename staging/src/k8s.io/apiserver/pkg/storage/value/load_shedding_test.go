@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingTransformer blocks in TransformToStorage until released.
+type blockingTransformer struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	return data, false, nil
+}
+
+func (t *blockingTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	t.entered <- struct{}{}
+	<-t.release
+	return data, nil
+}
+
+func TestLoadSheddingConcurrencyLimit(t *testing.T) {
+	blocking := &blockingTransformer{
+		entered: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	transformer := NewLoadSheddingTransformer(LoadSheddingConfig{
+		ProviderName:  "kms-test",
+		MaxConcurrent: 1,
+	}, blocking)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := transformer.TransformToStorage([]byte("first"), DefaultContext("")); err != nil {
+			t.Errorf("unexpected error for in-flight transformation: %v", err)
+		}
+	}()
+	// Wait for the first transformation to hold the only slot.
+	<-blocking.entered
+
+	_, err := transformer.TransformToStorage([]byte("second"), DefaultContext(""))
+	if !IsOverloadedError(err) {
+		t.Errorf("expected OverloadedError when concurrency limit is reached, got %v", err)
+	}
+
+	close(blocking.release)
+	wg.Wait()
+
+	// With the slot free again, transformations must be admitted.
+	if _, _, err := transformer.TransformFromStorage([]byte("third"), DefaultContext("")); err != nil {
+		t.Errorf("unexpected error after slot was released: %v", err)
+	}
+}
+
+func TestLoadSheddingLatencyBound(t *testing.T) {
+	transformer := NewLoadSheddingTransformer(LoadSheddingConfig{
+		ProviderName:     "kms-test",
+		P99LatencyBound:  time.Nanosecond,
+		SampleWindowSize: 4,
+	}, IdentityTransformer).(*loadSheddingTransformer)
+
+	// Before the sample window fills, nothing is rejected.
+	for i := 0; i < 3; i++ {
+		if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+			t.Fatalf("unexpected error before the sample window filled: %v", err)
+		}
+	}
+
+	// Fill the window with samples far above the bound.
+	for i := 0; i < len(transformer.samples); i++ {
+		transformer.observe(time.Second)
+	}
+
+	_, err := transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+	if !IsOverloadedError(err) {
+		t.Errorf("expected OverloadedError when p99 latency exceeds bound, got %v", err)
+	}
+	if _, _, err := transformer.TransformFromStorage([]byte("data"), DefaultContext("")); !IsOverloadedError(err) {
+		t.Errorf("expected OverloadedError on reads as well, got %v", err)
+	}
+}
+
+func TestLoadSheddingLatencyRecovery(t *testing.T) {
+	transformer := NewLoadSheddingTransformer(LoadSheddingConfig{
+		ProviderName:     "kms-test",
+		P99LatencyBound:  time.Nanosecond,
+		SampleWindowSize: 4,
+		SampleMaxAge:     50 * time.Millisecond,
+	}, IdentityTransformer).(*loadSheddingTransformer)
+
+	// Fill the window with samples far above the bound.
+	for i := 0; i < len(transformer.samples); i++ {
+		transformer.observe(time.Second)
+	}
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); !IsOverloadedError(err) {
+		t.Fatalf("expected OverloadedError while the window is full of slow samples, got %v", err)
+	}
+
+	// Once the slow samples have aged out, transformations must be admitted
+	// again even though shedding itself recorded no new samples in the
+	// meantime.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err != nil {
+		t.Errorf("unexpected error after the slow samples expired: %v", err)
+	}
+}
+
+func TestLoadSheddingDisabled(t *testing.T) {
+	transformer := NewLoadSheddingTransformer(LoadSheddingConfig{ProviderName: "kms-test"}, IdentityTransformer)
+	if transformer != IdentityTransformer {
+		t.Errorf("expected the delegate to be returned unwrapped when load shedding is disabled")
+	}
+}
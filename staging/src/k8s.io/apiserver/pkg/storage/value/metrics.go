@@ -95,6 +95,73 @@ var (
 			StabilityLevel: metrics.ALPHA,
 		},
 	)
+
+	transformationRetriesTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "transformation_retries_total",
+			Help:           "Total number of transformations retried after a transient provider failure, by provider and gRPC status code.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"provider", "status"},
+	)
+
+	transformationLoadShedTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "transformation_load_shed_total",
+			Help:           "Total number of transformations rejected because the provider was considered overloaded, by provider and reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"provider", "reason"},
+	)
+
+	circuitBreakerStateChangesTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "transformation_circuit_breaker_state_changes_total",
+			Help:           "Total number of transformation circuit breaker state changes, by provider and new state (closed, open, half-open).",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"provider", "state"},
+	)
+
+	circuitBreakerRejectionsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "transformation_circuit_breaker_rejections_total",
+			Help:           "Total number of transformations rejected without reaching the provider because the circuit breaker was open, by provider.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"provider"},
+	)
+
+	compressionOperationsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "compression_operations_total",
+			Help:           "Total number of value compression decisions, by codec, resource and outcome (compress, decompress, skipped_small, skipped_incompressible).",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"codec", "resource", "outcome"},
+	)
+
+	compressionRatio = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      namespace,
+			Subsystem:      subsystem,
+			Name:           "compression_ratio",
+			Help:           "Ratio of compressed to uncompressed value size for compressed values, by codec and resource.",
+			Buckets:        metrics.LinearBuckets(0.05, 0.05, 20),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"codec", "resource"},
+	)
 )
 
 var registerMetrics sync.Once
@@ -106,9 +173,46 @@ func RegisterMetrics() {
 		legacyregistry.MustRegister(envelopeTransformationCacheMissTotal)
 		legacyregistry.MustRegister(dataKeyGenerationLatencies)
 		legacyregistry.MustRegister(dataKeyGenerationFailuresTotal)
+		legacyregistry.MustRegister(transformationRetriesTotal)
+		legacyregistry.MustRegister(transformationLoadShedTotal)
+		legacyregistry.MustRegister(circuitBreakerStateChangesTotal)
+		legacyregistry.MustRegister(circuitBreakerRejectionsTotal)
+		legacyregistry.MustRegister(compressionOperationsTotal)
+		legacyregistry.MustRegister(compressionRatio)
 	})
 }
 
+// RecordCompression records the outcome of one value compression decision.
+// The ratio is only observed when a value was actually (de)compressed.
+func RecordCompression(codec, resource, outcome string, uncompressedSize, compressedSize int) {
+	compressionOperationsTotal.WithLabelValues(codec, resource, outcome).Inc()
+	if (outcome == "compress" || outcome == "decompress") && uncompressedSize > 0 {
+		compressionRatio.WithLabelValues(codec, resource).Observe(float64(compressedSize) / float64(uncompressedSize))
+	}
+}
+
+// RecordTransformationRetry records a transformation retried after a
+// transient provider failure.
+func RecordTransformationRetry(provider, status string) {
+	transformationRetriesTotal.WithLabelValues(provider, status).Inc()
+}
+
+// RecordLoadShed records a transformation rejected by load shedding.
+func RecordLoadShed(provider, reason string) {
+	transformationLoadShedTotal.WithLabelValues(provider, reason).Inc()
+}
+
+// RecordCircuitBreakerStateChange records a circuit breaker entering a state.
+func RecordCircuitBreakerStateChange(provider, state string) {
+	circuitBreakerStateChangesTotal.WithLabelValues(provider, state).Inc()
+}
+
+// RecordCircuitBreakerRejection records a transformation rejected by an open
+// circuit breaker.
+func RecordCircuitBreakerRejection(provider string) {
+	circuitBreakerRejectionsTotal.WithLabelValues(provider).Inc()
+}
+
 // RecordTransformation records latencies and count of TransformFromStorage and TransformToStorage operations.
 // Note that transformation_failures_total metric is deprecated, use transformation_operations_total instead.
 func RecordTransformation(transformationType, transformerPrefix string, start time.Time, err error) {
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// xorTransformer is a trivial reversible transformer for tests.
+type xorTransformer struct {
+	key byte
+}
+
+func (t *xorTransformer) xor(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ t.key
+	}
+	return out
+}
+
+func (t *xorTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	return t.xor(data), false, nil
+}
+
+func (t *xorTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	return t.xor(data), nil
+}
+
+func newTestConfiguration(prefix string, key byte) Transformer {
+	return NewPrefixTransformers(nil,
+		PrefixTransformer{Prefix: []byte(prefix), Transformer: &xorTransformer{key: key}},
+	)
+}
+
+func TestReencrypt(t *testing.T) {
+	oldConfig := newTestConfiguration("k8s:enc:test:v1:old:", 0x0f)
+	newConfig := newTestConfiguration("k8s:enc:test:v1:new:", 0xf0)
+	r := NewReencryptor(oldConfig, newConfig)
+
+	plaintext := []byte("secret data")
+	stored, err := oldConfig.TransformToStorage(plaintext, DefaultContext("/registry/secrets/ns/name"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := r.Reencrypt("/registry/secrets/ns/name", stored)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("k8s:enc:test:v1:new:")) {
+		t.Errorf("re-encrypted value does not carry the new prefix: %q", string(out))
+	}
+	roundTripped, _, err := newConfig.TransformFromStorage(out, DefaultContext("/registry/secrets/ns/name"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(roundTripped, plaintext) {
+		t.Errorf("got %q after the round trip, want %q", string(roundTripped), string(plaintext))
+	}
+}
+
+func TestReencryptUnknownPrefix(t *testing.T) {
+	r := NewReencryptor(newTestConfiguration("k8s:enc:test:v1:old:", 0x0f), newTestConfiguration("k8s:enc:test:v1:new:", 0xf0))
+	if _, err := r.Reencrypt("/registry/secrets/ns/name", []byte("k8s:enc:other:v1:key:junk")); err == nil {
+		t.Error("expected an error for a value no old transformer matches, got none")
+	}
+}
+
+func TestReencryptStream(t *testing.T) {
+	oldConfig := newTestConfiguration("k8s:enc:test:v1:old:", 0x0f)
+	newConfig := newTestConfiguration("k8s:enc:test:v1:new:", 0xf0)
+	r := NewReencryptor(oldConfig, newConfig)
+
+	values := make(chan KeyedValue)
+	go func() {
+		defer close(values)
+		for i := 0; i < 5; i++ {
+			key := fmt.Sprintf("/registry/secrets/ns/name-%d", i)
+			stored, err := oldConfig.TransformToStorage([]byte(fmt.Sprintf("data-%d", i)), DefaultContext(key))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			values <- KeyedValue{Key: key, Data: stored}
+		}
+		// A corrupt value must be reported without aborting the stream.
+		values <- KeyedValue{Key: "/registry/secrets/ns/corrupt", Data: []byte("not encrypted")}
+	}()
+
+	var results []ReencryptedValue
+	for result := range r.ReencryptStream(values) {
+		results = append(results, result)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for i := 0; i < 5; i++ {
+		result := results[i]
+		if result.Err != nil {
+			t.Errorf("unexpected error for %s: %v", result.Key, result.Err)
+			continue
+		}
+		if result.Key != fmt.Sprintf("/registry/secrets/ns/name-%d", i) {
+			t.Errorf("result %d has unexpected key %s, results must preserve the input order", i, result.Key)
+			continue
+		}
+		roundTripped, _, err := newConfig.TransformFromStorage(result.Data, DefaultContext(result.Key))
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", result.Key, err)
+			continue
+		}
+		if want := fmt.Sprintf("data-%d", i); string(roundTripped) != want {
+			t.Errorf("got %q after the round trip, want %q", string(roundTripped), want)
+		}
+	}
+	if results[5].Err == nil {
+		t.Error("expected an error for the corrupt value, got none")
+	}
+	if results[5].Data != nil {
+		t.Errorf("expected nil data for the corrupt value, got %q", string(results[5].Data))
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultRetryInitialBackoff = 10 * time.Millisecond
+	defaultRetryMaxBackoff     = 500 * time.Millisecond
+
+	// retryJitterFactor spreads out retries so that a momentary provider blip
+	// does not turn into a synchronized retry storm.
+	retryJitterFactor = 0.5
+)
+
+// RetryConfig bounds the retries performed for one transformation when the
+// underlying provider fails transiently.
+type RetryConfig struct {
+	// ProviderName identifies the provider in metrics.
+	ProviderName string
+	// MaxRetries is the maximum number of retries after a transient provider
+	// failure. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the backoff before the first retry. It is doubled
+	// after every retry up to MaxBackoff and jittered. Defaults to 10ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff between retries. Defaults to 500ms.
+	MaxBackoff time.Duration
+	// MaxElapsed bounds the total time one transformation may spend on
+	// retries. It should stay below the server's request timeout so that
+	// retries do not outlive the request deadline of the client request that
+	// triggered the transformation. Zero bounds retries by MaxRetries only.
+	MaxElapsed time.Duration
+}
+
+type retryTransformer struct {
+	delegate Transformer
+	config   RetryConfig
+}
+
+var _ Transformer = &retryTransformer{}
+
+// NewRetryTransformer wraps a transformer so that transient provider failures
+// (gRPC Unavailable and ResourceExhausted, as surfaced by envelope KMS
+// providers) are retried with jittered exponential backoff instead of turning
+// a momentary provider blip into an error for the whole client request.
+// Permanent failures, such as authentication errors, are returned immediately.
+func NewRetryTransformer(config RetryConfig, delegate Transformer) Transformer {
+	if config.MaxRetries <= 0 {
+		return delegate
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultRetryMaxBackoff
+	}
+	return &retryTransformer{
+		delegate: delegate,
+		config:   config,
+	}
+}
+
+func (t *retryTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	var out []byte
+	var stale bool
+	err := t.withRetries(func() error {
+		var err error
+		out, stale, err = t.delegate.TransformFromStorage(data, context)
+		return err
+	})
+	return out, stale, err
+}
+
+func (t *retryTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	var out []byte
+	err := t.withRetries(func() error {
+		var err error
+		out, err = t.delegate.TransformToStorage(data, context)
+		return err
+	})
+	return out, err
+}
+
+// withRetries invokes the operation and retries it with jittered exponential
+// backoff as long as it keeps failing transiently and the retry bounds have
+// not been exhausted. The last error is returned as-is.
+func (t *retryTransformer) withRetries(op func() error) error {
+	start := time.Now()
+	backoff := t.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || !isTransientTransformationError(err) || attempt == t.config.MaxRetries {
+			return err
+		}
+		sleep := wait.Jitter(backoff, retryJitterFactor)
+		if t.config.MaxElapsed > 0 && time.Since(start)+sleep > t.config.MaxElapsed {
+			return err
+		}
+		RecordTransformationRetry(t.config.ProviderName, status.Code(err).String())
+		time.Sleep(sleep)
+		backoff *= 2
+		if backoff > t.config.MaxBackoff {
+			backoff = t.config.MaxBackoff
+		}
+	}
+}
+
+// isTransientTransformationError returns true for provider failures that are
+// expected to resolve by themselves and are therefore worth retrying.
+func isTransientTransformationError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package value
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyTransformer fails the first failures transformations with err before
+// succeeding.
+type flakyTransformer struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (t *flakyTransformer) TransformFromStorage(data []byte, context Context) ([]byte, bool, error) {
+	t.calls++
+	if t.calls <= t.failures {
+		return nil, false, t.err
+	}
+	return data, false, nil
+}
+
+func (t *flakyTransformer) TransformToStorage(data []byte, context Context) ([]byte, error) {
+	t.calls++
+	if t.calls <= t.failures {
+		return nil, t.err
+	}
+	return data, nil
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		err           error
+		failures      int
+		expectedCalls int
+		expectErr     bool
+	}{
+		{
+			desc:          "unavailable errors are retried until the provider recovers",
+			err:           status.Error(codes.Unavailable, "etcdserver: kms is unavailable"),
+			failures:      2,
+			expectedCalls: 3,
+		},
+		{
+			desc:          "resource exhausted errors are retried",
+			err:           status.Error(codes.ResourceExhausted, "rate limited"),
+			failures:      1,
+			expectedCalls: 2,
+		},
+		{
+			desc:          "permanent provider errors are returned immediately",
+			err:           status.Error(codes.PermissionDenied, "wrong credentials"),
+			failures:      1,
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+		{
+			desc:          "plain errors are returned immediately",
+			err:           fmt.Errorf("invalid padding"),
+			failures:      1,
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+		{
+			desc:          "the last error is returned once retries are exhausted",
+			err:           status.Error(codes.Unavailable, "still down"),
+			failures:      10,
+			expectedCalls: 4,
+			expectErr:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			flaky := &flakyTransformer{failures: tc.failures, err: tc.err}
+			transformer := NewRetryTransformer(RetryConfig{
+				ProviderName:   "kms-test",
+				MaxRetries:     3,
+				InitialBackoff: time.Microsecond,
+				MaxBackoff:     time.Microsecond,
+			}, flaky)
+
+			_, err := transformer.TransformToStorage([]byte("data"), DefaultContext(""))
+			if tc.expectErr {
+				if err != tc.err {
+					t.Errorf("expected error %v, got %v", tc.err, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if flaky.calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, flaky.calls)
+			}
+		})
+	}
+}
+
+func TestRetryFromStorage(t *testing.T) {
+	flaky := &flakyTransformer{failures: 1, err: status.Error(codes.Unavailable, "blip")}
+	transformer := NewRetryTransformer(RetryConfig{
+		ProviderName:   "kms-test",
+		MaxRetries:     3,
+		InitialBackoff: time.Microsecond,
+		MaxBackoff:     time.Microsecond,
+	}, flaky)
+
+	out, stale, err := transformer.TransformFromStorage([]byte("data"), DefaultContext(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stale {
+		t.Errorf("unexpected stale result")
+	}
+	if string(out) != "data" {
+		t.Errorf("unexpected output %q", string(out))
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	flaky := &flakyTransformer{failures: 10, err: status.Error(codes.Unavailable, "still down")}
+	transformer := NewRetryTransformer(RetryConfig{
+		ProviderName:   "kms-test",
+		MaxRetries:     1000,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		MaxElapsed:     time.Millisecond,
+	}, flaky)
+
+	if _, err := transformer.TransformToStorage([]byte("data"), DefaultContext("")); err == nil {
+		t.Fatal("expected error when the retry budget is exhausted")
+	}
+	// The first backoff would already exceed MaxElapsed, so the operation must
+	// not have been retried.
+	if flaky.calls != 1 {
+		t.Errorf("expected 1 call, got %d", flaky.calls)
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	if _, ok := NewRetryTransformer(RetryConfig{}, IdentityTransformer).(identityTransformer); !ok {
+		t.Errorf("expected the delegate to be returned as-is when retries are disabled")
+	}
+}
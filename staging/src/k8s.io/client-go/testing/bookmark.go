@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// BookmarkEmitter is implemented by object trackers that can deliver watch
+// bookmark events to their watchers. The tracker behind the generated fake
+// clientsets implements it, so tests for bookmark handling in controllers can
+// do:
+//
+//	client := fake.NewSimpleClientset()
+//	client.Tracker().(testing.BookmarkEmitter).EmitBookmark(gvr, ns, obj)
+type BookmarkEmitter interface {
+	// EmitBookmark sends a bookmark event carrying obj to the watchers of the
+	// given resource in the given namespace, including all-namespaces
+	// watchers. The object's resource version is the position the bookmark
+	// marks in the watch stream.
+	EmitBookmark(gvr schema.GroupVersionResource, ns string, obj runtime.Object) error
+}
+
+var _ BookmarkEmitter = &tracker{}
+
+// EmitBookmark implements BookmarkEmitter.
+func (t *tracker) EmitBookmark(gvr schema.GroupVersionResource, ns string, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if len(accessor.GetResourceVersion()) == 0 {
+		return fmt.Errorf("bookmark object must carry a resource version")
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	for _, w := range t.getWatches(gvr, ns) {
+		w.Action(watch.Bookmark, obj.DeepCopyObject())
+	}
+	return nil
+}
+
+// NewBookmarkObject returns a copy of obj reduced to what the API server
+// sends in a bookmark event: the type information and the resource version.
+func NewBookmarkObject(obj runtime.Object, resourceVersion string) (runtime.Object, error) {
+	bookmark := obj.DeepCopyObject()
+	accessor, err := meta.Accessor(bookmark)
+	if err != nil {
+		return nil, err
+	}
+	accessor.SetResourceVersion(resourceVersion)
+	return bookmark, nil
+}
+
+// WaitForBookmark consumes events from the watch until a bookmark event
+// arrives and returns the resource version it carries, which is where a
+// controller resuming from the bookmark would restart its watch. Non-bookmark
+// events are discarded.
+func WaitForBookmark(w watch.Interface, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return "", fmt.Errorf("watch closed before a bookmark event was received")
+			}
+			if event.Type != watch.Bookmark {
+				continue
+			}
+			accessor, err := meta.Accessor(event.Object)
+			if err != nil {
+				return "", err
+			}
+			return accessor.GetResourceVersion(), nil
+		case <-timer.C:
+			return "", fmt.Errorf("timed out waiting for a bookmark event")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newBookmarkTestTracker(t *testing.T) ObjectTracker {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	codecs := serializer.NewCodecFactory(scheme)
+	return NewObjectTracker(scheme, codecs.UniversalDecoder())
+}
+
+func TestEmitBookmark(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	tracker := newBookmarkTestTracker(t)
+
+	nsWatch, err := tracker.Watch(gvr, "test-namespace")
+	assert.NoError(t, err)
+	allWatch, err := tracker.Watch(gvr, metav1.NamespaceAll)
+	assert.NoError(t, err)
+
+	bookmark := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "100"}}
+	err = tracker.(BookmarkEmitter).EmitBookmark(gvr, "test-namespace", bookmark)
+	assert.NoError(t, err)
+
+	for _, w := range []watch.Interface{nsWatch, allWatch} {
+		event := <-w.ResultChan()
+		assert.Equal(t, watch.Bookmark, event.Type)
+		pod, ok := event.Object.(*corev1.Pod)
+		assert.True(t, ok)
+		assert.Equal(t, "100", pod.ResourceVersion)
+	}
+}
+
+func TestEmitBookmarkWithoutResourceVersion(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	tracker := newBookmarkTestTracker(t)
+
+	err := tracker.(BookmarkEmitter).EmitBookmark(gvr, "test-namespace", &corev1.Pod{})
+	assert.Error(t, err)
+}
+
+func TestNewBookmarkObject(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: "1"}}
+	obj, err := NewBookmarkObject(pod, "42")
+	assert.NoError(t, err)
+	bookmark, ok := obj.(*corev1.Pod)
+	assert.True(t, ok)
+	assert.Equal(t, "42", bookmark.ResourceVersion)
+	// The original object must not be touched.
+	assert.Equal(t, "1", pod.ResourceVersion)
+}
+
+func TestWaitForBookmark(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	tracker := newBookmarkTestTracker(t)
+
+	w, err := tracker.Watch(gvr, "test-namespace")
+	assert.NoError(t, err)
+
+	// Deliver a regular event before the bookmark; WaitForBookmark must skip it.
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test-namespace"}}
+	assert.NoError(t, tracker.Add(pod))
+
+	bookmark, err := NewBookmarkObject(pod, "100")
+	assert.NoError(t, err)
+	assert.NoError(t, tracker.(BookmarkEmitter).EmitBookmark(gvr, "test-namespace", bookmark))
+
+	rv, err := WaitForBookmark(w, wait.ForeverTestTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", rv)
+}
+
+func TestWaitForBookmarkTimeout(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	tracker := newBookmarkTestTracker(t)
+
+	w, err := tracker.Watch(gvr, "test-namespace")
+	assert.NoError(t, err)
+
+	_, err = WaitForBookmark(w, 10*time.Millisecond)
+	assert.Error(t, err)
+}
@@ -28,8 +28,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
@@ -68,6 +70,24 @@ type ObjectTracker interface {
 	Watch(gvr schema.GroupVersionResource, ns string) (watch.Interface, error)
 }
 
+// IndexedObjectTracker is implemented by trackers that maintain namespace and
+// label indexes over the tracked objects. The tracker returned by
+// NewObjectTracker implements it.
+type IndexedObjectTracker interface {
+	ObjectTracker
+
+	// ListWithSelector is like List, but only returns the objects whose
+	// labels match the selector. Equality requirements in the selector are
+	// answered from the label index instead of scanning every tracked
+	// object.
+	ListWithSelector(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, selector labels.Selector) (runtime.Object, error)
+
+	// IndexedKeys returns the namespace/name keys of the tracked objects
+	// carrying the given label key/value pair, sorted by namespace and
+	// name. It is intended for assertions on the tracker's contents.
+	IndexedKeys(gvr schema.GroupVersionResource, key, value string) []types.NamespacedName
+}
+
 // ObjectScheme abstracts the implementation of common operations on objects.
 type ObjectScheme interface {
 	runtime.ObjectCreater
@@ -87,6 +107,11 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 		switch action := action.(type) {
 
 		case ListActionImpl:
+			restrictions := action.GetListRestrictions()
+			if indexed, ok := tracker.(IndexedObjectTracker); ok && restrictions.Labels != nil && !restrictions.Labels.Empty() {
+				obj, err := indexed.ListWithSelector(gvr, action.GetKind(), ns, restrictions.Labels)
+				return true, obj, err
+			}
 			obj, err := tracker.List(gvr, action.GetKind(), ns)
 			return true, obj, err
 
@@ -195,11 +220,145 @@ func ObjectReaction(tracker ObjectTracker) ReactionFunc {
 	}
 }
 
+// objectIndex indexes the tracked objects of a single resource by
+// namespace/name, by namespace and by label key/value pair, so that lookups
+// and selector matching do not have to scan every tracked object.
+type objectIndex struct {
+	byName      map[types.NamespacedName]runtime.Object
+	byNamespace map[string]map[types.NamespacedName]runtime.Object
+	byLabel     map[string]map[types.NamespacedName]runtime.Object
+}
+
+func newObjectIndex() *objectIndex {
+	return &objectIndex{
+		byName:      make(map[types.NamespacedName]runtime.Object),
+		byNamespace: make(map[string]map[types.NamespacedName]runtime.Object),
+		byLabel:     make(map[string]map[types.NamespacedName]runtime.Object),
+	}
+}
+
+// labelPair returns the index key of a single label key/value pair. Neither
+// label keys nor values may contain "=", so the key is unambiguous.
+func labelPair(key, value string) string {
+	return key + "=" + value
+}
+
+func (i *objectIndex) add(key types.NamespacedName, obj runtime.Object) error {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	// Drop the index entries of any previous version of the object, whose
+	// labels may differ.
+	i.remove(key)
+
+	i.byName[key] = obj
+	if i.byNamespace[key.Namespace] == nil {
+		i.byNamespace[key.Namespace] = make(map[types.NamespacedName]runtime.Object)
+	}
+	i.byNamespace[key.Namespace][key] = obj
+	for k, v := range acc.GetLabels() {
+		pair := labelPair(k, v)
+		if i.byLabel[pair] == nil {
+			i.byLabel[pair] = make(map[types.NamespacedName]runtime.Object)
+		}
+		i.byLabel[pair][key] = obj
+	}
+	return nil
+}
+
+func (i *objectIndex) remove(key types.NamespacedName) {
+	obj, ok := i.byName[key]
+	if !ok {
+		return
+	}
+	delete(i.byName, key)
+	if objs := i.byNamespace[key.Namespace]; objs != nil {
+		delete(objs, key)
+		if len(objs) == 0 {
+			delete(i.byNamespace, key.Namespace)
+		}
+	}
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	for k, v := range acc.GetLabels() {
+		pair := labelPair(k, v)
+		if objs := i.byLabel[pair]; objs != nil {
+			delete(objs, key)
+			if len(objs) == 0 {
+				delete(i.byLabel, pair)
+			}
+		}
+	}
+}
+
+func (i *objectIndex) get(key types.NamespacedName) (runtime.Object, bool) {
+	obj, ok := i.byName[key]
+	return obj, ok
+}
+
+// list returns the objects matching the provided namespace in deterministic
+// order. Empty namespace matches all objects.
+func (i *objectIndex) list(ns string) []runtime.Object {
+	objs := i.byName
+	if ns != "" {
+		objs = i.byNamespace[ns]
+	}
+	res := make([]runtime.Object, 0, len(objs))
+	for _, obj := range objs {
+		res = append(res, obj)
+	}
+	sortObjects(res)
+	return res
+}
+
+// listSelected returns the objects matching the provided namespace and label
+// selector in deterministic order. The smallest index bucket named by an
+// equality requirement of the selector is used as the candidate set; only
+// selectors without equality requirements fall back to scanning the
+// namespace.
+func (i *objectIndex) listSelected(ns string, selector labels.Selector) ([]runtime.Object, error) {
+	candidates := i.byName
+	if ns != "" {
+		candidates = i.byNamespace[ns]
+	}
+	if requirements, selectable := selector.Requirements(); selectable {
+		for _, r := range requirements {
+			op := r.Operator()
+			if op != selection.Equals && op != selection.DoubleEquals && !(op == selection.In && r.Values().Len() == 1) {
+				continue
+			}
+			if bucket := i.byLabel[labelPair(r.Key(), r.Values().List()[0])]; len(bucket) < len(candidates) {
+				candidates = bucket
+			}
+		}
+	}
+
+	var res []runtime.Object
+	for key, obj := range candidates {
+		if ns != "" && key.Namespace != ns {
+			continue
+		}
+		acc, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(acc.GetLabels())) {
+			continue
+		}
+		res = append(res, obj)
+	}
+	sortObjects(res)
+	return res, nil
+}
+
 type tracker struct {
 	scheme  ObjectScheme
 	decoder runtime.Decoder
 	lock    sync.RWMutex
-	objects map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object
+	objects map[schema.GroupVersionResource]*objectIndex
 	// The value type of watchers is a map of which the key is either a namespace or
 	// all/non namespace aka "" and its value is list of fake watchers.
 	// Manipulations on resources will broadcast the notification events into the
@@ -208,20 +367,25 @@ type tracker struct {
 	watchers map[schema.GroupVersionResource]map[string][]*watch.RaceFreeFakeWatcher
 }
 
-var _ ObjectTracker = &tracker{}
+var _ IndexedObjectTracker = &tracker{}
 
 // NewObjectTracker returns an ObjectTracker that can be used to keep track
-// of objects for the fake clientset. Mostly useful for unit tests.
+// of objects for the fake clientset. Mostly useful for unit tests. The
+// returned tracker also implements IndexedObjectTracker.
 func NewObjectTracker(scheme ObjectScheme, decoder runtime.Decoder) ObjectTracker {
 	return &tracker{
 		scheme:   scheme,
 		decoder:  decoder,
-		objects:  make(map[schema.GroupVersionResource]map[types.NamespacedName]runtime.Object),
+		objects:  make(map[schema.GroupVersionResource]*objectIndex),
 		watchers: make(map[schema.GroupVersionResource]map[string][]*watch.RaceFreeFakeWatcher),
 	}
 }
 
 func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string) (runtime.Object, error) {
+	return t.ListWithSelector(gvr, gvk, ns, labels.Everything())
+}
+
+func (t *tracker) ListWithSelector(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, selector labels.Selector) (runtime.Object, error) {
 	// Heuristic for list kind: original kind + List suffix. Might
 	// not always be true but this tracker has a pretty limited
 	// understanding of the actual API model.
@@ -245,12 +409,12 @@ func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionK
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	objs, ok := t.objects[gvr]
+	index, ok := t.objects[gvr]
 	if !ok {
 		return list, nil
 	}
 
-	matchingObjs, err := filterByNamespace(objs, ns)
+	matchingObjs, err := index.listSelected(ns, selector)
 	if err != nil {
 		return nil, err
 	}
@@ -260,6 +424,29 @@ func (t *tracker) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionK
 	return list.DeepCopyObject(), nil
 }
 
+func (t *tracker) IndexedKeys(gvr schema.GroupVersionResource, key, value string) []types.NamespacedName {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	index, ok := t.objects[gvr]
+	if !ok {
+		return nil
+	}
+
+	bucket := index.byLabel[labelPair(key, value)]
+	keys := make([]types.NamespacedName, 0, len(bucket))
+	for k := range bucket {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Namespace != keys[j].Namespace {
+			return keys[i].Namespace < keys[j].Namespace
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
 func (t *tracker) Watch(gvr schema.GroupVersionResource, ns string) (watch.Interface, error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -279,12 +466,12 @@ func (t *tracker) Get(gvr schema.GroupVersionResource, ns, name string) (runtime
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
-	objs, ok := t.objects[gvr]
+	index, ok := t.objects[gvr]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	matchingObj, ok := objs[types.NamespacedName{Namespace: ns, Name: name}]
+	matchingObj, ok := index.get(types.NamespacedName{Namespace: ns, Name: name})
 	if !ok {
 		return nil, errNotFound
 	}
@@ -391,18 +578,21 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 		return errors.NewBadRequest(msg)
 	}
 
-	_, ok := t.objects[gvr]
+	index, ok := t.objects[gvr]
 	if !ok {
-		t.objects[gvr] = make(map[types.NamespacedName]runtime.Object)
+		index = newObjectIndex()
+		t.objects[gvr] = index
 	}
 
 	namespacedName := types.NamespacedName{Namespace: newMeta.GetNamespace(), Name: newMeta.GetName()}
-	if _, ok = t.objects[gvr][namespacedName]; ok {
+	if _, ok = index.get(namespacedName); ok {
 		if replaceExisting {
+			if err := index.add(namespacedName, obj); err != nil {
+				return err
+			}
 			for _, w := range t.getWatches(gvr, ns) {
 				w.Modify(obj)
 			}
-			t.objects[gvr][namespacedName] = obj
 			return nil
 		}
 		return errors.NewAlreadyExists(gr, newMeta.GetName())
@@ -413,7 +603,9 @@ func (t *tracker) add(gvr schema.GroupVersionResource, obj runtime.Object, ns st
 		return errors.NewNotFound(gr, newMeta.GetName())
 	}
 
-	t.objects[gvr][namespacedName] = obj
+	if err := index.add(namespacedName, obj); err != nil {
+		return err
+	}
 
 	for _, w := range t.getWatches(gvr, ns) {
 		w.Add(obj)
@@ -443,51 +635,35 @@ func (t *tracker) Delete(gvr schema.GroupVersionResource, ns, name string) error
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	objs, ok := t.objects[gvr]
+	index, ok := t.objects[gvr]
 	if !ok {
 		return errors.NewNotFound(gvr.GroupResource(), name)
 	}
 
 	namespacedName := types.NamespacedName{Namespace: ns, Name: name}
-	obj, ok := objs[namespacedName]
+	obj, ok := index.get(namespacedName)
 	if !ok {
 		return errors.NewNotFound(gvr.GroupResource(), name)
 	}
 
-	delete(objs, namespacedName)
+	index.remove(namespacedName)
 	for _, w := range t.getWatches(gvr, ns) {
 		w.Delete(obj)
 	}
 	return nil
 }
 
-// filterByNamespace returns all objects in the collection that
-// match provided namespace. Empty namespace matches
-// non-namespaced objects.
-func filterByNamespace(objs map[types.NamespacedName]runtime.Object, ns string) ([]runtime.Object, error) {
-	var res []runtime.Object
-
-	for _, obj := range objs {
-		acc, err := meta.Accessor(obj)
-		if err != nil {
-			return nil, err
-		}
-		if ns != "" && acc.GetNamespace() != ns {
-			continue
-		}
-		res = append(res, obj)
-	}
-
-	// Sort res to get deterministic order.
-	sort.Slice(res, func(i, j int) bool {
-		acc1, _ := meta.Accessor(res[i])
-		acc2, _ := meta.Accessor(res[j])
+// sortObjects sorts the objects by namespace and name to get deterministic
+// order.
+func sortObjects(objs []runtime.Object) {
+	sort.Slice(objs, func(i, j int) bool {
+		acc1, _ := meta.Accessor(objs[i])
+		acc2, _ := meta.Accessor(objs[j])
 		if acc1.GetNamespace() != acc2.GetNamespace() {
 			return acc1.GetNamespace() < acc2.GetNamespace()
 		}
 		return acc1.GetName() < acc2.GetName()
 	})
-	return res, nil
 }
 
 func DefaultWatchReactor(watchInterface watch.Interface, err error) WatchReactionFunc {
@@ -25,9 +25,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
@@ -409,3 +412,133 @@ func Test_resourceCovers(t *testing.T) {
 		})
 	}
 }
+
+func newIndexTestTracker(t *testing.T) (ObjectTracker, schema.GroupVersionResource, schema.GroupVersionKind) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	codecs := serializer.NewCodecFactory(scheme)
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	return NewObjectTracker(scheme, codecs.UniversalDecoder()), gvr, gvk
+}
+
+func indexTestPod(name, namespace string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+	}
+}
+
+func listedPodNames(t *testing.T, obj runtime.Object) []string {
+	list, ok := obj.(*corev1.PodList)
+	if !ok {
+		t.Fatalf("unexpected list type %T", obj)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, pod := range list.Items {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestTrackerListWithSelector(t *testing.T) {
+	o, gvr, gvk := newIndexTestTracker(t)
+	indexed, ok := o.(IndexedObjectTracker)
+	if !ok {
+		t.Fatal("default tracker does not implement IndexedObjectTracker")
+	}
+
+	pods := []*corev1.Pod{
+		indexTestPod("pod-a", "ns1", map[string]string{"app": "foo"}),
+		indexTestPod("pod-b", "ns1", map[string]string{"app": "foo", "tier": "db"}),
+		indexTestPod("pod-c", "ns2", map[string]string{"app": "bar"}),
+		indexTestPod("pod-d", "ns2", nil),
+	}
+	for _, pod := range pods {
+		if err := o.Create(gvr, pod, pod.Namespace); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		ns       string
+		selector string
+		expected []string
+	}{
+		{"equality in one namespace", "ns1", "app=foo", []string{"pod-a", "pod-b"}},
+		{"equality across namespaces", "", "app=foo", []string{"pod-a", "pod-b"}},
+		{"two equality requirements", "ns1", "app=foo,tier=db", []string{"pod-b"}},
+		{"no matching objects", "ns2", "app=foo", []string{}},
+		{"set-based requirement", "", "app in (bar)", []string{"pod-c"}},
+		{"inequality scans the namespace", "ns2", "app!=foo", []string{"pod-c", "pod-d"}},
+		{"everything", "ns2", "", []string{"pod-c", "pod-d"}},
+	}
+	for _, test := range testCases {
+		selector, err := labels.Parse(test.selector)
+		if err != nil {
+			t.Fatalf("%s: failed to parse selector: %v", test.desc, err)
+		}
+		obj, err := indexed.ListWithSelector(gvr, gvk, test.ns, selector)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.desc, err)
+		}
+		assert.Equal(t, test.expected, listedPodNames(t, obj), test.desc)
+	}
+}
+
+func TestTrackerIndexedKeys(t *testing.T) {
+	o, gvr, _ := newIndexTestTracker(t)
+	indexed := o.(IndexedObjectTracker)
+
+	if err := o.Create(gvr, indexTestPod("pod-a", "ns1", map[string]string{"app": "foo"}), "ns1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Create(gvr, indexTestPod("pod-b", "ns2", map[string]string{"app": "foo"}), "ns2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []types.NamespacedName{
+		{Namespace: "ns1", Name: "pod-a"},
+		{Namespace: "ns2", Name: "pod-b"},
+	}
+	assert.Equal(t, expected, indexed.IndexedKeys(gvr, "app", "foo"))
+
+	// Updating the labels of an object has to move it between index buckets.
+	if err := o.Update(gvr, indexTestPod("pod-a", "ns1", map[string]string{"app": "bar"}), "ns1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, []types.NamespacedName{{Namespace: "ns2", Name: "pod-b"}}, indexed.IndexedKeys(gvr, "app", "foo"))
+	assert.Equal(t, []types.NamespacedName{{Namespace: "ns1", Name: "pod-a"}}, indexed.IndexedKeys(gvr, "app", "bar"))
+
+	// Deleting an object has to remove its index entries.
+	if err := o.Delete(gvr, "ns1", "pod-a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Empty(t, indexed.IndexedKeys(gvr, "app", "bar"))
+}
+
+func TestObjectReactionListWithSelector(t *testing.T) {
+	o, gvr, gvk := newIndexTestTracker(t)
+	if err := o.Create(gvr, indexTestPod("pod-a", "ns1", map[string]string{"app": "foo"}), "ns1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Create(gvr, indexTestPod("pod-b", "ns1", map[string]string{"app": "bar"}), "ns1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reaction := ObjectReaction(o)
+	handled, obj, err := reaction(NewListAction(gvr, gvk, "ns1", metav1.ListOptions{LabelSelector: "app=foo"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !handled {
+		t.Fatal("expected the list action to be handled")
+	}
+	assert.Equal(t, []string{"pod-a"}, listedPodNames(t, obj))
+}
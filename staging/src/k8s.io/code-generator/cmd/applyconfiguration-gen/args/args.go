@@ -42,6 +42,11 @@ type CustomArgs struct {
 	ExternalApplyConfigurations map[types.Name]string
 
 	OpenAPISchemaFilePath string
+
+	// JSONSchemaOutputFile, if set, is the file a JSON schema document
+	// describing all generated apply configurations is written to, for
+	// consumption by non-Go tooling.
+	JSONSchemaOutputFile string
 }
 
 // NewDefaults returns default arguments for the generator.
@@ -69,6 +74,8 @@ func (ca *CustomArgs) AddFlags(fs *pflag.FlagSet, inputBase string) {
 			"For example: k8s.io/api/apps/v1.Deployment:k8s.io/client-go/applyconfigurations/apps/v1")
 	pflag.StringVar(&ca.OpenAPISchemaFilePath, "openapi-schema", "",
 		"path to the openapi schema containing all the types that apply configurations will be generated for")
+	pflag.StringVar(&ca.JSONSchemaOutputFile, "json-schema-output", "",
+		"path of a file to write a JSON schema document describing all generated apply configurations to")
 }
 
 // Validate checks the given arguments.
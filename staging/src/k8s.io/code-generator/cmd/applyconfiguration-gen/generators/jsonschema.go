@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/gengo/types"
+)
+
+// schemaDocument is the root of the exported JSON schema. It contains one
+// definition per generated apply configuration so that non-Go tooling can
+// consume the same structural contract the Go builders implement.
+type schemaDocument struct {
+	Schema      string                       `json:"$schema"`
+	Description string                       `json:"description"`
+	Definitions map[string]*schemaDefinition `json:"definitions"`
+}
+
+// schemaDefinition is a JSON schema fragment describing a single type or
+// field. Only the subset of JSON schema needed to describe apply
+// configurations is used.
+type schemaDefinition struct {
+	Description          string                       `json:"description,omitempty"`
+	Type                 string                       `json:"type,omitempty"`
+	Format               string                       `json:"format,omitempty"`
+	Ref                  string                       `json:"$ref,omitempty"`
+	Properties           map[string]*schemaDefinition `json:"properties,omitempty"`
+	AdditionalProperties *schemaDefinition            `json:"additionalProperties,omitempty"`
+	Items                *schemaDefinition            `json:"items,omitempty"`
+	AllOf                []*schemaDefinition          `json:"allOf,omitempty"`
+}
+
+// writeJSONSchema exports a JSON schema document describing every apply
+// configuration generated in this run to the given file.
+func writeJSONSchema(outputFile string, pkgTypes map[string]*types.Package, refs refGraph) error {
+	// localTypes maps the types generated in this run to their go struct, so
+	// that references can be distinguished from apply configurations generated
+	// by other runs (e.g. client-go when generating a third party project).
+	localTypes := map[types.Name]*types.Type{}
+	for _, p := range pkgTypes {
+		for _, t := range p.Types {
+			if _, ok := refs[t.Name]; ok {
+				localTypes[t.Name] = t
+			}
+		}
+	}
+
+	doc := &schemaDocument{
+		Schema:      "http://json-schema.org/draft-07/schema#",
+		Description: "Apply configurations generated by applyconfiguration-gen.",
+		Definitions: map[string]*schemaDefinition{},
+	}
+	for name, t := range localTypes {
+		doc.Definitions[schemaDefinitionName(refs[name], name)] = schemaForStruct(t, localTypes, refs)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputFile, append(data, '\n'), 0644)
+}
+
+// schemaDefinitionName returns the name a generated apply configuration is
+// registered under in the definitions map, e.g.
+// "io.k8s.client-go.applyconfigurations.apps.v1.DeploymentApplyConfiguration".
+func schemaDefinitionName(pkg string, name types.Name) string {
+	return friendlyName(typeName(types.Ref(pkg, name.Name+ApplyConfigurationTypeSuffix)))
+}
+
+// schemaForStruct describes one apply configuration. Inline members are
+// merged in via allOf, all other members become optional properties named
+// after their json tag, mirroring the generated go builders.
+func schemaForStruct(t *types.Type, localTypes map[types.Name]*types.Type, refs refGraph) *schemaDefinition {
+	for t.Kind == types.Alias {
+		t = t.Underlying
+	}
+	def := &schemaDefinition{
+		Type:       "object",
+		Properties: map[string]*schemaDefinition{},
+	}
+	var inline []*schemaDefinition
+	for _, member := range t.Members {
+		tags, ok := lookupJSONTags(member)
+		if !ok {
+			continue
+		}
+		if tags.inline {
+			inline = append(inline, schemaForField(member.Type, localTypes, refs))
+			continue
+		}
+		def.Properties[tags.name] = schemaForField(member.Type, localTypes, refs)
+	}
+	if len(def.Properties) == 0 {
+		def.Properties = nil
+	}
+	if len(inline) > 0 {
+		def.AllOf = inline
+	}
+	return def
+}
+
+// schemaForField describes the value of a single field. References to other
+// apply configurations generated in this run become $refs; everything else is
+// approximated structurally.
+func schemaForField(t *types.Type, localTypes map[types.Name]*types.Type, refs refGraph) *schemaDefinition {
+	switch t.Kind {
+	case types.Pointer:
+		return schemaForField(t.Elem, localTypes, refs)
+	case types.Alias:
+		if pkg, ok := refs[t.Name]; ok {
+			if _, local := localTypes[t.Name]; local {
+				return &schemaDefinition{Ref: "#/definitions/" + schemaDefinitionName(pkg, t.Name)}
+			}
+		}
+		return schemaForField(t.Underlying, localTypes, refs)
+	case types.Slice:
+		if t.Elem == types.Byte {
+			// []byte is base64 encoded in JSON.
+			return &schemaDefinition{Type: "string", Format: "byte"}
+		}
+		return &schemaDefinition{Type: "array", Items: schemaForField(t.Elem, localTypes, refs)}
+	case types.Map:
+		return &schemaDefinition{Type: "object", AdditionalProperties: schemaForField(t.Elem, localTypes, refs)}
+	case types.Struct:
+		if pkg, ok := refs[t.Name]; ok {
+			if _, local := localTypes[t.Name]; local {
+				return &schemaDefinition{Ref: "#/definitions/" + schemaDefinitionName(pkg, t.Name)}
+			}
+		}
+		// Structs without an apply configuration in this run (e.g. types with
+		// custom marshalling such as resource.Quantity, or apply
+		// configurations generated by another run) accept any value.
+		return &schemaDefinition{Description: fmt.Sprintf("Go type: %s", typeName(t))}
+	case types.Builtin:
+		return schemaForBuiltin(t)
+	default:
+		// Interfaces (e.g. runtime.RawExtension fields) accept any value.
+		return &schemaDefinition{Description: fmt.Sprintf("Go type: %s", typeName(t))}
+	}
+}
+
+func schemaForBuiltin(t *types.Type) *schemaDefinition {
+	switch t {
+	case types.String:
+		return &schemaDefinition{Type: "string"}
+	case types.Bool:
+		return &schemaDefinition{Type: "boolean"}
+	case types.Int64, types.Uint64:
+		return &schemaDefinition{Type: "integer", Format: "int64"}
+	case types.Float64, types.Float32, types.Float:
+		return &schemaDefinition{Type: "number"}
+	default:
+		// The remaining builtins used by API types are all integers.
+		return &schemaDefinition{Type: "integer", Format: "int32"}
+	}
+}
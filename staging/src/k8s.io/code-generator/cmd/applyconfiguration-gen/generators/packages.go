@@ -69,6 +69,12 @@ func Packages(context *generator.Context, arguments *args.GeneratorArgs) generat
 		klog.Fatalf("Failed build type models from typeModels %s: %v", customArgs.OpenAPISchemaFilePath, err)
 	}
 
+	if len(customArgs.JSONSchemaOutputFile) > 0 {
+		if err := writeJSONSchema(customArgs.JSONSchemaOutputFile, pkgTypes, refs); err != nil {
+			klog.Fatalf("Failed to write JSON schema to %s: %v", customArgs.JSONSchemaOutputFile, err)
+		}
+	}
+
 	groupVersions := make(map[string]clientgentypes.GroupVersions)
 	groupGoNames := make(map[string]string)
 	applyConfigsForGroupVersion := make(map[clientgentypes.GroupVersion][]applyConfig)
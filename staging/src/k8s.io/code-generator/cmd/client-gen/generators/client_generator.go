@@ -161,6 +161,33 @@ func packageForGroup(gv clientgentypes.GroupVersion, typeList []*types.Type, cli
 					typeToMatch:               t,
 					imports:                   generator.NewImportTracker(),
 				})
+				generators = append(generators, &genMetadataForType{
+					DefaultGen: generator.DefaultGen{
+						OptionalName: strings.ToLower(c.Namers["private"].Name(t)) + "_metadata",
+					},
+					outputPackage:    groupVersionClientPackage,
+					inputPackage:     inputPackage,
+					clientsetPackage: clientsetPackage,
+					group:            gv.Group.NonEmpty(),
+					version:          gv.Version.String(),
+					groupGoName:      groupGoName,
+					typeToMatch:      t,
+					imports:          generator.NewImportTracker(),
+				})
+				if tags := util.MustParseClientGenTags(append(t.SecondClosestCommentLines, t.CommentLines...)); len(tags.EventReasons) > 0 {
+					generators = append(generators, &genEventRecorderForType{
+						DefaultGen: generator.DefaultGen{
+							OptionalName: strings.ToLower(c.Namers["private"].Name(t)) + "_events",
+						},
+						outputPackage: groupVersionClientPackage,
+						inputPackage:  inputPackage,
+						group:         gv.Group.NonEmpty(),
+						version:       gv.Version.String(),
+						groupGoName:   groupGoName,
+						typeToMatch:   t,
+						imports:       generator.NewImportTracker(),
+					})
+				}
 			}
 
 			generators = append(generators, &genGroup{
@@ -63,6 +63,19 @@ func PackageForGroup(gv clientgentypes.GroupVersion, typeList []*types.Type, cli
 					imports:                   generator.NewImportTracker(),
 					applyConfigurationPackage: applyBuilderPackage,
 				})
+				generators = append(generators, &genFakeMetadataForType{
+					DefaultGen: generator.DefaultGen{
+						OptionalName: "fake_" + strings.ToLower(c.Namers["private"].Name(t)) + "_metadata",
+					},
+					outputPackage:     outputPackage,
+					realClientPackage: realClientPackage,
+					inputPackage:      inputPackage,
+					group:             gv.Group.NonEmpty(),
+					version:           gv.Version.String(),
+					groupGoName:       groupGoName,
+					typeToMatch:       t,
+					imports:           generator.NewImportTracker(),
+				})
 			}
 
 			generators = append(generators, &genFakeForGroup{
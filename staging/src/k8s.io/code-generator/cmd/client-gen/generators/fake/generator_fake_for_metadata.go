@@ -0,0 +1,275 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+
+	"k8s.io/code-generator/cmd/client-gen/generators/util"
+)
+
+// genFakeMetadataForType produces a fake metadata-only client file for each
+// top-level type, mirroring the metadata clients of the real clientset. The
+// fakes serve PartialObjectMetadata derived from the full objects held by the
+// object tracker.
+type genFakeMetadataForType struct {
+	generator.DefaultGen
+	outputPackage     string
+	realClientPackage string
+	group             string
+	version           string
+	groupGoName       string
+	inputPackage      string
+	typeToMatch       *types.Type
+	imports           namer.ImportTracker
+}
+
+var _ generator.Generator = &genFakeMetadataForType{}
+
+// Filter ignores all but one type because we're making a single file per type.
+func (g *genFakeMetadataForType) Filter(c *generator.Context, t *types.Type) bool {
+	return t == g.typeToMatch
+}
+
+func (g *genFakeMetadataForType) Namers(c *generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		"raw": namer.NewRawNamer(g.outputPackage, g.imports),
+	}
+}
+
+func (g *genFakeMetadataForType) Imports(c *generator.Context) (imports []string) {
+	imports = g.imports.ImportLines()
+	// The import tracker already claims the "v1"-style alias for metav1, so the
+	// real client package gets an explicit, collision-free alias.
+	imports = append(imports, fmt.Sprintf("%s \"%s\"", g.realClientAlias(), g.realClientPackage))
+	return imports
+}
+
+// realClientAlias returns the import alias used for the real (non-fake) typed
+// client package.
+func (g *genFakeMetadataForType) realClientAlias() string {
+	return "typed" + strings.ToLower(g.groupGoName+filepath.Base(g.realClientPackage))
+}
+
+// GenerateType makes the body of a file implementing the fake metadata-only client for type t.
+func (g *genFakeMetadataForType) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	sw := generator.NewSnippetWriter(w, c, "$", "$")
+	tags, err := util.ParseClientGenTags(append(t.SecondClosestCommentLines, t.CommentLines...))
+	if err != nil {
+		return err
+	}
+
+	const pkgClientGoTesting = "k8s.io/client-go/testing"
+	m := map[string]interface{}{
+		"type":                      t,
+		"namespaced":                !tags.NonNamespaced,
+		"GroupGoName":               g.groupGoName,
+		"Version":                   namer.IC(g.version),
+		"realClientPackage":         g.realClientAlias(),
+		"GetOptions":                c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "GetOptions"}),
+		"ListOptions":               c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "ListOptions"}),
+		"PartialObjectMetadata":     c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "PartialObjectMetadata"}),
+		"PartialObjectMetadataList": c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "PartialObjectMetadataList"}),
+		"watchInterface":            c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/watch", Name: "Interface"}),
+		"watchEvent":                c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/watch", Name: "Event"}),
+		"watchFilter":               c.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/watch", Name: "Filter"}),
+		"metaAccessor":              c.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/api/meta", Name: "Accessor"}),
+		"metaExtractList":           c.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/api/meta", Name: "ExtractList"}),
+		"asPartialObjectMetadata":   c.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/api/meta", Name: "AsPartialObjectMetadata"}),
+		"NewGetAction":              c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewGetAction"}),
+		"NewRootGetAction":          c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewRootGetAction"}),
+		"NewListAction":             c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewListAction"}),
+		"NewRootListAction":         c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewRootListAction"}),
+		"NewWatchAction":            c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewWatchAction"}),
+		"NewRootWatchAction":        c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "NewRootWatchAction"}),
+		"ExtractFromListOptions":    c.Universe.Function(types.Name{Package: pkgClientGoTesting, Name: "ExtractFromListOptions"}),
+		"runtimeObject":             c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/runtime", Name: "Object"}),
+		"labelsEverything":          c.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/labels", Name: "Everything"}),
+		"labelsSet":                 c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/labels", Name: "Set"}),
+	}
+
+	if tags.NonNamespaced {
+		sw.Do(fakeMetadataStructNonNamespaced, m)
+		sw.Do(fakeMetadataGetterNonNamespaced, m)
+		sw.Do(fakeMetadataGetNonNamespacedTemplate, m)
+		sw.Do(fakeMetadataListNonNamespacedTemplate, m)
+		sw.Do(fakeMetadataWatchNonNamespacedTemplate, m)
+	} else {
+		sw.Do(fakeMetadataStructNamespaced, m)
+		sw.Do(fakeMetadataGetterNamespaced, m)
+		sw.Do(fakeMetadataGetNamespacedTemplate, m)
+		sw.Do(fakeMetadataListNamespacedTemplate, m)
+		sw.Do(fakeMetadataWatchNamespacedTemplate, m)
+	}
+	sw.Do(fakeMetadataHelpersTemplate, m)
+
+	return sw.Error()
+}
+
+var fakeMetadataStructNamespaced = `
+// Fake$.type|public$Metadata implements $.type|public$MetadataInterface
+type Fake$.type|public$Metadata struct {
+	Fake *Fake$.GroupGoName$$.Version$
+	ns   string
+}
+`
+
+var fakeMetadataStructNonNamespaced = `
+// Fake$.type|public$Metadata implements $.type|public$MetadataInterface
+type Fake$.type|public$Metadata struct {
+	Fake *Fake$.GroupGoName$$.Version$
+}
+`
+
+var fakeMetadataGetterNamespaced = `
+func (c *Fake$.GroupGoName$$.Version$) $.type|public$Metadata(namespace string) $.realClientPackage$.$.type|public$MetadataInterface {
+	return &Fake$.type|public$Metadata{c, namespace}
+}
+`
+
+var fakeMetadataGetterNonNamespaced = `
+func (c *Fake$.GroupGoName$$.Version$) $.type|public$Metadata() $.realClientPackage$.$.type|public$MetadataInterface {
+	return &Fake$.type|public$Metadata{c}
+}
+`
+
+var fakeMetadataGetNamespacedTemplate = `
+// Get takes name of the $.type|private$, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *Fake$.type|public$Metadata) Get(ctx context.Context, name string, options $.GetOptions|raw$) (*$.PartialObjectMetadata|raw$, error) {
+	obj, err := c.Fake.
+		Invokes($.NewGetAction|raw$($.type|allLowercasePlural$Resource, c.ns, name), &$.type|raw${})
+
+	if obj == nil {
+		return nil, err
+	}
+	accessor, err := $.metaAccessor|raw$(obj)
+	if err != nil {
+		return nil, err
+	}
+	return $.asPartialObjectMetadata|raw$(accessor), err
+}
+`
+
+var fakeMetadataGetNonNamespacedTemplate = `
+// Get takes name of the $.type|private$, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *Fake$.type|public$Metadata) Get(ctx context.Context, name string, options $.GetOptions|raw$) (*$.PartialObjectMetadata|raw$, error) {
+	obj, err := c.Fake.
+		Invokes($.NewRootGetAction|raw$($.type|allLowercasePlural$Resource, name), &$.type|raw${})
+
+	if obj == nil {
+		return nil, err
+	}
+	accessor, err := $.metaAccessor|raw$(obj)
+	if err != nil {
+		return nil, err
+	}
+	return $.asPartialObjectMetadata|raw$(accessor), err
+}
+`
+
+var fakeMetadataListNamespacedTemplate = `
+// List takes label and field selectors, and returns the partial object metadata of the $.type|publicPlural$ that match those selectors.
+func (c *Fake$.type|public$Metadata) List(ctx context.Context, opts $.ListOptions|raw$) (*$.PartialObjectMetadataList|raw$, error) {
+	obj, err := c.Fake.
+		Invokes($.NewListAction|raw$($.type|allLowercasePlural$Resource, $.type|allLowercasePlural$Kind, c.ns, opts), &$.type|raw$List{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return $.type|allLowercasePlural$PartialMetadataList(obj, opts)
+}
+`
+
+var fakeMetadataListNonNamespacedTemplate = `
+// List takes label and field selectors, and returns the partial object metadata of the $.type|publicPlural$ that match those selectors.
+func (c *Fake$.type|public$Metadata) List(ctx context.Context, opts $.ListOptions|raw$) (*$.PartialObjectMetadataList|raw$, error) {
+	obj, err := c.Fake.
+		Invokes($.NewRootListAction|raw$($.type|allLowercasePlural$Resource, $.type|allLowercasePlural$Kind, opts), &$.type|raw$List{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return $.type|allLowercasePlural$PartialMetadataList(obj, opts)
+}
+`
+
+var fakeMetadataWatchNamespacedTemplate = `
+// Watch returns a watch.Interface that watches the requested $.type|privatePlural$ as partial object metadata.
+func (c *Fake$.type|public$Metadata) Watch(ctx context.Context, opts $.ListOptions|raw$) ($.watchInterface|raw$, error) {
+	w, err := c.Fake.
+		InvokesWatch($.NewWatchAction|raw$($.type|allLowercasePlural$Resource, c.ns, opts))
+	if err != nil {
+		return nil, err
+	}
+	return $.type|allLowercasePlural$PartialMetadataWatch(w), nil
+}
+`
+
+var fakeMetadataWatchNonNamespacedTemplate = `
+// Watch returns a watch.Interface that watches the requested $.type|privatePlural$ as partial object metadata.
+func (c *Fake$.type|public$Metadata) Watch(ctx context.Context, opts $.ListOptions|raw$) ($.watchInterface|raw$, error) {
+	w, err := c.Fake.
+		InvokesWatch($.NewRootWatchAction|raw$($.type|allLowercasePlural$Resource, opts))
+	if err != nil {
+		return nil, err
+	}
+	return $.type|allLowercasePlural$PartialMetadataWatch(w), nil
+}
+`
+
+var fakeMetadataHelpersTemplate = `
+// $.type|allLowercasePlural$PartialMetadataList converts a tracked $.type|raw$List into a
+// PartialObjectMetadataList, honoring the label selector of the list options.
+func $.type|allLowercasePlural$PartialMetadataList(obj $.runtimeObject|raw$, opts $.ListOptions|raw$) (*$.PartialObjectMetadataList|raw$, error) {
+	label, _, _ := $.ExtractFromListOptions|raw$(opts)
+	if label == nil {
+		label = $.labelsEverything|raw$()
+	}
+	items, err := $.metaExtractList|raw$(obj)
+	if err != nil {
+		return nil, err
+	}
+	list := &$.PartialObjectMetadataList|raw${}
+	for _, item := range items {
+		accessor, err := $.metaAccessor|raw$(item)
+		if err != nil {
+			return nil, err
+		}
+		if label.Matches($.labelsSet|raw$(accessor.GetLabels())) {
+			list.Items = append(list.Items, *$.asPartialObjectMetadata|raw$(accessor))
+		}
+	}
+	return list, nil
+}
+
+// $.type|allLowercasePlural$PartialMetadataWatch rewrites watch events to carry partial object metadata.
+func $.type|allLowercasePlural$PartialMetadataWatch(w $.watchInterface|raw$) $.watchInterface|raw$ {
+	return $.watchFilter|raw$(w, func(in $.watchEvent|raw$) ($.watchEvent|raw$, bool) {
+		if accessor, err := $.metaAccessor|raw$(in.Object); err == nil {
+			in.Object = $.asPartialObjectMetadata|raw$(accessor)
+		}
+		return in, true
+	})
+}
+`
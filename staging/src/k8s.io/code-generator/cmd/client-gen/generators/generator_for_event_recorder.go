@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generators
+
+import (
+	"io"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+
+	"k8s.io/code-generator/cmd/client-gen/generators/util"
+)
+
+// genEventRecorderForType produces a typed event recorder for each top-level
+// type carrying a +genclient:eventReasons tag. The recorder derives the
+// objectReference from the typed object and only accepts the reasons declared
+// on the type, so controllers cannot emit events with a wrong kind,
+// apiVersion or a free-form reason.
+type genEventRecorderForType struct {
+	generator.DefaultGen
+	outputPackage string
+	inputPackage  string
+	group         string
+	version       string
+	groupGoName   string
+	typeToMatch   *types.Type
+	imports       namer.ImportTracker
+}
+
+var _ generator.Generator = &genEventRecorderForType{}
+
+// Filter ignores all but one type because we're making a single file per type.
+func (g *genEventRecorderForType) Filter(c *generator.Context, t *types.Type) bool {
+	return t == g.typeToMatch
+}
+
+func (g *genEventRecorderForType) Namers(c *generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		"raw": namer.NewRawNamer(g.outputPackage, g.imports),
+	}
+}
+
+func (g *genEventRecorderForType) Imports(c *generator.Context) (imports []string) {
+	return g.imports.ImportLines()
+}
+
+// apiVersion returns the apiVersion string recorded in object references,
+// e.g. "apps/v1", or just "v1" for the legacy core group.
+func (g *genEventRecorderForType) apiVersion() string {
+	if g.group == "core" || g.group == "" {
+		return g.version
+	}
+	return g.group + "/" + g.version
+}
+
+// GenerateType makes the body of a file implementing the typed event recorder for type t.
+func (g *genEventRecorderForType) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	sw := generator.NewSnippetWriter(w, c, "$", "$")
+	tags, err := util.ParseClientGenTags(append(t.SecondClosestCommentLines, t.CommentLines...))
+	if err != nil {
+		return err
+	}
+
+	m := map[string]interface{}{
+		"type":            t,
+		"apiVersion":      g.apiVersion(),
+		"EventRecorder":   c.Universe.Type(types.Name{Package: "k8s.io/client-go/tools/record", Name: "EventRecorder"}),
+		"ObjectReference": c.Universe.Type(types.Name{Package: "k8s.io/api/core/v1", Name: "ObjectReference"}),
+	}
+
+	sw.Do(eventReasonTypeTemplate, m)
+	sw.Do("const (\n", m)
+	for _, reason := range tags.EventReasons {
+		m["reason"] = reason
+		sw.Do(eventReasonConstTemplate, m)
+	}
+	sw.Do(")\n", m)
+	sw.Do(eventRecorderStructTemplate, m)
+	sw.Do(eventRecorderConstructorTemplate, m)
+	sw.Do(eventRecorderEventTemplate, m)
+	sw.Do(eventRecorderEventfTemplate, m)
+	sw.Do(eventRecorderObjectReferenceTemplate, m)
+
+	return sw.Error()
+}
+
+var eventReasonTypeTemplate = `
+// $.type|public$EventReason is the set of standardized reasons for events
+// recorded about $.type|publicPlural$, declared with the +genclient:eventReasons
+// tag on the type.
+type $.type|public$EventReason string
+`
+
+var eventReasonConstTemplate = `
+// $.type|public$EventReason$.reason$ is the "$.reason$" event reason.
+$.type|public$EventReason$.reason$ $.type|public$EventReason = "$.reason$"
+`
+
+var eventRecorderStructTemplate = `
+// $.type|public$EventRecorder records events about $.type|publicPlural$ with a
+// correctly populated objectReference and a standardized reason.
+type $.type|public$EventRecorder struct {
+	recorder $.EventRecorder|raw$
+}
+`
+
+var eventRecorderConstructorTemplate = `
+// EventRecorderFor$.type|public$ returns a typed event recorder for $.type|publicPlural$.
+func EventRecorderFor$.type|public$(recorder $.EventRecorder|raw$) *$.type|public$EventRecorder {
+	return &$.type|public$EventRecorder{recorder: recorder}
+}
+`
+
+var eventRecorderEventTemplate = `
+// Event records an event about the given $.type|private$.
+func (r *$.type|public$EventRecorder) Event(obj *$.type|raw$, eventtype string, reason $.type|public$EventReason, message string) {
+	r.recorder.Event(r.objectReference(obj), eventtype, string(reason), message)
+}
+`
+
+var eventRecorderEventfTemplate = `
+// Eventf records an event about the given $.type|private$ with a formatted message.
+func (r *$.type|public$EventRecorder) Eventf(obj *$.type|raw$, eventtype string, reason $.type|public$EventReason, messageFmt string, args ...interface{}) {
+	r.recorder.Eventf(r.objectReference(obj), eventtype, string(reason), messageFmt, args...)
+}
+`
+
+var eventRecorderObjectReferenceTemplate = `
+// objectReference builds the reference the event points at. Deriving it from
+// the typed object guarantees that kind, apiVersion and UID are always populated.
+func (r *$.type|public$EventRecorder) objectReference(obj *$.type|raw$) *$.ObjectReference|raw$ {
+	return &$.ObjectReference|raw${
+		Kind:            "$.type|singularKind$",
+		APIVersion:      "$.apiVersion$",
+		Namespace:       obj.Namespace,
+		Name:            obj.Name,
+		UID:             obj.UID,
+		ResourceVersion: obj.ResourceVersion,
+	}
+}
+`
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generators
+
+import (
+	"io"
+	"path/filepath"
+
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+
+	"k8s.io/code-generator/cmd/client-gen/generators/util"
+)
+
+// genMetadataForType produces a metadata-only client file for each top-level type.
+// Metadata-only clients retrieve PartialObjectMetadata instead of the full object,
+// so controllers that only need metadata avoid the full object decode.
+type genMetadataForType struct {
+	generator.DefaultGen
+	outputPackage    string
+	inputPackage     string
+	clientsetPackage string
+	group            string
+	version          string
+	groupGoName      string
+	typeToMatch      *types.Type
+	imports          namer.ImportTracker
+}
+
+var _ generator.Generator = &genMetadataForType{}
+
+// Filter ignores all but one type because we're making a single file per type.
+func (g *genMetadataForType) Filter(c *generator.Context, t *types.Type) bool {
+	return t == g.typeToMatch
+}
+
+func (g *genMetadataForType) Namers(c *generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		"raw": namer.NewRawNamer(g.outputPackage, g.imports),
+	}
+}
+
+func (g *genMetadataForType) Imports(c *generator.Context) (imports []string) {
+	return g.imports.ImportLines()
+}
+
+// GenerateType makes the body of a file implementing the metadata-only client for type t.
+func (g *genMetadataForType) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	sw := generator.NewSnippetWriter(w, c, "$", "$")
+	tags, err := util.ParseClientGenTags(append(t.SecondClosestCommentLines, t.CommentLines...))
+	if err != nil {
+		return err
+	}
+
+	m := map[string]interface{}{
+		"type":                       t,
+		"namespaced":                 !tags.NonNamespaced,
+		"GroupGoName":                g.groupGoName,
+		"Version":                    namer.IC(g.version),
+		"GetOptions":                 c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "GetOptions"}),
+		"ListOptions":                c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "ListOptions"}),
+		"PartialObjectMetadata":      c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "PartialObjectMetadata"}),
+		"PartialObjectMetadataList":  c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "PartialObjectMetadataList"}),
+		"watchInterface":             c.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/watch", Name: "Interface"}),
+		"RESTClientInterface":        c.Universe.Type(types.Name{Package: "k8s.io/client-go/rest", Name: "Interface"}),
+		"schemeParameterCodec":       c.Universe.Variable(types.Name{Package: filepath.Join(g.clientsetPackage, "scheme"), Name: "ParameterCodec"}),
+	}
+
+	sw.Do(metadataInterfaceTemplate, m)
+	if tags.NonNamespaced {
+		sw.Do(metadataGetterNonNamespaced, m)
+		sw.Do(metadataStructNonNamespaced, m)
+	} else {
+		sw.Do(metadataGetterNamespaced, m)
+		sw.Do(metadataStructNamespaced, m)
+	}
+	sw.Do(metadataGetTemplate, m)
+	sw.Do(metadataListTemplate, m)
+	sw.Do(metadataWatchTemplate, m)
+
+	return sw.Error()
+}
+
+var metadataInterfaceTemplate = `
+// $.type|private$MetadataAccept asks the server to return PartialObjectMetadata instead of the full object.
+const $.type|private$MetadataAccept = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json"
+
+// $.type|public$MetadataInterface supports retrieving only the object metadata of
+// $.type|public$ resources, avoiding the full object decode on the client.
+type $.type|public$MetadataInterface interface {
+	Get(ctx context.Context, name string, opts $.GetOptions|raw$) (*$.PartialObjectMetadata|raw$, error)
+	List(ctx context.Context, opts $.ListOptions|raw$) (*$.PartialObjectMetadataList|raw$, error)
+	Watch(ctx context.Context, opts $.ListOptions|raw$) ($.watchInterface|raw$, error)
+}
+`
+
+var metadataGetterNamespaced = `
+// $.type|public$Metadata returns an object supporting metadata-only retrieval of $.type|publicPlural$.
+func (c *$.GroupGoName$$.Version$Client) $.type|public$Metadata(namespace string) $.type|public$MetadataInterface {
+	return &$.type|private$Metadata{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+`
+
+var metadataGetterNonNamespaced = `
+// $.type|public$Metadata returns an object supporting metadata-only retrieval of $.type|publicPlural$.
+func (c *$.GroupGoName$$.Version$Client) $.type|public$Metadata() $.type|public$MetadataInterface {
+	return &$.type|private$Metadata{
+		client: c.RESTClient(),
+	}
+}
+`
+
+var metadataStructNamespaced = `
+// $.type|private$Metadata implements $.type|public$MetadataInterface
+type $.type|private$Metadata struct {
+	client $.RESTClientInterface|raw$
+	ns     string
+}
+`
+
+var metadataStructNonNamespaced = `
+// $.type|private$Metadata implements $.type|public$MetadataInterface
+type $.type|private$Metadata struct {
+	client $.RESTClientInterface|raw$
+}
+`
+
+var metadataGetTemplate = `
+// Get takes name of the $.type|private$, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *$.type|private$Metadata) Get(ctx context.Context, name string, options $.GetOptions|raw$) (result *$.PartialObjectMetadata|raw$, err error) {
+	result = &$.PartialObjectMetadata|raw${}
+	err = c.client.Get().
+		SetHeader("Accept", $.type|private$MetadataAccept).
+		$if .namespaced$Namespace(c.ns).$end$
+		Resource("$.type|resource$").
+		Name(name).
+		VersionedParams(&options, $.schemeParameterCodec|raw$).
+		Do(ctx).
+		Into(result)
+	return
+}
+`
+
+var metadataListTemplate = `
+// List takes label and field selectors, and returns the partial object metadata of the $.type|publicPlural$ that match those selectors.
+func (c *$.type|private$Metadata) List(ctx context.Context, opts $.ListOptions|raw$) (result *$.PartialObjectMetadataList|raw$, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil{
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &$.PartialObjectMetadataList|raw${}
+	err = c.client.Get().
+		SetHeader("Accept", $.type|private$MetadataAccept).
+		$if .namespaced$Namespace(c.ns).$end$
+		Resource("$.type|resource$").
+		VersionedParams(&opts, $.schemeParameterCodec|raw$).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+`
+
+var metadataWatchTemplate = `
+// Watch returns a watch.Interface that watches the requested $.type|privatePlural$ as partial object metadata.
+func (c *$.type|private$Metadata) Watch(ctx context.Context, opts $.ListOptions|raw$) ($.watchInterface|raw$, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil{
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		SetHeader("Accept", $.type|private$MetadataAccept).
+		$if .namespaced$Namespace(c.ns).$end$
+		Resource("$.type|resource$").
+		VersionedParams(&opts, $.schemeParameterCodec|raw$).
+		Timeout(timeout).
+		Watch(ctx)
+}
+`
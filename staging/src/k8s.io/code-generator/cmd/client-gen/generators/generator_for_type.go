@@ -17,6 +17,7 @@ limitations under the License.
 package generators
 
 import (
+	"fmt"
 	"io"
 	"path"
 	"path/filepath"
@@ -211,6 +212,16 @@ func (g *genClientForType) GenerateType(c *generator.Context, t *types.Type, w i
 		sw.Do(newStructNamespaced, m)
 	}
 
+	if len(tags.QPSHint) > 0 {
+		hint := util.SupportedQPSHints[tags.QPSHint]
+		m["qpsHint"] = tags.QPSHint
+		m["qpsHintQPS"] = fmt.Sprintf("%g", hint.QPS)
+		m["qpsHintBurst"] = fmt.Sprintf("%d", hint.Burst)
+		m["flowcontrolRateLimiter"] = c.Universe.Type(types.Name{Package: "k8s.io/client-go/util/flowcontrol", Name: "RateLimiter"})
+		m["flowcontrolNewTokenBucketRateLimiter"] = c.Universe.Function(types.Name{Package: "k8s.io/client-go/util/flowcontrol", Name: "NewTokenBucketRateLimiter"})
+		sw.Do(qpsHintTemplate, m)
+	}
+
 	if tags.NoVerbs {
 		return sw.Error()
 	}
@@ -453,6 +464,21 @@ func new$.type|publicPlural$(c *$.GroupGoName$$.Version$Client) *$.type|privateP
 	}
 }
 `
+
+// template for the rate limiter defaults derived from the qpsHint tag
+var qpsHintTemplate = `
+// $.type|publicPlural$QPSHint is the expected access pattern declared for
+// $.type|public$ clients with the +genclient:qpsHint tag.
+const $.type|publicPlural$QPSHint = "$.qpsHint$"
+
+// New$.type|publicPlural$RateLimiter returns the default client-side rate limiter
+// for $.type|public$ clients, derived from the declared "$.qpsHint$" access pattern
+// ($.qpsHintQPS$ QPS, burst $.qpsHintBurst$). Set it as the RateLimiter of the rest.Config
+// used to construct the clientset to apply the declared defaults.
+func New$.type|publicPlural$RateLimiter() $.flowcontrolRateLimiter|raw$ {
+	return $.flowcontrolNewTokenBucketRateLimiter|raw$($.qpsHintQPS$, $.qpsHintBurst$)
+}
+`
 var listTemplate = `
 // List takes label and field selectors, and returns the list of $.resultType|publicPlural$ that match those selectors.
 func (c *$.type|privatePlural$) List(ctx context.Context, opts $.ListOptions|raw$) (result *$.resultType|raw$List, err error) {
@@ -19,6 +19,7 @@ package util
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"k8s.io/gengo/types"
@@ -33,6 +34,8 @@ var supportedTags = []string{
 	"genclient:noStatus",
 	"genclient:readonly",
 	"genclient:method",
+	"genclient:qpsHint",
+	"genclient:eventReasons",
 }
 
 // SupportedVerbs is a list of supported verbs for +onlyVerbs and +skipVerbs.
@@ -60,6 +63,34 @@ var ReadonlyVerbs = []string{
 // genClientPrefix is the default prefix for all genclient tags.
 const genClientPrefix = "genclient:"
 
+// QPSHintValue holds the client-side rate limiter defaults generated for one
+// +genclient:qpsHint value.
+type QPSHintValue struct {
+	// QPS is the sustained request rate allowed per client.
+	QPS float32
+	// Burst is the number of requests a client may send above QPS in a burst.
+	Burst int
+}
+
+// SupportedQPSHints maps the access patterns accepted by +genclient:qpsHint
+// to the rate limiter defaults generated for them.
+var SupportedQPSHints = map[string]QPSHintValue{
+	"low":    {QPS: 1, Burst: 5},
+	"medium": {QPS: 5, Burst: 10},
+	"high":   {QPS: 20, Burst: 40},
+}
+
+// supportedQPSHintNames returns the supported qpsHint values in a stable
+// order, for use in error messages.
+func supportedQPSHintNames() []string {
+	names := make([]string, 0, len(SupportedQPSHints))
+	for name := range SupportedQPSHints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // unsupportedExtensionVerbs is a list of verbs we don't support generating
 // extension client functions for.
 var unsupportedExtensionVerbs = []string{
@@ -164,6 +195,10 @@ type Tags struct {
 	SkipVerbs []string
 	// +genclient:method=UpdateScale,verb=update,subresource=scale,input=Scale,result=Scale
 	Extensions []extension
+	// +genclient:qpsHint=low
+	QPSHint string
+	// +genclient:eventReasons=Created,Scaled
+	EventReasons []string
 }
 
 // HasVerb returns true if we should include the given verb in final client interface and
@@ -243,6 +278,26 @@ func ParseClientGenTags(lines []string) (Tags, error) {
 		}
 		ret.SkipVerbs = skipVerbs
 	}
+	if v, exists := values[genClientPrefix+"qpsHint"]; exists {
+		if len(v) == 0 || len(v[0]) == 0 {
+			return ret, fmt.Errorf("+genclient:qpsHint requires a value, one of: %v", supportedQPSHintNames())
+		}
+		if _, supported := SupportedQPSHints[v[0]]; !supported {
+			return ret, fmt.Errorf("+genclient:qpsHint=%s is invalid, supported values: %v", v[0], supportedQPSHintNames())
+		}
+		ret.QPSHint = v[0]
+	}
+	if v, exists := values[genClientPrefix+"eventReasons"]; exists {
+		if len(v) == 0 || len(v[0]) == 0 {
+			return ret, fmt.Errorf("+genclient:eventReasons requires a comma-separated list of reasons")
+		}
+		for _, reason := range strings.Split(v[0], ",") {
+			if !isValidEventReason(reason) {
+				return ret, fmt.Errorf("+genclient:eventReasons=%s is invalid, reason %q must be a CamelCase identifier", v[0], reason)
+			}
+			ret.EventReasons = append(ret.EventReasons, reason)
+		}
+	}
 	var err error
 	if ret.Extensions, err = parseClientExtensions(values); err != nil {
 		return ret, err
@@ -331,6 +386,24 @@ func parseClientExtensions(tags map[string][]string) ([]extension, error) {
 	return ret, nil
 }
 
+// isValidEventReason returns true if the reason is a CamelCase identifier, the
+// form event consumers expect machine-readable reasons in.
+func isValidEventReason(reason string) bool {
+	if len(reason) == 0 || reason[0] < 'A' || reason[0] > 'Z' {
+		return false
+	}
+	for _, r := range reason {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // validateTags validates that only supported genclient tags were provided.
 func validateClientGenTags(values map[string][]string) error {
 	for _, k := range supportedTags {
@@ -71,6 +71,30 @@ func TestParseTags(t *testing.T) {
 			lines:       []string{`+genclient`, `+genclient:invalid`},
 			expectError: true,
 		},
+		"genclient:qpsHint": {
+			lines:      []string{`+genclient`, `+genclient:qpsHint=low`},
+			expectTags: Tags{GenerateClient: true, QPSHint: "low"},
+		},
+		"genclient:qpsHint=unsupported": {
+			lines:       []string{`+genclient`, `+genclient:qpsHint=unlimited`},
+			expectError: true,
+		},
+		"genclient:qpsHint without value": {
+			lines:       []string{`+genclient`, `+genclient:qpsHint`},
+			expectError: true,
+		},
+		"genclient:eventReasons": {
+			lines:      []string{`+genclient`, `+genclient:eventReasons=Created,ScaledUp`},
+			expectTags: Tags{GenerateClient: true, EventReasons: []string{"Created", "ScaledUp"}},
+		},
+		"genclient:eventReasons without value": {
+			lines:       []string{`+genclient`, `+genclient:eventReasons`},
+			expectError: true,
+		},
+		"genclient:eventReasons not CamelCase": {
+			lines:       []string{`+genclient`, `+genclient:eventReasons=scaled up`},
+			expectError: true,
+		},
 	}
 	for key, c := range testCases {
 		result, err := ParseClientGenTags(c.lines)
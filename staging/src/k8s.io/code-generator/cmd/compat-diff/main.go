@@ -0,0 +1,195 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// compat-diff compares two generated client or applyconfiguration trees and
+// reports API breaking changes between them: exported functions or methods
+// that were removed and exported functions or methods whose signatures
+// changed. It is intended to gate staging repository publishes, so it exits
+// non-zero when breaking changes are found.
+//
+// Parameter and result names are ignored when comparing signatures, only the
+// types matter. Additions are never breaking and are not reported.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/klog/v2"
+)
+
+func main() {
+	klog.InitFlags(nil)
+
+	oldDir := pflag.String("old-dir", "", "Root of the previously published generated tree.")
+	newDir := pflag.String("new-dir", "", "Root of the generated tree about to be published.")
+	pflag.Parse()
+
+	if *oldDir == "" || *newDir == "" {
+		klog.Fatal("both --old-dir and --new-dir are required")
+	}
+
+	oldAPI, err := collectSignatures(*oldDir)
+	if err != nil {
+		klog.Fatalf("Error reading old tree: %v", err)
+	}
+	newAPI, err := collectSignatures(*newDir)
+	if err != nil {
+		klog.Fatalf("Error reading new tree: %v", err)
+	}
+
+	breaks := diffSignatures(oldAPI, newAPI)
+	for _, b := range breaks {
+		fmt.Println(b)
+	}
+	if len(breaks) > 0 {
+		fmt.Printf("%d breaking change(s) found\n", len(breaks))
+		os.Exit(1)
+	}
+}
+
+// collectSignatures walks all Go files below root and returns the signatures
+// of all exported functions and methods, keyed by package-relative path,
+// receiver type and name.
+func collectSignatures(root string) (map[string]string, error) {
+	signatures := map[string]string{}
+	fset := token.NewFileSet()
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", path, err)
+		}
+		relDir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || !funcDecl.Name.IsExported() {
+				continue
+			}
+			recv := receiverTypeName(funcDecl)
+			if recv != "" && !ast.IsExported(recv) {
+				continue
+			}
+			signatures[signatureKey(relDir, recv, funcDecl.Name.Name)] = renderSignature(fset, funcDecl.Type)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signatures, nil
+}
+
+// diffSignatures returns a sorted report of the breaking changes between the
+// old and the new API surface.
+func diffSignatures(oldAPI, newAPI map[string]string) []string {
+	var breaks []string
+	for key, oldSignature := range oldAPI {
+		newSignature, ok := newAPI[key]
+		if !ok {
+			breaks = append(breaks, fmt.Sprintf("removed: %s %s", key, oldSignature))
+			continue
+		}
+		if newSignature != oldSignature {
+			breaks = append(breaks, fmt.Sprintf("changed: %s %s -> %s", key, oldSignature, newSignature))
+		}
+	}
+	sort.Strings(breaks)
+	return breaks
+}
+
+// signatureKey identifies one function or method within a generated tree.
+func signatureKey(relDir, recv, name string) string {
+	if recv != "" {
+		return fmt.Sprintf("%s: %s.%s", relDir, recv, name)
+	}
+	return fmt.Sprintf("%s: %s", relDir, name)
+}
+
+// receiverTypeName returns the name of the method receiver type, without any
+// pointer indirection, or the empty string for plain functions.
+func receiverTypeName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+	expr := funcDecl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// renderSignature returns the canonical form of a function type with all
+// parameter and result names removed, so that renaming a parameter is not
+// reported as a breaking change.
+func renderSignature(fset *token.FileSet, funcType *ast.FuncType) string {
+	stripped := &ast.FuncType{
+		Params:  stripFieldNames(funcType.Params),
+		Results: stripFieldNames(funcType.Results),
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, stripped); err != nil {
+		return fmt.Sprintf("<unprintable: %v>", err)
+	}
+	return buf.String()
+}
+
+func stripFieldNames(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	stripped := &ast.FieldList{}
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		// A field may declare several names of one type; repeat the type so
+		// that the arity of the signature is preserved.
+		for i := 0; i < n; i++ {
+			stripped.List = append(stripped.List, &ast.Field{Type: field.Type})
+		}
+	}
+	return stripped
+}
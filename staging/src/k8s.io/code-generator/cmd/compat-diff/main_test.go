@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "compat-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDiffSignatures(t *testing.T) {
+	oldTree := writeTree(t, map[string]string{
+		"typed/pods.go": `package typed
+
+type Pods struct{}
+
+// Get is kept unchanged.
+func (c *Pods) Get(name string) (string, error) { return name, nil }
+
+// List changes its signature.
+func (c *Pods) List(selector string) ([]string, error) { return nil, nil }
+
+// Watch is removed in the new tree.
+func (c *Pods) Watch() error { return nil }
+
+// internal methods are not part of the API surface.
+func (c *Pods) refresh() {}
+`,
+		"util.go": `package client
+
+// New is a plain function.
+func New(host string) string { return host }
+`,
+	})
+	newTree := writeTree(t, map[string]string{
+		"typed/pods.go": `package typed
+
+type Pods struct{}
+
+// Get only renames a parameter, which is not breaking.
+func (c *Pods) Get(podName string) (string, error) { return podName, nil }
+
+func (c *Pods) List(selector string, limit int) ([]string, error) { return nil, nil }
+
+// Create is added, additions are not breaking.
+func (c *Pods) Create(name string) error { return nil }
+`,
+		"util.go": `package client
+
+func New(host string) string { return host }
+`,
+	})
+
+	oldAPI, err := collectSignatures(oldTree)
+	if err != nil {
+		t.Fatalf("error collecting old tree: %v", err)
+	}
+	newAPI, err := collectSignatures(newTree)
+	if err != nil {
+		t.Fatalf("error collecting new tree: %v", err)
+	}
+
+	got := diffSignatures(oldAPI, newAPI)
+	want := []string{
+		"changed: typed: Pods.List func(string) ([]string, error) -> func(string, int) ([]string, error)",
+		"removed: typed: Pods.Watch func() error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected diff:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestCollectSignaturesSkipsUnexported(t *testing.T) {
+	tree := writeTree(t, map[string]string{
+		"client.go": `package client
+
+type widget struct{}
+
+// Exported method on an unexported type is not part of the API surface.
+func (w *widget) Run() {}
+
+func helper() {}
+`,
+		"client_test.go": `package client
+
+// Test files are ignored.
+func TestSomething() {}
+`,
+	})
+
+	api, err := collectSignatures(tree)
+	if err != nil {
+		t.Fatalf("error collecting tree: %v", err)
+	}
+	if len(api) != 0 {
+		t.Errorf("expected empty API surface, got %v", api)
+	}
+}
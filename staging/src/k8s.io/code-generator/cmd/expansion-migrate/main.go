@@ -0,0 +1,281 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// expansion-migrate rewrites hand-written client expansion files
+// (*_expansion.go) to the context-aware form the generated clients use: every
+// method of an *Expansion interface and its implementation gets a leading
+// "ctx context.Context" parameter, and calls to context.TODO() or
+// context.Background() in the rewritten method bodies are replaced with ctx.
+//
+// Cases the tool cannot convert mechanically - embedded interfaces inside an
+// expansion interface, or methods which already take a first parameter named
+// ctx of some other type - are reported and left untouched. The tool exits
+// non-zero when it finds such cases, so large-scale migrations across staging
+// repositories surface the remaining manual work.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"k8s.io/klog/v2"
+)
+
+func main() {
+	klog.InitFlags(nil)
+
+	inputDir := pflag.String("input-dir", "", "Root of the tree whose *_expansion.go files should be migrated.")
+	dryRun := pflag.Bool("dry-run", false, "Only report what would be rewritten, do not modify any file.")
+	pflag.Parse()
+
+	if *inputDir == "" {
+		klog.Fatal("--input-dir is required")
+	}
+
+	var unconvertible int
+	err := filepath.Walk(*inputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "vendor" || info.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, "_expansion.go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		migrated, report, err := migrateSource(path, src)
+		if err != nil {
+			return err
+		}
+		for _, method := range report.converted {
+			fmt.Printf("%s: converted %s\n", path, method)
+		}
+		for _, reason := range report.unconvertible {
+			fmt.Printf("%s: cannot convert %s\n", path, reason)
+		}
+		unconvertible += len(report.unconvertible)
+		if bytes.Equal(migrated, src) || *dryRun {
+			return nil
+		}
+		return ioutil.WriteFile(path, migrated, info.Mode())
+	})
+	if err != nil {
+		klog.Fatalf("Error migrating %s: %v", *inputDir, err)
+	}
+	if unconvertible > 0 {
+		fmt.Printf("%d unconvertible case(s) found, convert them by hand\n", unconvertible)
+		os.Exit(1)
+	}
+}
+
+// migrationReport lists what migrateSource did to one file and what it had to
+// leave alone.
+type migrationReport struct {
+	// converted are the methods whose signatures were rewritten to ctx-first form.
+	converted []string
+	// unconvertible are the cases that need a manual migration, with the reason.
+	unconvertible []string
+}
+
+// migrateSource rewrites one expansion file to the ctx-first form and returns
+// the new content. The input is returned unchanged when there is nothing to
+// rewrite.
+func migrateSource(filename string, src []byte) ([]byte, migrationReport, error) {
+	var report migrationReport
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, report, err
+	}
+
+	// Pass one: rewrite the method signatures of the expansion interfaces.
+	methodNames := map[string]bool{}
+	changed := false
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok || !strings.HasSuffix(typeSpec.Name.Name, "Expansion") {
+				continue
+			}
+			interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
+			if !ok || interfaceType.Methods == nil {
+				continue
+			}
+			for _, method := range interfaceType.Methods.List {
+				if len(method.Names) == 0 {
+					report.unconvertible = append(report.unconvertible,
+						fmt.Sprintf("%s: embedded interface %s, convert it where it is defined", typeSpec.Name.Name, typeString(fset, method.Type)))
+					continue
+				}
+				funcType, ok := method.Type.(*ast.FuncType)
+				if !ok {
+					continue
+				}
+				name := method.Names[0].Name
+				switch classifyFirstParam(funcType) {
+				case firstParamContext:
+					// Already migrated; the implementation may still need work.
+					methodNames[name] = true
+				case firstParamCtxOtherType:
+					report.unconvertible = append(report.unconvertible,
+						fmt.Sprintf("%s.%s: first parameter is named ctx but is not a context.Context", typeSpec.Name.Name, name))
+				default:
+					insertCtxParam(funcType)
+					methodNames[name] = true
+					changed = true
+					report.converted = append(report.converted, fmt.Sprintf("%s.%s", typeSpec.Name.Name, name))
+				}
+			}
+		}
+	}
+
+	// Pass two: rewrite the implementations of the interface methods.
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv == nil || !methodNames[funcDecl.Name.Name] {
+			continue
+		}
+		switch classifyFirstParam(funcDecl.Type) {
+		case firstParamContext:
+			continue
+		case firstParamCtxOtherType:
+			report.unconvertible = append(report.unconvertible,
+				fmt.Sprintf("method %s: first parameter is named ctx but is not a context.Context", funcDecl.Name.Name))
+			continue
+		}
+		insertCtxParam(funcDecl.Type)
+		replaceContextConstructors(funcDecl.Body)
+		changed = true
+		report.converted = append(report.converted, fmt.Sprintf("method %s", funcDecl.Name.Name))
+	}
+
+	if !changed {
+		return src, report, nil
+	}
+
+	astutil.AddImport(fset, file, "context")
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, report, err
+	}
+	return buf.Bytes(), report, nil
+}
+
+const (
+	firstParamOther = iota
+	// firstParamContext means the signature already starts with a context.Context.
+	firstParamContext
+	// firstParamCtxOtherType means the first parameter is named ctx but has
+	// some other type, so a mechanical rewrite would shadow it.
+	firstParamCtxOtherType
+)
+
+func classifyFirstParam(funcType *ast.FuncType) int {
+	if funcType.Params == nil || len(funcType.Params.List) == 0 {
+		return firstParamOther
+	}
+	first := funcType.Params.List[0]
+	if isContextType(first.Type) {
+		return firstParamContext
+	}
+	if len(first.Names) > 0 && first.Names[0].Name == "ctx" {
+		return firstParamCtxOtherType
+	}
+	return firstParamOther
+}
+
+func isContextType(expr ast.Expr) bool {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	return ok && ident.Name == "context" && selector.Sel.Name == "Context"
+}
+
+// insertCtxParam prepends "ctx context.Context" to the parameter list.
+func insertCtxParam(funcType *ast.FuncType) {
+	ctxParam := &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent("ctx")},
+		Type: &ast.SelectorExpr{
+			X:   ast.NewIdent("context"),
+			Sel: ast.NewIdent("Context"),
+		},
+	}
+	if funcType.Params == nil {
+		funcType.Params = &ast.FieldList{}
+	}
+	funcType.Params.List = append([]*ast.Field{ctxParam}, funcType.Params.List...)
+}
+
+// replaceContextConstructors replaces context.TODO() and context.Background()
+// calls in the body with the new ctx parameter.
+func replaceContextConstructors(body *ast.BlockStmt) {
+	if body == nil {
+		return
+	}
+	astutil.Apply(body, nil, func(cursor *astutil.Cursor) bool {
+		call, ok := cursor.Node().(*ast.CallExpr)
+		if !ok || len(call.Args) != 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := selector.X.(*ast.Ident)
+		if !ok || ident.Name != "context" {
+			return true
+		}
+		if selector.Sel.Name != "TODO" && selector.Sel.Name != "Background" {
+			return true
+		}
+		cursor.Replace(ast.NewIdent("ctx"))
+		return true
+	})
+}
+
+func typeString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, expr); err != nil {
+		return fmt.Sprintf("<unprintable: %v>", err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrateSource(t *testing.T) {
+	src := `package v1
+
+type DeploymentExpansion interface {
+	Rollback(deploymentName string) error
+}
+
+func (c *deployments) Rollback(deploymentName string) error {
+	_, err := c.client.Post().Do(context.TODO()).Get()
+	return err
+}
+`
+	migrated, report, err := migrateSource("deployment_expansion.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(migrated)
+
+	if !strings.Contains(got, "Rollback(ctx context.Context, deploymentName string) error") {
+		t.Errorf("interface method was not rewritten to ctx-first form:\n%s", got)
+	}
+	if !strings.Contains(got, "func (c *deployments) Rollback(ctx context.Context, deploymentName string) error") {
+		t.Errorf("implementation was not rewritten to ctx-first form:\n%s", got)
+	}
+	if strings.Contains(got, "context.TODO()") {
+		t.Errorf("context.TODO() call was not replaced with ctx:\n%s", got)
+	}
+	if !strings.Contains(got, `"context"`) {
+		t.Errorf("context import was not added:\n%s", got)
+	}
+	if len(report.converted) != 2 {
+		t.Errorf("expected 2 conversions, got %v", report.converted)
+	}
+	if len(report.unconvertible) != 0 {
+		t.Errorf("expected no unconvertible cases, got %v", report.unconvertible)
+	}
+}
+
+func TestMigrateSourceAlreadyMigrated(t *testing.T) {
+	src := `package v1
+
+import "context"
+
+type DeploymentExpansion interface {
+	Rollback(ctx context.Context, deploymentName string) error
+}
+
+func (c *deployments) Rollback(ctx context.Context, deploymentName string) error {
+	return nil
+}
+`
+	migrated, report, err := migrateSource("deployment_expansion.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(migrated) != src {
+		t.Errorf("already migrated file was modified:\n%s", string(migrated))
+	}
+	if len(report.converted) != 0 || len(report.unconvertible) != 0 {
+		t.Errorf("expected an empty report, got %+v", report)
+	}
+}
+
+func TestMigrateSourceUnconvertible(t *testing.T) {
+	src := `package v1
+
+type PodExpansion interface {
+	ScaleExpansion
+	Bind(ctx string, binding string) error
+}
+
+func (c *pods) Bind(ctx string, binding string) error {
+	return nil
+}
+`
+	migrated, report, err := migrateSource("pod_expansion.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(migrated) != src {
+		t.Errorf("unconvertible file was modified:\n%s", string(migrated))
+	}
+	if len(report.unconvertible) != 2 {
+		t.Errorf("expected the embedded interface and the ctx parameter to be reported, got %v", report.unconvertible)
+	}
+}
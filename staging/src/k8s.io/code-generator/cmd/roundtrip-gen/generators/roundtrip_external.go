@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generators
+
+import (
+	"io"
+
+	clientgentypes "k8s.io/code-generator/cmd/client-gen/types"
+	"k8s.io/gengo/generator"
+	"k8s.io/gengo/namer"
+	"k8s.io/gengo/types"
+)
+
+// roundTripGenerator produces a _test.go file that fuzzes every object the
+// package registers and round-trips it through the codecs, exercising
+// defaulting, conversion and serialization the way the hand-written fuzzer
+// setup of the core groups does.
+type roundTripGenerator struct {
+	generator.DefaultGen
+	outputPackage string
+	gv            clientgentypes.GroupVersion
+	imports       namer.ImportTracker
+}
+
+var _ generator.Generator = &roundTripGenerator{}
+
+func (g *roundTripGenerator) Filter(_ *generator.Context, _ *types.Type) bool {
+	return false
+}
+
+func (g *roundTripGenerator) Imports(c *generator.Context) (imports []string) {
+	return g.imports.ImportLines()
+}
+
+func (g *roundTripGenerator) Namers(_ *generator.Context) namer.NameSystems {
+	return namer.NameSystems{
+		"raw": namer.NewRawNamer(g.outputPackage, g.imports),
+	}
+}
+
+func (g *roundTripGenerator) Finalize(context *generator.Context, w io.Writer) error {
+	sw := generator.NewSnippetWriter(w, context, "$", "$")
+	m := map[string]interface{}{
+		"group":            g.gv.Group,
+		"version":          g.gv.Version,
+		"testingT":         context.Universe.Type(types.Name{Package: "testing", Name: "T"}),
+		"newScheme":        context.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/runtime", Name: "NewScheme"}),
+		"fuzzerFuncs":      context.Universe.Type(types.Name{Package: "k8s.io/apimachinery/pkg/api/apitesting/fuzzer", Name: "FuzzerFuncs"}),
+		"mergeFuzzerFuncs": context.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/api/apitesting/fuzzer", Name: "MergeFuzzerFuncs"}),
+		"roundTripScheme":  context.Universe.Function(types.Name{Package: "k8s.io/apimachinery/pkg/api/apitesting/roundtrip", Name: "RoundTripTestForScheme"}),
+	}
+	sw.Do(roundTripTestTemplate, m)
+	return sw.Error()
+}
+
+var roundTripTestTemplate = `
+// roundTripFuzzerFuncs may be appended to from a hand-written _test.go file
+// in this package, typically in an init function, to constrain fields the
+// generic fuzzer cannot produce valid values for.
+var roundTripFuzzerFuncs []$.fuzzerFuncs|raw$
+
+// TestRoundTripTypes fuzzes every object registered by this package and
+// verifies that defaulting, registered conversions and serialization through
+// the codecs round-trip without losing data.
+func TestRoundTripTypes(t *$.testingT|raw$) {
+	scheme := $.newScheme|raw$()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register $.group$/$.version$: %v", err)
+	}
+	$.roundTripScheme|raw$(t, scheme, $.mergeFuzzerFuncs|raw$(roundTripFuzzerFuncs...))
+}
+`
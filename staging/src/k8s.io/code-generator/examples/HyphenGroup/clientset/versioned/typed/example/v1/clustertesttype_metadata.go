@@ -0,0 +1,97 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	scheme "k8s.io/code-generator/examples/HyphenGroup/clientset/versioned/scheme"
+)
+
+// clusterTestTypeMetadataAccept asks the server to return PartialObjectMetadata instead of the full object.
+const clusterTestTypeMetadataAccept = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json"
+
+// ClusterTestTypeMetadataInterface supports retrieving only the object metadata of
+// ClusterTestType resources, avoiding the full object decode on the client.
+type ClusterTestTypeMetadataInterface interface {
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1.PartialObjectMetadata, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1.PartialObjectMetadataList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+}
+
+// ClusterTestTypeMetadata returns an object supporting metadata-only retrieval of ClusterTestTypes.
+func (c *ExampleGroupV1Client) ClusterTestTypeMetadata() ClusterTestTypeMetadataInterface {
+	return &clusterTestTypeMetadata{
+		client: c.RESTClient(),
+	}
+}
+
+// clusterTestTypeMetadata implements ClusterTestTypeMetadataInterface
+type clusterTestTypeMetadata struct {
+	client rest.Interface
+}
+
+// Get takes name of the clusterTestType, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *clusterTestTypeMetadata) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1.PartialObjectMetadata, err error) {
+	result = &v1.PartialObjectMetadata{}
+	err = c.client.Get().
+		SetHeader("Accept", clusterTestTypeMetadataAccept).
+		Resource("clustertesttypes").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the partial object metadata of the ClusterTestTypes that match those selectors.
+func (c *clusterTestTypeMetadata) List(ctx context.Context, opts v1.ListOptions) (result *v1.PartialObjectMetadataList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.PartialObjectMetadataList{}
+	err = c.client.Get().
+		SetHeader("Accept", clusterTestTypeMetadataAccept).
+		Resource("clustertesttypes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested clusterTestTypes as partial object metadata.
+func (c *clusterTestTypeMetadata) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		SetHeader("Accept", clusterTestTypeMetadataAccept).
+		Resource("clustertesttypes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	meta "k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	examplev1 "k8s.io/code-generator/examples/HyphenGroup/apis/example/v1"
+	typedexamplegroupv1 "k8s.io/code-generator/examples/HyphenGroup/clientset/versioned/typed/example/v1"
+)
+
+// FakeClusterTestTypeMetadata implements ClusterTestTypeMetadataInterface
+type FakeClusterTestTypeMetadata struct {
+	Fake *FakeExampleGroupV1
+}
+
+func (c *FakeExampleGroupV1) ClusterTestTypeMetadata() typedexamplegroupv1.ClusterTestTypeMetadataInterface {
+	return &FakeClusterTestTypeMetadata{c}
+}
+
+// Get takes name of the clusterTestType, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *FakeClusterTestTypeMetadata) Get(ctx context.Context, name string, options v1.GetOptions) (*v1.PartialObjectMetadata, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(clustertesttypesResource, name), &examplev1.ClusterTestType{})
+
+	if obj == nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	return meta.AsPartialObjectMetadata(accessor), err
+}
+
+// List takes label and field selectors, and returns the partial object metadata of the ClusterTestTypes that match those selectors.
+func (c *FakeClusterTestTypeMetadata) List(ctx context.Context, opts v1.ListOptions) (*v1.PartialObjectMetadataList, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(clustertesttypesResource, clustertesttypesKind, opts), &examplev1.ClusterTestTypeList{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return clustertesttypesPartialMetadataList(obj, opts)
+}
+
+// Watch returns a watch.Interface that watches the requested clusterTestTypes as partial object metadata.
+func (c *FakeClusterTestTypeMetadata) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	w, err := c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(clustertesttypesResource, opts))
+	if err != nil {
+		return nil, err
+	}
+	return clustertesttypesPartialMetadataWatch(w), nil
+}
+
+// clustertesttypesPartialMetadataList converts a tracked examplev1.ClusterTestTypeList into a
+// PartialObjectMetadataList, honoring the label selector of the list options.
+func clustertesttypesPartialMetadataList(obj runtime.Object, opts v1.ListOptions) (*v1.PartialObjectMetadataList, error) {
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.PartialObjectMetadataList{}
+	for _, item := range items {
+		accessor, err := meta.Accessor(item)
+		if err != nil {
+			return nil, err
+		}
+		if label.Matches(labels.Set(accessor.GetLabels())) {
+			list.Items = append(list.Items, *meta.AsPartialObjectMetadata(accessor))
+		}
+	}
+	return list, nil
+}
+
+// clustertesttypesPartialMetadataWatch rewrites watch events to carry partial object metadata.
+func clustertesttypesPartialMetadataWatch(w watch.Interface) watch.Interface {
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		if accessor, err := meta.Accessor(in.Object); err == nil {
+			in.Object = meta.AsPartialObjectMetadata(accessor)
+		}
+		return in, true
+	})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	scheme "k8s.io/code-generator/examples/HyphenGroup/clientset/versioned/scheme"
+)
+
+// testTypeMetadataAccept asks the server to return PartialObjectMetadata instead of the full object.
+const testTypeMetadataAccept = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json"
+
+// TestTypeMetadataInterface supports retrieving only the object metadata of
+// TestType resources, avoiding the full object decode on the client.
+type TestTypeMetadataInterface interface {
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1.PartialObjectMetadata, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1.PartialObjectMetadataList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+}
+
+// TestTypeMetadata returns an object supporting metadata-only retrieval of TestTypes.
+func (c *ExampleGroupV1Client) TestTypeMetadata(namespace string) TestTypeMetadataInterface {
+	return &testTypeMetadata{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// testTypeMetadata implements TestTypeMetadataInterface
+type testTypeMetadata struct {
+	client rest.Interface
+	ns     string
+}
+
+// Get takes name of the testType, and returns the corresponding partial object metadata, and an error if there is any.
+func (c *testTypeMetadata) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1.PartialObjectMetadata, err error) {
+	result = &v1.PartialObjectMetadata{}
+	err = c.client.Get().
+		SetHeader("Accept", testTypeMetadataAccept).
+		Namespace(c.ns).
+		Resource("testtypes").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the partial object metadata of the TestTypes that match those selectors.
+func (c *testTypeMetadata) List(ctx context.Context, opts v1.ListOptions) (result *v1.PartialObjectMetadataList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.PartialObjectMetadataList{}
+	err = c.client.Get().
+		SetHeader("Accept", testTypeMetadataAccept).
+		Namespace(c.ns).
+		Resource("testtypes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested testTypes as partial object metadata.
+func (c *testTypeMetadata) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		SetHeader("Accept", testTypeMetadataAccept).
+		Namespace(c.ns).
+		Resource("testtypes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
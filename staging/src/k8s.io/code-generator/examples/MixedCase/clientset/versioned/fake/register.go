@@ -52,5 +52,7 @@ var AddToScheme = localSchemeBuilder.AddToScheme
 
 func init() {
 	v1.AddToGroupVersion(scheme, schema.GroupVersion{Version: "v1"})
+	// Metadata-only clients decode PartialObjectMetadata responses.
+	utilruntime.Must(v1.AddMetaToScheme(scheme))
 	utilruntime.Must(AddToScheme(scheme))
 }
@@ -23,6 +23,7 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	example "k8s.io/code-generator/examples/apiserver/apis/example/install"
 	secondexample "k8s.io/code-generator/examples/apiserver/apis/example2/install"
 	thirdexample "k8s.io/code-generator/examples/apiserver/apis/example3.io/install"
@@ -34,6 +35,8 @@ var ParameterCodec = runtime.NewParameterCodec(Scheme)
 
 func init() {
 	v1.AddToGroupVersion(Scheme, schema.GroupVersion{Version: "v1"})
+	// Metadata-only clients decode PartialObjectMetadata responses.
+	utilruntime.Must(v1.AddMetaToScheme(Scheme))
 	Install(Scheme)
 }
 
@@ -26,6 +26,7 @@ import (
 	types "k8s.io/apimachinery/pkg/types"
 	watch "k8s.io/apimachinery/pkg/watch"
 	rest "k8s.io/client-go/rest"
+	flowcontrol "k8s.io/client-go/util/flowcontrol"
 	v1 "k8s.io/code-generator/examples/crd/apis/example/v1"
 	scheme "k8s.io/code-generator/examples/crd/clientset/versioned/scheme"
 )
@@ -64,6 +65,18 @@ func newTestTypes(c *ExampleV1Client, namespace string) *testTypes {
 	}
 }
 
+// TestTypesQPSHint is the expected access pattern declared for
+// TestType clients with the +genclient:qpsHint tag.
+const TestTypesQPSHint = "low"
+
+// NewTestTypesRateLimiter returns the default client-side rate limiter
+// for TestType clients, derived from the declared "low" access pattern
+// (1 QPS, burst 5). Set it as the RateLimiter of the rest.Config
+// used to construct the clientset to apply the declared defaults.
+func NewTestTypesRateLimiter() flowcontrol.RateLimiter {
+	return flowcontrol.NewTokenBucketRateLimiter(1, 5)
+}
+
 // Get takes name of the testType, and returns the corresponding testType object, and an error if there is any.
 func (c *testTypes) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.TestType, err error) {
 	result = &v1.TestType{}
@@ -26,6 +26,8 @@ if [ "$#" -lt 5 ] || [ "${1}" == "--help" ]; then
 Usage: $(basename "$0") <generators> <output-package> <internal-apis-package> <extensiona-apis-package> <groups-versions> ...
 
   <generators>        the generators comma separated to run (deepcopy,defaulter,conversion,client,lister,informer,openapi) or "all".
+                      "roundtrip" additionally generates round-trip fuzz tests for the external packages; it is not part of "all"
+                      because it emits _test.go files.
   <output-package>    the output package name (e.g. github.com/example/project/pkg/generated).
   <int-apis-package>  the internal types dir (e.g. github.com/example/project/pkg/apis).
   <ext-apis-package>  the external types dir (e.g. github.com/example/project/pkg/apis or githubcom/example/apis).
@@ -47,7 +49,7 @@ EXT_APIS_PKG="$4"
 GROUPS_WITH_VERSIONS="$5"
 shift 5
 
-go install ./"$(dirname "${0}")"/cmd/{defaulter-gen,conversion-gen,client-gen,lister-gen,informer-gen,deepcopy-gen,openapi-gen}
+go install ./"$(dirname "${0}")"/cmd/{defaulter-gen,conversion-gen,client-gen,lister-gen,informer-gen,deepcopy-gen,openapi-gen,roundtrip-gen}
 
 function codegen::join() { local IFS="$1"; shift; echo "$*"; }
 
@@ -120,3 +122,8 @@ if [ "${GENS}" = "all" ] || grep -qw "openapi" <<<"${GENS}"; then
            -O zz_generated.openapi \
            "$@"
 fi
+
+if grep -qw "roundtrip" <<<"${GENS}"; then
+  echo "Generating round-trip fuzz tests"
+  "${GOPATH}/bin/roundtrip-gen" --input-dirs "$(codegen::join , "${EXT_FQ_APIS[@]}")" -O zz_generated.roundtrip_test "$@"
+fi
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"flag"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/component-base/config"
+	"k8s.io/klog/v2"
+)
+
+// bannerMessage is the message of the startup banner record. Support tooling
+// matches on it, so it must stay stable across releases.
+const bannerMessage = "Effective logging configuration"
+
+// logBanner emits a single structured record describing the logging
+// configuration that is in effect after Apply. It goes through the freshly
+// configured logger, so the record itself also proves which format won.
+func logBanner(c *config.LoggingConfiguration) {
+	klog.InfoS(bannerMessage, bannerKeysAndValues(c)...)
+}
+
+// bannerKeysAndValues returns the key/value pairs of the startup banner. The
+// verbosity settings live in klog flags rather than the configuration struct,
+// so they are read back from the registered flags on a best-effort basis.
+func bannerKeysAndValues(c *config.LoggingConfiguration) []interface{} {
+	keysAndValues := []interface{}{
+		"format", c.Format,
+		"verbosity", lookupFlagValue("v"),
+		"vmodule", lookupFlagValue("vmodule"),
+		"sanitization", c.Sanitization,
+	}
+	if logJSONDualWriteFile != "" {
+		keysAndValues = append(keysAndValues,
+			"dualWriteFile", logJSONDualWriteFile,
+			"dualWriteVerbosity", logJSONDualWriteVerbosity,
+		)
+	}
+	return keysAndValues
+}
+
+// lookupFlagValue returns the value of the flag as registered on either the
+// standard or the pflag command line, or "" when the component bound the flag
+// elsewhere.
+func lookupFlagValue(name string) string {
+	if f := flag.CommandLine.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	if f := pflag.CommandLine.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
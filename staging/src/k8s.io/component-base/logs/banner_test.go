@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"testing"
+
+	"k8s.io/component-base/config"
+)
+
+func bannerValue(t *testing.T, keysAndValues []interface{}, key string) (interface{}, bool) {
+	t.Helper()
+	if len(keysAndValues)%2 != 0 {
+		t.Fatalf("banner has an odd number of elements: %v", keysAndValues)
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if keysAndValues[i] == key {
+			return keysAndValues[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func TestBannerKeysAndValues(t *testing.T) {
+	c := &config.LoggingConfiguration{
+		Format:       JSONLogFormat,
+		Sanitization: true,
+	}
+
+	keysAndValues := bannerKeysAndValues(c)
+
+	if format, ok := bannerValue(t, keysAndValues, "format"); !ok || format != JSONLogFormat {
+		t.Errorf("expected format %q, got %v", JSONLogFormat, format)
+	}
+	if sanitization, ok := bannerValue(t, keysAndValues, "sanitization"); !ok || sanitization != true {
+		t.Errorf("expected sanitization true, got %v", sanitization)
+	}
+	if _, ok := bannerValue(t, keysAndValues, "verbosity"); !ok {
+		t.Error("expected the banner to report the verbosity")
+	}
+	if _, ok := bannerValue(t, keysAndValues, "vmodule"); !ok {
+		t.Error("expected the banner to report the vmodule setting")
+	}
+	if _, ok := bannerValue(t, keysAndValues, "dualWriteFile"); ok {
+		t.Error("expected no dual-write keys while dual-write is disabled")
+	}
+}
+
+func TestBannerKeysAndValuesDualWrite(t *testing.T) {
+	defer func(file string, verbosity int) {
+		logJSONDualWriteFile = file
+		logJSONDualWriteVerbosity = verbosity
+	}(logJSONDualWriteFile, logJSONDualWriteVerbosity)
+	logJSONDualWriteFile = "/var/log/component.json"
+	logJSONDualWriteVerbosity = 2
+
+	keysAndValues := bannerKeysAndValues(&config.LoggingConfiguration{Format: DefaultLogFormat})
+
+	if file, ok := bannerValue(t, keysAndValues, "dualWriteFile"); !ok || file != logJSONDualWriteFile {
+		t.Errorf("expected dualWriteFile %q, got %v", logJSONDualWriteFile, file)
+	}
+	if verbosity, ok := bannerValue(t, keysAndValues, "dualWriteVerbosity"); !ok || verbosity != 2 {
+		t.Errorf("expected dualWriteVerbosity 2, got %v", verbosity)
+	}
+}
@@ -60,6 +60,8 @@ func BindLoggingFlags(c *config.LoggingConfiguration, fs *pflag.FlagSet) {
 	LogRegistry.Freeze()
 	fs.BoolVar(&c.Sanitization, "experimental-logging-sanitization", c.Sanitization, `[Experimental] When enabled prevents logging of fields tagged as sensitive (passwords, keys, tokens).
 Runtime log sanitization may introduce significant computation overhead and therefore should not be enabled in production.`)
+	fs.StringVar(&logJSONDualWriteFile, logJSONDualWriteFileFlagName, logJSONDualWriteFile, `[Experimental] When set, additionally write every log entry in JSON format to the given file while keeping the text destination unchanged, to validate JSON pipelines before switching --logging-format. Only supported with --logging-format=text.`)
+	fs.IntVar(&logJSONDualWriteVerbosity, logJSONDualWriteVerbosityFlagName, logJSONDualWriteVerbosity, fmt.Sprintf(`[Experimental] Maximum verbosity of entries duplicated to the --%s file. Entries above this level are only written to the text destination.`, logJSONDualWriteFileFlagName))
 }
 
 // UnsupportedLoggingFlags lists unsupported logging flags
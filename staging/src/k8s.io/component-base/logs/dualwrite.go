@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/component-base/config"
+)
+
+// Flags for the JSON dual-write migration mode. During a migration window a
+// component can keep emitting its legacy text output unchanged while also
+// writing every entry as JSON to a separate file, so operators can validate
+// their JSON pipelines before switching --logging-format atomically.
+const (
+	logJSONDualWriteFileFlagName      = "log-json-dual-write-file"
+	logJSONDualWriteVerbosityFlagName = "log-json-dual-write-verbosity"
+)
+
+var (
+	logJSONDualWriteFile      string
+	logJSONDualWriteVerbosity int
+
+	// jsonLoggerFactory is installed by the logs/json/register package.
+	// It stays nil in components that do not link in JSON format support.
+	jsonLoggerFactory func(w io.Writer) logr.Logger
+)
+
+// SetJSONLoggerFactory installs the constructor used to build the JSON
+// logger for dual-write mode. It is called from the logs/json/register
+// package so that this package does not depend on the JSON implementation.
+func SetJSONLoggerFactory(factory func(w io.Writer) logr.Logger) {
+	jsonLoggerFactory = factory
+}
+
+// validateDualWrite checks the dual-write flags against the selected format.
+func validateDualWrite(c *config.LoggingConfiguration, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if logJSONDualWriteFile == "" {
+		return errs
+	}
+	if c.Format != DefaultLogFormat {
+		errs = append(errs, field.Invalid(fldPath.Child("format"), c.Format, fmt.Sprintf("--%s is only supported with the %q log format", logJSONDualWriteFileFlagName, DefaultLogFormat)))
+	}
+	if jsonLoggerFactory == nil {
+		errs = append(errs, field.Invalid(fldPath.Child("format"), c.Format, fmt.Sprintf("--%s requires a component with JSON log format support", logJSONDualWriteFileFlagName)))
+	}
+	if logJSONDualWriteVerbosity < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("format"), logJSONDualWriteVerbosity, fmt.Sprintf("--%s must not be negative", logJSONDualWriteVerbosityFlagName)))
+	}
+	return errs
+}
+
+// newDualWriteLogger opens the JSON destination file and returns a logger
+// that writes klog-style text to stderr and JSON to the file. The JSON
+// destination only receives entries up to jsonVerbosity; entries above it
+// are written to the text destination only. Note that klog's own -v flag
+// still gates which entries reach the logger at all.
+func newDualWriteLogger(jsonFile string, jsonVerbosity int) (logr.Logger, error) {
+	file, err := os.OpenFile(jsonFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSON dual-write file: %v", err)
+	}
+	return &dualWriteLogger{
+		sink: &dualWriteSink{
+			text:  os.Stderr,
+			jsonV: jsonVerbosity,
+		},
+		json: jsonLoggerFactory(file),
+	}, nil
+}
+
+// dualWriteSink holds the state shared between all loggers derived from one
+// dual-write logger via V, WithName and WithValues.
+type dualWriteSink struct {
+	mu    sync.Mutex
+	text  io.Writer
+	jsonV int
+}
+
+// dualWriteLogger fans each entry out to a klog-style text destination and a
+// JSON logger with its own verbosity limit. The text output mimics the klog
+// header (severity and timestamp) so existing pipelines keep working during
+// the migration window; the caller field is not included.
+type dualWriteLogger struct {
+	sink   *dualWriteSink
+	json   logr.Logger
+	lvl    int
+	prefix string
+	values []interface{}
+}
+
+var _ logr.Logger = &dualWriteLogger{}
+
+// Enabled should always return true, klog gates verbosity before delegating.
+func (l *dualWriteLogger) Enabled() bool {
+	return true
+}
+
+// Info writes the message to the text destination and, if the current level
+// is within the JSON verbosity limit, to the JSON destination.
+func (l *dualWriteLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.writeText('I', msg, nil, keysAndValues)
+	if l.lvl <= l.sink.jsonV {
+		l.json.Info(msg, keysAndValues...)
+	}
+}
+
+// Error writes the message to both destinations unconditionally.
+func (l *dualWriteLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.writeText('E', msg, err, keysAndValues)
+	l.json.Error(err, msg, keysAndValues...)
+}
+
+// V returns a logger for the given relative verbosity level.
+func (l *dualWriteLogger) V(level int) logr.Logger {
+	return &dualWriteLogger{
+		sink:   l.sink,
+		json:   l.json.V(level),
+		lvl:    l.lvl + level,
+		prefix: l.prefix,
+		values: l.values,
+	}
+}
+
+// WithValues returns a logger with additional key/value pairs attached.
+func (l *dualWriteLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	values := make([]interface{}, 0, len(l.values)+len(keysAndValues))
+	values = append(values, l.values...)
+	values = append(values, keysAndValues...)
+	return &dualWriteLogger{
+		sink:   l.sink,
+		json:   l.json.WithValues(keysAndValues...),
+		lvl:    l.lvl,
+		prefix: l.prefix,
+		values: values,
+	}
+}
+
+// WithName returns a logger named with the specified name.
+func (l *dualWriteLogger) WithName(name string) logr.Logger {
+	prefix := name
+	if l.prefix != "" {
+		prefix = l.prefix + "." + name
+	}
+	return &dualWriteLogger{
+		sink:   l.sink,
+		json:   l.json.WithName(name),
+		lvl:    l.lvl,
+		prefix: prefix,
+		values: l.values,
+	}
+}
+
+// writeText serializes one entry in klog InfoS style to the text destination.
+func (l *dualWriteLogger) writeText(severity byte, msg string, err error, keysAndValues []interface{}) {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	fmt.Fprintf(l.sink.text, "%c%s]", severity, timeNowDualWrite().Format("0102 15:04:05.000000"))
+	if l.prefix != "" {
+		fmt.Fprintf(l.sink.text, " %s:", l.prefix)
+	}
+	fmt.Fprintf(l.sink.text, " %q", msg)
+	if err != nil {
+		fmt.Fprintf(l.sink.text, " err=%q", err.Error())
+	}
+	writeTextKeysAndValues(l.sink.text, l.values)
+	writeTextKeysAndValues(l.sink.text, keysAndValues)
+	fmt.Fprintln(l.sink.text)
+}
+
+func writeTextKeysAndValues(w io.Writer, keysAndValues []interface{}) {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			fmt.Fprintf(w, " %v=%q", keysAndValues[i], value)
+		default:
+			fmt.Fprintf(w, " %v=%+v", keysAndValues[i], value)
+		}
+	}
+}
+
+// timeNowDualWrite is stubbed out for testing.
+var timeNowDualWrite = time.Now
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	json "k8s.io/component-base/logs/json"
+)
+
+// newTestDualWriteLogger builds a dual-write logger backed by in-memory
+// buffers instead of stderr and a file.
+func newTestDualWriteLogger(jsonVerbosity int) (*dualWriteLogger, *bytes.Buffer, *bytes.Buffer) {
+	textOut := &bytes.Buffer{}
+	jsonOut := &bytes.Buffer{}
+	logger := &dualWriteLogger{
+		sink: &dualWriteSink{
+			text:  textOut,
+			jsonV: jsonVerbosity,
+		},
+		json: json.NewJSONLogger(zapcore.AddSync(jsonOut)),
+	}
+	return logger, textOut, jsonOut
+}
+
+func TestDualWriteInfo(t *testing.T) {
+	timeNowDualWrite = func() time.Time {
+		return time.Date(1970, time.January, 1, 0, 0, 0, 123*int(time.Millisecond), time.UTC)
+	}
+	defer func() {
+		timeNowDualWrite = time.Now
+	}()
+
+	logger, textOut, jsonOut := newTestDualWriteLogger(0)
+	logger.Info("test", "ns", "default", "podnum", 2)
+
+	wantText := `I0101 00:00:00.123000] "test" ns="default" podnum=2` + "\n"
+	if textOut.String() != wantText {
+		t.Errorf("Unexpected text output.\nexpect:\t%q\n  got:\t%q", wantText, textOut.String())
+	}
+	for _, want := range []string{`"msg":"test"`, `"ns":"default"`, `"podnum":2`} {
+		if !strings.Contains(jsonOut.String(), want) {
+			t.Errorf("JSON output %q does not contain %q", jsonOut.String(), want)
+		}
+	}
+}
+
+func TestDualWriteVerbosity(t *testing.T) {
+	logger, textOut, jsonOut := newTestDualWriteLogger(1)
+
+	logger.V(2).Info("verbose entry")
+	if jsonOut.Len() != 0 {
+		t.Errorf("Entry above the JSON verbosity limit leaked into the JSON output: %q", jsonOut.String())
+	}
+	if !strings.Contains(textOut.String(), `"verbose entry"`) {
+		t.Errorf("Entry above the JSON verbosity limit missing from the text output: %q", textOut.String())
+	}
+
+	logger.V(1).Info("important entry")
+	if !strings.Contains(jsonOut.String(), `"msg":"important entry"`) {
+		t.Errorf("Entry within the JSON verbosity limit missing from the JSON output: %q", jsonOut.String())
+	}
+}
+
+func TestDualWriteError(t *testing.T) {
+	logger, textOut, jsonOut := newTestDualWriteLogger(0)
+
+	// Errors must reach both destinations regardless of verbosity.
+	logger.V(5).Error(errors.New("boom"), "failed")
+	if !strings.Contains(textOut.String(), `err="boom"`) {
+		t.Errorf("Error missing from the text output: %q", textOut.String())
+	}
+	if !strings.Contains(jsonOut.String(), `"err":"boom"`) {
+		t.Errorf("Error missing from the JSON output: %q", jsonOut.String())
+	}
+}
+
+func TestDualWriteWithNameAndValues(t *testing.T) {
+	logger, textOut, _ := newTestDualWriteLogger(0)
+
+	logger.WithName("controller").WithValues("ns", "default").Info("synced")
+	if !strings.Contains(textOut.String(), ` controller: "synced" ns="default"`) {
+		t.Errorf("Unexpected text output: %q", textOut.String())
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// hashChainCheckpointPrefix marks the checkpoint records a hash chain writer
+// interleaves with the log entries. The prefix is chosen so that checkpoint
+// records are cheap to recognize both by the verifier and by a human reading
+// the file.
+const hashChainCheckpointPrefix = "LOGCHAIN checkpoint"
+
+// NewHashChainWriter wraps the destination so that every line written through
+// it extends a rolling SHA-256 hash chain, and a checkpoint record carrying
+// the sequence number and the current chain value is appended after every
+// checkpointInterval entries. Tampering with or removing any entry before a
+// checkpoint invalidates that checkpoint and all later ones, which
+// VerifyHashChain detects when replaying the file. Entries written after the
+// last checkpoint are not yet covered, so the checkpoint interval bounds how
+// much of the tail can be truncated without detection.
+//
+// Each output destination that needs tamper evidence is wrapped separately,
+// giving every destination its own independent chain. The writer is safe for
+// concurrent use; partial lines are buffered until their newline arrives.
+func NewHashChainWriter(w io.Writer, checkpointInterval uint64) io.Writer {
+	if checkpointInterval == 0 {
+		checkpointInterval = 1
+	}
+	return &hashChainWriter{
+		out:      w,
+		chain:    make([]byte, sha256.Size),
+		interval: checkpointInterval,
+	}
+}
+
+type hashChainWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	// chain is the rolling hash covering all complete entries so far.
+	chain []byte
+	// entries counts the complete entries covered by chain.
+	entries uint64
+	// interval is the number of entries between checkpoint records.
+	interval uint64
+	// buf holds an incomplete line until its newline arrives.
+	buf []byte
+}
+
+func (w *hashChainWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.out.Write(p); err != nil {
+		return 0, err
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.extendChain(w.buf[:idx])
+		w.buf = w.buf[idx+1:]
+		if w.entries%w.interval == 0 {
+			if err := w.writeCheckpoint(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// extendChain folds one complete entry, without its trailing newline, into
+// the rolling hash.
+func (w *hashChainWriter) extendChain(entry []byte) {
+	h := sha256.New()
+	h.Write(w.chain)
+	h.Write(entry)
+	w.chain = h.Sum(w.chain[:0])
+	w.entries++
+}
+
+// writeCheckpoint appends a checkpoint record for the current chain state.
+// Checkpoint records are not part of the chain themselves; a modified
+// checkpoint is detected because it no longer matches the replayed chain.
+func (w *hashChainWriter) writeCheckpoint() error {
+	_, err := fmt.Fprintf(w.out, "%s seq=%d hash=%s\n", hashChainCheckpointPrefix, w.entries, hex.EncodeToString(w.chain))
+	return err
+}
+
+// VerifyHashChain replays the log entries of a file written through a hash
+// chain writer and checks every checkpoint record against the recomputed
+// chain. It returns the number of entries covered by the last valid
+// checkpoint. Entries after the last checkpoint cannot be verified and are
+// not counted.
+func VerifyHashChain(r io.Reader) (uint64, error) {
+	chain := make([]byte, sha256.Size)
+	var entries, verified uint64
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte(hashChainCheckpointPrefix)) {
+			var seq uint64
+			var sum string
+			if _, err := fmt.Sscanf(string(line[len(hashChainCheckpointPrefix):]), " seq=%d hash=%s", &seq, &sum); err != nil {
+				return verified, fmt.Errorf("malformed checkpoint record after %d entries: %v", entries, err)
+			}
+			if seq != entries {
+				return verified, fmt.Errorf("checkpoint sequence mismatch: record claims %d entries, found %d", seq, entries)
+			}
+			if sum != hex.EncodeToString(chain) {
+				return verified, fmt.Errorf("hash chain mismatch at checkpoint after %d entries: log entries were modified or removed", entries)
+			}
+			verified = entries
+			continue
+		}
+		h := sha256.New()
+		h.Write(chain)
+		h.Write(line)
+		chain = h.Sum(chain[:0])
+		entries++
+	}
+	if err := scanner.Err(); err != nil {
+		return verified, err
+	}
+	return verified, nil
+}
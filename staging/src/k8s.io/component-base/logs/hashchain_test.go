@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHashChainRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	w := NewHashChainWriter(&out, 3)
+
+	for i := 0; i < 10; i++ {
+		if _, err := fmt.Fprintf(w, "entry %d\n", i); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+
+	if got := strings.Count(out.String(), hashChainCheckpointPrefix); got != 3 {
+		t.Errorf("expected 3 checkpoint records for 10 entries at interval 3, got %d:\n%s", got, out.String())
+	}
+
+	verified, err := VerifyHashChain(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("verification of untampered log failed: %v", err)
+	}
+	if verified != 9 {
+		t.Errorf("expected 9 verified entries, got %d", verified)
+	}
+}
+
+func TestHashChainPartialWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := NewHashChainWriter(&out, 1)
+
+	// An entry delivered across multiple Write calls must hash as one entry.
+	for _, chunk := range []string{"one ", "split ", "entry\n"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+
+	verified, err := VerifyHashChain(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	if verified != 1 {
+		t.Errorf("expected 1 verified entry, got %d", verified)
+	}
+}
+
+func TestHashChainDetectsTampering(t *testing.T) {
+	var out bytes.Buffer
+	w := NewHashChainWriter(&out, 2)
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(w, "entry %d\n", i)
+	}
+
+	testCases := []struct {
+		desc   string
+		mangle func(string) string
+	}{
+		{
+			desc: "modified entry",
+			mangle: func(s string) string {
+				return strings.Replace(s, "entry 1", "entry x", 1)
+			},
+		},
+		{
+			desc: "removed entry",
+			mangle: func(s string) string {
+				return strings.Replace(s, "entry 2\n", "", 1)
+			},
+		},
+		{
+			desc: "inserted entry",
+			mangle: func(s string) string {
+				return strings.Replace(s, "entry 3\n", "entry 3\nforged\n", 1)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mangled := tc.mangle(out.String())
+			if mangled == out.String() {
+				t.Fatal("test case did not modify the log")
+			}
+			if _, err := VerifyHashChain(strings.NewReader(mangled)); err == nil {
+				t.Errorf("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestHashChainTruncatedTail(t *testing.T) {
+	var out bytes.Buffer
+	w := NewHashChainWriter(&out, 2)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(w, "entry %d\n", i)
+	}
+
+	// The fifth entry comes after the last checkpoint: it is not covered yet
+	// and its loss cannot be detected, but the four checkpointed entries are.
+	truncated := out.String()[:strings.LastIndex(out.String(), "entry 4")]
+	verified, err := VerifyHashChain(strings.NewReader(truncated))
+	if err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	if verified != 4 {
+		t.Errorf("expected 4 verified entries, got %d", verified)
+	}
+}
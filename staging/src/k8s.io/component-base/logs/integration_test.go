@@ -0,0 +1,239 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"k8s.io/component-base/logs"
+)
+
+// The integration tests launch the example logger command — the closest
+// thing in this repository to a real component using Options — once per
+// registered format and validate the output schema, flag behavior and
+// flush-on-exit semantics end to end.
+
+// expectedMessages are emitted by the example logger in order. The last one
+// is the flush marker: it is logged immediately before the process exits, so
+// its presence proves that FlushLogs drained buffered output.
+var expectedMessages = []string{
+	"Log using Infof, key: value",
+	"Log using InfoS",
+	"Log using Errorf, err: fail",
+	"Log using ErrorS",
+	"Log with sensitive key",
+}
+
+// klogLineRE matches the header klog prepends in the text format.
+var klogLineRE = regexp.MustCompile(`(?m)^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\s+\d+ \S+:\d+\] `)
+
+// dualWriteLineRE matches the klog-style header of the dual-write text
+// destination, which carries no caller field.
+var dualWriteLineRE = regexp.MustCompile(`(?m)^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\] `)
+
+// buildLoggerBinary builds the example logger command into a temporary
+// directory and returns its path. Inside the kubernetes repository the
+// staging module cannot be built standalone, so the build runs from the
+// repository root with the full import path, which resolves through the
+// vendor tree.
+func buildLoggerBinary(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skipf("go binary not found: %v", err)
+	}
+	binary := filepath.Join(t.TempDir(), "logger")
+	cmd := exec.Command("go", "build", "-o", binary, "./example/cmd")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to determine working directory: %v", err)
+	}
+	if root := repoRoot(wd); root != "" {
+		cmd = exec.Command("go", "build", "-o", binary, "k8s.io/component-base/logs/example/cmd")
+		cmd.Dir = root
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build example logger: %v\n%s", err, out)
+	}
+	return binary
+}
+
+// repoRoot returns the kubernetes repository root when dir is inside its
+// staging tree, or the empty string when the module is standalone.
+func repoRoot(dir string) string {
+	staging := filepath.Join("staging", "src", "k8s.io")
+	idx := strings.LastIndex(dir, string(filepath.Separator)+staging+string(filepath.Separator))
+	if idx < 0 {
+		return ""
+	}
+	return dir[:idx]
+}
+
+// runLogger runs the binary with the given flags and returns its combined
+// stdout and stderr.
+func runLogger(t *testing.T, binary string, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := cmd.Run()
+	return buf.String(), err
+}
+
+// validateTextOutput checks that the output consists of klog-style lines.
+func validateTextOutput(t *testing.T, output string) {
+	t.Helper()
+	if !klogLineRE.MatchString(output) {
+		t.Errorf("expected klog-style text lines, got:\n%s", output)
+	}
+}
+
+// validateJSONOutput checks that every line is a JSON object with the keys
+// the JSON format guarantees.
+func validateJSONOutput(t *testing.T, output string) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("line is not valid JSON: %v\n%s", err, line)
+			continue
+		}
+		if _, ok := entry["ts"]; !ok {
+			t.Errorf("JSON entry has no \"ts\" key: %s", line)
+		}
+		msg, ok := entry["msg"].(string)
+		if !ok || msg == "" {
+			t.Errorf("JSON entry has no \"msg\" key: %s", line)
+			continue
+		}
+		if strings.Contains(msg, "Log using InfoS") && entry["key"] != "value" {
+			t.Errorf("JSON entry lost the structured key/value pair: %s", line)
+		}
+	}
+}
+
+func TestLogFormatsEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs a real binary")
+	}
+	binary := buildLoggerBinary(t)
+
+	// The example binary links in the json register package, so it supports
+	// every format this repository ships. The test process intentionally
+	// does not, to keep the flag help output of the logs package unchanged.
+	validators := map[string]func(t *testing.T, output string){
+		logs.DefaultLogFormat: validateTextOutput,
+		logs.JSONLogFormat:    validateJSONOutput,
+	}
+
+	for format, validate := range validators {
+		t.Run(format, func(t *testing.T) {
+			output, err := runLogger(t, binary, fmt.Sprintf("--logging-format=%s", format))
+			if err != nil {
+				t.Fatalf("example logger failed with format %q: %v\n%s", format, err, output)
+			}
+			for _, msg := range expectedMessages {
+				if !strings.Contains(output, msg) {
+					t.Errorf("message %q missing from output with format %q:\n%s", msg, format, output)
+				}
+			}
+			validate(t, output)
+		})
+	}
+}
+
+func TestLogFormatFlagBehavior(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs a real binary")
+	}
+	binary := buildLoggerBinary(t)
+
+	testcases := []struct {
+		name        string
+		args        []string
+		expectError string
+	}{
+		{
+			name:        "unknown format is rejected",
+			args:        []string{"--logging-format=nosuchformat"},
+			expectError: "Unsupported log format",
+		},
+		{
+			name:        "dual-write requires the text format",
+			args:        []string{"--logging-format=json", "--log-json-dual-write-file=/tmp/ignored.json"},
+			expectError: "only supported",
+		},
+		{
+			name:        "negative dual-write verbosity is rejected",
+			args:        []string{"--log-json-dual-write-file=/tmp/ignored.json", "--log-json-dual-write-verbosity=-1"},
+			expectError: "must not be negative",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := runLogger(t, binary, tc.args...)
+			if err == nil {
+				t.Fatalf("expected the example logger to fail, got output:\n%s", output)
+			}
+			if !strings.Contains(output, tc.expectError) {
+				t.Errorf("expected error containing %q, got:\n%s", tc.expectError, output)
+			}
+		})
+	}
+}
+
+func TestJSONDualWriteEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs a real binary")
+	}
+	binary := buildLoggerBinary(t)
+
+	jsonFile := filepath.Join(t.TempDir(), "dual.json")
+	output, err := runLogger(t, binary, fmt.Sprintf("--log-json-dual-write-file=%s", jsonFile))
+	if err != nil {
+		t.Fatalf("example logger failed in dual-write mode: %v\n%s", err, output)
+	}
+
+	// The primary destination keeps a klog-style text schema, minus the
+	// caller field which the dual-write logger does not include.
+	if !dualWriteLineRE.MatchString(output) {
+		t.Errorf("expected klog-style dual-write text lines, got:\n%s", output)
+	}
+
+	// The secondary destination must contain all entries as JSON after the
+	// process has exited.
+	data, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("failed to read dual-write file: %v", err)
+	}
+	validateJSONOutput(t, string(data))
+	for _, msg := range expectedMessages {
+		if !strings.Contains(string(data), msg) {
+			t.Errorf("message %q missing from dual-write file:\n%s", msg, data)
+		}
+	}
+}
@@ -17,6 +17,11 @@ limitations under the License.
 package register
 
 import (
+	"io"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+
 	"k8s.io/component-base/logs"
 	json "k8s.io/component-base/logs/json"
 )
@@ -24,4 +29,8 @@ import (
 func init() {
 	// JSON format is optional klog format
 	logs.LogRegistry.Register(logs.JSONLogFormat, json.JSONLogger)
+	// Make JSON loggers available for the dual-write migration mode.
+	logs.SetJSONLoggerFactory(func(w io.Writer) logr.Logger {
+		return json.NewJSONLogger(zapcore.AddSync(w))
+	})
 }
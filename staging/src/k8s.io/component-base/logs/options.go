@@ -44,6 +44,7 @@ func NewOptions() *Options {
 // for non-default logging format
 func (o *Options) Validate() []error {
 	errs := ValidateLoggingConfiguration(&o.Config, nil)
+	errs = append(errs, validateDualWrite(&o.Config, nil)...)
 	if len(errs) != 0 {
 		return errs.ToAggregate().Errors()
 	}
@@ -59,8 +60,21 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 func (o *Options) Apply() {
 	// if log format not exists, use nil loggr
 	loggr, _ := LogRegistry.Get(o.Config.Format)
+	if logJSONDualWriteFile != "" && o.Config.Format == DefaultLogFormat && jsonLoggerFactory != nil {
+		dual, err := newDualWriteLogger(logJSONDualWriteFile, logJSONDualWriteVerbosity)
+		if err != nil {
+			klog.ErrorS(err, "Failed to enable JSON dual-write mode", "path", logJSONDualWriteFile)
+		} else {
+			loggr = dual
+		}
+	}
 	klog.SetLogger(loggr)
+	// Control characters and invalid UTF-8 in log arguments are always
+	// escaped, so that neither format can be broken by logged values.
+	filter := &sanitization.EscapingFilter{}
 	if o.Config.Sanitization {
-		klog.SetLogFilter(&sanitization.SanitizingFilter{})
+		filter.Delegate = &sanitization.SanitizingFilter{}
 	}
+	klog.SetLogFilter(filter)
+	logBanner(&o.Config)
 }
@@ -36,6 +36,8 @@ func TestFlags(t *testing.T) {
 	fs.PrintDefaults()
 	want := `      --experimental-logging-sanitization   [Experimental] When enabled prevents logging of fields tagged as sensitive (passwords, keys, tokens).
                                             Runtime log sanitization may introduce significant computation overhead and therefore should not be enabled in production.
+      --log-json-dual-write-file string     [Experimental] When set, additionally write every log entry in JSON format to the given file while keeping the text destination unchanged, to validate JSON pipelines before switching --logging-format. Only supported with --logging-format=text.
+      --log-json-dual-write-verbosity int   [Experimental] Maximum verbosity of entries duplicated to the --log-json-dual-write-file file. Entries above this level are only written to the text destination.
       --logging-format string               Sets the log format. Permitted formats: "text".
                                             Non-default formats don't honor these flags: --add_dir_header, --alsologtostderr, --log_backtrace_at, --log_dir, --log_file, --log_file_max_size, --logtostderr, --one_output, --skip_headers, --skip_log_headers, --stderrthreshold, --vmodule, --log-flush-frequency.
                                             Non-default choices are currently alpha and subject to change without warning. (default "text")
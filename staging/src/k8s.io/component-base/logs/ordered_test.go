@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOrderedWriterSequencesLines(t *testing.T) {
+	var out bytes.Buffer
+	w := NewOrderedWriter(&out)
+
+	// Lines may arrive split across several Write calls.
+	fmt.Fprintf(w, "first\nsec")
+	fmt.Fprintf(w, "ond\n")
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	expected := "seq=1 first\nseq=2 second\n"
+	if out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestOrderedWriterFlushesPartialLineOnClose(t *testing.T) {
+	var out bytes.Buffer
+	w := NewOrderedWriter(&out)
+
+	fmt.Fprintf(w, "no trailing newline")
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	if expected := "seq=1 no trailing newline\n"; out.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, out.String())
+	}
+	if _, err := w.Write([]byte("after close\n")); err == nil {
+		t.Errorf("expected an error when writing after close")
+	}
+}
+
+func TestOrderedWriterConcurrent(t *testing.T) {
+	const writers = 8
+	const linesPerWriter = 200
+
+	var out bytes.Buffer
+	w := NewOrderedWriter(&out)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < linesPerWriter; j++ {
+				fmt.Fprintf(w, "writer=%d line=%d\n", id, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != writers*linesPerWriter {
+		t.Fatalf("expected %d lines, got %d", writers*linesPerWriter, len(lines))
+	}
+
+	// Sequence numbers must appear in submission order without gaps, and the
+	// lines of each writer must keep their relative order.
+	lastLinePerWriter := make([]int, writers)
+	for i := range lastLinePerWriter {
+		lastLinePerWriter[i] = -1
+	}
+	for i, line := range lines {
+		var seq uint64
+		var writer, lineNum int
+		if _, err := fmt.Sscanf(line, "seq=%d writer=%d line=%d", &seq, &writer, &lineNum); err != nil {
+			t.Fatalf("malformed line %q: %v", line, err)
+		}
+		if seq != uint64(i+1) {
+			t.Fatalf("expected sequence %d at position %d, got %d", i+1, i, seq)
+		}
+		if lineNum != lastLinePerWriter[writer]+1 {
+			t.Fatalf("writer %d lines out of order: got line %d after line %d", writer, lineNum, lastLinePerWriter[writer])
+		}
+		lastLinePerWriter[writer] = lineNum
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sanitization
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/klog/v2"
+)
+
+// EscapingFilter implements the LogFilter interface from klog, escaping ASCII
+// control characters and replacing invalid UTF-8 sequences in string
+// arguments before they reach the log encoder. This prevents log injection
+// through attacker-controlled values (e.g. object names containing newlines)
+// in the text format and broken documents in the JSON format.
+type EscapingFilter struct {
+	// Delegate is an optional filter applied to the escaped arguments, e.g.
+	// the datapol SanitizingFilter.
+	Delegate klog.LogFilter
+}
+
+// Filter is the filter function for the non-formatting logging functions of klog.
+func (ef *EscapingFilter) Filter(args []interface{}) []interface{} {
+	args = escapeArgs(args)
+	if ef.Delegate != nil {
+		return ef.Delegate.Filter(args)
+	}
+	return args
+}
+
+// FilterF is the filter function for the formatting logging functions of klog.
+func (ef *EscapingFilter) FilterF(format string, args []interface{}) (string, []interface{}) {
+	args = escapeArgs(args)
+	if ef.Delegate != nil {
+		return ef.Delegate.FilterF(format, args)
+	}
+	return format, args
+}
+
+// FilterS is the filter for the structured logging functions of klog.
+func (ef *EscapingFilter) FilterS(msg string, keysAndValues []interface{}) (string, []interface{}) {
+	msg = EscapeString(msg)
+	keysAndValues = escapeArgs(keysAndValues)
+	if ef.Delegate != nil {
+		return ef.Delegate.FilterS(msg, keysAndValues)
+	}
+	return msg, keysAndValues
+}
+
+// escapeArgs escapes all string arguments. The slice is only copied when at
+// least one argument actually changes, keeping the clean path allocation-free.
+func escapeArgs(args []interface{}) []interface{} {
+	var escaped []interface{}
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		e := EscapeString(s)
+		if e == s {
+			continue
+		}
+		if escaped == nil {
+			escaped = make([]interface{}, len(args))
+			copy(escaped, args)
+		}
+		escaped[i] = e
+	}
+	if escaped != nil {
+		return escaped
+	}
+	return args
+}
+
+// EscapeString returns s with ASCII control characters escaped and invalid
+// UTF-8 sequences replaced by the Unicode replacement character. Clean
+// strings are returned unchanged without allocating.
+func EscapeString(s string) string {
+	if stringIsClean(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// stringIsClean reports whether s contains neither control characters nor
+// invalid UTF-8. This is the hot path: almost all logged strings are clean.
+func stringIsClean(s string) bool {
+	multibyte := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f {
+			return false
+		}
+		if c >= utf8.RuneSelf {
+			multibyte = true
+		}
+	}
+	return !multibyte || utf8.ValidString(s)
+}
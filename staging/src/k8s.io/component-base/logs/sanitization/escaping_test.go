@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sanitization
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscapeString(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "clean ascii",
+			input:    "pod default/foo started",
+			expected: "pod default/foo started",
+		},
+		{
+			name:     "clean multibyte",
+			input:    "héllo wörld 日本",
+			expected: "héllo wörld 日本",
+		},
+		{
+			name:     "newline",
+			input:    "line1\nline2",
+			expected: `line1\nline2`,
+		},
+		{
+			name:     "carriage return and tab",
+			input:    "a\r\tb",
+			expected: `a\r\tb`,
+		},
+		{
+			name:     "escape sequence",
+			input:    "\x1b[31mred\x1b[0m",
+			expected: `\u001b[31mred\u001b[0m`,
+		},
+		{
+			name:     "delete character",
+			input:    "a\x7fb",
+			expected: `a\u007fb`,
+		},
+		{
+			name:     "invalid utf8",
+			input:    "a\xffb",
+			expected: "a�b",
+		},
+		{
+			name:     "truncated multibyte sequence",
+			input:    "abc\xe6",
+			expected: "abc�",
+		},
+		{
+			name:     "control character after multibyte",
+			input:    "é\n",
+			expected: `é\n`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EscapeString(tc.input); got != tc.expected {
+				t.Errorf("EscapeString(%q) = %q, expected %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeStringNoCopyWhenClean(t *testing.T) {
+	input := "a perfectly clean string"
+	if got := EscapeString(input); got != input {
+		t.Errorf("EscapeString(%q) = %q, expected the input unchanged", input, got)
+	}
+}
+
+func TestEscapingFilter(t *testing.T) {
+	ef := &EscapingFilter{}
+
+	args := ef.Filter([]interface{}{"clean", "new\nline", 42})
+	expected := []interface{}{"clean", `new\nline`, 42}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Filter returned %v, expected %v", args, expected)
+	}
+
+	format, args := ef.FilterF("%s: %d", []interface{}{"evil\rvalue", 1})
+	if format != "%s: %d" {
+		t.Errorf("FilterF changed the format string to %q", format)
+	}
+	if !reflect.DeepEqual(args, []interface{}{`evil\rvalue`, 1}) {
+		t.Errorf("FilterF returned unexpected args %v", args)
+	}
+
+	msg, keysAndValues := ef.FilterS("msg\nwith newline", []interface{}{"key", "val\x00ue"})
+	if msg != `msg\nwith newline` {
+		t.Errorf("FilterS returned unexpected message %q", msg)
+	}
+	if !reflect.DeepEqual(keysAndValues, []interface{}{"key", "val\\u0000ue"}) {
+		t.Errorf("FilterS returned unexpected keysAndValues %v", keysAndValues)
+	}
+}
+
+func TestEscapingFilterDelegate(t *testing.T) {
+	ef := &EscapingFilter{Delegate: &SanitizingFilter{}}
+
+	msg, keysAndValues := ef.FilterS("msg", []interface{}{"key", "value\n"})
+	if msg != "msg" {
+		t.Errorf("FilterS returned unexpected message %q", msg)
+	}
+	if !reflect.DeepEqual(keysAndValues, []interface{}{"key", `value\n`}) {
+		t.Errorf("FilterS returned unexpected keysAndValues %v", keysAndValues)
+	}
+}
+
+func BenchmarkEscapeStringClean(b *testing.B) {
+	input := "Updated pod default/foo with new resource version"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		EscapeString(input)
+	}
+}
+
+func BenchmarkEscapeStringControlCharacters(b *testing.B) {
+	input := "Updated pod default/foo\nwith new resource version"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		EscapeString(input)
+	}
+}
+
+func BenchmarkFilterSClean(b *testing.B) {
+	ef := &EscapingFilter{}
+	keysAndValues := []interface{}{"pod", "default/foo", "resourceVersion", 42}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ef.FilterS("Updated pod", keysAndValues)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"golang.org/x/sync/singleflight"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// defaultTokenRefreshWindow is how long before expiry a cached token is
+// refreshed. It is deliberately longer than the refresh window adal applies
+// itself, so that a refresh happens while the old token is still valid and a
+// slow or failing token endpoint does not translate into failed ARM calls.
+const defaultTokenRefreshWindow = 10 * time.Minute
+
+// tokenRefresher is the subset of adal.ServicePrincipalToken the cache needs,
+// pulled out so that tests can substitute a fake.
+type tokenRefresher interface {
+	Token() adal.Token
+	OAuthToken() string
+	RefreshWithContext(ctx context.Context) error
+}
+
+// TokenCache wraps a service principal token so that all Azure clients built
+// on top of it share one token and one refresh flow. The token is refreshed
+// proactively before it expires and concurrent refreshes are deduplicated,
+// so a burst of ARM calls after a controller restart results in at most one
+// request to the token endpoint.
+//
+// TokenCache implements adal.OAuthTokenProvider and adal.RefresherWithContext,
+// so it can be handed to autorest.NewBearerAuthorizer directly.
+type TokenCache struct {
+	refresher     tokenRefresher
+	refreshWindow time.Duration
+	group         singleflight.Group
+}
+
+// NewTokenCache creates a TokenCache on top of the given service principal
+// token.
+func NewTokenCache(spt *adal.ServicePrincipalToken) *TokenCache {
+	return newTokenCache(spt, defaultTokenRefreshWindow)
+}
+
+func newTokenCache(refresher tokenRefresher, refreshWindow time.Duration) *TokenCache {
+	return &TokenCache{
+		refresher:     refresher,
+		refreshWindow: refreshWindow,
+	}
+}
+
+// OAuthToken returns the current access token.
+func (c *TokenCache) OAuthToken() string {
+	return c.refresher.OAuthToken()
+}
+
+// Token returns the current token.
+func (c *TokenCache) Token() adal.Token {
+	return c.refresher.Token()
+}
+
+// EnsureFresh refreshes the token if it is within the refresh window of its
+// expiry.
+func (c *TokenCache) EnsureFresh() error {
+	return c.EnsureFreshWithContext(context.Background())
+}
+
+// EnsureFreshWithContext refreshes the token if it is within the refresh
+// window of its expiry. Callers arriving while a refresh is in flight wait
+// for it and share its result instead of issuing their own token requests.
+func (c *TokenCache) EnsureFreshWithContext(ctx context.Context) error {
+	if !c.refresher.Token().WillExpireIn(c.refreshWindow) {
+		return nil
+	}
+	_, err, shared := c.group.Do("refresh", func() (interface{}, error) {
+		// Re-check under the singleflight, the previous holder may
+		// have refreshed the token already.
+		if !c.refresher.Token().WillExpireIn(c.refreshWindow) {
+			return nil, nil
+		}
+		start := time.Now()
+		err := c.refresher.RefreshWithContext(ctx)
+		observeTokenRefresh(err, time.Since(start))
+		if err != nil {
+			return nil, fmt.Errorf("refreshing the access token: %v", err)
+		}
+		tokenMetrics.expiryTimestamp.Set(float64(c.refresher.Token().Expires().Unix()))
+		return nil, nil
+	})
+	if shared {
+		klog.V(6).Infof("azure: deduplicated a concurrent token refresh")
+	}
+	return err
+}
+
+var (
+	// tokenCachesLock protects tokenCaches.
+	tokenCachesLock sync.Mutex
+	// tokenCaches holds one TokenCache per credential, so that all clients
+	// configured from the same credentials share tokens and refreshes.
+	tokenCaches = map[string]*TokenCache{}
+)
+
+// GetTokenCache returns the shared TokenCache for the given configuration,
+// creating it on first use. Callers with the same credentials and environment
+// get the same cache back.
+func GetTokenCache(config *AzureAuthConfig, env *azure.Environment) (*TokenCache, error) {
+	key := tokenCacheKey(config, env)
+
+	tokenCachesLock.Lock()
+	defer tokenCachesLock.Unlock()
+	if cache, found := tokenCaches[key]; found {
+		return cache, nil
+	}
+
+	spt, err := GetServicePrincipalToken(config, env)
+	if err != nil {
+		return nil, err
+	}
+	cache := NewTokenCache(spt)
+	tokenCaches[key] = cache
+	return cache, nil
+}
+
+// tokenCacheKey identifies the credential a token is issued for.
+func tokenCacheKey(config *AzureAuthConfig, env *azure.Environment) string {
+	return strings.Join([]string{
+		strings.ToLower(config.TenantID),
+		strings.ToLower(config.AADClientID),
+		fmt.Sprintf("%t", config.UseManagedIdentityExtension),
+		strings.ToLower(config.UserAssignedIdentityID),
+		strings.ToLower(config.IdentitySystem),
+		env.ServiceManagementEndpoint,
+	}, "|")
+}
+
+// tokenCallMetrics is the metrics measuring token refreshes of the shared
+// token caches.
+type tokenCallMetrics struct {
+	refreshCount    *metrics.CounterVec
+	refreshLatency  *metrics.Histogram
+	expiryTimestamp *metrics.Gauge
+}
+
+var tokenMetrics = registerTokenMetrics()
+
+// observeTokenRefresh records the outcome and duration of one token refresh.
+func observeTokenRefresh(err error, latency time.Duration) {
+	status := "succeeded"
+	if err != nil {
+		status = "failed"
+	}
+	tokenMetrics.refreshCount.WithLabelValues(status).Inc()
+	tokenMetrics.refreshLatency.Observe(latency.Seconds())
+}
+
+// registerTokenMetrics registers the token refresh metrics.
+func registerTokenMetrics() *tokenCallMetrics {
+	m := &tokenCallMetrics{
+		refreshCount: metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Namespace:      "cloudprovider_azure",
+				Name:           "token_refresh_count",
+				Help:           "Number of access token refreshes, partitioned by status",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"status"},
+		),
+		refreshLatency: metrics.NewHistogram(
+			&metrics.HistogramOpts{
+				Namespace:      "cloudprovider_azure",
+				Name:           "token_refresh_duration_seconds",
+				Help:           "Latency of access token refreshes",
+				Buckets:        []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+				StabilityLevel: metrics.ALPHA,
+			},
+		),
+		expiryTimestamp: metrics.NewGauge(
+			&metrics.GaugeOpts{
+				Namespace:      "cloudprovider_azure",
+				Name:           "token_expiry_time_seconds",
+				Help:           "Unix timestamp of the current access token's expiry",
+				StabilityLevel: metrics.ALPHA,
+			},
+		),
+	}
+
+	legacyregistry.MustRegister(m.refreshCount)
+	legacyregistry.MustRegister(m.refreshLatency)
+	legacyregistry.MustRegister(m.expiryTimestamp)
+
+	return m
+}
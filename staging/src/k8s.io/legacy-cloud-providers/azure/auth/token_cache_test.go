@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTokenRefresher counts refreshes and hands out tokens with a fixed
+// expiry. An optional block channel lets tests hold a refresh in flight.
+type fakeTokenRefresher struct {
+	mu        sync.Mutex
+	expiresIn time.Duration
+	refreshes int32
+	err       error
+	block     chan struct{}
+}
+
+func (f *fakeTokenRefresher) Token() adal.Token {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return adal.Token{
+		AccessToken: "token",
+		ExpiresOn:   unixJSONNumber(time.Now().Add(f.expiresIn).Unix()),
+	}
+}
+
+func (f *fakeTokenRefresher) OAuthToken() string {
+	return f.Token().AccessToken
+}
+
+func (f *fakeTokenRefresher) RefreshWithContext(ctx context.Context) error {
+	atomic.AddInt32(&f.refreshes, 1)
+	if f.block != nil {
+		<-f.block
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.mu.Lock()
+	f.expiresIn = time.Hour
+	f.mu.Unlock()
+	return nil
+}
+
+func TestTokenCacheSkipsFreshToken(t *testing.T) {
+	refresher := &fakeTokenRefresher{expiresIn: time.Hour}
+	cache := newTokenCache(refresher, defaultTokenRefreshWindow)
+
+	assert.NoError(t, cache.EnsureFresh())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&refresher.refreshes))
+	assert.Equal(t, "token", cache.OAuthToken())
+}
+
+func TestTokenCacheRefreshesBeforeExpiry(t *testing.T) {
+	refresher := &fakeTokenRefresher{expiresIn: time.Minute}
+	cache := newTokenCache(refresher, defaultTokenRefreshWindow)
+
+	assert.NoError(t, cache.EnsureFresh())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refresher.refreshes))
+
+	// The refreshed token is outside the refresh window, so later calls must
+	// not refresh again.
+	assert.NoError(t, cache.EnsureFresh())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refresher.refreshes))
+}
+
+func TestTokenCacheReturnsRefreshError(t *testing.T) {
+	refresher := &fakeTokenRefresher{expiresIn: time.Minute, err: fmt.Errorf("token endpoint is down")}
+	cache := newTokenCache(refresher, defaultTokenRefreshWindow)
+
+	err := cache.EnsureFresh()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "token endpoint is down")
+}
+
+func TestTokenCacheDeduplicatesConcurrentRefreshes(t *testing.T) {
+	refresher := &fakeTokenRefresher{expiresIn: time.Minute, block: make(chan struct{})}
+	cache := newTokenCache(refresher, defaultTokenRefreshWindow)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, cache.EnsureFresh())
+		}()
+	}
+	// Give all goroutines time to join the in-flight refresh, then let it
+	// finish.
+	time.Sleep(100 * time.Millisecond)
+	close(refresher.block)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refresher.refreshes))
+}
+
+func TestGetTokenCacheShared(t *testing.T) {
+	config := &AzureAuthConfig{
+		TenantID:        "TenantID",
+		AADClientID:     "AADClientID",
+		AADClientSecret: "AADClientSecret",
+	}
+	env := &azure.PublicCloud
+
+	first, err := GetTokenCache(config, env)
+	assert.NoError(t, err)
+	second, err := GetTokenCache(config, env)
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+
+	other := *config
+	other.AADClientID = "OtherClientID"
+	third, err := GetTokenCache(&other, env)
+	assert.NoError(t, err)
+	assert.NotSame(t, first, third)
+}
+
+// unixJSONNumber converts a unix timestamp into the json.Number adal stores
+// ExpiresOn as.
+func unixJSONNumber(v int64) json.Number {
+	return json.Number(strconv.FormatInt(v, 10))
+}
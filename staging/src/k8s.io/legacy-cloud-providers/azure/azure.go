@@ -49,6 +49,7 @@ import (
 	"k8s.io/legacy-cloud-providers/azure/clients/fileclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/interfaceclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/loadbalancerclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/natgatewayclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/publicipclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/routeclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/routetableclient"
@@ -56,6 +57,7 @@ import (
 	"k8s.io/legacy-cloud-providers/azure/clients/snapshotclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/storageaccountclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/subnetclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/usageclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/vmclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/vmsizeclient"
 	"k8s.io/legacy-cloud-providers/azure/clients/vmssclient"
@@ -178,6 +180,17 @@ type Config struct {
 	// DisableOutboundSNAT disables the outbound SNAT for public load balancer rules.
 	// It should only be set when loadBalancerSku is standard. If not set, it will be default to false.
 	DisableOutboundSNAT *bool `json:"disableOutboundSNAT,omitempty" yaml:"disableOutboundSNAT,omitempty"`
+	// OutboundRuleAllocatedPorts, when larger than zero, enables the management of an outbound
+	// rule on the standard load balancer with the given number of SNAT ports allocated per
+	// backend instance. It should only be set when loadBalancerSku is standard.
+	OutboundRuleAllocatedPorts int32 `json:"outboundRuleAllocatedPorts,omitempty" yaml:"outboundRuleAllocatedPorts,omitempty"`
+	// NatGatewayName is the name of a pre-created NAT gateway to associate with the cluster
+	// subnet for outbound connectivity. It should only be set when loadBalancerSku is standard.
+	// When set, outbound rule management on the load balancer is skipped.
+	NatGatewayName string `json:"natGatewayName,omitempty" yaml:"natGatewayName,omitempty"`
+	// NatGatewayResourceGroup is the resource group of the NAT gateway. If not set, it will
+	// be the same as the cluster resource group.
+	NatGatewayResourceGroup string `json:"natGatewayResourceGroup,omitempty" yaml:"natGatewayResourceGroup,omitempty"`
 
 	// Maximum allowed LoadBalancer Rule Count is the limit enforced by Azure Load balancer
 	MaximumLoadBalancerRuleCount int `json:"maximumLoadBalancerRuleCount,omitempty" yaml:"maximumLoadBalancerRuleCount,omitempty"`
@@ -185,6 +198,11 @@ type Config struct {
 	// The cloud configure type for Azure cloud provider. Supported values are file, secret and merge.
 	CloudConfigType cloudConfigType `json:"cloudConfigType,omitempty" yaml:"cloudConfigType,omitempty"`
 
+	// CloudConfigFile is the path of the cloud config file to watch for changes. When set, the
+	// controller manager periodically re-reads the file and re-initializes the Azure clients when
+	// its content has changed, so rotated credentials are picked up without a restart.
+	CloudConfigFile string `json:"cloudConfigFile,omitempty" yaml:"cloudConfigFile,omitempty"`
+
 	// LoadBalancerName determines the specific name of the load balancer user want to use, working with
 	// LoadBalancerResourceGroup
 	LoadBalancerName string `json:"loadBalancerName,omitempty" yaml:"loadBalancerName,omitempty"`
@@ -212,6 +230,8 @@ type Config struct {
 	VmssCacheTTLInSeconds int `json:"vmssCacheTTLInSeconds,omitempty" yaml:"vmssCacheTTLInSeconds,omitempty"`
 	// VmssVirtualMachinesCacheTTLInSeconds sets the cache TTL for vmssVirtualMachines
 	VmssVirtualMachinesCacheTTLInSeconds int `json:"vmssVirtualMachinesCacheTTLInSeconds,omitempty" yaml:"vmssVirtualMachinesCacheTTLInSeconds,omitempty"`
+	// VmssFlexNodesCacheTTLInSeconds sets the cache TTL for nodes of VMSS with flexible orchestration mode
+	VmssFlexNodesCacheTTLInSeconds int `json:"vmssFlexNodesCacheTTLInSeconds,omitempty" yaml:"vmssFlexNodesCacheTTLInSeconds,omitempty"`
 	// VmCacheTTLInSeconds sets the cache TTL for vm
 	VMCacheTTLInSeconds int `json:"vmCacheTTLInSeconds,omitempty" yaml:"vmCacheTTLInSeconds,omitempty"`
 	// LoadBalancerCacheTTLInSeconds sets the cache TTL for load balancer
@@ -228,6 +248,12 @@ type Config struct {
 	// includes load balancer, security group and route table. The supported format is `a=b,c=d,...`. After updated
 	// this config, the old tags would be replaced by the new ones.
 	Tags string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// EnableFakeCloud backs all Azure clients with an in-memory fake ARM store
+	// instead of real ARM endpoints, so that service and route controller logic
+	// can be exercised in CI without an Azure subscription. No credentials are
+	// required in this mode and it must never be enabled on a real cluster.
+	EnableFakeCloud bool `json:"enableFakeCloud,omitempty" yaml:"enableFakeCloud,omitempty"`
 }
 
 var (
@@ -259,6 +285,8 @@ type Cloud struct {
 	VirtualMachineScaleSetsClient   vmssclient.Interface
 	VirtualMachineScaleSetVMsClient vmssvmclient.Interface
 	VirtualMachineSizesClient       vmsizeclient.Interface
+	UsagesClient                    usageclient.Interface
+	NatGatewaysClient               natgatewayclient.Interface
 
 	ResourceRequestBackoff wait.Backoff
 	metadata               *InstanceMetadataService
@@ -290,11 +318,22 @@ type Cloud struct {
 	eventRecorder    record.EventRecorder
 	routeUpdater     *delayedRouteUpdater
 
+	// reloadConfigLock serializes re-initializations triggered by the cloud
+	// config file watcher.
+	reloadConfigLock sync.Mutex
+	// configFileHash is the hash of the cloud config file content that was
+	// last applied, used by the config file watcher to detect changes.
+	configFileHash string
+
 	vmCache  *azcache.TimedCache
 	lbCache  *azcache.TimedCache
 	nsgCache *azcache.TimedCache
 	rtCache  *azcache.TimedCache
 
+	// armStore is the in-memory resource store backing all clients in fake
+	// cloud mode, nil otherwise.
+	armStore *fakeARMStore
+
 	*BlobDiskController
 	*ManagedDiskController
 	*controllerCommon
@@ -396,8 +435,12 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret bool) erro
 		return err
 	}
 
-	servicePrincipalToken, err := auth.GetServicePrincipalToken(&config.AzureAuthConfig, env)
-	if err == auth.ErrorNoAuth {
+	var servicePrincipalToken *adal.ServicePrincipalToken
+	if config.EnableFakeCloud {
+		// Fake cloud mode needs no credentials; all clients are backed by the
+		// in-memory fake ARM store and no calls reach Azure.
+		klog.Warning("enableFakeCloud is set, Azure cloud provider is starting in fake cloud mode")
+	} else if servicePrincipalToken, err = auth.GetServicePrincipalToken(&config.AzureAuthConfig, env); err == auth.ErrorNoAuth {
 		// Only controller-manager would lazy-initialize from secret, and credentials are required for such case.
 		if fromSecret {
 			err := fmt.Errorf("no credentials provided for Azure cloud provider")
@@ -471,6 +514,12 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret bool) erro
 		if config.DisableOutboundSNAT != nil && *config.DisableOutboundSNAT {
 			return fmt.Errorf("disableOutboundSNAT should only set when loadBalancerSku is standard")
 		}
+		if config.OutboundRuleAllocatedPorts != 0 {
+			return fmt.Errorf("outboundRuleAllocatedPorts should only set when loadBalancerSku is standard")
+		}
+		if config.NatGatewayName != "" {
+			return fmt.Errorf("natGatewayName should only set when loadBalancerSku is standard")
+		}
 	}
 
 	az.Config = *config
@@ -481,27 +530,38 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret bool) erro
 		return err
 	}
 
-	// No credentials provided, InstanceMetadataService would be used for getting Azure resources.
-	// Note that this only applies to Kubelet, controller-manager should configure credentials for managing Azure resources.
-	if servicePrincipalToken == nil {
-		return nil
-	}
+	if config.EnableFakeCloud {
+		az.configureFakeClients()
+	} else {
+		// No credentials provided, InstanceMetadataService would be used for getting Azure resources.
+		// Note that this only applies to Kubelet, controller-manager should configure credentials for managing Azure resources.
+		if servicePrincipalToken == nil {
+			return nil
+		}
 
-	// If uses network resources in different AAD Tenant, then prepare corresponding Service Principal Token for VM/VMSS client and network resources client
-	var multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken
-	var networkResourceServicePrincipalToken *adal.ServicePrincipalToken
-	if az.Config.UsesNetworkResourceInDifferentTenant() {
-		multiTenantServicePrincipalToken, err = auth.GetMultiTenantServicePrincipalToken(&az.Config.AzureAuthConfig, &az.Environment)
-		if err != nil {
-			return err
+		// If uses network resources in different AAD Tenant, then prepare corresponding Service Principal Token for VM/VMSS client and network resources client
+		var multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken
+		var networkResourceServicePrincipalToken *adal.ServicePrincipalToken
+		if az.Config.UsesNetworkResourceInDifferentTenant() {
+			multiTenantServicePrincipalToken, err = auth.GetMultiTenantServicePrincipalToken(&az.Config.AzureAuthConfig, &az.Environment)
+			if err != nil {
+				return err
+			}
+			networkResourceServicePrincipalToken, err = auth.GetNetworkResourceServicePrincipalToken(&az.Config.AzureAuthConfig, &az.Environment)
+			if err != nil {
+				return err
+			}
 		}
-		networkResourceServicePrincipalToken, err = auth.GetNetworkResourceServicePrincipalToken(&az.Config.AzureAuthConfig, &az.Environment)
+
+		// All clients of this cloud share one token cache, so that a burst of ARM
+		// calls after a restart results in at most one token request.
+		tokenCache, err := auth.GetTokenCache(&az.Config.AzureAuthConfig, &az.Environment)
 		if err != nil {
 			return err
 		}
-	}
 
-	az.configAzureClients(servicePrincipalToken, multiTenantServicePrincipalToken, networkResourceServicePrincipalToken)
+		az.configAzureClients(tokenCache, multiTenantServicePrincipalToken, networkResourceServicePrincipalToken)
+	}
 
 	if az.MaximumLoadBalancerRuleCount == 0 {
 		az.MaximumLoadBalancerRuleCount = maximumLoadBalancerRuleCount
@@ -548,10 +608,10 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret bool) erro
 }
 
 func (az *Cloud) configAzureClients(
-	servicePrincipalToken *adal.ServicePrincipalToken,
+	tokenCache *auth.TokenCache,
 	multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken,
 	networkResourceServicePrincipalToken *adal.ServicePrincipalToken) {
-	azClientConfig := az.getAzureClientConfig(servicePrincipalToken)
+	azClientConfig := az.getAzureClientConfig(tokenCache)
 
 	// Prepare AzureClientConfig for all azure clients
 	interfaceClientConfig := azClientConfig.WithRateLimiter(az.Config.InterfaceRateLimit)
@@ -573,6 +633,9 @@ func (az *Cloud) configAzureClients(
 	publicIPClientConfig := azClientConfig.WithRateLimiter(az.Config.PublicIPAddressRateLimit)
 	// TODO(ZeroMagic): add azurefileRateLimit
 	fileClientConfig := azClientConfig.WithRateLimiter(nil)
+	// Usage listing is low-volume and shares the default rate limits.
+	usageClientConfig := azClientConfig.WithRateLimiter(nil)
+	natGatewayClientConfig := azClientConfig.WithRateLimiter(az.Config.NatGatewayRateLimit)
 
 	// If uses network resources in different AAD Tenant, update Authorizer for VM/VMSS client config
 	if multiTenantServicePrincipalToken != nil {
@@ -584,13 +647,14 @@ func (az *Cloud) configAzureClients(
 
 	// If uses network resources in different AAD Tenant, update SubscriptionID and Authorizer for network resources client config
 	if networkResourceServicePrincipalToken != nil {
-		networkResourceServicePrincipalTokenAuthorizer := autorest.NewBearerAuthorizer(networkResourceServicePrincipalToken)
+		networkResourceServicePrincipalTokenAuthorizer := autorest.NewBearerAuthorizer(auth.NewTokenCache(networkResourceServicePrincipalToken))
 		routeClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		subnetClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		routeTableClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		loadBalancerClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		securityGroupClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		publicIPClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
+		natGatewayClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 
 		routeClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
 		subnetClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
@@ -598,6 +662,7 @@ func (az *Cloud) configAzureClients(
 		loadBalancerClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
 		securityGroupClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
 		publicIPClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
+		natGatewayClientConfig.SubscriptionID = az.Config.NetworkResourceSubscriptionID
 	}
 
 	// Initialize all azure clients based on client config
@@ -616,15 +681,17 @@ func (az *Cloud) configAzureClients(
 	az.SecurityGroupsClient = securitygroupclient.New(securityGroupClientConfig)
 	az.PublicIPAddressesClient = publicipclient.New(publicIPClientConfig)
 	az.FileClient = fileclient.New(fileClientConfig)
+	az.UsagesClient = usageclient.New(usageClientConfig)
+	az.NatGatewaysClient = natgatewayclient.New(natGatewayClientConfig)
 }
 
-func (az *Cloud) getAzureClientConfig(servicePrincipalToken *adal.ServicePrincipalToken) *azclients.ClientConfig {
+func (az *Cloud) getAzureClientConfig(tokenCache *auth.TokenCache) *azclients.ClientConfig {
 	azClientConfig := &azclients.ClientConfig{
 		CloudName:               az.Config.Cloud,
 		Location:                az.Config.Location,
 		SubscriptionID:          az.Config.SubscriptionID,
 		ResourceManagerEndpoint: az.Environment.ResourceManagerEndpoint,
-		Authorizer:              autorest.NewBearerAuthorizer(servicePrincipalToken),
+		Authorizer:              autorest.NewBearerAuthorizer(tokenCache),
 		Backoff:                 &retry.Backoff{Steps: 1},
 	}
 
@@ -670,6 +737,10 @@ func (az *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder,
 	az.eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: az.KubeClient.CoreV1().Events("")})
 	az.eventRecorder = az.eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "azure-cloud-provider"})
 	az.InitializeCloudFromSecret()
+
+	if az.CloudConfigFile != "" {
+		go az.WatchCloudConfigFile(az.CloudConfigFile, stop)
+	}
 }
 
 // LoadBalancer returns a balancer interface. Also returns true if the interface is supported, false otherwise.
@@ -0,0 +1,121 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/legacy-cloud-providers/azure/metrics"
+)
+
+const (
+	// cloudConfigReloadInterval is how often the cloud config file is checked
+	// for changes by the config file watcher.
+	cloudConfigReloadInterval = 30 * time.Second
+
+	configReloadedReason     = "CloudConfigReloaded"
+	configReloadFailedReason = "CloudConfigReloadFailed"
+)
+
+// WatchCloudConfigFile periodically re-reads the cloud config file and, when
+// its content has changed, re-initializes the Azure clients (credentials, rate
+// limits and endpoints) from the new content. This allows rotating the
+// credentials in azure.json without restarting the controller manager. It
+// blocks until stopCh is closed.
+func (az *Cloud) WatchCloudConfigFile(configFilePath string, stopCh <-chan struct{}) {
+	// The content of the config file was already applied when the Cloud was
+	// constructed, so record its hash and only react to later changes.
+	if data, err := ioutil.ReadFile(configFilePath); err == nil {
+		az.reloadConfigLock.Lock()
+		az.configFileHash = configFileHash(data)
+		az.reloadConfigLock.Unlock()
+	}
+
+	klog.V(2).Infof("Starting Azure cloud config watcher on %s", configFilePath)
+	wait.Until(func() {
+		if err := az.refreshCloudConfigFile(configFilePath); err != nil {
+			klog.Errorf("Failed to reload cloud config from %s: %v", configFilePath, err)
+		}
+	}, cloudConfigReloadInterval, stopCh)
+}
+
+// refreshCloudConfigFile applies the cloud config file when its content has
+// changed since the last reload. The re-initialization happens under
+// reloadConfigLock so concurrent reloads cannot leave the clients half
+// rebuilt.
+func (az *Cloud) refreshCloudConfigFile(configFilePath string) error {
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read cloud config file %s: %v", configFilePath, err)
+	}
+	hash := configFileHash(data)
+
+	az.reloadConfigLock.Lock()
+	defer az.reloadConfigLock.Unlock()
+	if hash == az.configFileHash {
+		return nil
+	}
+
+	config, err := parseConfig(bytes.NewReader(data))
+	if err != nil {
+		metrics.ObserveConfigReload(false)
+		az.recordCloudConfigEvent(v1.EventTypeWarning, configReloadFailedReason, err.Error())
+		return fmt.Errorf("failed to parse cloud config file %s: %v", configFilePath, err)
+	}
+
+	klog.V(2).Infof("Cloud config file %s has changed, re-initializing Azure clients", configFilePath)
+	if err := az.InitializeCloudFromConfig(config, false); err != nil {
+		metrics.ObserveConfigReload(false)
+		az.recordCloudConfigEvent(v1.EventTypeWarning, configReloadFailedReason, err.Error())
+		return fmt.Errorf("failed to re-initialize from cloud config file %s: %v", configFilePath, err)
+	}
+
+	az.configFileHash = hash
+	metrics.ObserveConfigReload(true)
+	az.recordCloudConfigEvent(v1.EventTypeNormal, configReloadedReason, fmt.Sprintf("Reloaded cloud config from %s", configFilePath))
+	return nil
+}
+
+// recordCloudConfigEvent emits an event for a cloud config reload. The config
+// file has no API object of its own, so the event is attached to a synthetic
+// object reference.
+func (az *Cloud) recordCloudConfigEvent(eventType, reason, message string) {
+	if az.eventRecorder == nil {
+		return
+	}
+	az.Event(&v1.ObjectReference{
+		Kind:      "AzureCloudProvider",
+		Namespace: cloudConfigNamespace,
+		Name:      cloudConfigKey,
+	}, eventType, reason, message)
+}
+
+// configFileHash returns the hash used to detect cloud config file changes.
+func configFileHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,88 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshCloudConfigFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "azure-config-reload")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configFilePath := filepath.Join(tmpDir, "azure.json")
+	err = ioutil.WriteFile(configFilePath, []byte(`{"resourceGroup": "rg1", "useInstanceMetadata": true}`), 0600)
+	assert.NoError(t, err)
+
+	az := &Cloud{}
+	err = az.refreshCloudConfigFile(configFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "rg1", az.ResourceGroup)
+	assert.NotEmpty(t, az.configFileHash)
+	appliedHash := az.configFileHash
+
+	// Unchanged content must not re-apply the config.
+	az.ResourceGroup = "changed-by-hand"
+	err = az.refreshCloudConfigFile(configFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed-by-hand", az.ResourceGroup)
+	assert.Equal(t, appliedHash, az.configFileHash)
+
+	// Changed content must be applied and update the recorded hash.
+	err = ioutil.WriteFile(configFilePath, []byte(`{"resourceGroup": "rg2", "useInstanceMetadata": true}`), 0600)
+	assert.NoError(t, err)
+	err = az.refreshCloudConfigFile(configFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "rg2", az.ResourceGroup)
+	assert.NotEqual(t, appliedHash, az.configFileHash)
+}
+
+func TestRefreshCloudConfigFileErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "azure-config-reload")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	az := &Cloud{}
+
+	// A missing config file must be reported without touching the config.
+	err = az.refreshCloudConfigFile(filepath.Join(tmpDir, "missing.json"))
+	assert.Error(t, err)
+	assert.Empty(t, az.configFileHash)
+
+	// A config file that cannot be parsed must be reported and must not
+	// update the recorded hash, so a fixed file is picked up afterwards.
+	configFilePath := filepath.Join(tmpDir, "azure.json")
+	err = ioutil.WriteFile(configFilePath, []byte(`{invalid`), 0600)
+	assert.NoError(t, err)
+	err = az.refreshCloudConfigFile(configFilePath)
+	assert.Error(t, err)
+	assert.Empty(t, az.configFileHash)
+
+	err = ioutil.WriteFile(configFilePath, []byte(`{"resourceGroup": "rg1", "useInstanceMetadata": true}`), 0600)
+	assert.NoError(t, err)
+	err = az.refreshCloudConfigFile(configFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "rg1", az.ResourceGroup)
+}
@@ -67,6 +67,10 @@ const (
 
 	// see https://docs.microsoft.com/en-us/rest/api/compute/disks/createorupdate#create-a-managed-disk-from-an-existing-managed-disk-in-the-same-or-different-subscription.
 	managedDiskPath = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s"
+
+	// lock key suffixes guarding the per-node attach and detach disk queues
+	attachDiskMapKeySuffix = "attachdiskmap"
+	detachDiskMapKeySuffix = "detachdiskmap"
 )
 
 var defaultBackOff = kwait.Backoff{
@@ -91,6 +95,25 @@ type controllerCommon struct {
 	// vm disk map used to lock per vm update calls
 	vmLockMap *lockMap
 	cloud     *Cloud
+	// disk queues waiting for attach or detach on a specific node
+	// <nodeName, map<diskURI, *AttachDiskOptions/diskName>>
+	attachDiskMap sync.Map
+	detachDiskMap sync.Map
+}
+
+// AttachDiskOptions are the disk specific options of one attach request. The
+// LUN is assigned when the request is taken out of the attach queue.
+type AttachDiskOptions struct {
+	lun                     int32
+	isManagedDisk           bool
+	diskName                string
+	cachingMode             compute.CachingTypes
+	diskEncryptionSetID     string
+	writeAcceleratorEnabled bool
+}
+
+func (a *AttachDiskOptions) String() string {
+	return fmt.Sprintf("AttachDiskOptions{diskName: %q, lun: %d}", a.diskName, a.lun)
 }
 
 // getNodeVMSet gets the VMSet interface based on config.VMType and the real virtual machine type.
@@ -107,11 +130,16 @@ func (c *controllerCommon) getNodeVMSet(nodeName types.NodeName, crt azcache.Azu
 	}
 
 	// 3. If the node is managed by availability set, then return ss.availabilitySet.
+	// This includes instances of scale sets with flexible orchestration mode,
+	// which are standalone VMs and must be attached/detached through the VM API.
 	managedByAS, err := ss.isNodeManagedByAvailabilitySet(mapNodeNameToVMName(nodeName), crt)
 	if err != nil {
 		return nil, err
 	}
 	if managedByAS {
+		if managedByFlex, err := ss.isNodeManagedByFlexScaleSet(mapNodeNameToVMName(nodeName), crt); err == nil && managedByFlex {
+			klog.V(4).Infof("node %q is an instance of a flex scale set, using the standalone VM code paths", nodeName)
+		}
 		// vm is managed by availability set.
 		return ss.availabilitySet, nil
 	}
@@ -121,6 +149,8 @@ func (c *controllerCommon) getNodeVMSet(nodeName types.NodeName, crt azcache.Azu
 }
 
 // AttachDisk attaches a vhd to vm. The vhd must exist, can be identified by diskName, diskURI.
+// Attach requests of one node are queued and the caller that grabs the node's vm lock
+// attaches all queued disks in a single VM update call.
 // return (lun, error)
 func (c *controllerCommon) AttachDisk(isManagedDisk bool, diskName, diskURI string, nodeName types.NodeName, cachingMode compute.CachingTypes) (int32, error) {
 	diskEncryptionSetID := ""
@@ -187,22 +217,99 @@ func (c *controllerCommon) AttachDisk(isManagedDisk bool, diskName, diskURI stri
 		return -1, fmt.Errorf("failed to get azure instance id for node %q (%v)", nodeName, err)
 	}
 
-	c.vmLockMap.LockEntry(strings.ToLower(string(nodeName)))
-	defer c.vmLockMap.UnlockEntry(strings.ToLower(string(nodeName)))
+	options := &AttachDiskOptions{
+		lun:                     -1,
+		isManagedDisk:           isManagedDisk,
+		diskName:                diskName,
+		cachingMode:             cachingMode,
+		diskEncryptionSetID:     diskEncryptionSetID,
+		writeAcceleratorEnabled: writeAcceleratorEnabled,
+	}
+	node := strings.ToLower(string(nodeName))
+	disk := strings.ToLower(diskURI)
+	if err := c.insertAttachDiskRequest(disk, node, options); err != nil {
+		return -1, err
+	}
+
+	c.vmLockMap.LockEntry(node)
+	defer c.vmLockMap.UnlockEntry(node)
+	diskMap, err := c.cleanAttachDiskRequests(node)
+	if err != nil {
+		return -1, err
+	}
 
-	lun, err := c.GetNextDiskLun(nodeName)
+	klog.V(2).Infof("Trying to attach volume %q to node %q, %d requests in batch", diskURI, nodeName, len(diskMap))
+	if len(diskMap) == 0 {
+		// The disk was attached in the batch of an earlier caller. Look its LUN
+		// up from the latest VM model, so that a disk that failed in that batch
+		// is reported on its own request.
+		return c.GetDiskLun(diskName, diskURI, nodeName)
+	}
+
+	lun, err := c.SetDiskLun(nodeName, disk, diskMap)
 	if err != nil {
 		klog.Warningf("no LUN available for instance %q (%v)", nodeName, err)
 		return -1, fmt.Errorf("all LUNs are used, cannot attach volume (%s, %s) to instance %q (%v)", diskName, diskURI, instanceid, err)
 	}
 
-	klog.V(2).Infof("Trying to attach volume %q lun %d to node %q.", diskURI, lun, nodeName)
-	c.diskAttachDetachMap.Store(strings.ToLower(diskURI), "attaching")
-	defer c.diskAttachDetachMap.Delete(strings.ToLower(diskURI))
-	return lun, vmset.AttachDisk(isManagedDisk, diskName, diskURI, nodeName, lun, cachingMode, diskEncryptionSetID, writeAcceleratorEnabled)
+	for uri := range diskMap {
+		c.diskAttachDetachMap.Store(uri, "attaching")
+	}
+	defer func() {
+		for uri := range diskMap {
+			c.diskAttachDetachMap.Delete(uri)
+		}
+	}()
+	return lun, vmset.AttachDisk(nodeName, diskMap)
+}
+
+// insertAttachDiskRequest queues an attach request of diskURI for the node. A
+// request that is already queued is left in place, so that a retry does not
+// grow the batch.
+func (c *controllerCommon) insertAttachDiskRequest(diskURI, nodeName string, options *AttachDiskOptions) error {
+	var diskMap map[string]*AttachDiskOptions
+	attachDiskMapKey := nodeName + attachDiskMapKeySuffix
+	c.vmLockMap.LockEntry(attachDiskMapKey)
+	defer c.vmLockMap.UnlockEntry(attachDiskMapKey)
+	v, ok := c.attachDiskMap.Load(nodeName)
+	if ok {
+		if diskMap, ok = v.(map[string]*AttachDiskOptions); !ok {
+			return fmt.Errorf("convert attachDiskMap failure on node(%s)", nodeName)
+		}
+	} else {
+		diskMap = make(map[string]*AttachDiskOptions)
+		c.attachDiskMap.Store(nodeName, diskMap)
+	}
+	if _, ok = diskMap[diskURI]; ok {
+		klog.V(2).Infof("azureDisk - duplicated attach disk(%s) request on node(%s)", diskURI, nodeName)
+	} else {
+		diskMap[diskURI] = options
+	}
+	return nil
+}
+
+// cleanAttachDiskRequests drains the attach queue of the node and returns the
+// drained requests. The caller is responsible for attaching every returned
+// disk while it holds the node's vm lock.
+func (c *controllerCommon) cleanAttachDiskRequests(nodeName string) (map[string]*AttachDiskOptions, error) {
+	var diskMap map[string]*AttachDiskOptions
+	attachDiskMapKey := nodeName + attachDiskMapKeySuffix
+	c.vmLockMap.LockEntry(attachDiskMapKey)
+	defer c.vmLockMap.UnlockEntry(attachDiskMapKey)
+	v, ok := c.attachDiskMap.Load(nodeName)
+	if !ok {
+		return diskMap, nil
+	}
+	if diskMap, ok = v.(map[string]*AttachDiskOptions); !ok {
+		return nil, fmt.Errorf("convert attachDiskMap failure on node(%s)", nodeName)
+	}
+	c.attachDiskMap.Store(nodeName, make(map[string]*AttachDiskOptions))
+	return diskMap, nil
 }
 
 // DetachDisk detaches a disk from host. The vhd can be identified by diskName or diskURI.
+// Detach requests of one node are queued and the caller that grabs the node's vm lock
+// detaches all queued disks in a single VM update call.
 func (c *controllerCommon) DetachDisk(diskName, diskURI string, nodeName types.NodeName) error {
 	_, err := c.cloud.InstanceID(context.TODO(), nodeName)
 	if err != nil {
@@ -221,15 +328,31 @@ func (c *controllerCommon) DetachDisk(diskName, diskURI string, nodeName types.N
 		return err
 	}
 
-	klog.V(2).Infof("detach %v from node %q", diskURI, nodeName)
+	node := strings.ToLower(string(nodeName))
+	disk := strings.ToLower(diskURI)
+	if err := c.insertDetachDiskRequest(diskName, disk, node); err != nil {
+		return err
+	}
 
-	// make the lock here as small as possible
-	c.vmLockMap.LockEntry(strings.ToLower(string(nodeName)))
-	c.diskAttachDetachMap.Store(strings.ToLower(diskURI), "detaching")
-	err = vmset.DetachDisk(diskName, diskURI, nodeName)
-	c.diskAttachDetachMap.Delete(strings.ToLower(diskURI))
-	c.vmLockMap.UnlockEntry(strings.ToLower(string(nodeName)))
+	c.vmLockMap.LockEntry(node)
+	defer c.vmLockMap.UnlockEntry(node)
+	diskMap, err := c.cleanDetachDiskRequests(node)
+	if err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("Trying to detach volume %q from node %q, %d requests in batch", diskURI, nodeName, len(diskMap))
+	if len(diskMap) == 0 {
+		// The disk was detached in the batch of an earlier caller. Report an
+		// error when it is still attached, so that a disk that failed in that
+		// batch is reported on its own request.
+		if lun, errGetLun := c.GetDiskLun(diskName, diskURI, nodeName); errGetLun == nil {
+			return fmt.Errorf("disk(%s) is still attached to node(%s) on lun(%d)", diskURI, nodeName, lun)
+		}
+		return nil
+	}
 
+	err = c.detachDiskBatch(vmset, nodeName, diskMap)
 	if err != nil {
 		if isInstanceNotFoundError(err) {
 			// if host doesn't exist, no need to detach
@@ -240,11 +363,7 @@ func (c *controllerCommon) DetachDisk(diskName, diskURI string, nodeName types.N
 		if retry.IsErrorRetriable(err) && c.cloud.CloudProviderBackoff {
 			klog.Warningf("azureDisk - update backing off: detach disk(%s, %s), err: %v", diskName, diskURI, err)
 			retryErr := kwait.ExponentialBackoff(c.cloud.RequestBackoff(), func() (bool, error) {
-				c.vmLockMap.LockEntry(strings.ToLower(string(nodeName)))
-				c.diskAttachDetachMap.Store(strings.ToLower(diskURI), "detaching")
-				err := vmset.DetachDisk(diskName, diskURI, nodeName)
-				c.diskAttachDetachMap.Delete(strings.ToLower(diskURI))
-				c.vmLockMap.UnlockEntry(strings.ToLower(string(nodeName)))
+				err := c.detachDiskBatch(vmset, nodeName, diskMap)
 
 				retriable := false
 				if err != nil && retry.IsErrorRetriable(err) {
@@ -267,6 +386,64 @@ func (c *controllerCommon) DetachDisk(diskName, diskURI string, nodeName types.N
 	return nil
 }
 
+// detachDiskBatch detaches the disks in diskMap from the node in one VM update
+// call, keeping the attach/detach state map in sync.
+func (c *controllerCommon) detachDiskBatch(vmset VMSet, nodeName types.NodeName, diskMap map[string]string) error {
+	for uri := range diskMap {
+		c.diskAttachDetachMap.Store(uri, "detaching")
+	}
+	defer func() {
+		for uri := range diskMap {
+			c.diskAttachDetachMap.Delete(uri)
+		}
+	}()
+	return vmset.DetachDisk(nodeName, diskMap)
+}
+
+// insertDetachDiskRequest queues a detach request of diskURI for the node. A
+// request that is already queued is left in place, so that a retry does not
+// grow the batch.
+func (c *controllerCommon) insertDetachDiskRequest(diskName, diskURI, nodeName string) error {
+	var diskMap map[string]string
+	detachDiskMapKey := nodeName + detachDiskMapKeySuffix
+	c.vmLockMap.LockEntry(detachDiskMapKey)
+	defer c.vmLockMap.UnlockEntry(detachDiskMapKey)
+	v, ok := c.detachDiskMap.Load(nodeName)
+	if ok {
+		if diskMap, ok = v.(map[string]string); !ok {
+			return fmt.Errorf("convert detachDiskMap failure on node(%s)", nodeName)
+		}
+	} else {
+		diskMap = make(map[string]string)
+		c.detachDiskMap.Store(nodeName, diskMap)
+	}
+	if _, ok = diskMap[diskURI]; ok {
+		klog.V(2).Infof("azureDisk - duplicated detach disk(%s) request on node(%s)", diskURI, nodeName)
+	} else {
+		diskMap[diskURI] = diskName
+	}
+	return nil
+}
+
+// cleanDetachDiskRequests drains the detach queue of the node and returns the
+// drained requests. The caller is responsible for detaching every returned
+// disk while it holds the node's vm lock.
+func (c *controllerCommon) cleanDetachDiskRequests(nodeName string) (map[string]string, error) {
+	var diskMap map[string]string
+	detachDiskMapKey := nodeName + detachDiskMapKeySuffix
+	c.vmLockMap.LockEntry(detachDiskMapKey)
+	defer c.vmLockMap.UnlockEntry(detachDiskMapKey)
+	v, ok := c.detachDiskMap.Load(nodeName)
+	if !ok {
+		return diskMap, nil
+	}
+	if diskMap, ok = v.(map[string]string); !ok {
+		return nil, fmt.Errorf("convert detachDiskMap failure on node(%s)", nodeName)
+	}
+	c.detachDiskMap.Store(nodeName, make(map[string]string))
+	return diskMap, nil
+}
+
 // getNodeDataDisks invokes vmSet interfaces to get data disks for the node.
 func (c *controllerCommon) getNodeDataDisks(nodeName types.NodeName, crt azcache.AzureCacheReadType) ([]compute.DataDisk, error) {
 	vmset, err := c.getNodeVMSet(nodeName, crt)
@@ -325,6 +502,46 @@ func (c *controllerCommon) GetNextDiskLun(nodeName types.NodeName) (int32, error
 	return -1, fmt.Errorf("all luns are used")
 }
 
+// SetDiskLun assigns an unused lun to every disk in diskMap and returns the lun
+// assigned to diskURI. Return -1 and an error if there are not enough unused luns.
+func (c *controllerCommon) SetDiskLun(nodeName types.NodeName, diskURI string, diskMap map[string]*AttachDiskOptions) (int32, error) {
+	disks, err := c.getNodeDataDisks(nodeName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		klog.Errorf("error of getting data disks for node %q: %v", nodeName, err)
+		return -1, err
+	}
+
+	used := make([]bool, maxLUN)
+	for _, disk := range disks {
+		if disk.Lun != nil {
+			used[*disk.Lun] = true
+		}
+	}
+
+	lun := int32(-1)
+	count := 0
+	for uri, opt := range diskMap {
+		if opt == nil {
+			return -1, fmt.Errorf("unexpected nil pointer in diskMap(%v)", diskMap)
+		}
+		for k, v := range used {
+			if !v {
+				used[k] = true
+				opt.lun = int32(k)
+				if strings.EqualFold(uri, diskURI) {
+					lun = opt.lun
+				}
+				count++
+				break
+			}
+		}
+	}
+	if lun < 0 || count != len(diskMap) {
+		return -1, fmt.Errorf("could not find enough unused luns for diskURI(%s), %d disks in batch, %d luns assigned", diskURI, len(diskMap), count)
+	}
+	return lun, nil
+}
+
 // DisksAreAttached checks if a list of volumes are attached to the node with the specified NodeName.
 func (c *controllerCommon) DisksAreAttached(diskNames []string, nodeName types.NodeName) (map[string]bool, error) {
 	attached := make(map[string]bool)
@@ -19,9 +19,11 @@ limitations under the License.
 package azure
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
@@ -428,6 +430,323 @@ func TestGetNextDiskLun(t *testing.T) {
 	}
 }
 
+func TestSetDiskLun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		desc            string
+		diskURI         string
+		queuedDiskURIs  []string
+		isDataDisksFull bool
+		expectedLun     int32
+		expectedErr     bool
+	}{
+		{
+			desc:           "the minimal LUN shall be returned and assigned if there's enough room for the batch",
+			diskURI:        "diskuri1",
+			queuedDiskURIs: []string{"diskuri1"},
+			expectedLun:    1,
+			expectedErr:    false,
+		},
+		{
+			desc:           "a LUN shall be assigned to every disk of the batch",
+			diskURI:        "diskuri2",
+			queuedDiskURIs: []string{"diskuri1", "diskuri2"},
+			expectedErr:    false,
+		},
+		{
+			desc:           "LUN -1 and an error shall be returned if diskURI is not in the batch",
+			diskURI:        "diskuri3",
+			queuedDiskURIs: []string{"diskuri1"},
+			expectedLun:    -1,
+			expectedErr:    true,
+		},
+		{
+			desc:            "LUN -1 and an error shall be returned if there's no available LUN",
+			diskURI:         "diskuri1",
+			queuedDiskURIs:  []string{"diskuri1"},
+			isDataDisksFull: true,
+			expectedLun:     -1,
+			expectedErr:     true,
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		common := &controllerCommon{
+			location:              testCloud.Location,
+			storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+			resourceGroup:         testCloud.ResourceGroup,
+			subscriptionID:        testCloud.SubscriptionID,
+			cloud:                 testCloud,
+			vmLockMap:             newLockMap(),
+		}
+		expectedVMs := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, test.isDataDisksFull)
+		mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+		for _, vm := range expectedVMs {
+			mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, *vm.Name, gomock.Any()).Return(vm, nil).AnyTimes()
+		}
+
+		diskMap := make(map[string]*AttachDiskOptions, len(test.queuedDiskURIs))
+		for _, diskURI := range test.queuedDiskURIs {
+			diskMap[diskURI] = &AttachDiskOptions{lun: -1, diskName: diskURI}
+		}
+
+		lun, err := common.SetDiskLun("vm1", test.diskURI, diskMap)
+		if test.expectedLun != 0 {
+			assert.Equal(t, test.expectedLun, lun, "TestCase[%d]: %s", i, test.desc)
+		}
+		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		if !test.expectedErr {
+			// every disk of the batch got a distinct unused LUN
+			usedLuns := map[int32]bool{0: true}
+			for _, opt := range diskMap {
+				assert.False(t, usedLuns[opt.lun], "TestCase[%d]: %s, lun %d assigned twice", i, test.desc, opt.lun)
+				usedLuns[opt.lun] = true
+			}
+		}
+	}
+}
+
+func TestAttachDiskRequestFuncs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		desc                 string
+		diskURI              string
+		nodeName             string
+		diskName             string
+		diskNum              int
+		duplicateDiskRequest bool
+		expectedErr          bool
+	}{
+		{
+			desc:        "one attach disk request in queue",
+			diskURI:     "diskURI",
+			nodeName:    "nodeName",
+			diskName:    "diskName",
+			diskNum:     1,
+			expectedErr: false,
+		},
+		{
+			desc:        "multiple attach disk requests in queue",
+			diskURI:     "diskURI",
+			nodeName:    "nodeName",
+			diskName:    "diskName",
+			diskNum:     10,
+			expectedErr: false,
+		},
+		{
+			desc:                 "duplicated attach disk requests in queue",
+			diskURI:              "diskURI",
+			nodeName:             "nodeName",
+			diskName:             "diskName",
+			diskNum:              10,
+			duplicateDiskRequest: true,
+			expectedErr:          false,
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		common := &controllerCommon{
+			location:              testCloud.Location,
+			storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+			resourceGroup:         testCloud.ResourceGroup,
+			subscriptionID:        testCloud.SubscriptionID,
+			cloud:                 testCloud,
+			vmLockMap:             newLockMap(),
+		}
+		for j := 1; j <= test.diskNum; j++ {
+			diskURI := fmt.Sprintf("%s%d", test.diskURI, j)
+			diskName := fmt.Sprintf("%s%d", test.diskName, j)
+			options := &AttachDiskOptions{diskName: diskName}
+			err := common.insertAttachDiskRequest(diskURI, test.nodeName, options)
+			assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			if test.duplicateDiskRequest {
+				err := common.insertAttachDiskRequest(diskURI, test.nodeName, options)
+				assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			}
+		}
+
+		diskMap, err := common.cleanAttachDiskRequests(test.nodeName)
+		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.diskNum, len(diskMap), "TestCase[%d]: %s", i, test.desc)
+		for diskURI, opt := range diskMap {
+			assert.Equal(t, true, strings.Contains(diskURI, test.diskURI), "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, true, strings.Contains(opt.diskName, test.diskName), "TestCase[%d]: %s", i, test.desc)
+		}
+
+		diskMap, err = common.cleanAttachDiskRequests(test.nodeName)
+		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, 0, len(diskMap), "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestDetachDiskRequestFuncs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		desc                 string
+		diskURI              string
+		nodeName             string
+		diskName             string
+		diskNum              int
+		duplicateDiskRequest bool
+		expectedErr          bool
+	}{
+		{
+			desc:        "one detach disk request in queue",
+			diskURI:     "diskURI",
+			nodeName:    "nodeName",
+			diskName:    "diskName",
+			diskNum:     1,
+			expectedErr: false,
+		},
+		{
+			desc:        "multiple detach disk requests in queue",
+			diskURI:     "diskURI",
+			nodeName:    "nodeName",
+			diskName:    "diskName",
+			diskNum:     10,
+			expectedErr: false,
+		},
+		{
+			desc:                 "duplicated detach disk requests in queue",
+			diskURI:              "diskURI",
+			nodeName:             "nodeName",
+			diskName:             "diskName",
+			diskNum:              10,
+			duplicateDiskRequest: true,
+			expectedErr:          false,
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		common := &controllerCommon{
+			location:              testCloud.Location,
+			storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+			resourceGroup:         testCloud.ResourceGroup,
+			subscriptionID:        testCloud.SubscriptionID,
+			cloud:                 testCloud,
+			vmLockMap:             newLockMap(),
+		}
+		for j := 1; j <= test.diskNum; j++ {
+			diskURI := fmt.Sprintf("%s%d", test.diskURI, j)
+			diskName := fmt.Sprintf("%s%d", test.diskName, j)
+			err := common.insertDetachDiskRequest(diskName, diskURI, test.nodeName)
+			assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			if test.duplicateDiskRequest {
+				err := common.insertDetachDiskRequest(diskName, diskURI, test.nodeName)
+				assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			}
+		}
+
+		diskMap, err := common.cleanDetachDiskRequests(test.nodeName)
+		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.diskNum, len(diskMap), "TestCase[%d]: %s", i, test.desc)
+		for diskURI, diskName := range diskMap {
+			assert.Equal(t, true, strings.Contains(diskURI, test.diskURI), "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, true, strings.Contains(diskName, test.diskName), "TestCase[%d]: %s", i, test.desc)
+		}
+
+		diskMap, err = common.cleanDetachDiskRequests(test.nodeName)
+		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, 0, len(diskMap), "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCommonAttachDiskWithBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		vmLockMap:             newLockMap(),
+	}
+	diskURI1 := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk-batch-1",
+		testCloud.SubscriptionID, testCloud.ResourceGroup)
+	diskURI2 := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk-batch-2",
+		testCloud.SubscriptionID, testCloud.ResourceGroup)
+
+	expectedVMs := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, false)
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	for _, vm := range expectedVMs {
+		mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, *vm.Name, gomock.Any()).Return(vm, nil).AnyTimes()
+	}
+	var attachedDisks []compute.DataDisk
+	mockVMsClient.EXPECT().Update(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroupName, vmName string, parameters compute.VirtualMachineUpdate, source string) *retry.Error {
+			attachedDisks = *parameters.VirtualMachineProperties.StorageProfile.DataDisks
+			return nil
+		}).Times(1)
+
+	// queue an attach request for the second disk, as a concurrent caller would
+	options2 := &AttachDiskOptions{lun: -1, diskName: "disk-batch-2", cachingMode: compute.CachingTypesReadOnly}
+	err := common.insertAttachDiskRequest(strings.ToLower(diskURI2), "vm1", options2)
+	assert.NoError(t, err)
+
+	lun, err := common.AttachDisk(false, "disk-batch-1", diskURI1, "vm1", compute.CachingTypesReadOnly)
+	assert.NoError(t, err)
+
+	// both disks must be added to the existing data disk in a single VM update
+	assert.Equal(t, 3, len(attachedDisks))
+	assert.NotEqual(t, int32(-1), lun)
+	assert.NotEqual(t, int32(-1), options2.lun)
+	assert.NotEqual(t, lun, options2.lun)
+}
+
+func TestCommonDetachDiskWithBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		vmLockMap:             newLockMap(),
+	}
+	diskURI1 := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk1",
+		testCloud.SubscriptionID, testCloud.ResourceGroup)
+	diskURI2 := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk2",
+		testCloud.SubscriptionID, testCloud.ResourceGroup)
+
+	expectedVMs := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, false)
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	for _, vm := range expectedVMs {
+		mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, *vm.Name, gomock.Any()).Return(vm, nil).AnyTimes()
+	}
+	var updatedDisks []compute.DataDisk
+	mockVMsClient.EXPECT().Update(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroupName, vmName string, parameters compute.VirtualMachineUpdate, source string) *retry.Error {
+			updatedDisks = *parameters.VirtualMachineProperties.StorageProfile.DataDisks
+			return nil
+		}).Times(1)
+
+	// queue a detach request for a disk that is not attached, as a concurrent caller would
+	err := common.insertDetachDiskRequest("disk2", strings.ToLower(diskURI2), "vm1")
+	assert.NoError(t, err)
+
+	err = common.DetachDisk("disk1", diskURI1, "vm1")
+	assert.NoError(t, err)
+
+	// the attached disk of the batch is marked for detach in a single VM update
+	assert.Equal(t, 1, len(updatedDisks))
+	assert.Equal(t, true, *updatedDisks[0].ToBeDetached)
+}
+
 func TestDisksAreAttached(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
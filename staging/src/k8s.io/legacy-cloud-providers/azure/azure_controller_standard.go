@@ -30,9 +30,9 @@ import (
 	azcache "k8s.io/legacy-cloud-providers/azure/cache"
 )
 
-// AttachDisk attaches a vhd to vm
-// the vhd must exist, can be identified by diskName, diskURI, and lun.
-func (as *availabilitySet) AttachDisk(isManagedDisk bool, diskName, diskURI string, nodeName types.NodeName, lun int32, cachingMode compute.CachingTypes, diskEncryptionSetID string, writeAcceleratorEnabled bool) error {
+// AttachDisk attaches the disks in diskMap to the node in one VM update call.
+// The disks must exist and diskMap is keyed by the lowercased disk URI.
+func (as *availabilitySet) AttachDisk(nodeName types.NodeName, diskMap map[string]*AttachDiskOptions) error {
 	vm, err := as.getVirtualMachine(nodeName, azcache.CacheReadTypeDefault)
 	if err != nil {
 		return err
@@ -47,40 +47,45 @@ func (as *availabilitySet) AttachDisk(isManagedDisk bool, diskName, diskURI stri
 	disks := make([]compute.DataDisk, len(*vm.StorageProfile.DataDisks))
 	copy(disks, *vm.StorageProfile.DataDisks)
 
-	if isManagedDisk {
-		managedDisk := &compute.ManagedDiskParameters{ID: &diskURI}
-		if diskEncryptionSetID == "" {
-			if vm.StorageProfile.OsDisk != nil &&
-				vm.StorageProfile.OsDisk.ManagedDisk != nil &&
-				vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet != nil &&
-				vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet.ID != nil {
-				// set diskEncryptionSet as value of os disk by default
-				diskEncryptionSetID = *vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet.ID
+	for k, v := range diskMap {
+		diskURI := k
+		opt := v
+		if opt.isManagedDisk {
+			managedDisk := &compute.ManagedDiskParameters{ID: &diskURI}
+			diskEncryptionSetID := opt.diskEncryptionSetID
+			if diskEncryptionSetID == "" {
+				if vm.StorageProfile.OsDisk != nil &&
+					vm.StorageProfile.OsDisk.ManagedDisk != nil &&
+					vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet != nil &&
+					vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet.ID != nil {
+					// set diskEncryptionSet as value of os disk by default
+					diskEncryptionSetID = *vm.StorageProfile.OsDisk.ManagedDisk.DiskEncryptionSet.ID
+				}
 			}
+			if diskEncryptionSetID != "" {
+				managedDisk.DiskEncryptionSet = &compute.DiskEncryptionSetParameters{ID: &diskEncryptionSetID}
+			}
+			disks = append(disks,
+				compute.DataDisk{
+					Name:                    &opt.diskName,
+					Lun:                     &opt.lun,
+					Caching:                 opt.cachingMode,
+					CreateOption:            "attach",
+					ManagedDisk:             managedDisk,
+					WriteAcceleratorEnabled: to.BoolPtr(opt.writeAcceleratorEnabled),
+				})
+		} else {
+			disks = append(disks,
+				compute.DataDisk{
+					Name: &opt.diskName,
+					Vhd: &compute.VirtualHardDisk{
+						URI: &diskURI,
+					},
+					Lun:          &opt.lun,
+					Caching:      opt.cachingMode,
+					CreateOption: "attach",
+				})
 		}
-		if diskEncryptionSetID != "" {
-			managedDisk.DiskEncryptionSet = &compute.DiskEncryptionSetParameters{ID: &diskEncryptionSetID}
-		}
-		disks = append(disks,
-			compute.DataDisk{
-				Name:                    &diskName,
-				Lun:                     &lun,
-				Caching:                 cachingMode,
-				CreateOption:            "attach",
-				ManagedDisk:             managedDisk,
-				WriteAcceleratorEnabled: to.BoolPtr(writeAcceleratorEnabled),
-			})
-	} else {
-		disks = append(disks,
-			compute.DataDisk{
-				Name: &diskName,
-				Vhd: &compute.VirtualHardDisk{
-					URI: &diskURI,
-				},
-				Lun:          &lun,
-				Caching:      cachingMode,
-				CreateOption: "attach",
-			})
 	}
 
 	newVM := compute.VirtualMachineUpdate{
@@ -90,7 +95,7 @@ func (as *availabilitySet) AttachDisk(isManagedDisk bool, diskName, diskURI stri
 			},
 		},
 	}
-	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - attach disk(%s, %s) with DiskEncryptionSetID(%s)", nodeResourceGroup, vmName, diskName, diskURI, diskEncryptionSetID)
+	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - attach disk list(%v)", nodeResourceGroup, vmName, diskMap)
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
@@ -99,29 +104,29 @@ func (as *availabilitySet) AttachDisk(isManagedDisk bool, diskName, diskURI stri
 
 	rerr := as.VirtualMachinesClient.Update(ctx, nodeResourceGroup, vmName, newVM, "attach_disk")
 	if rerr != nil {
-		klog.Errorf("azureDisk - attach disk(%s, %s) on rg(%s) vm(%s) failed, err: %v", diskName, diskURI, nodeResourceGroup, vmName, rerr)
+		klog.Errorf("azureDisk - attach disk list(%v) on rg(%s) vm(%s) failed, err: %v", diskMap, nodeResourceGroup, vmName, rerr)
 		if rerr.HTTPStatusCode == http.StatusNotFound {
-			klog.Errorf("azureDisk - begin to filterNonExistingDisks(%s, %s) on rg(%s) vm(%s)", diskName, diskURI, nodeResourceGroup, vmName)
+			klog.Errorf("azureDisk - begin to filterNonExistingDisks(%v) on rg(%s) vm(%s)", diskMap, nodeResourceGroup, vmName)
 			disks := as.filterNonExistingDisks(ctx, *newVM.VirtualMachineProperties.StorageProfile.DataDisks)
 			newVM.VirtualMachineProperties.StorageProfile.DataDisks = &disks
 			rerr = as.VirtualMachinesClient.Update(ctx, nodeResourceGroup, vmName, newVM, "attach_disk")
 		}
 	}
 
-	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - attach disk(%s, %s) returned with %v", nodeResourceGroup, vmName, diskName, diskURI, rerr)
+	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - attach disk list(%v) returned with %v", nodeResourceGroup, vmName, diskMap, rerr)
 	if rerr != nil {
 		return rerr.Error()
 	}
 	return nil
 }
 
-// DetachDisk detaches a disk from host
-// the vhd can be identified by diskName or diskURI
-func (as *availabilitySet) DetachDisk(diskName, diskURI string, nodeName types.NodeName) error {
+// DetachDisk detaches the disks in diskMap from the node in one VM update call.
+// diskMap maps the lowercased disk URI to the disk name.
+func (as *availabilitySet) DetachDisk(nodeName types.NodeName, diskMap map[string]string) error {
 	vm, err := as.getVirtualMachine(nodeName, azcache.CacheReadTypeDefault)
 	if err != nil {
 		// if host doesn't exist, no need to detach
-		klog.Warningf("azureDisk - cannot find node %s, skip detaching disk(%s, %s)", nodeName, diskName, diskURI)
+		klog.Warningf("azureDisk - cannot find node %s, skip detaching disk list(%v)", nodeName, diskMap)
 		return nil
 	}
 
@@ -136,24 +141,32 @@ func (as *availabilitySet) DetachDisk(diskName, diskURI string, nodeName types.N
 
 	bFoundDisk := false
 	for i, disk := range disks {
-		if disk.Lun != nil && (disk.Name != nil && diskName != "" && strings.EqualFold(*disk.Name, diskName)) ||
-			(disk.Vhd != nil && disk.Vhd.URI != nil && diskURI != "" && strings.EqualFold(*disk.Vhd.URI, diskURI)) ||
-			(disk.ManagedDisk != nil && diskURI != "" && strings.EqualFold(*disk.ManagedDisk.ID, diskURI)) {
-			// found the disk
-			klog.V(2).Infof("azureDisk - detach disk: name %q uri %q", diskName, diskURI)
-			if strings.EqualFold(as.cloud.Environment.Name, AzureStackCloudName) {
-				disks = append(disks[:i], disks[i+1:]...)
-			} else {
+		for diskURI, diskName := range diskMap {
+			if disk.Lun != nil && (disk.Name != nil && diskName != "" && strings.EqualFold(*disk.Name, diskName)) ||
+				(disk.Vhd != nil && disk.Vhd.URI != nil && diskURI != "" && strings.EqualFold(*disk.Vhd.URI, diskURI)) ||
+				(disk.ManagedDisk != nil && diskURI != "" && strings.EqualFold(*disk.ManagedDisk.ID, diskURI)) {
+				// found the disk
+				klog.V(2).Infof("azureDisk - detach disk: name %q uri %q", diskName, diskURI)
 				disks[i].ToBeDetached = to.BoolPtr(true)
+				bFoundDisk = true
 			}
-			bFoundDisk = true
-			break
 		}
 	}
 
 	if !bFoundDisk {
 		// only log here, next action is to update VM status with original meta data
-		klog.Errorf("detach azure disk: disk %s not found, diskURI: %s", diskName, diskURI)
+		klog.Errorf("detach azure disk on node(%s): disk list(%v) not found", nodeName, diskMap)
+	}
+
+	if strings.EqualFold(as.cloud.Environment.Name, AzureStackCloudName) {
+		// Azure Stack does not support ToBeDetached flag, remove the disks from the data disk list instead
+		newDisks := []compute.DataDisk{}
+		for _, disk := range disks {
+			if !to.Bool(disk.ToBeDetached) {
+				newDisks = append(newDisks, disk)
+			}
+		}
+		disks = newDisks
 	}
 
 	newVM := compute.VirtualMachineUpdate{
@@ -163,7 +176,7 @@ func (as *availabilitySet) DetachDisk(diskName, diskURI string, nodeName types.N
 			},
 		},
 	}
-	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - detach disk(%s, %s)", nodeResourceGroup, vmName, diskName, diskURI)
+	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - detach disk list(%v)", nodeResourceGroup, vmName, diskMap)
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
@@ -172,16 +185,16 @@ func (as *availabilitySet) DetachDisk(diskName, diskURI string, nodeName types.N
 
 	rerr := as.VirtualMachinesClient.Update(ctx, nodeResourceGroup, vmName, newVM, "detach_disk")
 	if rerr != nil {
-		klog.Errorf("azureDisk - detach disk(%s, %s) on rg(%s) vm(%s) failed, err: %v", diskName, diskURI, nodeResourceGroup, vmName, rerr)
+		klog.Errorf("azureDisk - detach disk list(%v) on rg(%s) vm(%s) failed, err: %v", diskMap, nodeResourceGroup, vmName, rerr)
 		if rerr.HTTPStatusCode == http.StatusNotFound {
-			klog.Errorf("azureDisk - begin to filterNonExistingDisks(%s, %s) on rg(%s) vm(%s)", diskName, diskURI, nodeResourceGroup, vmName)
+			klog.Errorf("azureDisk - begin to filterNonExistingDisks(%v) on rg(%s) vm(%s)", diskMap, nodeResourceGroup, vmName)
 			disks := as.filterNonExistingDisks(ctx, *vm.StorageProfile.DataDisks)
 			newVM.VirtualMachineProperties.StorageProfile.DataDisks = &disks
 			rerr = as.VirtualMachinesClient.Update(ctx, nodeResourceGroup, vmName, newVM, "detach_disk")
 		}
 	}
 
-	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - detach disk(%s, %s) returned with %v", nodeResourceGroup, vmName, diskName, diskURI, rerr)
+	klog.V(2).Infof("azureDisk - update(%s): vm(%s) - detach disk list(%v) returned with %v", nodeResourceGroup, vmName, diskMap, rerr)
 	if rerr != nil {
 		return rerr.Error()
 	}
@@ -0,0 +1,551 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+
+	"k8s.io/legacy-cloud-providers/azure/clients/interfaceclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/loadbalancerclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/publicipclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/routeclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/routetableclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/securitygroupclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/subnetclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/vmclient"
+	"k8s.io/legacy-cloud-providers/azure/retry"
+)
+
+// Guard against the fake clients drifting from the client interfaces.
+var (
+	_ loadbalancerclient.Interface  = &fakeLoadBalancersClient{}
+	_ publicipclient.Interface      = &fakePublicIPsClient{}
+	_ securitygroupclient.Interface = &fakeSecurityGroupsClient{}
+	_ subnetclient.Interface        = &fakeSubnetsClient{}
+	_ routetableclient.Interface    = &fakeRouteTablesClient{}
+	_ routeclient.Interface         = &fakeRoutesClient{}
+	_ vmclient.Interface            = &fakeVirtualMachinesClient{}
+	_ interfaceclient.Interface     = &fakeInterfacesClient{}
+)
+
+// Fake cloud mode backs all Azure clients used by the service and route
+// controllers with one in-memory ARM resource store, so that their
+// reconciliation logic can be exercised end-to-end in CI jobs without an
+// Azure subscription. It is selected with enableFakeCloud in the cloud
+// config and must never be enabled on a real cluster: resources only exist
+// inside the process.
+
+// fakeARMStore is the in-memory resource store shared by all fake clients of
+// one cloud. Resources are keyed by lowercased "resourceGroup/name" because
+// Azure resource identifiers are case insensitive.
+type fakeARMStore struct {
+	lock           sync.RWMutex
+	subscriptionID string
+
+	loadBalancers   map[string]network.LoadBalancer
+	publicIPs       map[string]network.PublicIPAddress
+	securityGroups  map[string]network.SecurityGroup
+	subnets         map[string]network.Subnet
+	routeTables     map[string]network.RouteTable
+	virtualMachines map[string]compute.VirtualMachine
+	interfaces      map[string]network.Interface
+}
+
+func newFakeARMStore(subscriptionID string) *fakeARMStore {
+	return &fakeARMStore{
+		subscriptionID:  subscriptionID,
+		loadBalancers:   map[string]network.LoadBalancer{},
+		publicIPs:       map[string]network.PublicIPAddress{},
+		securityGroups:  map[string]network.SecurityGroup{},
+		subnets:         map[string]network.Subnet{},
+		routeTables:     map[string]network.RouteTable{},
+		virtualMachines: map[string]compute.VirtualMachine{},
+		interfaces:      map[string]network.Interface{},
+	}
+}
+
+// storeKey normalizes the resource key parts into the map key.
+func storeKey(parts ...string) string {
+	return strings.ToLower(strings.Join(parts, "/"))
+}
+
+// resourceID synthesizes the ARM ID of a stored resource.
+func (s *fakeARMStore) resourceID(resourceGroupName, provider, kind, name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s/%s", s.subscriptionID, resourceGroupName, provider, kind, name)
+}
+
+// fakeNotFound is the error ARM answers for a missing resource; the HTTP
+// status code is what checkResourceExistsFromError keys off.
+func fakeNotFound(kind, name string) *retry.Error {
+	return &retry.Error{
+		HTTPStatusCode: http.StatusNotFound,
+		RawError:       fmt.Errorf("%s %q not found in the fake ARM store", kind, name),
+	}
+}
+
+// configureFakeClients backs the clients needed by the service and route
+// controllers with one shared in-memory store. Clients of resources those
+// controllers do not manage (disks, storage, scale sets) stay nil.
+func (az *Cloud) configureFakeClients() {
+	store := newFakeARMStore(az.Config.SubscriptionID)
+	az.armStore = store
+	az.LoadBalancerClient = &fakeLoadBalancersClient{store: store}
+	az.PublicIPAddressesClient = &fakePublicIPsClient{store: store}
+	az.SecurityGroupsClient = &fakeSecurityGroupsClient{store: store}
+	az.SubnetsClient = &fakeSubnetsClient{store: store}
+	az.RouteTablesClient = &fakeRouteTablesClient{store: store}
+	az.RoutesClient = &fakeRoutesClient{store: store}
+	az.VirtualMachinesClient = &fakeVirtualMachinesClient{store: store}
+	az.InterfacesClient = &fakeInterfacesClient{store: store}
+}
+
+// SeedFakeVirtualMachine registers a virtual machine in the fake ARM store,
+// so that a test node of the same name resolves like a real Azure node. It
+// may only be called in fake cloud mode.
+func (az *Cloud) SeedFakeVirtualMachine(resourceGroupName string, vm compute.VirtualMachine) error {
+	if az.armStore == nil {
+		return fmt.Errorf("seeding resources requires enableFakeCloud")
+	}
+	if vm.Name == nil {
+		return fmt.Errorf("seeding a virtual machine requires its name to be set")
+	}
+	az.armStore.lock.Lock()
+	defer az.armStore.lock.Unlock()
+	if vm.ID == nil {
+		id := az.armStore.resourceID(resourceGroupName, "Microsoft.Compute", "virtualMachines", *vm.Name)
+		vm.ID = &id
+	}
+	az.armStore.virtualMachines[storeKey(resourceGroupName, *vm.Name)] = vm
+	return nil
+}
+
+// SeedFakeNetworkInterface registers a network interface in the fake ARM
+// store. It may only be called in fake cloud mode.
+func (az *Cloud) SeedFakeNetworkInterface(resourceGroupName string, nic network.Interface) error {
+	if az.armStore == nil {
+		return fmt.Errorf("seeding resources requires enableFakeCloud")
+	}
+	if nic.Name == nil {
+		return fmt.Errorf("seeding a network interface requires its name to be set")
+	}
+	az.armStore.lock.Lock()
+	defer az.armStore.lock.Unlock()
+	if nic.ID == nil {
+		id := az.armStore.resourceID(resourceGroupName, "Microsoft.Network", "networkInterfaces", *nic.Name)
+		nic.ID = &id
+	}
+	az.armStore.interfaces[storeKey(resourceGroupName, *nic.Name)] = nic
+	return nil
+}
+
+// SeedFakeSubnet registers a subnet in the fake ARM store, as needed for
+// internal load balancer scenarios. It may only be called in fake cloud
+// mode.
+func (az *Cloud) SeedFakeSubnet(resourceGroupName, virtualNetworkName string, subnet network.Subnet) error {
+	if az.armStore == nil {
+		return fmt.Errorf("seeding resources requires enableFakeCloud")
+	}
+	if subnet.Name == nil {
+		return fmt.Errorf("seeding a subnet requires its name to be set")
+	}
+	az.armStore.lock.Lock()
+	defer az.armStore.lock.Unlock()
+	az.armStore.subnets[storeKey(resourceGroupName, virtualNetworkName, *subnet.Name)] = subnet
+	return nil
+}
+
+type fakeLoadBalancersClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeLoadBalancersClient) Get(ctx context.Context, resourceGroupName string, loadBalancerName string, expand string) (network.LoadBalancer, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if lb, found := c.store.loadBalancers[storeKey(resourceGroupName, loadBalancerName)]; found {
+		return lb, nil
+	}
+	return network.LoadBalancer{}, fakeNotFound("load balancer", loadBalancerName)
+}
+
+func (c *fakeLoadBalancersClient) List(ctx context.Context, resourceGroupName string) ([]network.LoadBalancer, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	result := make([]network.LoadBalancer, 0)
+	for key, lb := range c.store.loadBalancers {
+		if strings.HasPrefix(key, storeKey(resourceGroupName)+"/") {
+			result = append(result, lb)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeLoadBalancersClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, loadBalancerName string, parameters network.LoadBalancer, etag string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &loadBalancerName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Network", "loadBalancers", loadBalancerName)
+		parameters.ID = &id
+	}
+	c.store.loadBalancers[storeKey(resourceGroupName, loadBalancerName)] = parameters
+	return nil
+}
+
+func (c *fakeLoadBalancersClient) Delete(ctx context.Context, resourceGroupName string, loadBalancerName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.loadBalancers, storeKey(resourceGroupName, loadBalancerName))
+	return nil
+}
+
+type fakePublicIPsClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakePublicIPsClient) Get(ctx context.Context, resourceGroupName string, publicIPAddressName string, expand string) (network.PublicIPAddress, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if pip, found := c.store.publicIPs[storeKey(resourceGroupName, publicIPAddressName)]; found {
+		return pip, nil
+	}
+	return network.PublicIPAddress{}, fakeNotFound("public IP address", publicIPAddressName)
+}
+
+func (c *fakePublicIPsClient) GetVirtualMachineScaleSetPublicIPAddress(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, virtualmachineIndex string, networkInterfaceName string, IPConfigurationName string, publicIPAddressName string, expand string) (network.PublicIPAddress, *retry.Error) {
+	return network.PublicIPAddress{}, fakeNotFound("scale set public IP address", publicIPAddressName)
+}
+
+func (c *fakePublicIPsClient) List(ctx context.Context, resourceGroupName string) ([]network.PublicIPAddress, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	result := make([]network.PublicIPAddress, 0)
+	for key, pip := range c.store.publicIPs {
+		if strings.HasPrefix(key, storeKey(resourceGroupName)+"/") {
+			result = append(result, pip)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakePublicIPsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, publicIPAddressName string, parameters network.PublicIPAddress) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &publicIPAddressName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Network", "publicIPAddresses", publicIPAddressName)
+		parameters.ID = &id
+	}
+	// A real public IP gets an address allocated by Azure; allocate a
+	// deterministic documentation-range address so that consumers reading
+	// the IP back see one.
+	if parameters.PublicIPAddressPropertiesFormat != nil && parameters.PublicIPAddressPropertiesFormat.IPAddress == nil {
+		address := fmt.Sprintf("192.0.2.%d", len(c.store.publicIPs)%254+1)
+		parameters.PublicIPAddressPropertiesFormat.IPAddress = &address
+	}
+	c.store.publicIPs[storeKey(resourceGroupName, publicIPAddressName)] = parameters
+	return nil
+}
+
+func (c *fakePublicIPsClient) Delete(ctx context.Context, resourceGroupName string, publicIPAddressName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.publicIPs, storeKey(resourceGroupName, publicIPAddressName))
+	return nil
+}
+
+type fakeSecurityGroupsClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeSecurityGroupsClient) Get(ctx context.Context, resourceGroupName string, networkSecurityGroupName string, expand string) (network.SecurityGroup, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if sg, found := c.store.securityGroups[storeKey(resourceGroupName, networkSecurityGroupName)]; found {
+		return sg, nil
+	}
+	return network.SecurityGroup{}, fakeNotFound("security group", networkSecurityGroupName)
+}
+
+func (c *fakeSecurityGroupsClient) List(ctx context.Context, resourceGroupName string) ([]network.SecurityGroup, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	result := make([]network.SecurityGroup, 0)
+	for key, sg := range c.store.securityGroups {
+		if strings.HasPrefix(key, storeKey(resourceGroupName)+"/") {
+			result = append(result, sg)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeSecurityGroupsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, networkSecurityGroupName string, parameters network.SecurityGroup, etag string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &networkSecurityGroupName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Network", "networkSecurityGroups", networkSecurityGroupName)
+		parameters.ID = &id
+	}
+	c.store.securityGroups[storeKey(resourceGroupName, networkSecurityGroupName)] = parameters
+	return nil
+}
+
+func (c *fakeSecurityGroupsClient) Delete(ctx context.Context, resourceGroupName string, networkSecurityGroupName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.securityGroups, storeKey(resourceGroupName, networkSecurityGroupName))
+	return nil
+}
+
+type fakeSubnetsClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeSubnetsClient) Get(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string, expand string) (network.Subnet, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if subnet, found := c.store.subnets[storeKey(resourceGroupName, virtualNetworkName, subnetName)]; found {
+		return subnet, nil
+	}
+	return network.Subnet{}, fakeNotFound("subnet", subnetName)
+}
+
+func (c *fakeSubnetsClient) List(ctx context.Context, resourceGroupName string, virtualNetworkName string) ([]network.Subnet, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	result := make([]network.Subnet, 0)
+	for key, subnet := range c.store.subnets {
+		if strings.HasPrefix(key, storeKey(resourceGroupName, virtualNetworkName)+"/") {
+			result = append(result, subnet)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeSubnetsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string, subnetParameters network.Subnet) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if subnetParameters.Name == nil {
+		subnetParameters.Name = &subnetName
+	}
+	c.store.subnets[storeKey(resourceGroupName, virtualNetworkName, subnetName)] = subnetParameters
+	return nil
+}
+
+func (c *fakeSubnetsClient) Delete(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.subnets, storeKey(resourceGroupName, virtualNetworkName, subnetName))
+	return nil
+}
+
+type fakeRouteTablesClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeRouteTablesClient) Get(ctx context.Context, resourceGroupName string, routeTableName string, expand string) (network.RouteTable, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if routeTable, found := c.store.routeTables[storeKey(resourceGroupName, routeTableName)]; found {
+		return routeTable, nil
+	}
+	return network.RouteTable{}, fakeNotFound("route table", routeTableName)
+}
+
+func (c *fakeRouteTablesClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, routeTableName string, parameters network.RouteTable, etag string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &routeTableName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Network", "routeTables", routeTableName)
+		parameters.ID = &id
+	}
+	c.store.routeTables[storeKey(resourceGroupName, routeTableName)] = parameters
+	return nil
+}
+
+type fakeRoutesClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeRoutesClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, routeTableName string, routeName string, routeParameters network.Route, etag string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	routeTable, found := c.store.routeTables[storeKey(resourceGroupName, routeTableName)]
+	if !found {
+		return fakeNotFound("route table", routeTableName)
+	}
+	if routeParameters.Name == nil {
+		routeParameters.Name = &routeName
+	}
+	if routeTable.RouteTablePropertiesFormat == nil {
+		routeTable.RouteTablePropertiesFormat = &network.RouteTablePropertiesFormat{}
+	}
+	routes := make([]network.Route, 0)
+	if routeTable.RouteTablePropertiesFormat.Routes != nil {
+		for _, route := range *routeTable.RouteTablePropertiesFormat.Routes {
+			if route.Name == nil || !strings.EqualFold(*route.Name, routeName) {
+				routes = append(routes, route)
+			}
+		}
+	}
+	routes = append(routes, routeParameters)
+	routeTable.RouteTablePropertiesFormat.Routes = &routes
+	c.store.routeTables[storeKey(resourceGroupName, routeTableName)] = routeTable
+	return nil
+}
+
+func (c *fakeRoutesClient) Delete(ctx context.Context, resourceGroupName string, routeTableName string, routeName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	routeTable, found := c.store.routeTables[storeKey(resourceGroupName, routeTableName)]
+	if !found || routeTable.RouteTablePropertiesFormat == nil || routeTable.RouteTablePropertiesFormat.Routes == nil {
+		return nil
+	}
+	routes := make([]network.Route, 0)
+	for _, route := range *routeTable.RouteTablePropertiesFormat.Routes {
+		if route.Name == nil || !strings.EqualFold(*route.Name, routeName) {
+			routes = append(routes, route)
+		}
+	}
+	routeTable.RouteTablePropertiesFormat.Routes = &routes
+	c.store.routeTables[storeKey(resourceGroupName, routeTableName)] = routeTable
+	return nil
+}
+
+type fakeVirtualMachinesClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeVirtualMachinesClient) Get(ctx context.Context, resourceGroupName string, VMName string, expand compute.InstanceViewTypes) (compute.VirtualMachine, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if vm, found := c.store.virtualMachines[storeKey(resourceGroupName, VMName)]; found {
+		return vm, nil
+	}
+	return compute.VirtualMachine{}, fakeNotFound("virtual machine", VMName)
+}
+
+func (c *fakeVirtualMachinesClient) List(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachine, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	result := make([]compute.VirtualMachine, 0)
+	for key, vm := range c.store.virtualMachines {
+		if strings.HasPrefix(key, storeKey(resourceGroupName)+"/") {
+			result = append(result, vm)
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeVirtualMachinesClient) ListVmssFlexVMsWithoutInstanceView(ctx context.Context, vmssFlexID string) ([]compute.VirtualMachine, *retry.Error) {
+	return []compute.VirtualMachine{}, nil
+}
+
+func (c *fakeVirtualMachinesClient) ListVmssFlexVMsWithOnlyInstanceView(ctx context.Context, vmssFlexID string) ([]compute.VirtualMachine, *retry.Error) {
+	return []compute.VirtualMachine{}, nil
+}
+
+func (c *fakeVirtualMachinesClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachine, source string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &VMName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Compute", "virtualMachines", VMName)
+		parameters.ID = &id
+	}
+	c.store.virtualMachines[storeKey(resourceGroupName, VMName)] = parameters
+	return nil
+}
+
+func (c *fakeVirtualMachinesClient) Update(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachineUpdate, source string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	vm, found := c.store.virtualMachines[storeKey(resourceGroupName, VMName)]
+	if !found {
+		return fakeNotFound("virtual machine", VMName)
+	}
+	if parameters.Tags != nil {
+		vm.Tags = parameters.Tags
+	}
+	c.store.virtualMachines[storeKey(resourceGroupName, VMName)] = vm
+	return nil
+}
+
+func (c *fakeVirtualMachinesClient) Delete(ctx context.Context, resourceGroupName string, VMName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.virtualMachines, storeKey(resourceGroupName, VMName))
+	return nil
+}
+
+type fakeInterfacesClient struct {
+	store *fakeARMStore
+}
+
+func (c *fakeInterfacesClient) Get(ctx context.Context, resourceGroupName string, networkInterfaceName string, expand string) (network.Interface, *retry.Error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+	if nic, found := c.store.interfaces[storeKey(resourceGroupName, networkInterfaceName)]; found {
+		return nic, nil
+	}
+	return network.Interface{}, fakeNotFound("network interface", networkInterfaceName)
+}
+
+func (c *fakeInterfacesClient) GetVirtualMachineScaleSetNetworkInterface(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, virtualmachineIndex string, networkInterfaceName string, expand string) (network.Interface, *retry.Error) {
+	return network.Interface{}, fakeNotFound("scale set network interface", networkInterfaceName)
+}
+
+func (c *fakeInterfacesClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, networkInterfaceName string, parameters network.Interface) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if parameters.Name == nil {
+		parameters.Name = &networkInterfaceName
+	}
+	if parameters.ID == nil {
+		id := c.store.resourceID(resourceGroupName, "Microsoft.Network", "networkInterfaces", networkInterfaceName)
+		parameters.ID = &id
+	}
+	c.store.interfaces[storeKey(resourceGroupName, networkInterfaceName)] = parameters
+	return nil
+}
+
+func (c *fakeInterfacesClient) Delete(ctx context.Context, resourceGroupName string, networkInterfaceName string) *retry.Error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	delete(c.store.interfaces, storeKey(resourceGroupName, networkInterfaceName))
+	return nil
+}
@@ -0,0 +1,129 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeModeCloud initializes a cloud from config as a CI job would, with
+// fake cloud mode enabled and without any credentials.
+func newFakeModeCloud(t *testing.T) *Cloud {
+	config := `{
+		"subscriptionId": "sub",
+		"resourceGroup": "rg",
+		"location": "eastus",
+		"vnetName": "vnet",
+		"subnetName": "subnet",
+		"routeTableName": "rt",
+		"securityGroupName": "nsg",
+		"enableFakeCloud": true
+	}`
+	az, err := NewCloudWithoutFeatureGates(strings.NewReader(config))
+	assert.NoError(t, err)
+	return az
+}
+
+func TestFakeCloudModeInitializesClients(t *testing.T) {
+	az := newFakeModeCloud(t)
+	assert.NotNil(t, az.armStore)
+	assert.NotNil(t, az.LoadBalancerClient)
+	assert.NotNil(t, az.RoutesClient)
+
+	ctx := context.Background()
+
+	_, rerr := az.LoadBalancerClient.Get(ctx, "rg", "lb", "")
+	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
+
+	rerr = az.LoadBalancerClient.CreateOrUpdate(ctx, "rg", "lb", network.LoadBalancer{}, "")
+	assert.Nil(t, rerr)
+
+	lb, rerr := az.LoadBalancerClient.Get(ctx, "RG", "LB", "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, "lb", *lb.Name)
+	assert.Contains(t, *lb.ID, "/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb")
+
+	lbs, rerr := az.LoadBalancerClient.List(ctx, "rg")
+	assert.Nil(t, rerr)
+	assert.Len(t, lbs, 1)
+
+	rerr = az.LoadBalancerClient.Delete(ctx, "rg", "lb")
+	assert.Nil(t, rerr)
+	lbs, rerr = az.LoadBalancerClient.List(ctx, "rg")
+	assert.Nil(t, rerr)
+	assert.Len(t, lbs, 0)
+}
+
+func TestFakeCloudModeRoutes(t *testing.T) {
+	az := newFakeModeCloud(t)
+	ctx := context.Background()
+
+	// Routes cannot be created before their route table exists.
+	rerr := az.RoutesClient.CreateOrUpdate(ctx, "rg", "rt", "route-a", network.Route{}, "")
+	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
+
+	assert.Nil(t, az.RouteTablesClient.CreateOrUpdate(ctx, "rg", "rt", network.RouteTable{}, ""))
+	assert.Nil(t, az.RoutesClient.CreateOrUpdate(ctx, "rg", "rt", "route-a", network.Route{}, ""))
+	assert.Nil(t, az.RoutesClient.CreateOrUpdate(ctx, "rg", "rt", "route-b", network.Route{}, ""))
+	// Updating an existing route must replace it, not duplicate it.
+	assert.Nil(t, az.RoutesClient.CreateOrUpdate(ctx, "rg", "rt", "route-a", network.Route{}, ""))
+
+	routeTable, rerr := az.RouteTablesClient.Get(ctx, "rg", "rt", "")
+	assert.Nil(t, rerr)
+	assert.Len(t, *routeTable.RouteTablePropertiesFormat.Routes, 2)
+
+	assert.Nil(t, az.RoutesClient.Delete(ctx, "rg", "rt", "route-b"))
+	routeTable, rerr = az.RouteTablesClient.Get(ctx, "rg", "rt", "")
+	assert.Nil(t, rerr)
+	assert.Len(t, *routeTable.RouteTablePropertiesFormat.Routes, 1)
+	assert.Equal(t, "route-a", *(*routeTable.RouteTablePropertiesFormat.Routes)[0].Name)
+}
+
+func TestFakeCloudModeSeeding(t *testing.T) {
+	az := newFakeModeCloud(t)
+	ctx := context.Background()
+
+	assert.NoError(t, az.SeedFakeVirtualMachine("rg", compute.VirtualMachine{Name: to.StringPtr("node-0")}))
+	assert.NoError(t, az.SeedFakeNetworkInterface("rg", network.Interface{Name: to.StringPtr("node-0-nic")}))
+
+	vm, rerr := az.VirtualMachinesClient.Get(ctx, "rg", "node-0", "")
+	assert.Nil(t, rerr)
+	assert.Contains(t, *vm.ID, "/virtualMachines/node-0")
+
+	nic, rerr := az.InterfacesClient.Get(ctx, "rg", "node-0-nic", "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, "node-0-nic", *nic.Name)
+
+	// Seeding requires fake cloud mode.
+	realCloud := &Cloud{}
+	assert.Error(t, realCloud.SeedFakeVirtualMachine("rg", compute.VirtualMachine{Name: to.StringPtr("node-0")}))
+
+	// Seeding a resource without a name must fail instead of panicking.
+	assert.Error(t, az.SeedFakeVirtualMachine("rg", compute.VirtualMachine{}))
+	assert.Error(t, az.SeedFakeNetworkInterface("rg", network.Interface{}))
+	assert.Error(t, az.SeedFakeSubnet("rg", "vnet", network.Subnet{}))
+}
@@ -29,6 +29,7 @@ import (
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
 	azcache "k8s.io/legacy-cloud-providers/azure/cache"
+	utilnet "k8s.io/utils/net"
 )
 
 const (
@@ -63,6 +64,27 @@ func (az *Cloud) addressGetter(nodeName types.NodeName) ([]v1.NodeAddress, error
 			Address: publicIP,
 		})
 	}
+
+	if az.ipv6DualStackEnabled {
+		privateIPs, err := az.getPrivateIPsForMachine(nodeName)
+		if err != nil {
+			klog.V(2).Infof("NodeAddresses(%s) abort backoff: %v", nodeName, err)
+			return nil, err
+		}
+
+		// Report the first private IP of the other family as well, so that
+		// dual-stack nodes expose both families without manual patching.
+		for _, privateIP := range privateIPs {
+			if utilnet.IsIPv6String(privateIP) != utilnet.IsIPv6String(ip) {
+				addresses = append(addresses, v1.NodeAddress{
+					Type:    v1.NodeInternalIP,
+					Address: privateIP,
+				})
+				break
+			}
+		}
+	}
+
 	return addresses, nil
 }
 
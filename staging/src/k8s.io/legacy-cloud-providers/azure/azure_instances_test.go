@@ -735,6 +735,37 @@ func TestInstanceExistsByProviderID(t *testing.T) {
 	}
 }
 
+func TestAddressGetterDualStack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cloud := GetTestCloud(ctrl)
+	cloud.ipv6DualStackEnabled = true
+
+	mockVMSet := NewMockVMSet(ctrl)
+	mockVMSet.EXPECT().GetIPByNodeName("vm1").Return("10.240.0.1", "", nil)
+	mockVMSet.EXPECT().GetPrivateIPsByNodeName("vm1").Return([]string{"10.240.0.1", "fd00::5"}, nil)
+	cloud.VMSet = mockVMSet
+
+	expectedNodeAddress := []v1.NodeAddress{
+		{
+			Type:    v1.NodeInternalIP,
+			Address: "10.240.0.1",
+		},
+		{
+			Type:    v1.NodeHostName,
+			Address: "vm1",
+		},
+		{
+			Type:    v1.NodeInternalIP,
+			Address: "fd00::5",
+		},
+	}
+
+	ipAddresses, err := cloud.addressGetter("vm1")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNodeAddress, ipAddresses)
+}
+
 func TestNodeAddressesByProviderID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
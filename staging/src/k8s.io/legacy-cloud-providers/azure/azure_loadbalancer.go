@@ -697,6 +697,15 @@ func (az *Cloud) ensurePublicIPExists(service *v1.Service, pipName string, domai
 		if shouldPIPExisted {
 			return nil, fmt.Errorf("PublicIP from annotation azure-pip-name=%s for service %s doesn't exist", pipName, serviceName)
 		}
+
+		quotaCtx, quotaCancel := getContextWithCancel()
+		err = az.checkNetworkQuota(quotaCtx, networkUsagePublicIPAddresses, 1)
+		quotaCancel()
+		if err != nil {
+			az.Event(service, v1.EventTypeWarning, "PublicIPQuotaExceeded", err.Error())
+			return nil, fmt.Errorf("ensurePublicIPExists for service(%s): cannot create pip(%s): %v", serviceName, pipName, err)
+		}
+
 		pip.Name = to.StringPtr(pipName)
 		pip.Location = to.StringPtr(az.Location)
 		pip.PublicIPAddressPropertiesFormat = &network.PublicIPAddressPropertiesFormat{
@@ -1425,6 +1434,21 @@ func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service,
 		dirtyLb = true
 	}
 
+	// Ensure outbound connectivity: a NAT gateway association or a managed
+	// outbound rule, depending on the cloud configuration.
+	if wantLb {
+		if err := az.reconcileNatGateway(); err != nil {
+			return nil, err
+		}
+	}
+	outboundChanged, err := az.reconcileOutboundRules(lb, service, wantLb, lbBackendPoolID)
+	if err != nil {
+		return nil, err
+	}
+	if outboundChanged {
+		dirtyLb = true
+	}
+
 	// We don't care if the LB exists or not
 	// We only care about if there is any change in the LB, which means dirtyLB
 	// If it is not exist, and no change to that, we don't CreateOrUpdate LB
@@ -180,6 +180,10 @@ func (c *ManagedDiskController) CreateManagedDisk(options *ManagedDiskOptions) (
 
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
+	if err := c.common.cloud.checkComputeQuota(ctx, diskCountUsageName(diskSku), 1); err != nil {
+		return "", fmt.Errorf("AzureDisk - cannot create disk %s: %v", options.DiskName, err)
+	}
+
 	rerr := c.common.cloud.DisksClient.CreateOrUpdate(ctx, options.ResourceGroup, options.DiskName, model)
 	if rerr != nil {
 		return "", rerr.Error()
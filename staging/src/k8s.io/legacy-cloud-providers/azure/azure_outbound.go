@@ -0,0 +1,214 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts overrides the number of SNAT
+	// ports allocated per backend instance by the managed outbound rule on the standard
+	// load balancer. It only takes effect when outbound rule management is enabled via
+	// the outboundRuleAllocatedPorts cloud config option.
+	ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts = "service.beta.kubernetes.io/azure-load-balancer-outbound-rule-allocated-ports"
+
+	// outboundRuleName is the name of the outbound rule managed by the cloud provider.
+	outboundRuleName = "k8s-outbound-rule"
+
+	// maximumAllocatedOutboundPorts is the limit enforced by Azure load balancer.
+	maximumAllocatedOutboundPorts = 64000
+)
+
+// getOutboundRuleAllocatedPorts returns the number of SNAT ports the managed outbound
+// rule should allocate per backend instance, taking the service annotation over the
+// cloud config value. Zero means outbound rule management is disabled.
+func (az *Cloud) getOutboundRuleAllocatedPorts(service *v1.Service) (int32, error) {
+	val, ok := service.Annotations[ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts]
+	if !ok {
+		return az.OutboundRuleAllocatedPorts, nil
+	}
+
+	ports, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing %s: %v", ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts, err)
+	}
+	if ports < 0 || ports > maximumAllocatedOutboundPorts {
+		return 0, fmt.Errorf("%s must be between 0 and %d", ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts, maximumAllocatedOutboundPorts)
+	}
+	return int32(ports), nil
+}
+
+// reconcileOutboundRules ensures the outbound rule managed by the cloud provider on the
+// standard load balancer allocates the desired number of SNAT ports across all of the
+// load balancer's frontend IP configurations, so that SNAT exhaustion can be managed
+// without manual portal changes. It returns true when the load balancer was modified.
+// The rule is shared by all services on the load balancer and is hence kept when a
+// single service is deleted.
+func (az *Cloud) reconcileOutboundRules(lb *network.LoadBalancer, service *v1.Service, wantLb bool, lbBackendPoolID string) (bool, error) {
+	if !az.useStandardLoadBalancer() || requiresInternalLoadBalancer(service) || az.NatGatewayName != "" {
+		return false, nil
+	}
+	if !wantLb {
+		return false, nil
+	}
+
+	allocatedPorts, err := az.getOutboundRuleAllocatedPorts(service)
+	if err != nil {
+		return false, err
+	}
+	if allocatedPorts == 0 {
+		return false, nil
+	}
+
+	var frontendIPConfigurations []network.SubResource
+	if lb.LoadBalancerPropertiesFormat != nil && lb.FrontendIPConfigurations != nil {
+		for _, fipConfig := range *lb.FrontendIPConfigurations {
+			frontendIPConfigurations = append(frontendIPConfigurations, network.SubResource{ID: fipConfig.ID})
+		}
+	}
+	if len(frontendIPConfigurations) == 0 {
+		return false, nil
+	}
+
+	expectedRule := network.OutboundRule{
+		Name: to.StringPtr(outboundRuleName),
+		OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+			AllocatedOutboundPorts:   to.Int32Ptr(allocatedPorts),
+			FrontendIPConfigurations: &frontendIPConfigurations,
+			BackendAddressPool:       &network.SubResource{ID: to.StringPtr(lbBackendPoolID)},
+			Protocol:                 network.LoadBalancerOutboundRuleProtocolAll,
+			EnableTCPReset:           to.BoolPtr(true),
+		},
+	}
+
+	var outboundRules []network.OutboundRule
+	if lb.OutboundRules != nil {
+		outboundRules = *lb.OutboundRules
+	}
+	for i, existingRule := range outboundRules {
+		if !strings.EqualFold(to.String(existingRule.Name), outboundRuleName) {
+			continue
+		}
+		if equalOutboundRule(existingRule, expectedRule) {
+			return false, nil
+		}
+		klog.V(2).Infof("reconcileOutboundRules for service (%s): updating outbound rule %s with %d allocated ports", getServiceName(service), outboundRuleName, allocatedPorts)
+		outboundRules[i] = expectedRule
+		lb.OutboundRules = &outboundRules
+		return true, nil
+	}
+
+	klog.V(2).Infof("reconcileOutboundRules for service (%s): adding outbound rule %s with %d allocated ports", getServiceName(service), outboundRuleName, allocatedPorts)
+	outboundRules = append(outboundRules, expectedRule)
+	lb.OutboundRules = &outboundRules
+	return true, nil
+}
+
+// equalOutboundRule compares the properties of two outbound rules that the cloud
+// provider manages.
+func equalOutboundRule(existing, expected network.OutboundRule) bool {
+	if existing.OutboundRulePropertiesFormat == nil || expected.OutboundRulePropertiesFormat == nil {
+		return false
+	}
+	if to.Int32(existing.AllocatedOutboundPorts) != to.Int32(expected.AllocatedOutboundPorts) {
+		return false
+	}
+	if existing.Protocol != expected.Protocol {
+		return false
+	}
+	if existing.BackendAddressPool == nil ||
+		!strings.EqualFold(to.String(existing.BackendAddressPool.ID), to.String(expected.BackendAddressPool.ID)) {
+		return false
+	}
+	return equalSubResourceIDs(existing.FrontendIPConfigurations, expected.FrontendIPConfigurations)
+}
+
+// equalSubResourceIDs compares two lists of subresources by their IDs, ignoring order.
+func equalSubResourceIDs(existing, expected *[]network.SubResource) bool {
+	if existing == nil || expected == nil {
+		return existing == expected
+	}
+	if len(*existing) != len(*expected) {
+		return false
+	}
+	ids := make(map[string]bool, len(*existing))
+	for _, subResource := range *existing {
+		ids[strings.ToLower(to.String(subResource.ID))] = true
+	}
+	for _, subResource := range *expected {
+		if !ids[strings.ToLower(to.String(subResource.ID))] {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileNatGateway associates the NAT gateway configured in the cloud config with
+// the cluster subnet, so that outbound connectivity is provided by the NAT gateway
+// instead of load balancer SNAT. The NAT gateway itself must be pre-created.
+func (az *Cloud) reconcileNatGateway() error {
+	if az.NatGatewayName == "" || az.NatGatewaysClient == nil {
+		return nil
+	}
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	natGatewayResourceGroup := az.NatGatewayResourceGroup
+	if natGatewayResourceGroup == "" {
+		natGatewayResourceGroup = az.ResourceGroup
+	}
+	natGateway, rerr := az.NatGatewaysClient.Get(ctx, natGatewayResourceGroup, az.NatGatewayName, "")
+	if rerr != nil {
+		return fmt.Errorf("reconcileNatGateway: failed to get NAT gateway %s: %v", az.NatGatewayName, rerr.Error())
+	}
+
+	vnetResourceGroup := az.VnetResourceGroup
+	if vnetResourceGroup == "" {
+		vnetResourceGroup = az.ResourceGroup
+	}
+	subnet, rerr := az.SubnetsClient.Get(ctx, vnetResourceGroup, az.VnetName, az.SubnetName, "")
+	if rerr != nil {
+		return fmt.Errorf("reconcileNatGateway: failed to get subnet %s/%s: %v", az.VnetName, az.SubnetName, rerr.Error())
+	}
+
+	if subnet.SubnetPropertiesFormat != nil && subnet.NatGateway != nil &&
+		strings.EqualFold(to.String(subnet.NatGateway.ID), to.String(natGateway.ID)) {
+		return nil
+	}
+	if subnet.SubnetPropertiesFormat == nil {
+		subnet.SubnetPropertiesFormat = &network.SubnetPropertiesFormat{}
+	}
+	subnet.NatGateway = &network.SubResource{ID: natGateway.ID}
+
+	klog.V(2).Infof("reconcileNatGateway: associating NAT gateway %s with subnet %s/%s", az.NatGatewayName, az.VnetName, az.SubnetName)
+	if rerr := az.SubnetsClient.CreateOrUpdate(ctx, vnetResourceGroup, az.VnetName, az.SubnetName, subnet); rerr != nil {
+		return fmt.Errorf("reconcileNatGateway: failed to update subnet %s/%s: %v", az.VnetName, az.SubnetName, rerr.Error())
+	}
+	return nil
+}
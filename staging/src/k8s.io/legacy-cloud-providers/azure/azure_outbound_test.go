@@ -0,0 +1,204 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/legacy-cloud-providers/azure/clients/natgatewayclient/mocknatgatewayclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/subnetclient/mocksubnetclient"
+)
+
+func TestGetOutboundRuleAllocatedPorts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	az.OutboundRuleAllocatedPorts = 1024
+
+	testCases := []struct {
+		desc        string
+		annotations map[string]string
+		expected    int32
+		expectError bool
+	}{
+		{
+			desc:     "no annotation should return the cloud config value",
+			expected: 1024,
+		},
+		{
+			desc:        "annotation should override the cloud config value",
+			annotations: map[string]string{ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts: "2048"},
+			expected:    2048,
+		},
+		{
+			desc:        "non-numeric annotation should report an error",
+			annotations: map[string]string{ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts: "lots"},
+			expectError: true,
+		},
+		{
+			desc:        "out of range annotation should report an error",
+			annotations: map[string]string{ServiceAnnotationLoadBalancerOutboundRuleAllocatedPorts: "64008"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		service := getTestService("servicea", v1.ProtocolTCP, test.annotations, false, 80)
+		ports, err := az.getOutboundRuleAllocatedPorts(&service)
+		if test.expectError {
+			assert.Error(t, err, test.desc)
+			continue
+		}
+		assert.NoError(t, err, test.desc)
+		assert.Equal(t, test.expected, ports, test.desc)
+	}
+}
+
+func TestReconcileOutboundRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = loadBalancerSkuStandard
+	az.OutboundRuleAllocatedPorts = 1024
+
+	service := getTestService("servicea", v1.ProtocolTCP, nil, false, 80)
+	lb := network.LoadBalancer{
+		Name: to.StringPtr("lb"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{ID: to.StringPtr("fip1")},
+			},
+		},
+	}
+
+	changed, err := az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotNil(t, lb.OutboundRules)
+	assert.Len(t, *lb.OutboundRules, 1)
+	rule := (*lb.OutboundRules)[0]
+	assert.Equal(t, outboundRuleName, to.String(rule.Name))
+	assert.Equal(t, int32(1024), to.Int32(rule.AllocatedOutboundPorts))
+	assert.Equal(t, "pool", to.String(rule.BackendAddressPool.ID))
+
+	// A second reconciliation with the same configuration should be a no-op.
+	changed, err = az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// Changing the allocated ports should update the existing rule in place.
+	az.OutboundRuleAllocatedPorts = 2048
+	changed, err = az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, *lb.OutboundRules, 1)
+	assert.Equal(t, int32(2048), to.Int32((*lb.OutboundRules)[0].AllocatedOutboundPorts))
+
+	// The shared rule must be kept when a service is deleted.
+	changed, err = az.reconcileOutboundRules(&lb, &service, false, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Len(t, *lb.OutboundRules, 1)
+}
+
+func TestReconcileOutboundRulesDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lb := network.LoadBalancer{
+		Name: to.StringPtr("lb"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{ID: to.StringPtr("fip1")},
+			},
+		},
+	}
+
+	// Basic load balancers do not support outbound rules.
+	az := GetTestCloud(ctrl)
+	az.OutboundRuleAllocatedPorts = 1024
+	service := getTestService("servicea", v1.ProtocolTCP, nil, false, 80)
+	changed, err := az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// Internal load balancers are skipped.
+	az.LoadBalancerSku = loadBalancerSkuStandard
+	internalService := getInternalTestService("serviceb", 80)
+	changed, err = az.reconcileOutboundRules(&lb, &internalService, true, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// A configured NAT gateway takes over outbound connectivity.
+	az.NatGatewayName = "ngw"
+	changed, err = az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// Zero allocated ports disables the management entirely.
+	az.NatGatewayName = ""
+	az.OutboundRuleAllocatedPorts = 0
+	changed, err = az.reconcileOutboundRules(&lb, &service, true, "pool")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, lb.OutboundRules)
+}
+
+func TestReconcileNatGateway(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = loadBalancerSkuStandard
+	az.NatGatewayName = "ngw"
+
+	natGatewayID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/natGateways/ngw"
+	mockNatGatewaysClient := mocknatgatewayclient.NewMockInterface(ctrl)
+	az.NatGatewaysClient = mockNatGatewaysClient
+	mockSubnetsClient := az.SubnetsClient.(*mocksubnetclient.MockInterface)
+
+	// The subnet is not yet associated with the NAT gateway, an update is expected.
+	mockNatGatewaysClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "ngw", "").Return(network.NatGateway{ID: to.StringPtr(natGatewayID)}, nil).Times(1)
+	mockSubnetsClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, az.VnetName, az.SubnetName, "").Return(network.Subnet{
+		Name:                   to.StringPtr(az.SubnetName),
+		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{},
+	}, nil).Times(1)
+	mockSubnetsClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, az.VnetName, az.SubnetName, network.Subnet{
+		Name: to.StringPtr(az.SubnetName),
+		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+			NatGateway: &network.SubResource{ID: to.StringPtr(natGatewayID)},
+		},
+	}).Return(nil).Times(1)
+	assert.NoError(t, az.reconcileNatGateway())
+
+	// The subnet is already associated, no update is expected.
+	mockNatGatewaysClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "ngw", "").Return(network.NatGateway{ID: to.StringPtr(natGatewayID)}, nil).Times(1)
+	mockSubnetsClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, az.VnetName, az.SubnetName, "").Return(network.Subnet{
+		Name: to.StringPtr(az.SubnetName),
+		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+			NatGateway: &network.SubResource{ID: to.StringPtr(natGatewayID)},
+		},
+	}, nil).Times(1)
+	assert.NoError(t, az.reconcileNatGateway())
+}
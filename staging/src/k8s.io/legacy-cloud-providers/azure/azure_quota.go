@@ -0,0 +1,118 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+
+	"k8s.io/klog/v2"
+)
+
+// Usage names reported by the compute and network resource providers. The
+// names are the unlocalized Name.Value of the usage entries.
+const (
+	computeUsageStandardDisks    = "StandardDiskCount"
+	computeUsageStandardSSDDisks = "StandardSSDDiskCount"
+	computeUsagePremiumDisks     = "PremiumDiskCount"
+	computeUsageUltraSSDDisks    = "UltraSSDDiskCount"
+
+	networkUsagePublicIPAddresses = "PublicIPAddresses"
+)
+
+// diskCountUsageName returns the compute usage name tracking the number of
+// managed disks of the given storage account type.
+func diskCountUsageName(storageAccountType compute.DiskStorageAccountTypes) string {
+	switch storageAccountType {
+	case compute.PremiumLRS:
+		return computeUsagePremiumDisks
+	case compute.StandardSSDLRS:
+		return computeUsageStandardSSDDisks
+	case compute.UltraSSDLRS:
+		return computeUsageUltraSSDDisks
+	default:
+		return computeUsageStandardDisks
+	}
+}
+
+// checkComputeQuota returns an error if creating requested more resources
+// tracked by the given compute usage name would exceed the subscription quota
+// in the cloud's location. The check is advisory: if the usages cannot be
+// fetched or the usage name is unknown, creation proceeds and ARM remains the
+// authority on quota.
+func (az *Cloud) checkComputeQuota(ctx context.Context, usageName string, requested int64) error {
+	if az.UsagesClient == nil {
+		return nil
+	}
+
+	usages, rerr := az.UsagesClient.ListComputeUsages(ctx, az.Location)
+	if rerr != nil {
+		klog.Warningf("checkComputeQuota(%s): failed to list compute usages: %v", usageName, rerr)
+		return nil
+	}
+
+	for _, usage := range usages {
+		if usage.Name == nil || usage.Name.Value == nil || !strings.EqualFold(*usage.Name.Value, usageName) {
+			continue
+		}
+		if usage.CurrentValue == nil || usage.Limit == nil {
+			return nil
+		}
+		if int64(*usage.CurrentValue)+requested > *usage.Limit {
+			return fmt.Errorf("creating %d resource(s) would exceed quota %q in location %q: current usage %d, limit %d", requested, usageName, az.Location, *usage.CurrentValue, *usage.Limit)
+		}
+		return nil
+	}
+
+	klog.V(4).Infof("checkComputeQuota(%s): usage not reported in location %s, skipping quota check", usageName, az.Location)
+	return nil
+}
+
+// checkNetworkQuota is the network resource provider counterpart of
+// checkComputeQuota.
+func (az *Cloud) checkNetworkQuota(ctx context.Context, usageName string, requested int64) error {
+	if az.UsagesClient == nil {
+		return nil
+	}
+
+	usages, rerr := az.UsagesClient.ListNetworkUsages(ctx, az.Location)
+	if rerr != nil {
+		klog.Warningf("checkNetworkQuota(%s): failed to list network usages: %v", usageName, rerr)
+		return nil
+	}
+
+	for _, usage := range usages {
+		if usage.Name == nil || usage.Name.Value == nil || !strings.EqualFold(*usage.Name.Value, usageName) {
+			continue
+		}
+		if usage.CurrentValue == nil || usage.Limit == nil {
+			return nil
+		}
+		if *usage.CurrentValue+requested > *usage.Limit {
+			return fmt.Errorf("creating %d resource(s) would exceed quota %q in location %q: current usage %d, limit %d", requested, usageName, az.Location, *usage.CurrentValue, *usage.Limit)
+		}
+		return nil
+	}
+
+	klog.V(4).Infof("checkNetworkQuota(%s): usage not reported in location %s, skipping quota check", usageName, az.Location)
+	return nil
+}
@@ -0,0 +1,186 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/legacy-cloud-providers/azure/clients/usageclient/mockusageclient"
+	"k8s.io/legacy-cloud-providers/azure/retry"
+)
+
+func TestDiskCountUsageName(t *testing.T) {
+	testCases := []struct {
+		storageAccountType compute.DiskStorageAccountTypes
+		expected           string
+	}{
+		{compute.PremiumLRS, computeUsagePremiumDisks},
+		{compute.StandardSSDLRS, computeUsageStandardSSDDisks},
+		{compute.UltraSSDLRS, computeUsageUltraSSDDisks},
+		{compute.StandardLRS, computeUsageStandardDisks},
+		{compute.DiskStorageAccountTypes(""), computeUsageStandardDisks},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.expected, diskCountUsageName(test.storageAccountType))
+	}
+}
+
+func TestCheckComputeQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		desc        string
+		usages      []compute.Usage
+		listErr     *retry.Error
+		requested   int64
+		expectError bool
+	}{
+		{
+			desc: "under quota",
+			usages: []compute.Usage{
+				getComputeUsage(computeUsageStandardDisks, 5, 10),
+			},
+			requested: 1,
+		},
+		{
+			desc: "exactly at limit",
+			usages: []compute.Usage{
+				getComputeUsage(computeUsageStandardDisks, 9, 10),
+			},
+			requested: 1,
+		},
+		{
+			desc: "quota exceeded",
+			usages: []compute.Usage{
+				getComputeUsage(computeUsageStandardDisks, 10, 10),
+			},
+			requested:   1,
+			expectError: true,
+		},
+		{
+			desc: "usage not reported",
+			usages: []compute.Usage{
+				getComputeUsage(computeUsagePremiumDisks, 10, 10),
+			},
+			requested: 1,
+		},
+		{
+			desc:      "list failure is advisory",
+			listErr:   retry.NewError(true, fmt.Errorf("server error")),
+			requested: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		az := GetTestCloud(ctrl)
+		usagesClient := mockusageclient.NewMockInterface(ctrl)
+		usagesClient.EXPECT().ListComputeUsages(gomock.Any(), az.Location).Return(test.usages, test.listErr).Times(1)
+		az.UsagesClient = usagesClient
+
+		err := az.checkComputeQuota(context.TODO(), computeUsageStandardDisks, test.requested)
+		if test.expectError {
+			assert.Error(t, err, test.desc)
+		} else {
+			assert.NoError(t, err, test.desc)
+		}
+	}
+}
+
+func TestCheckNetworkQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		desc        string
+		usages      []network.Usage
+		listErr     *retry.Error
+		requested   int64
+		expectError bool
+	}{
+		{
+			desc: "under quota",
+			usages: []network.Usage{
+				getNetworkUsage(networkUsagePublicIPAddresses, 5, 10),
+			},
+			requested: 1,
+		},
+		{
+			desc: "quota exceeded",
+			usages: []network.Usage{
+				getNetworkUsage(networkUsagePublicIPAddresses, 10, 10),
+			},
+			requested:   1,
+			expectError: true,
+		},
+		{
+			desc:      "list failure is advisory",
+			listErr:   retry.NewError(true, fmt.Errorf("server error")),
+			requested: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		az := GetTestCloud(ctrl)
+		usagesClient := mockusageclient.NewMockInterface(ctrl)
+		usagesClient.EXPECT().ListNetworkUsages(gomock.Any(), az.Location).Return(test.usages, test.listErr).Times(1)
+		az.UsagesClient = usagesClient
+
+		err := az.checkNetworkQuota(context.TODO(), networkUsagePublicIPAddresses, test.requested)
+		if test.expectError {
+			assert.Error(t, err, test.desc)
+		} else {
+			assert.NoError(t, err, test.desc)
+		}
+	}
+}
+
+func TestCheckQuotaWithoutUsagesClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	assert.NoError(t, az.checkComputeQuota(context.TODO(), computeUsageStandardDisks, 1))
+	assert.NoError(t, az.checkNetworkQuota(context.TODO(), networkUsagePublicIPAddresses, 1))
+}
+
+func getComputeUsage(name string, current int32, limit int64) compute.Usage {
+	return compute.Usage{
+		Name:         &compute.UsageName{Value: to.StringPtr(name)},
+		CurrentValue: to.Int32Ptr(current),
+		Limit:        to.Int64Ptr(limit),
+	}
+}
+
+func getNetworkUsage(name string, current, limit int64) network.Usage {
+	return network.Usage{
+		Name:         &network.UsageName{Value: to.StringPtr(name)},
+		CurrentValue: to.Int64Ptr(current),
+		Limit:        to.Int64Ptr(limit),
+	}
+}
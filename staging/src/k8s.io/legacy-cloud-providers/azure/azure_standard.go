@@ -618,6 +618,56 @@ func (as *availabilitySet) GetPrivateIPsByNodeName(name string) ([]string, error
 	return ips, nil
 }
 
+// EnsureNodeIPv6Configuration ensures the node's primary network interface
+// carries a secondary IPv6 IP configuration, so that the node gets addresses
+// of both families without manual patching.
+func (as *availabilitySet) EnsureNodeIPv6Configuration(nodeName types.NodeName) error {
+	vmName := mapNodeNameToVMName(nodeName)
+	nic, err := as.GetPrimaryInterface(vmName)
+	if err != nil {
+		return err
+	}
+
+	if nic.ProvisioningState != nil && *nic.ProvisioningState == nicFailedState {
+		klog.Warningf("EnsureNodeIPv6Configuration skips node %s because its primary nic %s is in Failed state", nodeName, *nic.Name)
+		return nil
+	}
+
+	// Nothing to do when the interface already carries an IPv6 IP configuration.
+	if _, err := getIPConfigByIPFamily(nic, true); err == nil {
+		return nil
+	}
+
+	primaryIPConfig, err := getPrimaryIPConfig(nic)
+	if err != nil {
+		return err
+	}
+
+	// IPv6 configuration is only supported as non-primary, so the primary IPv4
+	// configuration is kept and its subnet is shared.
+	ipConfigurations := append(*nic.IPConfigurations, network.InterfaceIPConfiguration{
+		Name: to.StringPtr(fmt.Sprintf("%s-ipv6", to.String(primaryIPConfig.Name))),
+		InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+			Primary:                   to.BoolPtr(false),
+			PrivateIPAddressVersion:   network.IPv6,
+			PrivateIPAllocationMethod: network.Dynamic,
+			Subnet:                    primaryIPConfig.Subnet,
+		},
+	})
+	nic.IPConfigurations = &ipConfigurations
+
+	klog.V(2).Infof("EnsureNodeIPv6Configuration begins to update nic(%s) of node %s with a secondary IPv6 IP configuration", to.String(nic.Name), nodeName)
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+	rerr := as.InterfacesClient.CreateOrUpdate(ctx, as.ResourceGroup, to.String(nic.Name), nic)
+	if rerr != nil {
+		klog.Errorf("EnsureNodeIPv6Configuration InterfacesClient.CreateOrUpdate(%s) failed, err: %v", to.String(nic.Name), rerr)
+		return rerr.Error()
+	}
+
+	return nil
+}
+
 // getAgentPoolAvailabilitySets lists the virtual machines for the resource group and then builds
 // a list of availability sets that match the nodes available to k8s.
 func (as *availabilitySet) getAgentPoolAvailabilitySets(nodes []*v1.Node) (agentPoolAvailabilitySets *[]string, err error) {
@@ -631,6 +681,10 @@ func (as *availabilitySet) getAgentPoolAvailabilitySets(nodes []*v1.Node) (agent
 		vm := vms[vmx]
 		if vm.AvailabilitySet != nil {
 			vmNameToAvailabilitySetID[*vm.Name] = *vm.AvailabilitySet.ID
+		} else if vm.VirtualMachineProperties != nil && vm.VirtualMachineScaleSet != nil {
+			// Instances of flex scale sets have no availability set; their
+			// scale set takes its place as the vm set of the node.
+			vmNameToAvailabilitySetID[*vm.Name] = *vm.VirtualMachineScaleSet.ID
 		}
 	}
 	availabilitySetIDs := sets.NewString()
@@ -770,11 +824,23 @@ func (as *availabilitySet) getPrimaryInterfaceWithVMSet(nodeName, vmSetName stri
 		needCheck = true
 	}
 	if vmSetName != "" && needCheck {
-		expectedAvailabilitySetID := as.getAvailabilitySetID(nodeResourceGroup, vmSetName)
-		if machine.AvailabilitySet == nil || !strings.EqualFold(*machine.AvailabilitySet.ID, expectedAvailabilitySetID) {
-			klog.V(3).Infof(
-				"GetPrimaryInterface: nic (%s) is not in the availabilitySet(%s)", nicName, vmSetName)
-			return network.Interface{}, "", errNotInVMSet
+		if machine.AvailabilitySet == nil && machine.VirtualMachineScaleSet != nil {
+			// The VM is an instance of a scale set with flexible orchestration
+			// mode; its scale set name takes the place of the availability set
+			// name.
+			flexScaleSetName, err := getLastSegment(to.String(machine.VirtualMachineScaleSet.ID), "/")
+			if err != nil || !strings.EqualFold(flexScaleSetName, vmSetName) {
+				klog.V(3).Infof(
+					"GetPrimaryInterface: nic (%s) is not in the flex scale set(%s)", nicName, vmSetName)
+				return network.Interface{}, "", errNotInVMSet
+			}
+		} else {
+			expectedAvailabilitySetID := as.getAvailabilitySetID(nodeResourceGroup, vmSetName)
+			if machine.AvailabilitySet == nil || !strings.EqualFold(*machine.AvailabilitySet.ID, expectedAvailabilitySetID) {
+				klog.V(3).Infof(
+					"GetPrimaryInterface: nic (%s) is not in the availabilitySet(%s)", nicName, vmSetName)
+				return network.Interface{}, "", errNotInVMSet
+			}
 		}
 	}
 
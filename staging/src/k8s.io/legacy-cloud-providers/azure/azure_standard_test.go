@@ -1338,6 +1338,77 @@ func TestStandardEnsureHostInPool(t *testing.T) {
 	}
 }
 
+func TestStandardEnsureNodeIPv6Configuration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	availabilitySetID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/availabilitySets/myAvailabilitySet"
+
+	testCases := []struct {
+		name              string
+		nodeName          types.NodeName
+		nicName           string
+		nicID             string
+		nicProvisionState string
+		hasIPv6Config     bool
+		expectedPutNIC    int
+	}{
+		{
+			name:           "EnsureNodeIPv6Configuration should add a secondary IPv6 IP configuration",
+			nodeName:       "vm1",
+			nicName:        "nic1",
+			nicID:          "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic1",
+			expectedPutNIC: 1,
+		},
+		{
+			name:          "EnsureNodeIPv6Configuration should do nothing if the nic already has an IPv6 IP configuration",
+			nodeName:      "vm2",
+			nicName:       "nic2",
+			nicID:         "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic2",
+			hasIPv6Config: true,
+		},
+		{
+			name:              "EnsureNodeIPv6Configuration should skip the nic in Failed state",
+			nodeName:          "vm3",
+			nicName:           "nic3",
+			nicID:             "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic3",
+			nicProvisionState: nicFailedState,
+		},
+	}
+
+	for _, test := range testCases {
+		cloud := GetTestCloud(ctrl)
+
+		testVM := buildDefaultTestVirtualMachine(availabilitySetID, []string{test.nicID})
+		testVM.Name = to.StringPtr(string(test.nodeName))
+		testNIC := buildDefaultTestInterface(true, []string{})
+		testNIC.Name = to.StringPtr(test.nicName)
+		testNIC.ID = to.StringPtr(test.nicID)
+		if test.nicProvisionState != "" {
+			testNIC.ProvisioningState = to.StringPtr(test.nicProvisionState)
+		}
+		if test.hasIPv6Config {
+			ipConfigurations := append(*testNIC.IPConfigurations, network.InterfaceIPConfiguration{
+				InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+					PrivateIPAddress:        to.StringPtr("fd00::5"),
+					PrivateIPAddressVersion: network.IPv6,
+				},
+			})
+			testNIC.IPConfigurations = &ipConfigurations
+		}
+
+		mockVMClient := cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+		mockVMClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, string(test.nodeName), gomock.Any()).Return(testVM, nil).AnyTimes()
+
+		mockInterfaceClient := cloud.InterfacesClient.(*mockinterfaceclient.MockInterface)
+		mockInterfaceClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, test.nicName, gomock.Any()).Return(testNIC, nil).AnyTimes()
+		mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.ResourceGroup, test.nicName, gomock.Any()).Return(nil).Times(test.expectedPutNIC)
+
+		err := cloud.VMSet.EnsureNodeIPv6Configuration(test.nodeName)
+		assert.NoError(t, err, test.name)
+	}
+}
+
 func TestStandardEnsureHostsInPool(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -31,7 +31,7 @@ import (
 // VMSet defines functions all vmsets (including scale set and availability
 // set) should be implemented.
 // Don't forget to run the following command to generate the mock client:
-// mockgen -source=$GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/azure_vmsets.go -package=mockvmsets VMSet > $GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/mockvmsets/azure_mock_vmsets.go
+// mockgen -source=$GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/azure_vmsets.go -package=azure VMSet > $GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/azure_mock_vmsets.go
 type VMSet interface {
 	// GetInstanceIDByNodeName gets the cloud provider ID by node name.
 	// It must return ("", cloudprovider.InstanceNotFound) if the instance does
@@ -66,10 +66,12 @@ type VMSet interface {
 	// EnsureBackendPoolDeleted ensures the loadBalancer backendAddressPools deleted from the specified nodes.
 	EnsureBackendPoolDeleted(service *v1.Service, backendPoolID, vmSetName string, backendAddressPools *[]network.BackendAddressPool) error
 
-	// AttachDisk attaches a vhd to vm. The vhd must exist, can be identified by diskName, diskURI, and lun.
-	AttachDisk(isManagedDisk bool, diskName, diskURI string, nodeName types.NodeName, lun int32, cachingMode compute.CachingTypes, diskEncryptionSetID string, writeAcceleratorEnabled bool) error
-	// DetachDisk detaches a vhd from host. The vhd can be identified by diskName or diskURI.
-	DetachDisk(diskName, diskURI string, nodeName types.NodeName) error
+	// AttachDisk attaches the disks in diskMap to the node in one VM update call.
+	// The disks must exist and diskMap is keyed by the lowercased disk URI.
+	AttachDisk(nodeName types.NodeName, diskMap map[string]*AttachDiskOptions) error
+	// DetachDisk detaches the disks in diskMap from the node in one VM update call.
+	// diskMap maps the lowercased disk URI to the disk name.
+	DetachDisk(nodeName types.NodeName, diskMap map[string]string) error
 	// GetDataDisks gets a list of data disks attached to the node.
 	GetDataDisks(nodeName types.NodeName, string azcache.AzureCacheReadType) ([]compute.DataDisk, error)
 
@@ -79,6 +81,11 @@ type VMSet interface {
 	// GetPrivateIPsByNodeName returns a slice of all private ips assigned to node (ipv6 and ipv4)
 	GetPrivateIPsByNodeName(name string) ([]string, error)
 
+	// EnsureNodeIPv6Configuration ensures the node's primary network configuration
+	// carries a secondary IPv6 IP configuration, so that dual-stack nodes get
+	// addresses of both families without manual patching.
+	EnsureNodeIPv6Configuration(nodeName types.NodeName) error
+
 	// GetNodeNameByIPConfigurationID gets the nodeName and vmSetName by IP configuration ID.
 	GetNodeNameByIPConfigurationID(ipConfigurationID string) (string, string, error)
 }
@@ -81,6 +81,7 @@ type scaleSet struct {
 	vmssCache                 *azcache.TimedCache
 	vmssVMCache               *sync.Map // [resourcegroup/vmssname]*azcache.TimedCache
 	availabilitySetNodesCache *azcache.TimedCache
+	vmssFlexNodesCache        *azcache.TimedCache
 }
 
 // newScaleSet creates a new scaleSet.
@@ -107,6 +108,11 @@ func newScaleSet(az *Cloud) (VMSet, error) {
 		return nil, err
 	}
 
+	ss.vmssFlexNodesCache, err = ss.newVmssFlexNodesCache()
+	if err != nil {
+		return nil, err
+	}
+
 	return ss, nil
 }
 
@@ -534,6 +540,91 @@ func (ss *scaleSet) GetPrivateIPsByNodeName(nodeName string) ([]string, error) {
 	return ips, nil
 }
 
+// EnsureNodeIPv6Configuration ensures the scale set the node belongs to carries
+// a secondary IPv6 IP configuration on its primary network configuration, so
+// that its instances get addresses of both families without manual patching.
+func (ss *scaleSet) EnsureNodeIPv6Configuration(nodeName types.NodeName) error {
+	vmName := mapNodeNameToVMName(nodeName)
+	managedByAS, err := ss.isNodeManagedByAvailabilitySet(vmName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		klog.Errorf("Failed to check isNodeManagedByAvailabilitySet: %v", err)
+		return err
+	}
+	if managedByAS {
+		// vm is managed by availability set.
+		return ss.availabilitySet.EnsureNodeIPv6Configuration(nodeName)
+	}
+
+	vmssName, _, _, err := ss.getVmssVM(vmName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return err
+	}
+
+	vmss, err := ss.getVMSS(vmssName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return err
+	}
+
+	// When vmss is being deleted, CreateOrUpdate API would report "the vmss is being deleted" error.
+	// Since it is being deleted, we shouldn't send more CreateOrUpdate requests for it.
+	if vmss.ProvisioningState != nil && strings.EqualFold(*vmss.ProvisioningState, virtualMachineScaleSetsDeallocating) {
+		klog.V(3).Infof("EnsureNodeIPv6Configuration: found vmss %s being deleted, skipping", vmssName)
+		return nil
+	}
+
+	if vmss.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations == nil {
+		klog.V(4).Infof("EnsureNodeIPv6Configuration: cannot obtain the primary network interface configuration of vmss %s", vmssName)
+		return nil
+	}
+	vmssNIC := *vmss.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations
+	primaryNIC, err := ss.getPrimaryNetworkInterfaceConfigurationForScaleSet(vmssNIC, vmssName)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to do when the scale set already carries an IPv6 IP configuration.
+	if _, err := ss.getConfigForScaleSetByIPFamily(primaryNIC, vmName, true); err == nil {
+		return nil
+	}
+
+	primaryIPConfig, err := getPrimaryIPConfigFromVMSSNetworkConfig(primaryNIC)
+	if err != nil {
+		return err
+	}
+
+	// Compose a new vmss with the secondary IPv6 IP configuration added.
+	// IPv6 configuration is only supported as non-primary, so the primary IPv4
+	// configuration is kept and its subnet is shared.
+	ipConfigurations := append(*primaryNIC.IPConfigurations, compute.VirtualMachineScaleSetIPConfiguration{
+		Name: to.StringPtr(fmt.Sprintf("%s-ipv6", to.String(primaryIPConfig.Name))),
+		VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{
+			Primary:                 to.BoolPtr(false),
+			PrivateIPAddressVersion: compute.IPv6,
+			Subnet:                  primaryIPConfig.Subnet,
+		},
+	})
+	primaryNIC.IPConfigurations = &ipConfigurations
+	newVMSS := compute.VirtualMachineScaleSet{
+		Location: vmss.Location,
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
+			VirtualMachineProfile: &compute.VirtualMachineScaleSetVMProfile{
+				NetworkProfile: &compute.VirtualMachineScaleSetNetworkProfile{
+					NetworkInterfaceConfigurations: &vmssNIC,
+				},
+			},
+		},
+	}
+
+	klog.V(2).Infof("EnsureNodeIPv6Configuration begins to update vmss(%s) with a secondary IPv6 IP configuration", vmssName)
+	rerr := ss.CreateOrUpdateVMSS(ss.ResourceGroup, vmssName, newVMSS)
+	if rerr != nil {
+		klog.Errorf("EnsureNodeIPv6Configuration CreateOrUpdateVMSS(%s) failed, err: %v", vmssName, rerr)
+		return rerr.Error()
+	}
+
+	return nil
+}
+
 // This returns the full identifier of the primary NIC for the given VM.
 func (ss *scaleSet) getPrimaryInterfaceID(machine compute.VirtualMachineScaleSetVM) (string, error) {
 	if machine.NetworkProfile == nil || machine.NetworkProfile.NetworkInterfaces == nil {
@@ -619,8 +710,8 @@ func (ss *scaleSet) listScaleSets(resourceGroup string) ([]string, error) {
 			continue
 		}
 
-		if vmss.VirtualMachineScaleSetProperties == nil || vmss.VirtualMachineScaleSetProperties.VirtualMachineProfile == nil {
-			klog.V(3).Infof("VMSS %q orchestrationMode is VirtualMachine, skipping", name)
+		if isFlexScaleSet(vmss) || vmss.VirtualMachineScaleSetProperties == nil {
+			klog.V(3).Infof("VMSS %q orchestrationMode is Flexible, its instances are handled as standalone VMs, skipping", name)
 			continue
 		}
 
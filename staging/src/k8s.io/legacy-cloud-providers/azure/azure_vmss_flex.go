@@ -0,0 +1,164 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"k8s.io/klog/v2"
+	azcache "k8s.io/legacy-cloud-providers/azure/cache"
+)
+
+// Instances of a scale set with flexible orchestration mode are regular
+// VirtualMachines that merely reference their scale set, so they are managed
+// through the standalone VM (availability set) code paths. The helpers here
+// detect flex scale sets and map nodes to them, so that code which matches
+// nodes against vm set names (basic and multiple standard load balancers)
+// can treat the flex scale set name like an availability set name.
+
+var (
+	vmssFlexNodesKey = "k8sVmssFlexNodesKey"
+
+	vmssFlexNodesCacheTTLDefaultInSeconds = 900
+)
+
+// isFlexScaleSet returns true when the scale set runs with flexible
+// orchestration mode. The compute API of this provider predates the
+// orchestrationMode field; a scale set without a virtual machine profile can
+// only be a flex one.
+func isFlexScaleSet(vmss compute.VirtualMachineScaleSet) bool {
+	return vmss.VirtualMachineScaleSetProperties != nil && vmss.VirtualMachineScaleSetProperties.VirtualMachineProfile == nil
+}
+
+// listFlexScaleSets lists the names of all scale sets with flexible
+// orchestration mode in the resource group.
+func (ss *scaleSet) listFlexScaleSets(resourceGroup string) ([]string, error) {
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	allScaleSets, rerr := ss.VirtualMachineScaleSetsClient.List(ctx, resourceGroup)
+	if rerr != nil {
+		klog.Errorf("VirtualMachineScaleSetsClient.List failed: %v", rerr)
+		return nil, rerr.Error()
+	}
+
+	ssNames := make([]string, 0)
+	for _, vmss := range allScaleSets {
+		if isFlexScaleSet(vmss) && vmss.Name != nil {
+			ssNames = append(ssNames, *vmss.Name)
+		}
+	}
+
+	return ssNames, nil
+}
+
+// newVmssFlexNodesCache builds a cache mapping node names to the name of the
+// flex scale set they belong to. Nodes outside any flex scale set are not in
+// the map.
+func (ss *scaleSet) newVmssFlexNodesCache() (*azcache.TimedCache, error) {
+	getter := func(key string) (interface{}, error) {
+		nodeNameToScaleSet := make(map[string]string)
+		resourceGroups, err := ss.GetResourceGroups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resourceGroup := range resourceGroups.List() {
+			vmList, err := ss.Cloud.ListVirtualMachines(resourceGroup)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, vm := range vmList {
+				if vm.Name == nil || vm.VirtualMachineProperties == nil || vm.VirtualMachineScaleSet == nil {
+					continue
+				}
+				vmssName, err := getLastSegment(to.String(vm.VirtualMachineScaleSet.ID), "/")
+				if err != nil {
+					klog.Errorf("newVmssFlexNodesCache: failed to parse scale set ID %q of VM %q: %v", to.String(vm.VirtualMachineScaleSet.ID), *vm.Name, err)
+					continue
+				}
+				nodeNameToScaleSet[strings.ToLower(*vm.Name)] = vmssName
+			}
+		}
+
+		return nodeNameToScaleSet, nil
+	}
+
+	if ss.Config.VmssFlexNodesCacheTTLInSeconds == 0 {
+		ss.Config.VmssFlexNodesCacheTTLInSeconds = vmssFlexNodesCacheTTLDefaultInSeconds
+	}
+	return azcache.NewTimedcache(time.Duration(ss.Config.VmssFlexNodesCacheTTLInSeconds)*time.Second, getter)
+}
+
+// getFlexScaleSetNameByNodeName returns the name of the flex scale set the
+// node belongs to, or the empty string when the node is not part of one.
+func (ss *scaleSet) getFlexScaleSetNameByNodeName(nodeName string, crt azcache.AzureCacheReadType) (string, error) {
+	cached, err := ss.vmssFlexNodesCache.Get(vmssFlexNodesKey, crt)
+	if err != nil {
+		return "", err
+	}
+
+	vmssName, found := cached.(map[string]string)[strings.ToLower(nodeName)]
+	if !found && crt != azcache.CacheReadTypeForceRefresh {
+		// The node may have joined after the last cache refresh.
+		cached, err = ss.vmssFlexNodesCache.Get(vmssFlexNodesKey, azcache.CacheReadTypeForceRefresh)
+		if err != nil {
+			return "", err
+		}
+		vmssName = cached.(map[string]string)[strings.ToLower(nodeName)]
+	}
+
+	return vmssName, nil
+}
+
+// isNodeManagedByFlexScaleSet returns true when the node is an instance of a
+// scale set with flexible orchestration mode.
+func (ss *scaleSet) isNodeManagedByFlexScaleSet(nodeName string, crt azcache.AzureCacheReadType) (bool, error) {
+	vmssName, err := ss.getFlexScaleSetNameByNodeName(nodeName, crt)
+	if err != nil {
+		return false, err
+	}
+	return vmssName != "", nil
+}
+
+// GetFlexScaleSetVMs lists the VMs of the flex scale set through the VM list
+// API, since flex instances are not addressable through the scale set VM API.
+func (ss *scaleSet) GetFlexScaleSetVMs(resourceGroup, vmssFlexName string) ([]compute.VirtualMachine, error) {
+	vmssFlexID := ss.makeFlexScaleSetID(resourceGroup, vmssFlexName)
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+	vms, rerr := ss.VirtualMachinesClient.ListVmssFlexVMsWithoutInstanceView(ctx, vmssFlexID)
+	if rerr != nil {
+		klog.Errorf("VirtualMachinesClient.ListVmssFlexVMsWithoutInstanceView(%s) failed: %v", vmssFlexID, rerr)
+		return nil, rerr.Error()
+	}
+
+	return vms, nil
+}
+
+// makeFlexScaleSetID returns the full resource ID of the flex scale set.
+func (ss *scaleSet) makeFlexScaleSetID(resourceGroup, vmssFlexName string) string {
+	return "/subscriptions/" + ss.SubscriptionID + "/resourceGroups/" + resourceGroup + "/providers/Microsoft.Compute/virtualMachineScaleSets/" + vmssFlexName
+}
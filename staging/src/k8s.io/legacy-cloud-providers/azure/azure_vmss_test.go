@@ -2036,6 +2036,65 @@ func TestEnsureVMSSInPool(t *testing.T) {
 	}
 }
 
+func TestEnsureNodeIPv6Configuration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCases := []struct {
+		description        string
+		nodeName           types.NodeName
+		isVMSSDeallocating bool
+		setIPv6Config      bool
+		expectedPutVMSS    bool
+	}{
+		{
+			description:     "EnsureNodeIPv6Configuration should add a secondary IPv6 IP configuration to the VMSS",
+			nodeName:        "vmss-vm-000000",
+			expectedPutVMSS: true,
+		},
+		{
+			description:     "EnsureNodeIPv6Configuration should do nothing if the VMSS already has an IPv6 IP configuration",
+			nodeName:        "vmss-vm-000000",
+			setIPv6Config:   true,
+			expectedPutVMSS: false,
+		},
+		{
+			description:        "EnsureNodeIPv6Configuration should skip the VMSS if it is deallocating",
+			nodeName:           "vmss-vm-000000",
+			isVMSSDeallocating: true,
+			expectedPutVMSS:    false,
+		},
+	}
+
+	for _, test := range testCases {
+		ss, err := newTestScaleSet(ctrl)
+		assert.NoError(t, err, test.description)
+
+		expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, test.setIPv6Config)
+		if test.isVMSSDeallocating {
+			expectedVMSS.ProvisioningState = &virtualMachineScaleSetsDeallocating
+		}
+		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		vmssPutTimes := 0
+		if test.expectedPutVMSS {
+			vmssPutTimes = 1
+			mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
+		}
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).Times(vmssPutTimes)
+
+		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{string(test.nodeName)}, "", test.setIPv6Config)
+		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+
+		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
+
+		err = ss.EnsureNodeIPv6Configuration(test.nodeName)
+		assert.NoError(t, err, test.description)
+	}
+}
+
 func TestEnsureHostsInPool(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -48,6 +48,8 @@ type Client struct {
 	baseURI      string
 	apiVersion   string
 	clientRegion string
+
+	getCache *responseCache
 }
 
 // New creates a ARM client
@@ -77,6 +79,7 @@ func New(authorizer autorest.Authorizer, baseURI, userAgent, apiVersion, clientR
 		backoff:      backoff,
 		apiVersion:   apiVersion,
 		clientRegion: NormalizeAzureRegion(clientRegion),
+		getCache:     newResponseCache(defaultResponseCacheEntries, defaultResponseCacheTTL),
 	}
 }
 
@@ -120,6 +123,11 @@ func (c *Client) sendRequest(ctx context.Context, request *http.Request) (*http.
 
 // Send sends a http request to ARM service with possible retry to regional ARM endpoint.
 func (c *Client) Send(ctx context.Context, request *http.Request) (*http.Response, *retry.Error) {
+	// A write makes every response cached for the resource stale.
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		c.getCache.invalidatePath(request.URL.Path)
+	}
+
 	response, rerr := c.sendRequest(ctx, request)
 	if rerr != nil {
 		return response, rerr
@@ -315,7 +323,7 @@ func (c *Client) GetResource(ctx context.Context, resourceID, expand string) (*h
 		return nil, retry.NewError(false, err)
 	}
 
-	return c.Send(ctx, request)
+	return c.getWithCache(ctx, request)
 }
 
 // GetResourceWithDecorators get a resource with decorators by resource ID
@@ -330,7 +338,7 @@ func (c *Client) GetResourceWithDecorators(ctx context.Context, resourceID strin
 		return nil, retry.NewError(false, err)
 	}
 
-	return c.Send(ctx, request)
+	return c.getWithCache(ctx, request)
 }
 
 // PutResource puts a resource by resource ID
@@ -0,0 +1,195 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package armclient
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/legacy-cloud-providers/azure/metrics"
+	"k8s.io/legacy-cloud-providers/azure/retry"
+)
+
+const (
+	// defaultResponseCacheEntries bounds how many GET responses are kept.
+	defaultResponseCacheEntries = 1024
+	// defaultResponseCacheTTL bounds how long an unused entry may be served
+	// from the cache after a 304 Not Modified answer.
+	defaultResponseCacheTTL = 30 * time.Minute
+	// responseCacheMaxBodySize keeps single huge payloads from dominating the
+	// cache; larger responses are simply not cached.
+	responseCacheMaxBodySize = 1024 * 1024
+)
+
+// getCacheEntry is one cached GET response together with the ETag ARM
+// returned for it.
+type getCacheEntry struct {
+	etag     string
+	header   http.Header
+	body     []byte
+	lastUsed time.Time
+}
+
+// responseCache caches GET responses by request URL so that reconciliation
+// reads of unchanged resources can be answered by ARM with a cheap
+// 304 Not Modified instead of the full payload. The cache is bounded both in
+// entry count and in entry age; the oldest entry is evicted first.
+type responseCache struct {
+	lock       sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*getCacheEntry
+}
+
+func newResponseCache(maxEntries int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*getCacheEntry),
+	}
+}
+
+// get returns the entry cached for the URL, or nil when there is none or it
+// aged out.
+func (rc *responseCache) get(url string) *getCacheEntry {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	entry, found := rc.entries[url]
+	if !found {
+		return nil
+	}
+	if time.Since(entry.lastUsed) > rc.ttl {
+		delete(rc.entries, url)
+		metrics.ObserveResponseCacheEviction()
+		return nil
+	}
+	entry.lastUsed = time.Now()
+	return entry
+}
+
+// put stores the entry for the URL, evicting the least recently used entry
+// when the cache is full.
+func (rc *responseCache) put(url string, entry *getCacheEntry) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	if _, found := rc.entries[url]; !found && len(rc.entries) >= rc.maxEntries {
+		oldestURL := ""
+		for candidateURL, candidate := range rc.entries {
+			if oldestURL == "" || candidate.lastUsed.Before(rc.entries[oldestURL].lastUsed) {
+				oldestURL = candidateURL
+			}
+		}
+		delete(rc.entries, oldestURL)
+		metrics.ObserveResponseCacheEviction()
+	}
+	entry.lastUsed = time.Now()
+	rc.entries[url] = entry
+}
+
+// invalidatePath drops every entry whose URL refers to the written resource,
+// including the ones with different query parameters (expands, filters).
+// Azure resource IDs are case insensitive.
+func (rc *responseCache) invalidatePath(path string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	loweredPath := strings.ToLower(path)
+	for url := range rc.entries {
+		if strings.Contains(strings.ToLower(url), loweredPath) {
+			delete(rc.entries, url)
+		}
+	}
+}
+
+// getWithCache sends the prepared GET request with an If-None-Match header
+// when a cached response is available, and answers 304 Not Modified from the
+// cache. Full 200 responses carrying an ETag are stored for later requests.
+func (c *Client) getWithCache(ctx context.Context, request *http.Request) (*http.Response, *retry.Error) {
+	url := request.URL.String()
+	entry := c.getCache.get(url)
+	if entry != nil {
+		if request.Header == nil {
+			request.Header = http.Header{}
+		}
+		request.Header.Set("If-None-Match", entry.etag)
+	}
+
+	response, rerr := c.Send(ctx, request)
+	if entry != nil && response != nil && response.StatusCode == http.StatusNotModified {
+		metrics.ObserveResponseCacheRequest("hit")
+		c.CloseResponse(ctx, response)
+		return cachedResponse(request, entry), nil
+	}
+	metrics.ObserveResponseCacheRequest("miss")
+	if rerr != nil {
+		return response, rerr
+	}
+
+	if response.StatusCode == http.StatusOK {
+		if etag := response.Header.Get("ETag"); etag != "" && response.ContentLength <= responseCacheMaxBodySize {
+			body, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "get.cache", url, err)
+				return response, retry.GetError(response, err)
+			}
+			response.Body.Close()
+			response.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if len(body) <= responseCacheMaxBodySize {
+				c.getCache.put(url, &getCacheEntry{
+					etag:   etag,
+					header: cloneHeader(response.Header),
+					body:   body,
+				})
+			}
+		}
+	}
+	return response, nil
+}
+
+// cachedResponse builds a 200 OK response from the cached entry, as if ARM
+// had returned the full payload again.
+func cachedResponse(request *http.Request, entry *getCacheEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(entry.header),
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       request,
+	}
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
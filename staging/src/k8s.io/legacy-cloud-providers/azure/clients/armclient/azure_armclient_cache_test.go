@@ -0,0 +1,133 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package armclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/legacy-cloud-providers/azure/retry"
+)
+
+func TestGetResourceNotModified(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		count++
+		if r.Header.Get("If-None-Match") == `"etag-0"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"etag-0"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{data: testPIP}"))
+	}))
+
+	backoff := &retry.Backoff{Steps: 1}
+	armClient := New(nil, server.URL, "test", "2019-01-01", "eastus", backoff)
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	ctx := context.Background()
+
+	// First request fetches the full payload and populates the cache.
+	response, rerr := armClient.GetResource(ctx, testResourceID, "")
+	assert.Nil(t, rerr)
+	byteResponseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "{data: testPIP}", string(byteResponseBody))
+	assert.Equal(t, 1, count)
+
+	// Second request is answered with 304 and served from the cache.
+	response, rerr = armClient.GetResource(ctx, testResourceID, "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	byteResponseBody, _ = ioutil.ReadAll(response.Body)
+	assert.Equal(t, "{data: testPIP}", string(byteResponseBody))
+	assert.Equal(t, 2, count)
+}
+
+func TestGetResourceCacheInvalidatedByWrite(t *testing.T) {
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.Header.Get("If-None-Match"))
+		if r.Method == "GET" {
+			w.Header().Set("ETag", `"etag-0"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("{data: testPIP}"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	backoff := &retry.Backoff{Steps: 1}
+	armClient := New(nil, server.URL, "test", "2019-01-01", "eastus", backoff)
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	ctx := context.Background()
+	_, rerr := armClient.GetResource(ctx, testResourceID, "")
+	assert.Nil(t, rerr)
+
+	_, rerr = armClient.PutResource(ctx, testResourceID, nil)
+	assert.Nil(t, rerr)
+
+	// The write dropped the cached entry, so no If-None-Match is sent on the
+	// final GET. The PUT itself is followed by a GET fetching the operation
+	// result, which bypasses the cache.
+	_, rerr = armClient.GetResource(ctx, testResourceID, "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{"GET ", "PUT ", "GET ", "GET "}, requests)
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cache := newResponseCache(2, time.Minute)
+	cache.put("a", &getCacheEntry{etag: "1"})
+	cache.put("b", &getCacheEntry{etag: "2"})
+	assert.NotNil(t, cache.get("a"))
+	cache.put("c", &getCacheEntry{etag: "3"})
+
+	// "b" was the least recently used entry.
+	assert.Nil(t, cache.get("b"))
+	assert.NotNil(t, cache.get("a"))
+	assert.NotNil(t, cache.get("c"))
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	cache := newResponseCache(2, time.Millisecond)
+	cache.put("a", &getCacheEntry{etag: "1"})
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, cache.get("a"))
+}
+
+func TestResponseCacheInvalidatePath(t *testing.T) {
+	cache := newResponseCache(4, time.Minute)
+	cache.put("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb?api-version=1", &getCacheEntry{etag: "1"})
+	cache.put("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb?api-version=1&%24expand=data", &getCacheEntry{etag: "2"})
+	cache.put("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg?api-version=1", &getCacheEntry{etag: "3"})
+
+	cache.invalidatePath("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/LB")
+
+	assert.Nil(t, cache.get("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb?api-version=1"))
+	assert.Nil(t, cache.get("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb?api-version=1&%24expand=data"))
+	assert.NotNil(t, cache.get("https://host/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg?api-version=1"))
+}
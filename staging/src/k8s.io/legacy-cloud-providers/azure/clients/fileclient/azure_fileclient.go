@@ -25,14 +25,22 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
 
 	"k8s.io/klog/v2"
 	azclients "k8s.io/legacy-cloud-providers/azure/clients"
+	"k8s.io/legacy-cloud-providers/azure/clients/armclient"
 )
 
 // Client implements the azure file client interface
 type Client struct {
 	fileSharesClient storage.FileSharesClient
+
+	// armClient is used for the file service settings which are not
+	// available in the compiled storage SDK, see SMBSettingsAPIVersion.
+	armClient      armclient.Interface
+	subscriptionID string
 }
 
 // ShareOptions contains the fields which are used to create file share.
@@ -46,9 +54,12 @@ type ShareOptions struct {
 func New(config *azclients.ClientConfig) *Client {
 	client := storage.NewFileSharesClientWithBaseURI(config.ResourceManagerEndpoint, config.SubscriptionID)
 	client.Authorizer = config.Authorizer
+	armClient := armclient.New(config.Authorizer, config.ResourceManagerEndpoint, config.UserAgent, SMBSettingsAPIVersion, config.Location, config.Backoff)
 
 	return &Client{
 		fileSharesClient: client,
+		armClient:        armClient,
+		subscriptionID:   config.SubscriptionID,
 	}
 }
 
@@ -118,3 +129,137 @@ func (c *Client) ResizeFileShare(resourceGroupName, accountName, name string, si
 func (c *Client) GetFileShare(resourceGroupName, accountName, name string) (storage.FileShare, error) {
 	return c.fileSharesClient.Get(context.Background(), resourceGroupName, accountName, name, storage.Stats)
 }
+
+// Storage class parameters controlling the account-level SMB security
+// settings. Parameter names are matched case-insensitively.
+const (
+	smbVersionsField                 = "smbversions"
+	smbAuthenticationMethodsField    = "smbauthenticationmethods"
+	smbKerberosTicketEncryptionField = "smbkerberosticketencryption"
+	smbChannelEncryptionField        = "smbchannelencryption"
+)
+
+// SMBSettings contains the account-level SMB security settings of a file
+// service. Each field holds a semicolon-separated list of values accepted by
+// the file service; empty fields are left unchanged on the service.
+type SMBSettings struct {
+	// Versions is the set of SMB protocol dialects clients may use, e.g.
+	// "SMB2.1;SMB3.0;SMB3.1.1".
+	Versions string `json:"versions,omitempty"`
+	// AuthenticationMethods is the set of allowed authentication methods,
+	// e.g. "NTLMv2;Kerberos".
+	AuthenticationMethods string `json:"authenticationMethods,omitempty"`
+	// KerberosTicketEncryption is the set of allowed Kerberos ticket
+	// encryption algorithms, e.g. "RC4-HMAC;AES-256".
+	KerberosTicketEncryption string `json:"kerberosTicketEncryption,omitempty"`
+	// ChannelEncryption is the set of allowed SMB channel encryption
+	// algorithms, e.g. "AES-128-CCM;AES-128-GCM;AES-256-GCM".
+	ChannelEncryption string `json:"channelEncryption,omitempty"`
+}
+
+// protocolSettings and fileServiceProperties are the wire format of the file
+// service settings under SMBSettingsAPIVersion.
+type protocolSettings struct {
+	SMB *SMBSettings `json:"smb,omitempty"`
+}
+
+type fileServicePropertiesProperties struct {
+	ProtocolSettings *protocolSettings `json:"protocolSettings,omitempty"`
+}
+
+type fileServiceProperties struct {
+	Properties *fileServicePropertiesProperties `json:"properties,omitempty"`
+}
+
+// fileServicesResourceID returns the resource ID of the default file service
+// of the given storage account.
+func (c *Client) fileServicesResourceID(resourceGroupName, accountName string) string {
+	return armclient.GetChildResourceID(c.subscriptionID, resourceGroupName,
+		"Microsoft.Storage/storageAccounts", accountName, "fileServices", "default")
+}
+
+// GetSMBSettings gets the account-level SMB security settings of the file
+// service.
+func (c *Client) GetSMBSettings(resourceGroupName, accountName string) (*SMBSettings, error) {
+	ctx := context.Background()
+	resourceID := c.fileServicesResourceID(resourceGroupName, accountName)
+	response, rerr := c.armClient.GetResource(ctx, resourceID, "")
+	defer c.armClient.CloseResponse(ctx, response)
+	if rerr != nil {
+		return nil, fmt.Errorf("failed to get file service properties of account(%s): %v", accountName, rerr.Error())
+	}
+
+	properties := fileServiceProperties{}
+	err := autorest.Respond(
+		response,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&properties))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode file service properties of account(%s): %v", accountName, err)
+	}
+
+	if properties.Properties == nil || properties.Properties.ProtocolSettings == nil || properties.Properties.ProtocolSettings.SMB == nil {
+		return &SMBSettings{}, nil
+	}
+	return properties.Properties.ProtocolSettings.SMB, nil
+}
+
+// SetSMBSettings sets the account-level SMB security settings of the file
+// service. Only the fields set in settings are sent; the service keeps its
+// defaults for the others.
+func (c *Client) SetSMBSettings(resourceGroupName, accountName string, settings *SMBSettings) error {
+	if settings == nil {
+		return fmt.Errorf("SMB settings is nil")
+	}
+
+	ctx := context.Background()
+	resourceID := c.fileServicesResourceID(resourceGroupName, accountName)
+	properties := fileServiceProperties{
+		Properties: &fileServicePropertiesProperties{
+			ProtocolSettings: &protocolSettings{
+				SMB: settings,
+			},
+		},
+	}
+	response, rerr := c.armClient.PutResource(ctx, resourceID, properties)
+	defer c.armClient.CloseResponse(ctx, response)
+	if rerr != nil {
+		return fmt.Errorf("failed to set SMB settings on account(%s): %v", accountName, rerr.Error())
+	}
+
+	klog.V(4).Infof("set SMB settings completed, resourceGroupName(%s), accountName: %s", resourceGroupName, accountName)
+	return nil
+}
+
+// ParseSMBSettings extracts account-level SMB security settings from storage
+// class parameters. It returns nil when none of the SMB setting parameters
+// are present, and an error for unknown parameters with the "smb" prefix.
+func ParseSMBSettings(parameters map[string]string) (*SMBSettings, error) {
+	settings := &SMBSettings{}
+	found := false
+	for k, v := range parameters {
+		name := strings.ToLower(k)
+		switch name {
+		case smbVersionsField:
+			settings.Versions = v
+			found = true
+		case smbAuthenticationMethodsField:
+			settings.AuthenticationMethods = v
+			found = true
+		case smbKerberosTicketEncryptionField:
+			settings.KerberosTicketEncryption = v
+			found = true
+		case smbChannelEncryptionField:
+			settings.ChannelEncryption = v
+			found = true
+		default:
+			if strings.HasPrefix(name, "smb") {
+				return nil, fmt.Errorf("invalid SMB setting parameter %q", k)
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return settings, nil
+}
@@ -0,0 +1,168 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/legacy-cloud-providers/azure/clients/armclient"
+	"k8s.io/legacy-cloud-providers/azure/clients/armclient/mockarmclient"
+)
+
+const testFileServicesResourceID = "/subscriptions/subscriptionID/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/account/fileServices/default"
+
+func getTestFileClient(armClient armclient.Interface) *Client {
+	return &Client{
+		armClient:      armClient,
+		subscriptionID: "subscriptionID",
+	}
+}
+
+func TestGetSMBSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body: ioutil.NopCloser(bytes.NewReader([]byte(
+			`{"properties":{"protocolSettings":{"smb":{"versions":"SMB3.0;SMB3.1.1","channelEncryption":"AES-256-GCM"}}}}`))),
+	}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResource(gomock.Any(), testFileServicesResourceID, "").Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	fileClient := getTestFileClient(armClient)
+	settings, err := fileClient.GetSMBSettings("rg", "account")
+	assert.NoError(t, err)
+	assert.Equal(t, &SMBSettings{
+		Versions:          "SMB3.0;SMB3.1.1",
+		ChannelEncryption: "AES-256-GCM",
+	}, settings)
+}
+
+func TestGetSMBSettingsNotConfigured(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"properties":{}}`))),
+	}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResource(gomock.Any(), testFileServicesResourceID, "").Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	fileClient := getTestFileClient(armClient)
+	settings, err := fileClient.GetSMBSettings("rg", "account")
+	assert.NoError(t, err)
+	assert.Equal(t, &SMBSettings{}, settings)
+}
+
+func TestSetSMBSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	settings := &SMBSettings{
+		Versions:                 "SMB3.1.1",
+		AuthenticationMethods:    "Kerberos",
+		KerberosTicketEncryption: "AES-256",
+		ChannelEncryption:        "AES-256-GCM",
+	}
+	expectedParameters := fileServiceProperties{
+		Properties: &fileServicePropertiesProperties{
+			ProtocolSettings: &protocolSettings{
+				SMB: settings,
+			},
+		},
+	}
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+	}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().PutResource(gomock.Any(), testFileServicesResourceID, expectedParameters).Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	fileClient := getTestFileClient(armClient)
+	assert.NoError(t, fileClient.SetSMBSettings("rg", "account", settings))
+
+	assert.Error(t, fileClient.SetSMBSettings("rg", "account", nil))
+}
+
+func TestParseSMBSettings(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		parameters  map[string]string
+		expected    *SMBSettings
+		expectError bool
+	}{
+		{
+			desc:       "nil parameters should return nil settings",
+			parameters: nil,
+			expected:   nil,
+		},
+		{
+			desc:       "parameters without SMB settings should return nil settings",
+			parameters: map[string]string{"skuName": "Premium_LRS"},
+			expected:   nil,
+		},
+		{
+			desc: "SMB setting parameters should be matched case-insensitively",
+			parameters: map[string]string{
+				"smbVersions":                 "SMB3.0;SMB3.1.1",
+				"smbAuthenticationMethods":    "Kerberos",
+				"smbKerberosTicketEncryption": "AES-256",
+				"smbChannelEncryption":        "AES-256-GCM",
+				"skuName":                     "Premium_LRS",
+			},
+			expected: &SMBSettings{
+				Versions:                 "SMB3.0;SMB3.1.1",
+				AuthenticationMethods:    "Kerberos",
+				KerberosTicketEncryption: "AES-256",
+				ChannelEncryption:        "AES-256-GCM",
+			},
+		},
+		{
+			desc:       "a subset of the SMB setting parameters should be accepted",
+			parameters: map[string]string{"smbversions": "SMB3.1.1"},
+			expected:   &SMBSettings{Versions: "SMB3.1.1"},
+		},
+		{
+			desc:        "unknown smb-prefixed parameters should report an error",
+			parameters:  map[string]string{"smbEncryption": "AES-256-GCM"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		settings, err := ParseSMBSettings(test.parameters)
+		if test.expectError {
+			assert.Error(t, err, test.desc)
+			continue
+		}
+		assert.NoError(t, err, test.desc)
+		assert.Equal(t, test.expected, settings, test.desc)
+	}
+}
@@ -22,6 +22,11 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
 )
 
+// SMBSettingsAPIVersion is the API version used to manage the account-level
+// SMB security settings of file services. The settings are not available in
+// the storage SDK version the client is compiled against.
+const SMBSettingsAPIVersion = "2021-02-01"
+
 // Interface is the client interface for creating file shares, interface for test injection.
 // mockgen -source=$GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/clients/fileclient/interface.go -package=mockfileclient Interface > $GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/clients/fileclient/mockfileclient/interface.go
 type Interface interface {
@@ -29,4 +34,6 @@ type Interface interface {
 	DeleteFileShare(resourceGroupName, accountName, name string) error
 	ResizeFileShare(resourceGroupName, accountName, name string, sizeGiB int) error
 	GetFileShare(resourceGroupName, accountName, name string) (storage.FileShare, error)
+	GetSMBSettings(resourceGroupName, accountName string) (*SMBSettings, error)
+	SetSMBSettings(resourceGroupName, accountName string, settings *SMBSettings) error
 }
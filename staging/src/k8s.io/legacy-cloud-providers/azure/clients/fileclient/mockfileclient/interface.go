@@ -104,3 +104,32 @@ func (mr *MockInterfaceMockRecorder) GetFileShare(resourceGroupName, accountName
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileShare", reflect.TypeOf((*MockInterface)(nil).GetFileShare), resourceGroupName, accountName, name)
 }
+
+// GetSMBSettings mocks base method
+func (m *MockInterface) GetSMBSettings(resourceGroupName, accountName string) (*fileclient.SMBSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSMBSettings", resourceGroupName, accountName)
+	ret0, _ := ret[0].(*fileclient.SMBSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSMBSettings indicates an expected call of GetSMBSettings
+func (mr *MockInterfaceMockRecorder) GetSMBSettings(resourceGroupName, accountName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSMBSettings", reflect.TypeOf((*MockInterface)(nil).GetSMBSettings), resourceGroupName, accountName)
+}
+
+// SetSMBSettings mocks base method
+func (m *MockInterface) SetSMBSettings(resourceGroupName, accountName string, settings *fileclient.SMBSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSMBSettings", resourceGroupName, accountName, settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSMBSettings indicates an expected call of SetSMBSettings
+func (mr *MockInterfaceMockRecorder) SetSMBSettings(resourceGroupName, accountName, settings interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSMBSettings", reflect.TypeOf((*MockInterface)(nil).SetSMBSettings), resourceGroupName, accountName, settings)
+}
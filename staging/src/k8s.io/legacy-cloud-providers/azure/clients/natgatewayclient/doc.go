@@ -1,7 +1,7 @@
 // +build !providerless
 
 /*
-Copyright 2020 The Kubernetes Authors.
+Copyright 2021 The Kubernetes Authors.
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -16,5 +16,5 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package mockvmsets implements the mock client for VMSet.
-package mockvmsets // import "k8s.io/legacy-cloud-providers/azure/mockvmsets"
+// Package natgatewayclient implements the client for NatGateway.
+package natgatewayclient // import "k8s.io/legacy-cloud-providers/azure/clients/natgatewayclient"
@@ -0,0 +1,45 @@
+// +build !providerless
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usageclient
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-12-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2019-06-01/network"
+	"k8s.io/legacy-cloud-providers/azure/retry"
+)
+
+const (
+	// ComputeAPIVersion is the API version for compute usages.
+	ComputeAPIVersion = "2019-07-01"
+	// NetworkAPIVersion is the API version for network usages.
+	NetworkAPIVersion = "2019-06-01"
+)
+
+// Interface is the client interface for compute and network usages.
+// Don't forget to run the following command to generate the mock client:
+// mockgen -source=$GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/clients/usageclient/interface.go -package=mockusageclient Interface > $GOPATH/src/k8s.io/kubernetes/staging/src/k8s.io/legacy-cloud-providers/azure/clients/usageclient/mockusageclient/interface.go
+type Interface interface {
+	// ListComputeUsages gets the compute usages and limits of the location.
+	ListComputeUsages(ctx context.Context, location string) (result []compute.Usage, rerr *retry.Error)
+
+	// ListNetworkUsages gets the network usages and limits of the location.
+	ListNetworkUsages(ctx context.Context, location string) (result []network.Usage, rerr *retry.Error)
+}
@@ -40,6 +40,14 @@ type Interface interface {
 	// List gets a list of VirtualMachines in the resourceGroupName.
 	List(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachine, *retry.Error)
 
+	// ListVmssFlexVMsWithoutInstanceView gets a list of VirtualMachines in the VMSS with flexible
+	// orchestration mode, excluding the instance views.
+	ListVmssFlexVMsWithoutInstanceView(ctx context.Context, vmssFlexID string) ([]compute.VirtualMachine, *retry.Error)
+
+	// ListVmssFlexVMsWithOnlyInstanceView gets a list of VirtualMachines in the VMSS with flexible
+	// orchestration mode, with only the instance views.
+	ListVmssFlexVMsWithOnlyInstanceView(ctx context.Context, vmssFlexID string) ([]compute.VirtualMachine, *retry.Error)
+
 	// CreateOrUpdate creates or updates a VirtualMachine.
 	CreateOrUpdate(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachine, source string) *retry.Error
 
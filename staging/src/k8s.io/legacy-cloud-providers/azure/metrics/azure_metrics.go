@@ -38,8 +38,10 @@ var (
 		"source",          // Operation source(optional)
 	}
 
-	apiMetrics       = registerAPIMetrics(metricLabels...)
-	operationMetrics = registerOperationMetrics(metricLabels...)
+	apiMetrics           = registerAPIMetrics(metricLabels...)
+	operationMetrics     = registerOperationMetrics(metricLabels...)
+	configReloadMetrics  = registerConfigReloadMetrics()
+	responseCacheMetrics = registerResponseCacheMetrics()
 )
 
 // apiCallMetrics is the metrics measuring the performance of a single API call
@@ -58,6 +60,18 @@ type operationCallMetrics struct {
 	operationFailureCount *metrics.CounterVec
 }
 
+// responseCacheCallMetrics is the metrics measuring the ARM response cache.
+type responseCacheCallMetrics struct {
+	requestCount  *metrics.CounterVec
+	evictionCount *metrics.Counter
+}
+
+// configReloadCallMetrics is the metrics measuring cloud provider config reloads.
+type configReloadCallMetrics struct {
+	reloadCount         *metrics.CounterVec
+	lastReloadTimestamp *metrics.Gauge
+}
+
 // MetricContext indicates the context for Azure client metrics.
 type MetricContext struct {
 	start      time.Time
@@ -107,6 +121,30 @@ func (mc *MetricContext) CountFailedOperation() {
 	operationMetrics.operationFailureCount.WithLabelValues(mc.attributes...).Inc()
 }
 
+// ObserveConfigReload records the outcome of one cloud provider config reload.
+func ObserveConfigReload(succeeded bool) {
+	status := "succeeded"
+	if !succeeded {
+		status = "failed"
+	}
+	configReloadMetrics.reloadCount.WithLabelValues(status).Inc()
+	if succeeded {
+		configReloadMetrics.lastReloadTimestamp.SetToCurrentTime()
+	}
+}
+
+// ObserveResponseCacheRequest records the outcome of one GET request going
+// through the ARM response cache: "hit" when ARM answered 304 Not Modified and
+// the cached payload was served, "miss" when a full payload was fetched.
+func ObserveResponseCacheRequest(status string) {
+	responseCacheMetrics.requestCount.WithLabelValues(status).Inc()
+}
+
+// ObserveResponseCacheEviction records the eviction of one ARM response cache entry.
+func ObserveResponseCacheEviction() {
+	responseCacheMetrics.evictionCount.Inc()
+}
+
 // registerAPIMetrics registers the API metrics.
 func registerAPIMetrics(attributes ...string) *apiCallMetrics {
 	metrics := &apiCallMetrics{
@@ -186,3 +224,59 @@ func registerOperationMetrics(attributes ...string) *operationCallMetrics {
 
 	return metrics
 }
+
+// registerResponseCacheMetrics registers the ARM response cache metrics.
+func registerResponseCacheMetrics() *responseCacheCallMetrics {
+	metrics := &responseCacheCallMetrics{
+		requestCount: metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Namespace:      azureMetricsNamespace,
+				Name:           "armclient_response_cache_requests",
+				Help:           "Number of GET requests going through the ARM response cache, partitioned by status",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"status"},
+		),
+		evictionCount: metrics.NewCounter(
+			&metrics.CounterOpts{
+				Namespace:      azureMetricsNamespace,
+				Name:           "armclient_response_cache_evictions",
+				Help:           "Number of entries evicted from the ARM response cache",
+				StabilityLevel: metrics.ALPHA,
+			},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.requestCount)
+	legacyregistry.MustRegister(metrics.evictionCount)
+
+	return metrics
+}
+
+// registerConfigReloadMetrics registers the config reload metrics.
+func registerConfigReloadMetrics() *configReloadCallMetrics {
+	metrics := &configReloadCallMetrics{
+		reloadCount: metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Namespace:      azureMetricsNamespace,
+				Name:           "config_reload_count",
+				Help:           "Number of cloud provider config reloads, partitioned by status",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"status"},
+		),
+		lastReloadTimestamp: metrics.NewGauge(
+			&metrics.GaugeOpts{
+				Namespace:      azureMetricsNamespace,
+				Name:           "config_last_reload_time_seconds",
+				Help:           "Unix timestamp of the last successful cloud provider config reload",
+				StabilityLevel: metrics.ALPHA,
+			},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.reloadCount)
+	legacyregistry.MustRegister(metrics.lastReloadTimestamp)
+
+	return metrics
+}
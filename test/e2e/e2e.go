@@ -126,6 +126,12 @@ func RunE2ETests(t *testing.T) {
 		r = append(r, e2ereporters.NewDetailsReporterFile(framework.TestContext.SpecSummaryOutput))
 	}
 
+	// The ConformanceReporter exports the outcome of every conformance spec in a
+	// machine-readable schema for certification tooling.
+	if len(framework.TestContext.ConformanceResultsFile) > 0 {
+		r = append(r, e2ereporters.NewConformanceReporterFile(framework.TestContext.ConformanceResultsFile))
+	}
+
 	klog.Infof("Starting e2e run %q on Ginkgo node %d", framework.RunID, config.GinkgoConfig.ParallelNode)
 	ginkgo.RunSpecsWithDefaultAndCustomReporters(t, "Kubernetes e2e suite", r)
 }
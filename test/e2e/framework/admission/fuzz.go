@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admission contains helpers for testing admission webhooks,
+// in particular a structured object mutation fuzzer which makes it cheap to
+// build negative-path coverage for webhook validation rules.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Mutation is a single structured change to an object.
+type Mutation struct {
+	// Name describes the change, e.g. `set spec.replicas=-1`.
+	Name string
+	// Apply performs the change. It is handed a deep copy of the base
+	// object, so mutations never interfere with each other.
+	Apply func(obj *unstructured.Unstructured)
+}
+
+// Result records how the API server responded to one mutation.
+type Result struct {
+	// Mutation is the name of the mutation that was submitted.
+	Mutation string
+	// Rejected is true when the create request failed, i.e. when admission
+	// (or schema validation) refused the mutated object.
+	Rejected bool
+	// Message is the error message for rejected mutations.
+	Message string
+}
+
+// fieldPath addresses one leaf in an unstructured object. String elements are
+// map keys, int elements are slice indexes.
+type fieldPath []interface{}
+
+func (p fieldPath) String() string {
+	var b strings.Builder
+	for _, e := range p {
+		switch v := e.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(v)
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		}
+	}
+	return b.String()
+}
+
+// skippedFields are not worth mutating: they are either immutable routing
+// information or read-only fields the API server resets anyway.
+var skippedFields = map[string]bool{
+	"apiVersion":                 true,
+	"kind":                       true,
+	"status":                     true,
+	"metadata.creationTimestamp": true,
+	"metadata.generation":        true,
+	"metadata.managedFields":     true,
+	"metadata.resourceVersion":   true,
+	"metadata.selfLink":          true,
+	"metadata.uid":               true,
+}
+
+// FuzzObject generates mutations for the leaf fields present in the object.
+// Mutations respect the schema in that a mutated field keeps its JSON type;
+// in addition one mutation per map field removes the field entirely. The
+// same seed always produces the same mutations. When max is positive, a
+// deterministic subset of at most max mutations is returned.
+func FuzzObject(obj *unstructured.Unstructured, seed int64, max int) []Mutation {
+	var paths []fieldPath
+	collectLeafPaths(obj.Object, nil, &paths)
+	sort.Slice(paths, func(i, j int) bool { return paths[i].String() < paths[j].String() })
+
+	rng := rand.New(rand.NewSource(seed))
+	var mutations []Mutation
+	for _, path := range paths {
+		if skippedFields[path.String()] || skippedFields[strings.SplitN(path.String(), ".", 2)[0]] {
+			continue
+		}
+		value, ok := resolvePath(obj.Object, path)
+		if !ok {
+			continue
+		}
+		for _, mutated := range mutatedValues(value, rng) {
+			path := path
+			mutated := mutated
+			mutations = append(mutations, Mutation{
+				Name: fmt.Sprintf("set %s=%v", path, mutated),
+				Apply: func(obj *unstructured.Unstructured) {
+					setPath(obj.Object, path, mutated)
+				},
+			})
+		}
+		// Removal only works for map keys; slice elements cannot be
+		// spliced out without touching their parent.
+		if _, isKey := path[len(path)-1].(string); isKey {
+			path := path
+			mutations = append(mutations, Mutation{
+				Name: fmt.Sprintf("remove %s", path),
+				Apply: func(obj *unstructured.Unstructured) {
+					removePath(obj.Object, path)
+				},
+			})
+		}
+	}
+
+	if max > 0 && len(mutations) > max {
+		rng.Shuffle(len(mutations), func(i, j int) {
+			mutations[i], mutations[j] = mutations[j], mutations[i]
+		})
+		mutations = mutations[:max]
+		sort.Slice(mutations, func(i, j int) bool { return mutations[i].Name < mutations[j].Name })
+	}
+	return mutations
+}
+
+// SubmitMutations applies every mutation to a copy of the base object and
+// submits it as a dry-run create, so nothing is persisted but the full
+// admission chain runs. It returns one result per mutation recording whether
+// the mutated object was rejected.
+func SubmitMutations(c dynamic.Interface, gvr schema.GroupVersionResource, base *unstructured.Unstructured, mutations []Mutation) []Result {
+	results := make([]Result, 0, len(mutations))
+	for _, m := range mutations {
+		obj := base.DeepCopy()
+		m.Apply(obj)
+		_, err := c.Resource(gvr).Namespace(obj.GetNamespace()).Create(context.TODO(), obj, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+		result := Result{Mutation: m.Name}
+		if err != nil {
+			result.Rejected = true
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Rejected filters the results down to the mutations admission rejected.
+func Rejected(results []Result) []Result {
+	var rejected []Result
+	for _, r := range results {
+		if r.Rejected {
+			rejected = append(rejected, r)
+		}
+	}
+	return rejected
+}
+
+// collectLeafPaths gathers the paths of all scalar leaves under v.
+func collectLeafPaths(v interface{}, prefix fieldPath, out *[]fieldPath) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			childPath := append(append(fieldPath{}, prefix...), key)
+			collectLeafPaths(child, childPath, out)
+		}
+	case []interface{}:
+		for i, child := range value {
+			childPath := append(append(fieldPath{}, prefix...), i)
+			collectLeafPaths(child, childPath, out)
+		}
+	case nil:
+		// Null leaves carry no type information to mutate within.
+	default:
+		*out = append(*out, prefix)
+	}
+}
+
+// resolvePath returns the value at the given path.
+func resolvePath(root interface{}, path fieldPath) (interface{}, bool) {
+	v := root
+	for _, e := range path {
+		switch key := e.(type) {
+		case string:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[key]; !ok {
+				return nil, false
+			}
+		case int:
+			s, ok := v.([]interface{})
+			if !ok || key >= len(s) {
+				return nil, false
+			}
+			v = s[key]
+		}
+	}
+	return v, true
+}
+
+// setPath replaces the value at the given path.
+func setPath(root map[string]interface{}, path fieldPath, value interface{}) {
+	parent, ok := resolvePath(root, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	switch last := path[len(path)-1].(type) {
+	case string:
+		if m, ok := parent.(map[string]interface{}); ok {
+			m[last] = value
+		}
+	case int:
+		if s, ok := parent.([]interface{}); ok && last < len(s) {
+			s[last] = value
+		}
+	}
+}
+
+// removePath deletes the map key at the given path.
+func removePath(root map[string]interface{}, path fieldPath) {
+	parent, ok := resolvePath(root, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	if last, ok := path[len(path)-1].(string); ok {
+		if m, ok := parent.(map[string]interface{}); ok {
+			delete(m, last)
+		}
+	}
+}
+
+// mutatedValues produces replacement values of the same JSON type as v.
+func mutatedValues(v interface{}, rng *rand.Rand) []interface{} {
+	switch value := v.(type) {
+	case string:
+		return []interface{}{fmt.Sprintf("%s-fuzz%d", value, rng.Intn(1000)), ""}
+	case bool:
+		return []interface{}{!value}
+	case int64:
+		return []interface{}{int64(-1), value + 1 + rng.Int63n(1000)}
+	case float64:
+		return []interface{}{-value - 1, value + float64(rng.Intn(1000))}
+	default:
+		return nil
+	}
+}
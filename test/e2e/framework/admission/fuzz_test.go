@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func fuzzTestObject() *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "webhook-target",
+				"namespace": "default",
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"paused":   false,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "app",
+								"image": "registry.example.com/app:v1",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func mutationNames(mutations []Mutation) []string {
+	names := make([]string, 0, len(mutations))
+	for _, m := range mutations {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestFuzzObjectDeterminism(t *testing.T) {
+	first := mutationNames(FuzzObject(fuzzTestObject(), 42, 0))
+	second := mutationNames(FuzzObject(fuzzTestObject(), 42, 0))
+	if len(first) == 0 {
+		t.Fatalf("expected mutations to be generated")
+	}
+	if strings.Join(first, "\n") != strings.Join(second, "\n") {
+		t.Errorf("the same seed produced different mutations:\n%v\n%v", first, second)
+	}
+
+	other := mutationNames(FuzzObject(fuzzTestObject(), 7, 0))
+	if strings.Join(first, "\n") == strings.Join(other, "\n") {
+		t.Errorf("different seeds produced identical mutations")
+	}
+}
+
+func TestFuzzObjectRespectsSchema(t *testing.T) {
+	obj := fuzzTestObject()
+	for _, m := range FuzzObject(obj, 1, 0) {
+		mutated := obj.DeepCopy()
+		m.Apply(mutated)
+
+		// Immutable routing fields must never be touched.
+		if mutated.GetAPIVersion() != obj.GetAPIVersion() || mutated.GetKind() != obj.GetKind() {
+			t.Errorf("mutation %q changed apiVersion/kind", m.Name)
+		}
+
+		// Fields that survive the mutation must keep their JSON type.
+		if replicas, ok, _ := unstructured.NestedFieldNoCopy(mutated.Object, "spec", "replicas"); ok {
+			if _, isInt := replicas.(int64); !isInt {
+				t.Errorf("mutation %q changed the type of spec.replicas: %T", m.Name, replicas)
+			}
+		}
+		if image, ok, _ := unstructured.NestedFieldNoCopy(mutated.Object, "spec", "template", "spec", "containers"); ok {
+			containers := image.([]interface{})
+			if len(containers) != 1 {
+				t.Errorf("mutation %q changed the container list length", m.Name)
+			}
+		}
+	}
+}
+
+func TestFuzzObjectMax(t *testing.T) {
+	all := FuzzObject(fuzzTestObject(), 3, 0)
+	if len(all) < 5 {
+		t.Fatalf("expected more than 5 mutations for the test object, got %d", len(all))
+	}
+	limited := FuzzObject(fuzzTestObject(), 3, 5)
+	if len(limited) != 5 {
+		t.Errorf("expected 5 mutations, got %d", len(limited))
+	}
+	again := FuzzObject(fuzzTestObject(), 3, 5)
+	if strings.Join(mutationNames(limited), "\n") != strings.Join(mutationNames(again), "\n") {
+		t.Errorf("limited mutation subset is not deterministic")
+	}
+}
+
+func TestSubmitMutations(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	scheme := runtime.NewScheme()
+	client := dynamicfake.NewSimpleDynamicClient(scheme)
+
+	// Simulate a validating webhook which refuses negative replica counts.
+	client.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		obj := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		if replicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); ok && replicas < 0 {
+			return true, nil, fmt.Errorf("admission webhook denied the request: replicas must not be negative")
+		}
+		return true, obj, nil
+	})
+
+	mutations := []Mutation{
+		{
+			Name: "set spec.replicas=-1",
+			Apply: func(obj *unstructured.Unstructured) {
+				unstructured.SetNestedField(obj.Object, int64(-1), "spec", "replicas")
+			},
+		},
+		{
+			Name: "set spec.replicas=5",
+			Apply: func(obj *unstructured.Unstructured) {
+				unstructured.SetNestedField(obj.Object, int64(5), "spec", "replicas")
+			},
+		},
+	}
+
+	results := SubmitMutations(client, gvr, fuzzTestObject(), mutations)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].Rejected || !strings.Contains(results[0].Message, "must not be negative") {
+		t.Errorf("expected the negative replica mutation to be rejected, got %+v", results[0])
+	}
+	if results[1].Rejected {
+		t.Errorf("expected the valid mutation to be accepted, got %+v", results[1])
+	}
+
+	rejected := Rejected(results)
+	if len(rejected) != 1 || rejected[0].Mutation != "set spec.replicas=-1" {
+		t.Errorf("unexpected rejected set: %+v", rejected)
+	}
+}
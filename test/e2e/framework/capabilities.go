@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"sort"
+	"sync"
+)
+
+// ProviderCapability is a named piece of cloud provider functionality that
+// tests may depend on. Providers declare the capabilities they support via
+// RegisterProviderCapabilities, and tests skip themselves through
+// skipper.RequireProviderCapability instead of comparing provider names.
+type ProviderCapability string
+
+const (
+	// CapabilityLoadBalancerSourceRanges indicates that load balancers honor
+	// Service.Spec.LoadBalancerSourceRanges.
+	CapabilityLoadBalancerSourceRanges ProviderCapability = "LoadBalancerSourceRanges"
+	// CapabilityUDPLoadBalancer indicates that services with UDP ports can be
+	// exposed through a load balancer.
+	CapabilityUDPLoadBalancer ProviderCapability = "UDPLoadBalancer"
+	// CapabilityInternalLoadBalancer indicates that load balancers can be
+	// restricted to the cluster's internal network via annotations.
+	CapabilityInternalLoadBalancer ProviderCapability = "InternalLoadBalancer"
+	// CapabilityInstanceMetadata indicates that nodes can reach a provider
+	// instance metadata service.
+	CapabilityInstanceMetadata ProviderCapability = "InstanceMetadata"
+	// CapabilityZoneLabels indicates that nodes carry the well-known zone and
+	// region topology labels.
+	CapabilityZoneLabels ProviderCapability = "ZoneLabels"
+)
+
+var (
+	capabilitiesMutex sync.Mutex
+	// capabilities maps a provider name to the set of capabilities it has
+	// declared support for.
+	capabilities = make(map[string]map[ProviderCapability]bool)
+)
+
+// RegisterProviderCapabilities declares the capabilities supported by the
+// named provider. It is expected to be called during application init,
+// typically next to RegisterProvider, and may be called multiple times for
+// the same provider to extend the set.
+func RegisterProviderCapabilities(name string, caps ...ProviderCapability) {
+	capabilitiesMutex.Lock()
+	defer capabilitiesMutex.Unlock()
+	set, ok := capabilities[name]
+	if !ok {
+		set = make(map[ProviderCapability]bool)
+		capabilities[name] = set
+	}
+	for _, capability := range caps {
+		set[capability] = true
+	}
+}
+
+// ProviderHasCapability returns true if the provider under test has declared
+// support for the given capability.
+func ProviderHasCapability(capability ProviderCapability) bool {
+	capabilitiesMutex.Lock()
+	defer capabilitiesMutex.Unlock()
+	return capabilities[TestContext.Provider][capability]
+}
+
+// ProvidersWithCapability returns the sorted names of all providers that have
+// declared support for the given capability, for use in skip messages.
+func ProvidersWithCapability(capability ProviderCapability) []string {
+	capabilitiesMutex.Lock()
+	defer capabilitiesMutex.Unlock()
+	var names []string
+	for name, set := range capabilities {
+		if set[capability] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/common/model"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// Metrics and labels the CPU throttling report is computed from. The metrics
+// are exported by cadvisor through the kubelet's /metrics/cadvisor endpoint.
+const (
+	cadvisorCPUPeriodsMetric          = "container_cpu_cfs_periods_total"
+	cadvisorCPUThrottledPeriodsMetric = "container_cpu_cfs_throttled_periods_total"
+
+	namespaceLabel = model.LabelName("namespace")
+	podLabel       = model.LabelName("pod")
+	containerLabel = model.LabelName("container")
+)
+
+// DefaultCPUThrottlingThreshold is the fraction of cfs periods a container may
+// spend throttled before the report flags it. Control-plane components that
+// exceed it are usually running with CPU limits that are too tight for the
+// load the suite generates.
+const DefaultCPUThrottlingThreshold = 0.25
+
+// CadvisorMetrics is metrics exported by cadvisor for the containers on a
+// single node.
+type CadvisorMetrics testutil.Metrics
+
+// Equal returns true if all metrics are the same as the arguments.
+func (m *CadvisorMetrics) Equal(o CadvisorMetrics) bool {
+	return (*testutil.Metrics)(m).Equal(testutil.Metrics(o))
+}
+
+func newCadvisorMetrics() CadvisorMetrics {
+	result := testutil.NewMetrics()
+	return CadvisorMetrics(result)
+}
+
+func parseCadvisorMetrics(data string) (CadvisorMetrics, error) {
+	result := newCadvisorMetrics()
+	if err := testutil.ParseMetrics(data, (*testutil.Metrics)(&result)); err != nil {
+		return CadvisorMetrics{}, err
+	}
+	return result, nil
+}
+
+// ContainerCPUThrottling is the CPU throttling of a single container.
+type ContainerCPUThrottling struct {
+	// Node is the node the container runs on.
+	Node string
+	// Namespace, Pod and Container identify the container.
+	Namespace string
+	Pod       string
+	Container string
+	// Periods is the number of elapsed cfs enforcement intervals.
+	Periods float64
+	// ThrottledPeriods is the number of intervals in which the container hit
+	// its CPU limit.
+	ThrottledPeriods float64
+	// ThrottlingRatio is ThrottledPeriods divided by Periods, in [0, 1].
+	ThrottlingRatio float64
+	// Throttled reports whether ThrottlingRatio is above the report's
+	// threshold.
+	Throttled bool
+}
+
+// CPUThrottlingReport lists the CPU throttling of the containers in one
+// namespace, worst first.
+type CPUThrottlingReport struct {
+	Threshold float64
+	Entries   []ContainerCPUThrottling
+}
+
+// ComputeCPUThrottlingReport condenses grabbed cadvisor metrics, keyed by node
+// name, into a CPU throttling report for the containers in the given
+// namespace. Pass metav1.NamespaceSystem and DefaultCPUThrottlingThreshold to
+// check the control-plane pods. Containers without cfs periods, for example
+// because they run without a CPU limit, are left out.
+func ComputeCPUThrottlingReport(metricsPerNode map[string]CadvisorMetrics, namespace string, threshold float64) *CPUThrottlingReport {
+	report := &CPUThrottlingReport{Threshold: threshold}
+	for node, grabbed := range metricsPerNode {
+		periods := sumSamplesByContainer(grabbed[cadvisorCPUPeriodsMetric], namespace)
+		throttled := sumSamplesByContainer(grabbed[cadvisorCPUThrottledPeriodsMetric], namespace)
+		for key, total := range periods {
+			if total <= 0 {
+				continue
+			}
+			entry := ContainerCPUThrottling{
+				Node:             node,
+				Namespace:        namespace,
+				Pod:              key.pod,
+				Container:        key.container,
+				Periods:          total,
+				ThrottledPeriods: throttled[key],
+			}
+			entry.ThrottlingRatio = entry.ThrottledPeriods / entry.Periods
+			entry.Throttled = entry.ThrottlingRatio > threshold
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		if report.Entries[i].ThrottlingRatio != report.Entries[j].ThrottlingRatio {
+			return report.Entries[i].ThrottlingRatio > report.Entries[j].ThrottlingRatio
+		}
+		if report.Entries[i].Pod != report.Entries[j].Pod {
+			return report.Entries[i].Pod < report.Entries[j].Pod
+		}
+		return report.Entries[i].Container < report.Entries[j].Container
+	})
+	return report
+}
+
+type containerKey struct {
+	pod       string
+	container string
+}
+
+// sumSamplesByContainer adds up the values of all samples in the given
+// namespace per container. Samples for the pod sandbox and for cgroup
+// aggregates carry an empty container label and are skipped.
+func sumSamplesByContainer(samples model.Samples, namespace string) map[containerKey]float64 {
+	sums := map[containerKey]float64{}
+	for _, sample := range samples {
+		if string(sample.Metric[namespaceLabel]) != namespace {
+			continue
+		}
+		key := containerKey{
+			pod:       string(sample.Metric[podLabel]),
+			container: string(sample.Metric[containerLabel]),
+		}
+		if key.pod == "" || key.container == "" {
+			continue
+		}
+		sums[key] += float64(sample.Value)
+	}
+	return sums
+}
+
+// ThrottledContainers returns the namespace/pod/container names of the
+// containers whose throttling ratio is above the threshold.
+func (r *CPUThrottlingReport) ThrottledContainers() []string {
+	var throttled []string
+	for _, entry := range r.Entries {
+		if entry.Throttled {
+			throttled = append(throttled, fmt.Sprintf("%s/%s/%s", entry.Namespace, entry.Pod, entry.Container))
+		}
+	}
+	return throttled
+}
+
+// PrintHumanReadable returns the CPU throttling report as one line per
+// container.
+func (r *CPUThrottlingReport) PrintHumanReadable() string {
+	buf := bytes.Buffer{}
+	for _, entry := range r.Entries {
+		buf.WriteString(fmt.Sprintf("%v/%v/%v on %v: throttled in %v of %v period(s) (ratio %.4f, threshold %.4f, throttled %v)\n",
+			entry.Namespace, entry.Pod, entry.Container, entry.Node, entry.ThrottledPeriods, entry.Periods, entry.ThrottlingRatio, r.Threshold, entry.Throttled))
+	}
+	return buf.String()
+}
+
+// PrintJSON returns the CPU throttling report with JSON format.
+func (r *CPUThrottlingReport) PrintJSON() string {
+	return PrettyPrintJSON(r)
+}
+
+// SummaryKind returns the summary of the CPU throttling report.
+func (r *CPUThrottlingReport) SummaryKind() string {
+	return "CPUThrottlingReport"
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeCPUThrottlingReport(t *testing.T) {
+	data := `# HELP container_cpu_cfs_periods_total Number of elapsed enforcement period intervals.
+# TYPE container_cpu_cfs_periods_total counter
+container_cpu_cfs_periods_total{container="kube-apiserver",namespace="kube-system",pod="kube-apiserver-master"} 1000
+container_cpu_cfs_periods_total{container="kube-scheduler",namespace="kube-system",pod="kube-scheduler-master"} 500
+container_cpu_cfs_periods_total{container="",namespace="kube-system",pod="kube-apiserver-master"} 1000
+container_cpu_cfs_periods_total{container="app",namespace="default",pod="workload"} 100
+# HELP container_cpu_cfs_throttled_periods_total Number of throttled period intervals.
+# TYPE container_cpu_cfs_throttled_periods_total counter
+container_cpu_cfs_throttled_periods_total{container="kube-apiserver",namespace="kube-system",pod="kube-apiserver-master"} 400
+container_cpu_cfs_throttled_periods_total{container="kube-scheduler",namespace="kube-system",pod="kube-scheduler-master"} 50
+container_cpu_cfs_throttled_periods_total{container="app",namespace="default",pod="workload"} 100
+`
+	metrics, err := parseCadvisorMetrics(data)
+	if err != nil {
+		t.Fatalf("error parsing metrics: %v", err)
+	}
+	report := ComputeCPUThrottlingReport(map[string]CadvisorMetrics{"master": metrics}, "kube-system", DefaultCPUThrottlingThreshold)
+
+	// The entry for the default namespace and the pod sandbox sample with an
+	// empty container label must not show up.
+	if len(report.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", report.Entries)
+	}
+	apiServer := report.Entries[0]
+	if apiServer.Container != "kube-apiserver" || apiServer.Node != "master" {
+		t.Errorf("expected the kube-apiserver entry on master first, got %+v", apiServer)
+	}
+	if apiServer.ThrottlingRatio != 0.4 || !apiServer.Throttled {
+		t.Errorf("expected kube-apiserver to be throttled with ratio 0.4, got %+v", apiServer)
+	}
+	scheduler := report.Entries[1]
+	if scheduler.ThrottlingRatio != 0.1 || scheduler.Throttled {
+		t.Errorf("expected kube-scheduler to be below the threshold with ratio 0.1, got %+v", scheduler)
+	}
+
+	throttled := report.ThrottledContainers()
+	if expected := []string{"kube-system/kube-apiserver-master/kube-apiserver"}; !reflect.DeepEqual(throttled, expected) {
+		t.Errorf("expected throttled containers %v, got %v", expected, throttled)
+	}
+	if report.PrintHumanReadable() == "" {
+		t.Errorf("expected a non-empty human readable report")
+	}
+}
+
+func TestComputeCPUThrottlingReportWithoutPeriods(t *testing.T) {
+	report := ComputeCPUThrottlingReport(map[string]CadvisorMetrics{"master": newCadvisorMetrics()}, "kube-system", DefaultCPUThrottlingThreshold)
+	if len(report.Entries) != 0 {
+		t.Errorf("expected no entries, got %+v", report.Entries)
+	}
+	if throttled := report.ThrottledContainers(); len(throttled) != 0 {
+		t.Errorf("expected no throttled containers, got %v", throttled)
+	}
+}
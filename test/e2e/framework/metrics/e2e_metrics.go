@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"k8s.io/component-base/metrics/testutil"
 	e2elog "k8s.io/kubernetes/test/e2e/framework/log"
@@ -85,6 +86,17 @@ func (m *ComponentCollection) PrintHumanReadable() string {
 			}
 		}
 	}
+	if len((*m).ScrapeDurations) > 0 {
+		components := make([]string, 0, len((*m).ScrapeDurations))
+		for component := range (*m).ScrapeDurations {
+			components = append(components, component)
+		}
+		sort.Strings(components)
+		buf.WriteString("Scrape durations:\n")
+		for _, component := range components {
+			buf.WriteString(fmt.Sprintf("\t%v: %v\n", component, (*m).ScrapeDurations[component]))
+		}
+	}
 	return buf.String()
 }
 
@@ -108,6 +120,9 @@ func PrettyPrintJSON(metrics interface{}) string {
 // PrintJSON returns e2e metrics with JSON format.
 func (m *ComponentCollection) PrintJSON() string {
 	m.filterMetrics()
+	if redactionRules != nil {
+		m.RedactLabels(redactionRules)
+	}
 	return PrettyPrintJSON(m)
 }
 
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Metrics and labels the error budget report is computed from.
+const (
+	apiServerRequestsMetric              = "apiserver_request_total"
+	schedulerAttemptsMetric              = "scheduler_schedule_attempts_total"
+	kubeletRuntimeOperationsMetric       = "kubelet_runtime_operations_total"
+	kubeletRuntimeOperationsErrorsMetric = "kubelet_runtime_operations_errors_total"
+
+	codeLabel   = model.LabelName("code")
+	resultLabel = model.LabelName("result")
+
+	schedulerErrorResult = "error"
+)
+
+// Components the error budget report covers.
+const (
+	APIServerErrorBudget = "apiserver"
+	SchedulerErrorBudget = "scheduler"
+	KubeletErrorBudget   = "kubelet"
+)
+
+// DefaultErrorBudgets is the fraction of failed operations each component is
+// allowed before the report flags it as exceeded.
+var DefaultErrorBudgets = map[string]float64{
+	APIServerErrorBudget: 0.01,
+	SchedulerErrorBudget: 0.01,
+	KubeletErrorBudget:   0.05,
+}
+
+// ErrorBudgetEntry is the error budget of a single component.
+type ErrorBudgetEntry struct {
+	// Component is the component the entry was computed for.
+	Component string
+	// Errors is the number of failed operations of the component.
+	Errors float64
+	// Total is the total number of operations of the component. A zero total
+	// means the component reported no samples; such entries never exceed
+	// their budget.
+	Total float64
+	// ErrorRatio is Errors divided by Total, in [0, 1].
+	ErrorRatio float64
+	// Budget is the allowed error ratio.
+	Budget float64
+	// Exceeded reports whether ErrorRatio is above Budget.
+	Exceeded bool
+}
+
+// ErrorBudgetReport combines the error budgets of the components sampled
+// during a suite run into a single triage summary.
+type ErrorBudgetReport struct {
+	Entries []ErrorBudgetEntry
+}
+
+// ComputeErrorBudgetReport condenses a grabbed Collection into an error
+// budget report. Budgets maps component names to their allowed error ratios;
+// pass DefaultErrorBudgets unless the job has its own thresholds. Components
+// without samples in the collection get a zero-total entry so that the report
+// always lists every covered component.
+func ComputeErrorBudgetReport(c Collection, budgets map[string]float64) *ErrorBudgetReport {
+	apiServerErrors := sumSamples(c.APIServerMetrics[apiServerRequestsMetric], func(metric model.Metric) bool {
+		return strings.HasPrefix(string(metric[codeLabel]), "5")
+	})
+	apiServerTotal := sumSamples(c.APIServerMetrics[apiServerRequestsMetric], nil)
+
+	schedulerErrors := sumSamples(c.SchedulerMetrics[schedulerAttemptsMetric], func(metric model.Metric) bool {
+		return string(metric[resultLabel]) == schedulerErrorResult
+	})
+	schedulerTotal := sumSamples(c.SchedulerMetrics[schedulerAttemptsMetric], nil)
+
+	var kubeletErrors, kubeletTotal float64
+	for _, grabbed := range c.KubeletMetrics {
+		kubeletErrors += sumSamples(grabbed[kubeletRuntimeOperationsErrorsMetric], nil)
+		kubeletTotal += sumSamples(grabbed[kubeletRuntimeOperationsMetric], nil)
+	}
+
+	return &ErrorBudgetReport{
+		Entries: []ErrorBudgetEntry{
+			newErrorBudgetEntry(APIServerErrorBudget, apiServerErrors, apiServerTotal, budgets[APIServerErrorBudget]),
+			newErrorBudgetEntry(SchedulerErrorBudget, schedulerErrors, schedulerTotal, budgets[SchedulerErrorBudget]),
+			newErrorBudgetEntry(KubeletErrorBudget, kubeletErrors, kubeletTotal, budgets[KubeletErrorBudget]),
+		},
+	}
+}
+
+func newErrorBudgetEntry(component string, errors, total, budget float64) ErrorBudgetEntry {
+	entry := ErrorBudgetEntry{
+		Component: component,
+		Errors:    errors,
+		Total:     total,
+		Budget:    budget,
+	}
+	if total > 0 {
+		entry.ErrorRatio = errors / total
+		entry.Exceeded = entry.ErrorRatio > budget
+	}
+	return entry
+}
+
+// sumSamples adds up the values of all samples matching the filter. A nil
+// filter matches every sample.
+func sumSamples(samples model.Samples, match func(model.Metric) bool) float64 {
+	var sum float64
+	for _, sample := range samples {
+		if match == nil || match(sample.Metric) {
+			sum += float64(sample.Value)
+		}
+	}
+	return sum
+}
+
+// ExceededComponents returns the components whose error ratio is above their
+// budget.
+func (r *ErrorBudgetReport) ExceededComponents() []string {
+	var exceeded []string
+	for _, entry := range r.Entries {
+		if entry.Exceeded {
+			exceeded = append(exceeded, entry.Component)
+		}
+	}
+	return exceeded
+}
+
+// PrintHumanReadable returns the error budget report as one line per
+// component.
+func (r *ErrorBudgetReport) PrintHumanReadable() string {
+	buf := bytes.Buffer{}
+	for _, entry := range r.Entries {
+		buf.WriteString(fmt.Sprintf("%v: %v of %v operation(s) failed (ratio %.4f, budget %.4f, exceeded %v)\n",
+			entry.Component, entry.Errors, entry.Total, entry.ErrorRatio, entry.Budget, entry.Exceeded))
+	}
+	return buf.String()
+}
+
+// PrintJSON returns the error budget report with JSON format.
+func (r *ErrorBudgetReport) PrintJSON() string {
+	return PrettyPrintJSON(r)
+}
+
+// SummaryKind returns the summary of the error budget report.
+func (r *ErrorBudgetReport) SummaryKind() string {
+	return "ErrorBudgetReport"
+}
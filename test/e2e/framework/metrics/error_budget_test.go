@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+const apiServerErrorBudgetFixture = `# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",resource="pods",verb="GET"} 900
+apiserver_request_total{code="404",resource="pods",verb="GET"} 80
+apiserver_request_total{code="500",resource="pods",verb="POST"} 15
+apiserver_request_total{code="503",resource="nodes",verb="GET"} 5
+`
+
+const schedulerErrorBudgetFixture = `# TYPE scheduler_schedule_attempts_total counter
+scheduler_schedule_attempts_total{profile="default-scheduler",result="scheduled"} 195
+scheduler_schedule_attempts_total{profile="default-scheduler",result="unschedulable"} 4
+scheduler_schedule_attempts_total{profile="default-scheduler",result="error"} 1
+`
+
+const kubeletErrorBudgetFixture = `# TYPE kubelet_runtime_operations_total counter
+kubelet_runtime_operations_total{operation_type="create_container"} 30
+kubelet_runtime_operations_total{operation_type="remove_container"} 20
+# TYPE kubelet_runtime_operations_errors_total counter
+kubelet_runtime_operations_errors_total{operation_type="create_container"} 2
+`
+
+func errorBudgetCollection(t *testing.T) Collection {
+	t.Helper()
+	apiServerMetrics, err := parseAPIServerMetrics(apiServerErrorBudgetFixture)
+	if err != nil {
+		t.Fatalf("failed to parse apiserver fixture: %v", err)
+	}
+	schedulerMetrics, err := parseSchedulerMetrics(schedulerErrorBudgetFixture)
+	if err != nil {
+		t.Fatalf("failed to parse scheduler fixture: %v", err)
+	}
+	kubeletMetrics, err := parseKubeletMetrics(kubeletErrorBudgetFixture)
+	if err != nil {
+		t.Fatalf("failed to parse kubelet fixture: %v", err)
+	}
+	return Collection{
+		APIServerMetrics: apiServerMetrics,
+		SchedulerMetrics: schedulerMetrics,
+		KubeletMetrics:   map[string]KubeletMetrics{"node1": kubeletMetrics},
+	}
+}
+
+func TestComputeErrorBudgetReport(t *testing.T) {
+	report := ComputeErrorBudgetReport(errorBudgetCollection(t), DefaultErrorBudgets)
+	if got, want := len(report.Entries), 3; got != want {
+		t.Fatalf("got %d entries, want %d", got, want)
+	}
+
+	entries := map[string]ErrorBudgetEntry{}
+	for _, entry := range report.Entries {
+		entries[entry.Component] = entry
+	}
+
+	apiServer := entries[APIServerErrorBudget]
+	if apiServer.Errors != 20 || apiServer.Total != 1000 {
+		t.Errorf("got apiserver errors %v of %v, want 20 of 1000", apiServer.Errors, apiServer.Total)
+	}
+	if apiServer.ErrorRatio != 0.02 {
+		t.Errorf("got apiserver error ratio %v, want 0.02", apiServer.ErrorRatio)
+	}
+	if !apiServer.Exceeded {
+		t.Error("expected the apiserver entry to exceed its budget")
+	}
+
+	scheduler := entries[SchedulerErrorBudget]
+	if scheduler.Errors != 1 || scheduler.Total != 200 {
+		t.Errorf("got scheduler errors %v of %v, want 1 of 200", scheduler.Errors, scheduler.Total)
+	}
+	if scheduler.Exceeded {
+		t.Error("expected the scheduler entry to stay within its budget")
+	}
+
+	kubelet := entries[KubeletErrorBudget]
+	if kubelet.Errors != 2 || kubelet.Total != 50 {
+		t.Errorf("got kubelet errors %v of %v, want 2 of 50", kubelet.Errors, kubelet.Total)
+	}
+	if kubelet.Exceeded {
+		t.Error("expected the kubelet entry to stay within its budget")
+	}
+
+	if got := report.ExceededComponents(); len(got) != 1 || got[0] != APIServerErrorBudget {
+		t.Errorf("got exceeded components %v, want [%s]", got, APIServerErrorBudget)
+	}
+}
+
+func TestComputeErrorBudgetReportEmptyCollection(t *testing.T) {
+	report := ComputeErrorBudgetReport(Collection{}, DefaultErrorBudgets)
+	if got, want := len(report.Entries), 3; got != want {
+		t.Fatalf("got %d entries, want %d", got, want)
+	}
+	for _, entry := range report.Entries {
+		if entry.Total != 0 {
+			t.Errorf("got total %v for component %s without samples, want 0", entry.Total, entry.Component)
+		}
+		if entry.Exceeded {
+			t.Errorf("component %s without samples must not exceed its budget", entry.Component)
+		}
+	}
+	if got := report.ExceededComponents(); len(got) != 0 {
+		t.Errorf("got exceeded components %v for an empty collection, want none", got)
+	}
+}
+
+func TestErrorBudgetReportPrinting(t *testing.T) {
+	report := ComputeErrorBudgetReport(errorBudgetCollection(t), DefaultErrorBudgets)
+
+	humanReadable := report.PrintHumanReadable()
+	for _, component := range []string{APIServerErrorBudget, SchedulerErrorBudget, KubeletErrorBudget} {
+		if !strings.Contains(humanReadable, component) {
+			t.Errorf("human readable report does not mention component %s:\n%s", component, humanReadable)
+		}
+	}
+
+	reportJSON := report.PrintJSON()
+	if !strings.Contains(reportJSON, `"Component": "apiserver"`) {
+		t.Errorf("JSON report does not contain the apiserver entry:\n%s", reportJSON)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/common/model"
+)
+
+// API Priority and Fairness metrics exposed by the API server.
+const (
+	requestConcurrencyLimitMetric  = "apiserver_flowcontrol_request_concurrency_limit"
+	requestConcurrencyInUseMetric  = "apiserver_flowcontrol_request_concurrency_in_use"
+	currentInqueueRequestsMetric   = "apiserver_flowcontrol_current_inqueue_requests"
+	currentExecutingRequestsMetric = "apiserver_flowcontrol_current_executing_requests"
+	rejectedRequestsMetric         = "apiserver_flowcontrol_rejected_requests_total"
+	dispatchedRequestsMetric       = "apiserver_flowcontrol_dispatched_requests_total"
+
+	priorityLevelLabel = model.LabelName("priority_level")
+)
+
+// PriorityLevelSummary summarizes the API Priority and Fairness (APF) metrics
+// of a single priority level.
+type PriorityLevelSummary struct {
+	PriorityLevel string
+	// ConcurrencyLimit is the nominal number of execution seats of the
+	// priority level.
+	ConcurrencyLimit int64
+	// SeatUtilization is the fraction of execution seats in use, in [0, 1].
+	// It is zero when the concurrency limit is not reported.
+	SeatUtilization float64
+	// QueuedRequests is the number of requests waiting in queues.
+	QueuedRequests int64
+	// ExecutingRequests is the number of requests currently executing.
+	ExecutingRequests int64
+	// RejectedRequests is the cumulative number of rejected requests, summed
+	// over all flow schemas and rejection reasons.
+	RejectedRequests int64
+	// DispatchedRequests is the cumulative number of dispatched requests,
+	// summed over all flow schemas.
+	DispatchedRequests int64
+}
+
+// APFMetricsSummary maps priority level names to their summaries.
+type APFMetricsSummary map[string]*PriorityLevelSummary
+
+// SummarizeAPFMetrics condenses grabbed API server metrics into
+// per-priority-level APF summaries.
+func SummarizeAPFMetrics(m APIServerMetrics) APFMetricsSummary {
+	summary := APFMetricsSummary{}
+	level := func(sample *model.Sample) *PriorityLevelSummary {
+		name := string(sample.Metric[priorityLevelLabel])
+		if summary[name] == nil {
+			summary[name] = &PriorityLevelSummary{PriorityLevel: name}
+		}
+		return summary[name]
+	}
+
+	for _, sample := range m[requestConcurrencyLimitMetric] {
+		level(sample).ConcurrencyLimit = int64(sample.Value)
+	}
+	for _, sample := range m[currentInqueueRequestsMetric] {
+		level(sample).QueuedRequests += int64(sample.Value)
+	}
+	for _, sample := range m[currentExecutingRequestsMetric] {
+		level(sample).ExecutingRequests += int64(sample.Value)
+	}
+	for _, sample := range m[rejectedRequestsMetric] {
+		level(sample).RejectedRequests += int64(sample.Value)
+	}
+	for _, sample := range m[dispatchedRequestsMetric] {
+		level(sample).DispatchedRequests += int64(sample.Value)
+	}
+	for _, sample := range m[requestConcurrencyInUseMetric] {
+		if pl := level(sample); pl.ConcurrencyLimit > 0 {
+			pl.SeatUtilization = float64(sample.Value) / float64(pl.ConcurrencyLimit)
+		}
+	}
+
+	return summary
+}
+
+// PriorityLevels returns the sorted names of the summarized priority levels.
+func (s APFMetricsSummary) PriorityLevels() []string {
+	levels := make([]string, 0, len(s))
+	for name := range s {
+		levels = append(levels, name)
+	}
+	sort.Strings(levels)
+	return levels
+}
+
+// VerifyNoRejectedRequests returns an error if any priority level rejected
+// requests.
+func (s APFMetricsSummary) VerifyNoRejectedRequests() error {
+	for _, name := range s.PriorityLevels() {
+		if s[name].RejectedRequests > 0 {
+			return fmt.Errorf("priority level %q rejected %d request(s)", name, s[name].RejectedRequests)
+		}
+	}
+	return nil
+}
+
+// VerifySeatUtilizationBelow returns an error if the seat utilization of the
+// given priority level is not below the bound.
+func (s APFMetricsSummary) VerifySeatUtilizationBelow(priorityLevel string, bound float64) error {
+	pl, ok := s[priorityLevel]
+	if !ok {
+		return fmt.Errorf("no metrics found for priority level %q", priorityLevel)
+	}
+	if pl.SeatUtilization >= bound {
+		return fmt.Errorf("priority level %q has seat utilization %v, expected below %v", priorityLevel, pl.SeatUtilization, bound)
+	}
+	return nil
+}
+
+// GrabAPFMetricsSummary grabs API server metrics and summarizes them per
+// priority level.
+func (g *Grabber) GrabAPFMetricsSummary() (APFMetricsSummary, error) {
+	metrics, err := g.GrabFromAPIServer()
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeAPFMetrics(metrics), nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+)
+
+const apfMetricsFixture = `# HELP apiserver_flowcontrol_request_concurrency_limit [ALPHA] Shared concurrency limit in the sample and water mark periods
+# TYPE apiserver_flowcontrol_request_concurrency_limit gauge
+apiserver_flowcontrol_request_concurrency_limit{priority_level="global-default"} 98
+apiserver_flowcontrol_request_concurrency_limit{priority_level="workload-low"} 245
+# TYPE apiserver_flowcontrol_request_concurrency_in_use gauge
+apiserver_flowcontrol_request_concurrency_in_use{flow_schema="global-default",priority_level="global-default"} 49
+# TYPE apiserver_flowcontrol_current_inqueue_requests gauge
+apiserver_flowcontrol_current_inqueue_requests{flow_schema="global-default",priority_level="global-default"} 3
+# TYPE apiserver_flowcontrol_current_executing_requests gauge
+apiserver_flowcontrol_current_executing_requests{flow_schema="global-default",priority_level="global-default"} 2
+# TYPE apiserver_flowcontrol_rejected_requests_total counter
+apiserver_flowcontrol_rejected_requests_total{flow_schema="global-default",priority_level="global-default",reason="queue-full"} 5
+apiserver_flowcontrol_rejected_requests_total{flow_schema="global-default",priority_level="global-default",reason="timeout"} 2
+# TYPE apiserver_flowcontrol_dispatched_requests_total counter
+apiserver_flowcontrol_dispatched_requests_total{flow_schema="global-default",priority_level="global-default"} 100
+apiserver_flowcontrol_dispatched_requests_total{flow_schema="service-accounts",priority_level="workload-low"} 40
+`
+
+func TestSummarizeAPFMetrics(t *testing.T) {
+	metrics, err := parseAPIServerMetrics(apfMetricsFixture)
+	if err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+
+	summary := SummarizeAPFMetrics(metrics)
+	if got, want := len(summary), 2; got != want {
+		t.Fatalf("got %d priority levels, want %d: %v", got, want, summary.PriorityLevels())
+	}
+
+	globalDefault := summary["global-default"]
+	if globalDefault == nil {
+		t.Fatal("missing summary for priority level global-default")
+	}
+	if globalDefault.ConcurrencyLimit != 98 {
+		t.Errorf("got concurrency limit %d, want 98", globalDefault.ConcurrencyLimit)
+	}
+	if globalDefault.SeatUtilization != 0.5 {
+		t.Errorf("got seat utilization %v, want 0.5", globalDefault.SeatUtilization)
+	}
+	if globalDefault.QueuedRequests != 3 {
+		t.Errorf("got %d queued requests, want 3", globalDefault.QueuedRequests)
+	}
+	if globalDefault.ExecutingRequests != 2 {
+		t.Errorf("got %d executing requests, want 2", globalDefault.ExecutingRequests)
+	}
+	if globalDefault.RejectedRequests != 7 {
+		t.Errorf("got %d rejected requests, want 7", globalDefault.RejectedRequests)
+	}
+	if globalDefault.DispatchedRequests != 100 {
+		t.Errorf("got %d dispatched requests, want 100", globalDefault.DispatchedRequests)
+	}
+
+	workloadLow := summary["workload-low"]
+	if workloadLow == nil {
+		t.Fatal("missing summary for priority level workload-low")
+	}
+	if workloadLow.SeatUtilization != 0 {
+		t.Errorf("got seat utilization %v without in-use samples, want 0", workloadLow.SeatUtilization)
+	}
+	if workloadLow.DispatchedRequests != 40 {
+		t.Errorf("got %d dispatched requests, want 40", workloadLow.DispatchedRequests)
+	}
+}
+
+func TestAPFMetricsSummaryVerifiers(t *testing.T) {
+	metrics, err := parseAPIServerMetrics(apfMetricsFixture)
+	if err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	summary := SummarizeAPFMetrics(metrics)
+
+	if err := summary.VerifyNoRejectedRequests(); err == nil {
+		t.Error("expected an error from VerifyNoRejectedRequests, got none")
+	}
+	if err := summary.VerifySeatUtilizationBelow("global-default", 0.6); err != nil {
+		t.Errorf("unexpected error from VerifySeatUtilizationBelow: %v", err)
+	}
+	if err := summary.VerifySeatUtilizationBelow("global-default", 0.5); err == nil {
+		t.Error("expected an error for seat utilization at the bound, got none")
+	}
+	if err := summary.VerifySeatUtilizationBelow("no-such-level", 0.5); err == nil {
+		t.Error("expected an error for an unknown priority level, got none")
+	}
+
+	delete(summary, "global-default")
+	if err := summary.VerifyNoRejectedRequests(); err != nil {
+		t.Errorf("unexpected error from VerifyNoRejectedRequests: %v", err)
+	}
+}
@@ -88,7 +88,7 @@ func parseKubeletMetrics(data string) (KubeletMetrics, error) {
 	return result, nil
 }
 
-func (g *Grabber) getMetricsFromNode(nodeName string, kubeletPort int) (string, error) {
+func (g *Grabber) getMetricsFromNode(nodeName string, kubeletPort int, pathSuffix ...string) (string, error) {
 	// There's a problem with timing out during proxy. Wrapping this in a goroutine to prevent deadlock.
 	finished := make(chan struct{}, 1)
 	var err error
@@ -98,7 +98,7 @@ func (g *Grabber) getMetricsFromNode(nodeName string, kubeletPort int) (string,
 			Resource("nodes").
 			SubResource("proxy").
 			Name(fmt.Sprintf("%v:%v", nodeName, kubeletPort)).
-			Suffix("metrics").
+			Suffix(append([]string{"metrics"}, pathSuffix...)...).
 			Do(context.TODO()).Raw()
 		finished <- struct{}{}
 	}()
@@ -25,7 +25,10 @@ import (
 	"sync"
 	"time"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -43,6 +46,17 @@ const (
 	kubeControllerManagerPort = 10257
 	// snapshotControllerPort is the port for the snapshot controller
 	snapshotControllerPort = 9102
+	// nodeLocalDNSPort is the default prometheus metrics port of the node-local-dns cache
+	nodeLocalDNSPort = 9253
+	// nodeLocalDNSLabelSelector selects the pods of the node-local-dns DaemonSet
+	nodeLocalDNSLabelSelector = "k8s-app=node-local-dns"
+	// metricsGrabberSAName is the name of the service account, cluster role
+	// and cluster role binding used for scraping components that require
+	// authenticated metrics requests.
+	metricsGrabberSAName = "metrics-grabber"
+	// metricsTokenExpirationSeconds is the requested lifetime of the bound
+	// service account token, long enough for any single test suite run.
+	metricsTokenExpirationSeconds = int64(3600)
 )
 
 // MetricsGrabbingDisabledError is an error that is wrapped by the
@@ -59,6 +73,10 @@ type Collection struct {
 	KubeletMetrics            map[string]KubeletMetrics
 	SchedulerMetrics          SchedulerMetrics
 	ClusterAutoscalerMetrics  ClusterAutoscalerMetrics
+	// ScrapeDurations is how long the scrape+parse of each component took,
+	// keyed by component (kubelets are keyed per node). Slow scrapes usually
+	// point at huge metric payloads which should be bounded by filtering.
+	ScrapeDurations map[string]time.Duration
 }
 
 // Grabber provides functions which grab metrics from components
@@ -78,6 +96,35 @@ type Grabber struct {
 	waitForControllerManagerReadyOnce  sync.Once
 	snapshotController                 string
 	waitForSnapshotControllerReadyOnce sync.Once
+	saToken                            string
+	saTokenErr                         error
+	saTokenOnce                        sync.Once
+	scrapeDurationsLock                sync.Mutex
+	scrapeDurations                    map[string]time.Duration
+}
+
+// recordScrapeDuration records how long the scrape+parse of one component
+// took. It is meant to be deferred at the top of a Grab function with the
+// start time of the scrape.
+func (g *Grabber) recordScrapeDuration(component string, start time.Time) {
+	g.scrapeDurationsLock.Lock()
+	defer g.scrapeDurationsLock.Unlock()
+	if g.scrapeDurations == nil {
+		g.scrapeDurations = map[string]time.Duration{}
+	}
+	g.scrapeDurations[component] = time.Since(start)
+}
+
+// ScrapeDurations returns the duration of the most recent scrape of each
+// component grabbed so far.
+func (g *Grabber) ScrapeDurations() map[string]time.Duration {
+	g.scrapeDurationsLock.Lock()
+	defer g.scrapeDurationsLock.Unlock()
+	durations := make(map[string]time.Duration, len(g.scrapeDurations))
+	for component, duration := range g.scrapeDurations {
+		durations[component] = duration
+	}
+	return durations
 }
 
 // NewMetricsGrabber prepares for grabbing metrics data from several different
@@ -184,6 +231,7 @@ func (g *Grabber) GrabFromKubelet(nodeName string) (KubeletMetrics, error) {
 }
 
 func (g *Grabber) grabFromKubeletInternal(nodeName string, kubeletPort int) (KubeletMetrics, error) {
+	defer g.recordScrapeDuration("kubelet/"+nodeName, time.Now())
 	if kubeletPort <= 0 || kubeletPort > 65535 {
 		return KubeletMetrics{}, fmt.Errorf("Invalid Kubelet port %v. Skipping Kubelet's metrics gathering", kubeletPort)
 	}
@@ -194,8 +242,92 @@ func (g *Grabber) grabFromKubeletInternal(nodeName string, kubeletPort int) (Kub
 	return parseKubeletMetrics(output)
 }
 
+// GrabCadvisorMetricsFromKubelet returns the cadvisor container metrics
+// exported through the kubelet's /metrics/cadvisor endpoint on the given
+// node. They carry the per-container cfs throttling counters the CPU
+// throttling report is computed from.
+func (g *Grabber) GrabCadvisorMetricsFromKubelet(nodeName string) (CadvisorMetrics, error) {
+	defer g.recordScrapeDuration("cadvisor/"+nodeName, time.Now())
+	nodes, err := g.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{FieldSelector: fields.Set{"metadata.name": nodeName}.AsSelector().String()})
+	if err != nil {
+		return CadvisorMetrics{}, err
+	}
+	if len(nodes.Items) != 1 {
+		return CadvisorMetrics{}, fmt.Errorf("Error listing nodes with name %v, got %v", nodeName, nodes.Items)
+	}
+	kubeletPort := int(nodes.Items[0].Status.DaemonEndpoints.KubeletEndpoint.Port)
+	if kubeletPort <= 0 || kubeletPort > 65535 {
+		return CadvisorMetrics{}, fmt.Errorf("Invalid Kubelet port %v. Skipping Kubelet's metrics gathering", kubeletPort)
+	}
+	output, err := g.getMetricsFromNode(nodeName, kubeletPort, "cadvisor")
+	if err != nil {
+		return CadvisorMetrics{}, err
+	}
+	return parseCadvisorMetrics(output)
+}
+
+// GrabCadvisorMetricsFromAllKubelets grabs the cadvisor container metrics of
+// every node in the cluster, keyed by node name, in the shape
+// ComputeCPUThrottlingReport expects.
+func (g *Grabber) GrabCadvisorMetricsFromAllKubelets() (map[string]CadvisorMetrics, error) {
+	nodes, err := g.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]CadvisorMetrics)
+	var errs []error
+	for _, node := range nodes.Items {
+		metrics, err := g.GrabCadvisorMetricsFromKubelet(node.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result[node.Name] = metrics
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("Errors while grabbing cadvisor metrics: %v", errs)
+	}
+	return result, nil
+}
+
+// GrabFromNodeLocalDNS returns metrics from the node-local-dns cache pod
+// running on the given node. Together with the upstream CoreDNS metrics this
+// allows DNS tests to attribute latency between the node cache and the
+// cluster DNS server.
+func (g *Grabber) GrabFromNodeLocalDNS(nodeName string) (NodeLocalDNSMetrics, error) {
+	defer g.recordScrapeDuration("node-local-dns/"+nodeName, time.Now())
+	pod, err := g.nodeLocalDNSPod(nodeName)
+	if err != nil {
+		return NodeLocalDNSMetrics{}, err
+	}
+	output, err := g.getMetricsFromPod(g.client, pod.Name, metav1.NamespaceSystem, nodeLocalDNSPort)
+	if err != nil {
+		return NodeLocalDNSMetrics{}, err
+	}
+	return parseNodeLocalDNSMetrics(output)
+}
+
+// nodeLocalDNSPod returns the node-local-dns DaemonSet pod scheduled on the
+// given node. Clusters without the node-local-dns addon get an error derived
+// from MetricsGrabbingDisabledError, so tests can skip the check.
+func (g *Grabber) nodeLocalDNSPod(nodeName string) (*v1.Pod, error) {
+	options := metav1.ListOptions{
+		LabelSelector: nodeLocalDNSLabelSelector,
+		FieldSelector: fields.Set{"spec.nodeName": nodeName}.AsSelector().String(),
+	}
+	podList, err := g.client.CoreV1().Pods(metav1.NamespaceSystem).List(context.TODO(), options)
+	if err != nil {
+		return nil, err
+	}
+	if len(podList.Items) != 1 {
+		return nil, fmt.Errorf("expected one node-local-dns pod on node %s, found %d: %w", nodeName, len(podList.Items), MetricsGrabbingDisabledError)
+	}
+	return &podList.Items[0], nil
+}
+
 // GrabFromScheduler returns metrics from scheduler
 func (g *Grabber) GrabFromScheduler() (SchedulerMetrics, error) {
+	defer g.recordScrapeDuration("kube-scheduler", time.Now())
 	if !g.grabFromScheduler {
 		return SchedulerMetrics{}, fmt.Errorf("kube-scheduler: %w", MetricsGrabbingDisabledError)
 	}
@@ -220,6 +352,7 @@ func (g *Grabber) GrabFromScheduler() (SchedulerMetrics, error) {
 
 // GrabFromClusterAutoscaler returns metrics from cluster autoscaler
 func (g *Grabber) GrabFromClusterAutoscaler() (ClusterAutoscalerMetrics, error) {
+	defer g.recordScrapeDuration("cluster-autoscaler", time.Now())
 	if !g.HasControlPlanePods() && g.externalClient == nil {
 		return ClusterAutoscalerMetrics{}, fmt.Errorf("ClusterAutoscaler: %w", MetricsGrabbingDisabledError)
 	}
@@ -241,6 +374,7 @@ func (g *Grabber) GrabFromClusterAutoscaler() (ClusterAutoscalerMetrics, error)
 
 // GrabFromControllerManager returns metrics from controller manager
 func (g *Grabber) GrabFromControllerManager() (ControllerManagerMetrics, error) {
+	defer g.recordScrapeDuration("kube-controller-manager", time.Now())
 	if !g.grabFromControllerManager {
 		return ControllerManagerMetrics{}, fmt.Errorf("kube-controller-manager: %w", MetricsGrabbingDisabledError)
 	}
@@ -271,6 +405,7 @@ func (g *Grabber) GrabFromControllerManager() (ControllerManagerMetrics, error)
 
 // GrabFromSnapshotController returns metrics from controller manager
 func (g *Grabber) GrabFromSnapshotController(podName string, port int) (SnapshotControllerMetrics, error) {
+	defer g.recordScrapeDuration("snapshot-controller", time.Now())
 	if !g.grabFromSnapshotController {
 		return SnapshotControllerMetrics{}, fmt.Errorf("snapshot controller: %w", MetricsGrabbingDisabledError)
 	}
@@ -313,6 +448,7 @@ func (g *Grabber) GrabFromSnapshotController(podName string, port int) (Snapshot
 
 // GrabFromAPIServer returns metrics from API server
 func (g *Grabber) GrabFromAPIServer() (APIServerMetrics, error) {
+	defer g.recordScrapeDuration("kube-apiserver", time.Now())
 	output, err := g.getMetricsFromAPIServer()
 	if err != nil {
 		return APIServerMetrics{}, nil
@@ -380,6 +516,7 @@ func (g *Grabber) Grab() (Collection, error) {
 			}
 		}
 	}
+	result.ScrapeDurations = g.ScrapeDurations()
 	if len(errs) > 0 {
 		return result, fmt.Errorf("Errors while grabbing metrics: %v", errs)
 	}
@@ -442,8 +579,106 @@ func (g *Grabber) getSecureMetricsFromPod(podName string, namespace string, port
 	rawOutput, err := metricClient.RESTClient().Get().
 		AbsPath("metrics").
 		Do(context.TODO()).Raw()
+	if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+		// The component rejected the suite's client credentials. Retry
+		// with a bound service account token, which components that
+		// delegate authentication and authorization to the API server
+		// can verify through TokenReview.
+		klog.V(2).Infof("Request for metrics of %s was rejected (%v), retrying with a bound service account token", podName, err)
+		token, tokenErr := g.boundSAToken()
+		if tokenErr != nil {
+			return "", fmt.Errorf("failed to get a bound service account token after %q: %v", err, tokenErr)
+		}
+		metricConfig.BearerToken = token
+		metricConfig.TLSClientConfig.CertFile = ""
+		metricConfig.TLSClientConfig.KeyFile = ""
+		metricConfig.TLSClientConfig.CertData = nil
+		metricConfig.TLSClientConfig.KeyData = nil
+		metricClient, err = clientset.NewForConfig(metricConfig)
+		if err != nil {
+			return "", err
+		}
+		rawOutput, err = metricClient.RESTClient().Get().
+			AbsPath("metrics").
+			Do(context.TODO()).Raw()
+	}
 	if err != nil {
 		return "", err
 	}
 	return string(rawOutput), nil
 }
+
+// boundSAToken returns a bound service account token which grants access to
+// the /metrics endpoints of components that require authenticated requests.
+// The service account and its RBAC rules are created on first use and the
+// token is cached for the lifetime of the grabber.
+func (g *Grabber) boundSAToken() (string, error) {
+	g.saTokenOnce.Do(func() {
+		g.saToken, g.saTokenErr = requestBoundSAToken(g.client)
+	})
+	return g.saToken, g.saTokenErr
+}
+
+// requestBoundSAToken sets up a service account which may GET the /metrics
+// non-resource URL and requests a bound token for it through the
+// TokenRequest API.
+func requestBoundSAToken(c clientset.Interface) (string, error) {
+	ctx := context.TODO()
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metricsGrabberSAName,
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+	if _, err := c.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("error creating service account: %v", err)
+	}
+
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: metricsGrabberSAName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		},
+	}
+	if _, err := c.RbacV1().ClusterRoles().Create(ctx, role, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("error creating cluster role: %v", err)
+	}
+
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: metricsGrabberSAName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     metricsGrabberSAName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      metricsGrabberSAName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+	if _, err := c.RbacV1().ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("error creating cluster role binding: %v", err)
+	}
+
+	expirationSeconds := metricsTokenExpirationSeconds
+	tr := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	tr, err := c.CoreV1().ServiceAccounts(metav1.NamespaceSystem).CreateToken(ctx, metricsGrabberSAName, tr, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error requesting bound service account token: %v", err)
+	}
+	return tr.Status.Token, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import "k8s.io/component-base/metrics/testutil"
+
+// NodeLocalDNSMetrics is metrics for the node-local-dns cache on a single node
+type NodeLocalDNSMetrics testutil.Metrics
+
+// Equal returns true if all metrics are the same as the arguments.
+func (m *NodeLocalDNSMetrics) Equal(o NodeLocalDNSMetrics) bool {
+	return (*testutil.Metrics)(m).Equal(testutil.Metrics(o))
+}
+
+// CacheHits returns the total number of DNS queries answered from the
+// node-local cache, summed over all server blocks and record types.
+func (m *NodeLocalDNSMetrics) CacheHits() float64 {
+	return m.sumMetric("coredns_cache_hits_total")
+}
+
+// CacheMisses returns the total number of DNS queries which missed the
+// node-local cache and were forwarded to the upstream DNS server.
+func (m *NodeLocalDNSMetrics) CacheMisses() float64 {
+	return m.sumMetric("coredns_cache_misses_total")
+}
+
+func (m *NodeLocalDNSMetrics) sumMetric(metricName string) float64 {
+	sum := float64(0)
+	for _, sample := range (*m)[metricName] {
+		sum += float64(sample.Value)
+	}
+	return sum
+}
+
+func newNodeLocalDNSMetrics() NodeLocalDNSMetrics {
+	result := testutil.NewMetrics()
+	return NodeLocalDNSMetrics(result)
+}
+
+func parseNodeLocalDNSMetrics(data string) (NodeLocalDNSMetrics, error) {
+	result := newNodeLocalDNSMetrics()
+	if err := testutil.ParseMetrics(data, (*testutil.Metrics)(&result)); err != nil {
+		return NodeLocalDNSMetrics{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import "testing"
+
+func TestParseNodeLocalDNSMetrics(t *testing.T) {
+	data := `# HELP coredns_cache_hits_total The count of cache hits.
+# TYPE coredns_cache_hits_total counter
+coredns_cache_hits_total{server="dns://169.254.20.10:53",type="denial"} 100
+coredns_cache_hits_total{server="dns://169.254.20.10:53",type="success"} 250
+# HELP coredns_cache_misses_total The count of cache misses.
+# TYPE coredns_cache_misses_total counter
+coredns_cache_misses_total{server="dns://169.254.20.10:53"} 50
+`
+	metrics, err := parseNodeLocalDNSMetrics(data)
+	if err != nil {
+		t.Fatalf("error parsing metrics: %v", err)
+	}
+	if hits := metrics.CacheHits(); hits != 350 {
+		t.Errorf("expected 350 cache hits, got %v", hits)
+	}
+	if misses := metrics.CacheMisses(); misses != 50 {
+		t.Errorf("expected 50 cache misses, got %v", misses)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/prometheus/common/model"
+)
+
+// LabelRedaction controls how the value of a label is rewritten before a
+// collection is persisted as a test artifact.
+type LabelRedaction int
+
+const (
+	// RedactionHash replaces the label value with a short, deterministic hash.
+	// Time series which shared a value before redaction still share one after
+	// it, so correlation within an artifact keeps working.
+	RedactionHash LabelRedaction = iota
+	// RedactionDrop removes the label from the time series entirely.
+	RedactionDrop
+)
+
+// DefaultRedactionRules covers the labels which are known to be either
+// high-cardinality (they grow with every pod churned through the cluster and
+// inflate persisted artifacts) or cluster-identifying (they should not end up
+// in artifacts shared publicly).
+var DefaultRedactionRules = map[string]LabelRedaction{
+	"uid":          RedactionHash,
+	"pod_uid":      RedactionHash,
+	"container_id": RedactionHash,
+	"boot_id":      RedactionDrop,
+	"machine_id":   RedactionDrop,
+	"system_uuid":  RedactionDrop,
+}
+
+// redactionRules are the rules applied every time a ComponentCollection is
+// persisted. nil means redaction is disabled.
+var redactionRules map[string]LabelRedaction
+
+// EnableLabelRedaction makes every subsequently persisted ComponentCollection
+// rewrite the label values matched by the given rules. Passing nil rules
+// enables DefaultRedactionRules.
+func EnableLabelRedaction(rules map[string]LabelRedaction) {
+	if rules == nil {
+		rules = DefaultRedactionRules
+	}
+	redactionRules = rules
+}
+
+// redactedLabelValue returns the deterministic replacement for a redacted
+// label value.
+func redactedLabelValue(value model.LabelValue) model.LabelValue {
+	sum := sha256.Sum256([]byte(value))
+	return model.LabelValue("redacted-" + hex.EncodeToString(sum[:6]))
+}
+
+func redactSamples(samples model.Samples, rules map[string]LabelRedaction) {
+	for _, sample := range samples {
+		for label, redaction := range rules {
+			name := model.LabelName(label)
+			value, found := sample.Metric[name]
+			if !found {
+				continue
+			}
+			switch redaction {
+			case RedactionDrop:
+				delete(sample.Metric, name)
+			case RedactionHash:
+				sample.Metric[name] = redactedLabelValue(value)
+			}
+		}
+	}
+}
+
+func redactMetrics(metrics map[string]model.Samples, rules map[string]LabelRedaction) {
+	for _, samples := range metrics {
+		redactSamples(samples, rules)
+	}
+}
+
+// RedactLabels rewrites the label values matched by the given rules in all
+// metrics of the collection, in place.
+func (m *ComponentCollection) RedactLabels(rules map[string]LabelRedaction) {
+	redactMetrics(m.APIServerMetrics, rules)
+	redactMetrics(m.ControllerManagerMetrics, rules)
+	redactMetrics(m.SnapshotControllerMetrics, rules)
+	redactMetrics(m.SchedulerMetrics, rules)
+	redactMetrics(m.ClusterAutoscalerMetrics, rules)
+	for _, kubeletMetrics := range m.KubeletMetrics {
+		redactMetrics(kubeletMetrics, rules)
+	}
+}
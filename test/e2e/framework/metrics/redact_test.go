@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+func redactionTestCollection() *ComponentCollection {
+	return &ComponentCollection{
+		APIServerMetrics: APIServerMetrics{
+			"apiserver_request_total": model.Samples{
+				&model.Sample{
+					Metric: model.Metric{
+						"resource": "pods",
+						"uid":      "f54bc048-3261-4c89-a40e-e2d04c4a7149",
+					},
+					Value: 1,
+				},
+			},
+		},
+		KubeletMetrics: map[string]KubeletMetrics{
+			"node-1": {
+				"kubelet_running_containers": model.Samples{
+					&model.Sample{
+						Metric: model.Metric{
+							"container_id": "containerd://deadbeef",
+							"machine_id":   "ec2fe8a1c13ab18d112e1f4a",
+							"pod":          "test-pod",
+						},
+						Value: 1,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRedactLabels(t *testing.T) {
+	collection := redactionTestCollection()
+	collection.RedactLabels(DefaultRedactionRules)
+
+	apiSample := collection.APIServerMetrics["apiserver_request_total"][0]
+	if got := string(apiSample.Metric["uid"]); !strings.HasPrefix(got, "redacted-") {
+		t.Errorf("expected the uid label to be hashed, got %q", got)
+	}
+	if got := string(apiSample.Metric["resource"]); got != "pods" {
+		t.Errorf("expected the resource label to be kept, got %q", got)
+	}
+
+	kubeletSample := collection.KubeletMetrics["node-1"]["kubelet_running_containers"][0]
+	if got := string(kubeletSample.Metric["container_id"]); !strings.HasPrefix(got, "redacted-") {
+		t.Errorf("expected the container_id label to be hashed, got %q", got)
+	}
+	if _, found := kubeletSample.Metric["machine_id"]; found {
+		t.Errorf("expected the machine_id label to be dropped, got %v", kubeletSample.Metric)
+	}
+	if got := string(kubeletSample.Metric["pod"]); got != "test-pod" {
+		t.Errorf("expected the pod label to be kept, got %q", got)
+	}
+}
+
+func TestRedactLabelsIsDeterministic(t *testing.T) {
+	first := redactionTestCollection()
+	first.RedactLabels(DefaultRedactionRules)
+	second := redactionTestCollection()
+	second.RedactLabels(DefaultRedactionRules)
+
+	firstUID := first.APIServerMetrics["apiserver_request_total"][0].Metric["uid"]
+	secondUID := second.APIServerMetrics["apiserver_request_total"][0].Metric["uid"]
+	if firstUID != secondUID {
+		t.Errorf("expected the same value to redact identically, got %q and %q", firstUID, secondUID)
+	}
+}
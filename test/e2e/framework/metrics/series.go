@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"k8s.io/klog/v2"
+)
+
+// Sample is one metrics Collection together with the time it was grabbed.
+type Sample struct {
+	// Time is when the collection was grabbed.
+	Time time.Time
+	// Collection holds the metrics of all grabbed components.
+	Collection Collection
+}
+
+// Sampler grabs metrics Collections at a fixed interval in the background, so
+// that tests can assert on how metrics evolve over a window instead of
+// comparing a single before/after pair. Typical use is leak detection on
+// control-plane components:
+//
+//	sampler := metrics.NewSampler(grabber, 10*time.Second)
+//	sampler.Start()
+//	defer sampler.Stop()
+//	... run the workload ...
+//	framework.ExpectNoError(sampler.Series("go_goroutines").IncreasesByLessThan(100))
+type Sampler struct {
+	grabber  *Grabber
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples []Sample
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewSampler returns a Sampler grabbing a Collection every interval.
+func NewSampler(grabber *Grabber, interval time.Duration) *Sampler {
+	return &Sampler{
+		grabber:  grabber,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins sampling in the background. The first sample is grabbed
+// immediately so that short windows still contain a baseline.
+func (s *Sampler) Start() {
+	go func() {
+		defer close(s.doneCh)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.sample()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+}
+
+// Stop ends the background sampling and waits for it to finish.
+func (s *Sampler) Stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+// Samples returns a copy of the samples grabbed so far.
+func (s *Sampler) Samples() []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Sample{}, s.samples...)
+}
+
+// Series extracts the time series of the given metric from the sampled
+// window. The metric values of one sample are summed across components and
+// label sets, which matches counters like request totals and singleton gauges
+// like goroutine or memory usage.
+func (s *Sampler) Series(metric string) Series {
+	return NewSeries(metric, s.Samples())
+}
+
+// sample grabs one Collection. Failed grabs are logged and skipped so that a
+// temporarily unreachable component does not abort the whole window.
+func (s *Sampler) sample() {
+	collection, err := s.grabber.Grab()
+	if err != nil {
+		klog.Errorf("Metrics sampler failed to grab metrics: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, Sample{Time: time.Now(), Collection: collection})
+}
+
+// SeriesPoint is the value of a metric at one point of the sampled window.
+type SeriesPoint struct {
+	// Time is when the value was sampled.
+	Time time.Time
+	// Value is the metric value, summed across components and label sets.
+	Value float64
+}
+
+// Series is the evolution of a single metric over the sampled window.
+type Series struct {
+	// Metric is the name of the metric the series was extracted for.
+	Metric string
+	// Points are the sampled values in chronological order. Samples in which
+	// the metric did not appear are left out.
+	Points []SeriesPoint
+}
+
+// NewSeries extracts the time series of the given metric from the samples.
+func NewSeries(metric string, samples []Sample) Series {
+	series := Series{Metric: metric}
+	for _, sample := range samples {
+		values := collectionSamples(sample.Collection, metric)
+		if len(values) == 0 {
+			continue
+		}
+		series.Points = append(series.Points, SeriesPoint{
+			Time:  sample.Time,
+			Value: sumSamples(values, nil),
+		})
+	}
+	return series
+}
+
+// NeverExceeds returns an error when any sampled value is above the limit.
+func (s Series) NeverExceeds(limit float64) error {
+	if err := s.nonEmpty(); err != nil {
+		return err
+	}
+	for _, point := range s.Points {
+		if point.Value > limit {
+			return fmt.Errorf("metric %q was %v at %v, above the limit %v", s.Metric, point.Value, point.Time.Format(time.RFC3339), limit)
+		}
+	}
+	return nil
+}
+
+// IncreasesByLessThan returns an error when the metric grew by the limit or
+// more between the first and the last sample. This is the leak check: a
+// goroutine or memory metric of a healthy component may fluctuate, but its
+// growth over the window must stay bounded.
+func (s Series) IncreasesByLessThan(limit float64) error {
+	increase, err := s.Increase()
+	if err != nil {
+		return err
+	}
+	if increase >= limit {
+		return fmt.Errorf("metric %q increased by %v over %d samples, expected an increase below %v", s.Metric, increase, len(s.Points), limit)
+	}
+	return nil
+}
+
+// Increase returns the difference between the last and the first sampled
+// value.
+func (s Series) Increase() (float64, error) {
+	if err := s.nonEmpty(); err != nil {
+		return 0, err
+	}
+	return s.Points[len(s.Points)-1].Value - s.Points[0].Value, nil
+}
+
+// Max returns the highest sampled value.
+func (s Series) Max() (float64, error) {
+	if err := s.nonEmpty(); err != nil {
+		return 0, err
+	}
+	max := s.Points[0].Value
+	for _, point := range s.Points[1:] {
+		if point.Value > max {
+			max = point.Value
+		}
+	}
+	return max, nil
+}
+
+// nonEmpty guards the assertions against windows in which the metric was
+// never seen, which would otherwise pass silently.
+func (s Series) nonEmpty() error {
+	if len(s.Points) == 0 {
+		return fmt.Errorf("metric %q was not found in any sample", s.Metric)
+	}
+	return nil
+}
+
+// collectionSamples gathers the samples of one metric from every component of
+// a Collection.
+func collectionSamples(c Collection, metric string) model.Samples {
+	var out model.Samples
+	out = append(out, c.APIServerMetrics[metric]...)
+	out = append(out, c.ControllerManagerMetrics[metric]...)
+	out = append(out, c.SchedulerMetrics[metric]...)
+	out = append(out, c.SnapshotControllerMetrics[metric]...)
+	out = append(out, c.ClusterAutoscalerMetrics[metric]...)
+	for _, kubelet := range c.KubeletMetrics {
+		out = append(out, kubelet[metric]...)
+	}
+	return out
+}
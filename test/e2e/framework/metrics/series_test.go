@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seriesSample builds a Sample with the given goroutine count on the
+// apiserver and one kubelet, mimicking what background sampling grabs.
+func seriesSample(t *testing.T, at time.Time, apiServerGoroutines, kubeletGoroutines int) Sample {
+	t.Helper()
+	apiServerMetrics, err := parseAPIServerMetrics(fmt.Sprintf("go_goroutines %d\n", apiServerGoroutines))
+	if err != nil {
+		t.Fatalf("failed to parse apiserver fixture: %v", err)
+	}
+	kubeletMetrics, err := parseKubeletMetrics(fmt.Sprintf("go_goroutines %d\n", kubeletGoroutines))
+	if err != nil {
+		t.Fatalf("failed to parse kubelet fixture: %v", err)
+	}
+	return Sample{
+		Time: at,
+		Collection: Collection{
+			APIServerMetrics: apiServerMetrics,
+			KubeletMetrics:   map[string]KubeletMetrics{"node1": kubeletMetrics},
+		},
+	}
+}
+
+func goroutineSeries(t *testing.T, counts ...int) Series {
+	t.Helper()
+	start := time.Now()
+	samples := make([]Sample, 0, len(counts))
+	for i, count := range counts {
+		samples = append(samples, seriesSample(t, start.Add(time.Duration(i)*time.Second), count, 10))
+	}
+	return NewSeries("go_goroutines", samples)
+}
+
+func TestNewSeries(t *testing.T) {
+	series := goroutineSeries(t, 100, 120, 110)
+	if got, want := len(series.Points), 3; got != want {
+		t.Fatalf("got %d points, want %d", got, want)
+	}
+	// Values are summed across the apiserver and the kubelet.
+	if got, want := series.Points[0].Value, float64(110); got != want {
+		t.Errorf("got first value %v, want %v", got, want)
+	}
+	if got, want := series.Points[1].Value, float64(130); got != want {
+		t.Errorf("got second value %v, want %v", got, want)
+	}
+
+	missing := NewSeries("go_threads", []Sample{seriesSample(t, time.Now(), 100, 10)})
+	if len(missing.Points) != 0 {
+		t.Errorf("expected no points for a metric missing from the samples, got %v", missing.Points)
+	}
+}
+
+func TestSeriesNeverExceeds(t *testing.T) {
+	series := goroutineSeries(t, 100, 120, 110)
+	if err := series.NeverExceeds(200); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := series.NeverExceeds(125)
+	if err == nil || !strings.Contains(err.Error(), "above the limit") {
+		t.Errorf("expected a limit violation, got %v", err)
+	}
+}
+
+func TestSeriesIncreasesByLessThan(t *testing.T) {
+	series := goroutineSeries(t, 100, 150, 140)
+	if err := series.IncreasesByLessThan(50); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := series.IncreasesByLessThan(40)
+	if err == nil || !strings.Contains(err.Error(), "increased by") {
+		t.Errorf("expected a growth violation, got %v", err)
+	}
+
+	// A shrinking series always passes the leak check.
+	if err := goroutineSeries(t, 150, 100).IncreasesByLessThan(1); err != nil {
+		t.Errorf("unexpected error for a decreasing series: %v", err)
+	}
+}
+
+func TestSeriesMax(t *testing.T) {
+	series := goroutineSeries(t, 100, 150, 140)
+	max, err := series.Max()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := float64(160); max != want {
+		t.Errorf("got max %v, want %v", max, want)
+	}
+}
+
+func TestSeriesEmpty(t *testing.T) {
+	series := NewSeries("go_goroutines", nil)
+	if err := series.NeverExceeds(100); err == nil {
+		t.Error("expected an error for an empty series")
+	}
+	if err := series.IncreasesByLessThan(100); err == nil {
+		t.Error("expected an error for an empty series")
+	}
+	if _, err := series.Max(); err == nil {
+		t.Error("expected an error for an empty series")
+	}
+}
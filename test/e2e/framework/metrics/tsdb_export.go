@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// componentLabel and nodeLabel are attached to every exported sample so that
+// samples from different components (and kubelets from different nodes)
+// remain distinct series when loaded into one TSDB.
+const (
+	componentLabel = "component"
+	nodeLabel      = "node"
+)
+
+// WriteOpenMetrics writes the collection as an OpenMetrics stream, the
+// backfill input format of `promtool tsdb create-blocks-from openmetrics`.
+// Samples without a timestamp get the given default. The output is sorted so
+// that identical collections serialize identically.
+func (m *ComponentCollection) WriteOpenMetrics(w io.Writer, defaultTimestamp time.Time) error {
+	lines := make([]string, 0)
+	appendComponent := func(component, node string, metrics map[string]model.Samples) {
+		for _, samples := range metrics {
+			for _, sample := range samples {
+				lines = append(lines, openMetricsLine(sample, component, node, defaultTimestamp))
+			}
+		}
+	}
+	appendComponent("apiserver", "", m.APIServerMetrics)
+	appendComponent("controller-manager", "", m.ControllerManagerMetrics)
+	appendComponent("snapshot-controller", "", m.SnapshotControllerMetrics)
+	appendComponent("scheduler", "", m.SchedulerMetrics)
+	appendComponent("cluster-autoscaler", "", m.ClusterAutoscalerMetrics)
+	for node, metrics := range m.KubeletMetrics {
+		appendComponent("kubelet", node, metrics)
+	}
+
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// ExportTSDBBlock writes the collection as a Prometheus TSDB block directory
+// under outputDir, ready to be placed into a local Prometheus data directory
+// for ad-hoc querying. The conversion is done by `promtool tsdb
+// create-blocks-from openmetrics`, which must be available in the PATH; when
+// it is not, the intermediate OpenMetrics file is kept under outputDir so it
+// can be converted elsewhere.
+func (m *ComponentCollection) ExportTSDBBlock(outputDir string, defaultTimestamp time.Time) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	openMetricsFile := filepath.Join(outputDir, "e2e-metrics.om")
+	f, err := os.Create(openMetricsFile)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteOpenMetrics(f, defaultTimestamp); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	promtool, err := exec.LookPath("promtool")
+	if err != nil {
+		return fmt.Errorf("promtool not found in PATH, kept OpenMetrics data at %s for conversion with `promtool tsdb create-blocks-from openmetrics`: %w", openMetricsFile, err)
+	}
+	out, err := exec.Command(promtool, "tsdb", "create-blocks-from", "openmetrics", openMetricsFile, outputDir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("converting OpenMetrics data at %s into a TSDB block failed: %v, output: %s", openMetricsFile, err, out)
+	}
+	return os.Remove(openMetricsFile)
+}
+
+// openMetricsLine serializes one sample, with the component and node labels
+// attached, as one OpenMetrics sample line.
+func openMetricsLine(sample *model.Sample, component, node string, defaultTimestamp time.Time) string {
+	labelNames := make([]string, 0, len(sample.Metric))
+	for name := range sample.Metric {
+		if name != model.MetricNameLabel && name != componentLabel && name != nodeLabel {
+			labelNames = append(labelNames, string(name))
+		}
+	}
+	sort.Strings(labelNames)
+
+	var sb strings.Builder
+	sb.WriteString(string(sample.Metric[model.MetricNameLabel]))
+	sb.WriteByte('{')
+	sb.WriteString(fmt.Sprintf("%s=%q", componentLabel, component))
+	if node != "" {
+		sb.WriteString(fmt.Sprintf(",%s=%q", nodeLabel, node))
+	}
+	// %q escapes backslashes, quotes and newlines exactly as the OpenMetrics
+	// exposition format requires.
+	for _, name := range labelNames {
+		sb.WriteString(fmt.Sprintf(",%s=%q", name, string(sample.Metric[model.LabelName(name)])))
+	}
+	sb.WriteByte('}')
+
+	timestamp := sample.Timestamp
+	if timestamp == 0 {
+		timestamp = model.TimeFromUnixNano(defaultTimestamp.UnixNano())
+	}
+	sb.WriteString(fmt.Sprintf(" %s %s\n", strconv.FormatFloat(float64(sample.Value), 'g', -1, 64), timestamp.String()))
+	return sb.String()
+}
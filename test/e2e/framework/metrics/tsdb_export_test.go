@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func exportTestCollection() *ComponentCollection {
+	return &ComponentCollection{
+		APIServerMetrics: APIServerMetrics{
+			"apiserver_request_total": model.Samples{
+				&model.Sample{
+					Metric: model.Metric{
+						model.MetricNameLabel: "apiserver_request_total",
+						"resource":            "pods",
+						"verb":                "GET",
+					},
+					Value:     42,
+					Timestamp: model.TimeFromUnixNano(time.Unix(1600000000, 0).UnixNano()),
+				},
+			},
+		},
+		KubeletMetrics: map[string]KubeletMetrics{
+			"node-1": {
+				"kubelet_running_pods": model.Samples{
+					&model.Sample{
+						Metric: model.Metric{
+							model.MetricNameLabel: "kubelet_running_pods",
+						},
+						Value: 7,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	defaultTimestamp := time.Unix(1600000123, 0)
+	var buf bytes.Buffer
+	if err := exportTestCollection().WriteOpenMetrics(&buf, defaultTimestamp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	expected := []string{
+		`apiserver_request_total{component="apiserver",resource="pods",verb="GET"} 42 1600000000`,
+		`kubelet_running_pods{component="kubelet",node="node-1"} 7 1600000123`,
+		`# EOF`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestWriteOpenMetricsIsDeterministic(t *testing.T) {
+	var first, second bytes.Buffer
+	defaultTimestamp := time.Unix(1600000123, 0)
+	if err := exportTestCollection().WriteOpenMetrics(&first, defaultTimestamp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exportTestCollection().WriteOpenMetrics(&second, defaultTimestamp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("expected identical collections to serialize identically:\n%s\nvs:\n%s", first.String(), second.String())
+	}
+}
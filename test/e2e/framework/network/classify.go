@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+// ProbeFailureClass characterizes why a network probe failed. The class
+// decides both which evidence is worth collecting and whether a persistent
+// failure points at the product or at the test infrastructure.
+type ProbeFailureClass string
+
+const (
+	// ProbeFailureDNS is a failure to resolve the probe target. Persistent
+	// resolution failures usually point at the test infrastructure (or
+	// cluster DNS when the target is a service name).
+	ProbeFailureDNS ProbeFailureClass = "dns-resolution"
+	// ProbeFailureConnect is a refused or otherwise failed TCP connection.
+	// The target address resolved and was reachable enough to answer, so
+	// this usually points at kube-proxy programming or the backend.
+	ProbeFailureConnect ProbeFailureClass = "tcp-connect"
+	// ProbeFailureTimeout is a probe that got no answer in time. Packets
+	// being silently dropped can be either infrastructure or missing
+	// dataplane rules; the collected evidence is needed to tell them apart.
+	ProbeFailureTimeout ProbeFailureClass = "timeout"
+	// ProbeFailureHTTPStatus is a completed HTTP transaction with an
+	// unexpected status code. The dataplane delivered the request, so this
+	// points at the product.
+	ProbeFailureHTTPStatus ProbeFailureClass = "http-status"
+	// ProbeFailureResponse is a completed HTTP transaction with an
+	// unexpected body, for example an answer from the wrong backend.
+	ProbeFailureResponse ProbeFailureClass = "http-response"
+	// ProbeFailureUnknown is any failure not matching a more precise class.
+	ProbeFailureUnknown ProbeFailureClass = "unknown"
+)
+
+// LikelyInfrastructureFlake returns true for failure classes that, even when
+// persistent, are more often caused by the test infrastructure than by the
+// product. Failures of every other class should be treated as product
+// failures rather than retried away.
+func (c ProbeFailureClass) LikelyInfrastructureFlake() bool {
+	return c == ProbeFailureDNS || c == ProbeFailureUnknown
+}
+
+// ClassifyHTTPPokeResult maps a failed HTTP poke onto a failure class. The
+// result of a successful poke classifies as "", which no failure class
+// equals.
+func ClassifyHTTPPokeResult(result HTTPPokeResult) ProbeFailureClass {
+	switch result.Status {
+	case HTTPSuccess:
+		return ""
+	case HTTPRefused:
+		return ProbeFailureConnect
+	case HTTPTimeout:
+		return ProbeFailureTimeout
+	case HTTPWrongCode, HTTPRetryCode:
+		return ProbeFailureHTTPStatus
+	case HTTPBadResponse:
+		return ProbeFailureResponse
+	}
+	var dnsError *net.DNSError
+	if result.Error != nil && (errors.As(result.Error, &dnsError) || strings.Contains(result.Error.Error(), "no such host")) {
+		return ProbeFailureDNS
+	}
+	return ProbeFailureUnknown
+}
+
+// ProbeFailureEvidence is the dataplane state collected when a probe failure
+// is reported, so that the failure can be attributed afterwards without
+// reproducing it.
+type ProbeFailureEvidence struct {
+	Class ProbeFailureClass
+	// Conntrack is a bounded dump of the connection tracking table of the
+	// node the probe ran from.
+	Conntrack string
+	// KubeProxyMetrics is a snapshot of the kube-proxy metrics of the node
+	// the probe ran from, notably the sync_proxy_rules family showing
+	// whether and when the dataplane was programmed.
+	KubeProxyMetrics string
+}
+
+// CollectProbeFailureEvidence gathers the evidence relevant for the failure
+// class from the host-network test container of the config. Collection
+// failures are recorded in place of the evidence instead of failing the test
+// a second time.
+func (config *NetworkingTestConfig) CollectProbeFailureEvidence(class ProbeFailureClass) *ProbeFailureEvidence {
+	evidence := &ProbeFailureEvidence{Class: class}
+	evidence.Conntrack = config.hostCommandOutput("conntrack -L 2>&1 | head -n 200 || cat /proc/net/nf_conntrack 2>&1 | head -n 200")
+	evidence.KubeProxyMetrics = config.hostCommandOutput("curl -s --max-time 10 http://localhost:10249/metrics | grep -E '^(kubeproxy_|rest_client_requests_total)'")
+	return evidence
+}
+
+// hostCommandOutput runs the command in the host-network test container and
+// returns its output, or a description of why it could not be collected.
+func (config *NetworkingTestConfig) hostCommandOutput(cmd string) string {
+	out, err := framework.RunHostCmd(config.Namespace, config.HostTestContainerPod.Name, cmd)
+	if err != nil {
+		return fmt.Sprintf("<failed to collect: %v>", err)
+	}
+	return out
+}
+
+// PokeHTTPUntilSuccess pokes the target repeatedly until it answers as
+// expected or the timeout expires. A persistent failure is classified,
+// dataplane evidence for the class is collected, and the test is failed with
+// a verdict distinguishing probable product failures from probable
+// infrastructure flakes, so that kube-proxy bugs do not get dismissed as
+// flakes.
+func (config *NetworkingTestConfig) PokeHTTPUntilSuccess(host string, port int, path string, params *HTTPPokeParams, timeout time.Duration) {
+	var result HTTPPokeResult
+	start := time.Now()
+	for time.Since(start) < timeout {
+		result = PokeHTTP(host, port, path, params)
+		if result.Status == HTTPSuccess {
+			return
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	class := ClassifyHTTPPokeResult(result)
+	evidence := config.CollectProbeFailureEvidence(class)
+	verdict := "likely a product failure (kube-proxy or backend)"
+	if class.LikelyInfrastructureFlake() {
+		verdict = "likely an infrastructure flake"
+	}
+	framework.Logf("Probe failure evidence (%s):\nconntrack:\n%s\nkube-proxy metrics:\n%s", class, evidence.Conntrack, evidence.KubeProxyMetrics)
+	framework.Failf("Poking %v:%v%v kept failing for %v: class %s (%s), last error: %v", host, port, path, timeout, class, verdict, result.Error)
+}
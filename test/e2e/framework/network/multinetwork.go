@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+)
+
+// Helpers for pods with Multus-style secondary network interfaces. Secondary
+// attachments are declared through annotations understood by CNI meta
+// plugins, and the resulting interfaces are reported back through a status
+// annotation. None of this is part of the core API, so every helper degrades
+// gracefully (or skips) on clusters without multi-network support.
+const (
+	// NetworkAttachmentAnnotation lists the secondary networks a pod wants
+	// to be attached to, by NetworkAttachmentDefinition name.
+	NetworkAttachmentAnnotation = "k8s.v1.cni.cncf.io/networks"
+	// NetworkStatusAnnotation is set by the CNI meta plugin and reports the
+	// interfaces and IPs of all attachments, including the default network.
+	NetworkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+	// multiNetworkGroupVersion is the API group/version serving
+	// NetworkAttachmentDefinition objects on clusters that support them.
+	multiNetworkGroupVersion = "k8s.cni.cncf.io/v1"
+	// secondaryInterfaceProbeTimeout bounds how long a connectivity probe
+	// across a secondary interface retries before it is considered failed.
+	secondaryInterfaceProbeTimeout = 2 * time.Minute
+)
+
+// NetworkStatus mirrors one entry of the network-status annotation.
+type NetworkStatus struct {
+	Name      string   `json:"name"`
+	Interface string   `json:"interface,omitempty"`
+	IPs       []string `json:"ips,omitempty"`
+	Default   bool     `json:"default,omitempty"`
+}
+
+// DeclareSecondaryNetworks annotates the pod spec with the given
+// NetworkAttachmentDefinition names so that a multi-network capable CNI
+// attaches one secondary interface per network. It must be called before the
+// pod is created.
+func DeclareSecondaryNetworks(pod *v1.Pod, networks ...string) *v1.Pod {
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[NetworkAttachmentAnnotation] = strings.Join(networks, ",")
+	return pod
+}
+
+// PodNetworkStatus returns the attachments reported in the pod's
+// network-status annotation. Pods on clusters without multi-network support
+// carry no such annotation and yield an empty list.
+func PodNetworkStatus(pod *v1.Pod) ([]NetworkStatus, error) {
+	annotation, ok := pod.Annotations[NetworkStatusAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var statuses []NetworkStatus
+	if err := json.Unmarshal([]byte(annotation), &statuses); err != nil {
+		return nil, fmt.Errorf("error parsing annotation %s of pod %s/%s: %v", NetworkStatusAnnotation, pod.Namespace, pod.Name, err)
+	}
+	return statuses, nil
+}
+
+// SecondaryInterfaceIPs returns the IPs the pod got on its attachment to the
+// given secondary network. The network name matches either plain or
+// namespaced ("namespace/name") form, as reported by the CNI plugin.
+func SecondaryInterfaceIPs(pod *v1.Pod, network string) ([]string, error) {
+	statuses, err := PodNetworkStatus(pod)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Name == network || strings.HasSuffix(status.Name, "/"+network) {
+			return status.IPs, nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s/%s has no attachment to network %q", pod.Namespace, pod.Name, network)
+}
+
+// ClusterSupportsMultiNetwork checks whether the cluster serves
+// NetworkAttachmentDefinition objects, which is the capability the helpers
+// in this file rely on.
+func ClusterSupportsMultiNetwork(c clientset.Interface) bool {
+	resources, err := c.Discovery().ServerResourcesForGroupVersion(multiNetworkGroupVersion)
+	if err != nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "network-attachment-definitions" {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipUnlessMultiNetwork skips the current test if the cluster has no
+// multi-network support.
+func SkipUnlessMultiNetwork(c clientset.Interface) {
+	if !ClusterSupportsMultiNetwork(c) {
+		e2eskipper.Skipf("Cluster does not serve %s resources, multi-network support is required", multiNetworkGroupVersion)
+	}
+}
+
+// ProbeSecondaryInterface verifies connectivity to a netexec endpoint
+// listening on a secondary interface IP, by issuing HTTP requests from
+// inside the given pod. It retries until the probe succeeds or times out, so
+// it tolerates secondary interfaces that come up after the pod is running.
+func ProbeSecondaryInterface(namespace, fromPod, targetIP string, targetPort int) error {
+	url := fmt.Sprintf("http://%s/hostname", net.JoinHostPort(targetIP, fmt.Sprint(targetPort)))
+	cmd := fmt.Sprintf("curl -g -q -s --max-time 15 --connect-timeout 1 %s", url)
+
+	var lastErr error
+	if pollErr := wait.PollImmediate(framework.Poll, secondaryInterfaceProbeTimeout, func() (bool, error) {
+		stdout, err := framework.RunHostCmd(namespace, fromPod, cmd)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		if strings.TrimSpace(stdout) == "" {
+			lastErr = fmt.Errorf("probe of %s returned an empty hostname", url)
+			return false, nil
+		}
+		return true, nil
+	}); pollErr != nil {
+		return fmt.Errorf("probe of %s from pod %s/%s failed: %v; last error: %v", url, namespace, fromPod, pollErr, lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	e2essh "k8s.io/kubernetes/test/e2e/framework/ssh"
+)
+
+// MaxClockSkew bounds the offset SkewClock accepts. Larger steps would let
+// certificates and leases expire outright instead of simulating the drift a
+// broken time source produces, and would take NTP very long to slew back.
+const MaxClockSkew = 30 * time.Minute
+
+// SkewClock steps the node's clock by offset (positive or negative) over SSH
+// and returns a restore func that steps it back and re-enables NTP
+// synchronization. NTP is disabled while the skew is in place so that the
+// time daemon does not correct the clock mid-test; restore must therefore be
+// called even when the test fails, typically via defer.
+func SkewClock(node *v1.Node, provider string, offset time.Duration) (restore func() error, err error) {
+	if offset > MaxClockSkew || -offset > MaxClockSkew {
+		return nil, fmt.Errorf("clock skew offset %v exceeds the supported bound of %v", offset, MaxClockSkew)
+	}
+
+	// Not every distro runs systemd-timesyncd, so a failure to toggle NTP is
+	// tolerated; the clock step below is what the test depends on.
+	disableNTP := "timedatectl set-ntp false 2>/dev/null || true"
+	if err := e2essh.IssueSSHCommand(disableNTP, provider, node); err != nil {
+		return nil, fmt.Errorf("disabling NTP synchronization on node %s: %v", node.Name, err)
+	}
+	if err := e2essh.IssueSSHCommand(stepClockCmd(offset), provider, node); err != nil {
+		return nil, fmt.Errorf("stepping the clock of node %s by %v: %v", node.Name, offset, err)
+	}
+
+	return func() error {
+		if err := e2essh.IssueSSHCommand(stepClockCmd(-offset), provider, node); err != nil {
+			return fmt.Errorf("stepping the clock of node %s back by %v: %v", node.Name, offset, err)
+		}
+		enableNTP := "timedatectl set-ntp true 2>/dev/null || true"
+		if err := e2essh.IssueSSHCommand(enableNTP, provider, node); err != nil {
+			return fmt.Errorf("re-enabling NTP synchronization on node %s: %v", node.Name, err)
+		}
+		return nil
+	}, nil
+}
+
+// stepClockCmd steps the clock relative to its current value, so restoring
+// does not undo the real time that passed while the clock was skewed.
+func stepClockCmd(offset time.Duration) string {
+	return fmt.Sprintf("sudo date --set @$(($(date +%%s) + %d))", int64(offset.Seconds()))
+}
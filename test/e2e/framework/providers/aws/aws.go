@@ -34,6 +34,11 @@ import (
 
 func init() {
 	framework.RegisterProvider("aws", newProvider)
+	framework.RegisterProviderCapabilities("aws",
+		framework.CapabilityLoadBalancerSourceRanges,
+		framework.CapabilityInstanceMetadata,
+		framework.CapabilityZoneLabels,
+	)
 }
 
 func newProvider() (framework.ProviderInterface, error) {
@@ -31,6 +31,11 @@ import (
 
 func init() {
 	framework.RegisterProvider("azure", newProvider)
+	framework.RegisterProviderCapabilities("azure",
+		framework.CapabilityInternalLoadBalancer,
+		framework.CapabilityInstanceMetadata,
+		framework.CapabilityZoneLabels,
+	)
 }
 
 func newProvider() (framework.ProviderInterface, error) {
@@ -42,6 +42,15 @@ import (
 func init() {
 	framework.RegisterProvider("gce", factory)
 	framework.RegisterProvider("gke", factory)
+	for _, provider := range []string{"gce", "gke"} {
+		framework.RegisterProviderCapabilities(provider,
+			framework.CapabilityLoadBalancerSourceRanges,
+			framework.CapabilityUDPLoadBalancer,
+			framework.CapabilityInternalLoadBalancer,
+			framework.CapabilityInstanceMetadata,
+			framework.CapabilityZoneLabels,
+		)
+	}
 }
 
 func factory() (framework.ProviderInterface, error) {
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// The suite-level RNG makes randomized test inputs (names, label values,
+// scheduling jitter) reproducible: the seed in use is logged and recorded in
+// the report directory, and re-running with --random-seed set to that value
+// replays the same pseudo-random sequence.
+
+// randomSeedFileName is the artifact the resolved seed is recorded in when a
+// report directory is configured.
+const randomSeedFileName = "random-seed.txt"
+
+// randStringAlphabet is restricted to characters that are valid anywhere in
+// object names and label values.
+const randStringAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var (
+	randomSeedOnce sync.Once
+	suiteRngMu     sync.Mutex
+	suiteRng       *rand.Rand
+)
+
+// RandomSeed returns the seed of the suite-level RNG, resolving and recording
+// it on first use. A seed set via --random-seed is kept as-is so that a failed
+// run can be reproduced; otherwise a time-based seed is picked.
+func RandomSeed() int64 {
+	randomSeedOnce.Do(func() {
+		if TestContext.RandomSeed == 0 {
+			TestContext.RandomSeed = time.Now().UnixNano()
+		}
+		suiteRng = rand.New(rand.NewSource(TestContext.RandomSeed))
+		klog.Infof("Using random seed %d for randomized test inputs, rerun with --random-seed=%d to reproduce", TestContext.RandomSeed, TestContext.RandomSeed)
+		recordRandomSeed(TestContext.RandomSeed)
+	})
+	return TestContext.RandomSeed
+}
+
+// recordRandomSeed records the resolved seed in the report directory, next to
+// the JUnit reports, so that a flaky failure can be reproduced from the run's
+// artifacts alone.
+func recordRandomSeed(seed int64) {
+	if TestContext.ReportDir == "" {
+		return
+	}
+	filePath := filepath.Join(TestContext.ReportDir, randomSeedFileName)
+	if err := ioutil.WriteFile(filePath, []byte(fmt.Sprintf("%d\n", seed)), 0644); err != nil {
+		klog.Warningf("Failed to record random seed in %s: %v", filePath, err)
+	}
+}
+
+// RandIntn returns, from the suite-level RNG, a non-negative pseudo-random
+// number in [0,n).
+func RandIntn(n int) int {
+	RandomSeed()
+	suiteRngMu.Lock()
+	defer suiteRngMu.Unlock()
+	return suiteRng.Intn(n)
+}
+
+// RandInt63n returns, from the suite-level RNG, a non-negative pseudo-random
+// 63-bit integer in [0,n).
+func RandInt63n(n int64) int64 {
+	RandomSeed()
+	suiteRngMu.Lock()
+	defer suiteRngMu.Unlock()
+	return suiteRng.Int63n(n)
+}
+
+// RandString returns a pseudo-random string of the given length from the
+// suite-level RNG, suitable for object names and label values.
+func RandString(length int) string {
+	RandomSeed()
+	suiteRngMu.Lock()
+	defer suiteRngMu.Unlock()
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = randStringAlphabet[suiteRng.Intn(len(randStringAlphabet))]
+	}
+	return string(b)
+}
+
+// RandJitterDuration returns duration plus a pseudo-random jitter in
+// [0, maxFactor*duration) from the suite-level RNG, for use as reproducible
+// scheduling jitter.
+func RandJitterDuration(duration time.Duration, maxFactor float64) time.Duration {
+	if maxFactor <= 0.0 {
+		return duration
+	}
+	RandomSeed()
+	suiteRngMu.Lock()
+	defer suiteRngMu.Unlock()
+	return duration + time.Duration(suiteRng.Float64()*maxFactor*float64(duration))
+}
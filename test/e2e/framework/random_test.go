@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+func TestSuiteRandomSeed(t *testing.T) {
+	reportDir, err := ioutil.TempDir("", "random-seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	framework.TestContext.ReportDir = reportDir
+	framework.TestContext.RandomSeed = 42
+
+	// The seed set via the flag must be kept as-is and recorded in the
+	// artifacts, so that a run can be reproduced.
+	assert.Equal(t, int64(42), framework.RandomSeed())
+	recorded, err := ioutil.ReadFile(filepath.Join(reportDir, "random-seed.txt"))
+	if err != nil {
+		t.Fatalf("random seed was not recorded in the report directory: %v", err)
+	}
+	assert.Equal(t, "42\n", string(recorded))
+
+	// Later calls must not re-resolve the seed.
+	assert.Equal(t, int64(42), framework.RandomSeed())
+}
+
+func TestRandString(t *testing.T) {
+	s := framework.RandString(10)
+	assert.Len(t, s, 10)
+	assert.Equal(t, "", strings.Trim(s, "abcdefghijklmnopqrstuvwxyz0123456789"))
+}
+
+func TestRandIntn(t *testing.T) {
+	assert.Equal(t, 0, framework.RandIntn(1))
+	for i := 0; i < 100; i++ {
+		n := framework.RandIntn(10)
+		assert.GreaterOrEqual(t, n, 0)
+		assert.Less(t, n, 10)
+	}
+}
+
+func TestRandJitterDuration(t *testing.T) {
+	assert.Equal(t, time.Second, framework.RandJitterDuration(time.Second, 0.0))
+	for i := 0; i < 100; i++ {
+		d := framework.RandJitterDuration(time.Second, 0.5)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.Less(t, d, 1500*time.Millisecond)
+	}
+}
@@ -202,6 +202,15 @@ func SkipUnlessProviderIs(supportedProviders ...string) {
 	}
 }
 
+// RequireProviderCapability skips if the provider under test has not declared
+// support for the given capability via framework.RegisterProviderCapabilities.
+func RequireProviderCapability(capability framework.ProviderCapability) {
+	if !framework.ProviderHasCapability(capability) {
+		skipInternalf(1, "Requires the %s capability, which provider %s does not declare (supported by %v)",
+			capability, framework.TestContext.Provider, framework.ProvidersWithCapability(capability))
+	}
+}
+
 // SkipUnlessMultizone skips if the cluster does not have multizone.
 func SkipUnlessMultizone(c clientset.Interface) {
 	zones, err := e2enode.GetClusterZones(c)
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot provides a fixture for destructive e2e suites: capture the
+// relevant cluster state before the test and restore it afterwards, so the
+// same suite can be run repeatedly against a long-lived cluster.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+// fieldManager identifies the server-side apply operations issued on restore.
+const fieldManager = "e2e-cluster-snapshot"
+
+// ClusterSnapshot holds the captured state of selected namespaces and of the
+// schedulable properties of the nodes (labels and taints). Create one with
+// Capture and bring the cluster back with Restore.
+type ClusterSnapshot struct {
+	cs clientset.Interface
+	dc dynamic.Interface
+
+	// objects are the captured namespaced API objects, grouped by resource
+	// so they can be re-applied through the dynamic client.
+	objects map[schema.GroupVersionResource][]*unstructured.Unstructured
+	nodes   []nodeState
+}
+
+type nodeState struct {
+	name   string
+	labels map[string]string
+	taints []v1.Taint
+}
+
+// Capture records the current state of the given resources in the given
+// namespaces, plus the labels and taints of all nodes. The returned snapshot
+// does not track cluster-scoped objects other than node metadata; destructive
+// tests that touch more than that need their own cleanup.
+func Capture(cs clientset.Interface, dc dynamic.Interface, namespaces []string, gvrs []schema.GroupVersionResource) (*ClusterSnapshot, error) {
+	s := &ClusterSnapshot{
+		cs:      cs,
+		dc:      dc,
+		objects: map[schema.GroupVersionResource][]*unstructured.Unstructured{},
+	}
+
+	for _, gvr := range gvrs {
+		for _, ns := range namespaces {
+			list, err := dc.Resource(gvr).Namespace(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %v in namespace %q: %v", gvr, ns, err)
+			}
+			for i := range list.Items {
+				s.objects[gvr] = append(s.objects[gvr], sanitizeForApply(&list.Items[i]))
+			}
+		}
+	}
+
+	nodes, err := cs.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		labels := map[string]string{}
+		for k, v := range node.Labels {
+			labels[k] = v
+		}
+		s.nodes = append(s.nodes, nodeState{
+			name:   node.Name,
+			labels: labels,
+			taints: append([]v1.Taint{}, node.Spec.Taints...),
+		})
+	}
+
+	framework.Logf("Captured cluster snapshot: %d resource kinds across %d namespaces, %d nodes", len(gvrs), len(namespaces), len(s.nodes))
+	return s, nil
+}
+
+// Restore applies the captured objects and node properties back to the
+// cluster via server-side apply, forcing ownership of conflicting fields.
+// Objects deleted since the capture are recreated; objects created since the
+// capture are left alone. All errors are collected so a single broken object
+// does not prevent the rest of the state from being restored.
+func (s *ClusterSnapshot) Restore() error {
+	var errs []error
+
+	for gvr, objects := range s.objects {
+		for _, obj := range objects {
+			data, err := json.Marshal(obj)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to marshal %v %s/%s: %v", gvr, obj.GetNamespace(), obj.GetName(), err))
+				continue
+			}
+			_, err = s.dc.Resource(gvr).Namespace(obj.GetNamespace()).Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+				FieldManager: fieldManager,
+				Force:        boolPtr(true),
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to restore %v %s/%s: %v", gvr, obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+
+	for _, node := range s.nodes {
+		if err := s.restoreNode(node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to restore cluster snapshot: %v", errs)
+	}
+	return nil
+}
+
+// restoreNode applies the captured labels and taints of one node. Nodes that
+// disappeared since the capture (e.g. recreated by the cloud provider) are
+// skipped, their state is owned by whoever recreated them.
+func (s *ClusterSnapshot) restoreNode(state nodeState) error {
+	apply := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata": map[string]interface{}{
+			"name":   state.name,
+			"labels": state.labels,
+		},
+		"spec": map[string]interface{}{
+			"taints": state.taints,
+		},
+	}
+	data, err := json.Marshal(apply)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node state for %q: %v", state.name, err)
+	}
+	_, err = s.cs.CoreV1().Nodes().Patch(context.TODO(), state.name, types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        boolPtr(true),
+	})
+	if apierrors.IsNotFound(err) {
+		framework.Logf("Node %q no longer exists, skipping restore", state.name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore node %q: %v", state.name, err)
+	}
+	return nil
+}
+
+// sanitizeForApply strips the fields that must not be sent back to the
+// server when re-applying a captured object.
+func sanitizeForApply(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	copied := obj.DeepCopy()
+	copied.SetResourceVersion("")
+	copied.SetUID("")
+	copied.SetCreationTimestamp(metav1.Time{})
+	copied.SetManagedFields(nil)
+	copied.SetSelfLink("")
+	copied.SetGeneration(0)
+	unstructured.RemoveNestedField(copied.Object, "status")
+	return copied
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
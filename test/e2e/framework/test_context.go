@@ -180,6 +180,9 @@ type TestContextType struct {
 	// SpecSummaryOutput is the file to write ginkgo.SpecSummary objects to as tests complete. Useful for debugging and test introspection.
 	SpecSummaryOutput string
 
+	// ConformanceResultsFile is the file to write per-spec conformance outcomes to in a machine-readable schema. If empty, no results are written.
+	ConformanceResultsFile string
+
 	// DockerConfigFile is a file that contains credentials which can be used to pull images from certain private registries, needed for a test.
 	DockerConfigFile string
 
@@ -188,6 +191,11 @@ type TestContextType struct {
 
 	// SnapshotControllerHTTPPort the port used for communicating with the snapshot controller HTTP endpoint.
 	SnapshotControllerHTTPPort int
+
+	// RandomSeed seeds the suite-level RNG used for randomized test inputs
+	// such as names, label values and scheduling jitter. Zero (the default)
+	// selects a time-based seed.
+	RandomSeed int64
 }
 
 // NodeKillerConfig describes configuration of NodeKiller -- a utility to
@@ -322,10 +330,12 @@ func RegisterCommonFlags(flags *flag.FlagSet) {
 
 	flags.StringVar(&TestContext.ProgressReportURL, "progress-report-url", "", "The URL to POST progress updates to as the suite runs to assist in aiding integrations. If empty, no messages sent.")
 	flags.StringVar(&TestContext.SpecSummaryOutput, "spec-dump", "", "The file to dump all ginkgo.SpecSummary to after tests run. If empty, no objects are saved/printed.")
+	flags.StringVar(&TestContext.ConformanceResultsFile, "conformance-results-file", "", "The file to write per-spec conformance outcomes to, in a machine-readable schema for certification tooling. If empty, no results are written.")
 	flags.StringVar(&TestContext.DockerConfigFile, "docker-config-file", "", "A file that contains credentials which can be used to pull images from certain private registries, needed for a test.")
 
 	flags.StringVar(&TestContext.SnapshotControllerPodName, "snapshot-controller-pod-name", "", "The pod name to use for identifying the snapshot controller in the kube-system namespace.")
 	flags.IntVar(&TestContext.SnapshotControllerHTTPPort, "snapshot-controller-http-port", 0, "The port to use for snapshot controller HTTP communication.")
+	flags.Int64Var(&TestContext.RandomSeed, "random-seed", 0, "Seed for the suite-level RNG used for randomized test inputs. Zero (the default) selects a time-based seed. Re-running with the seed recorded in a previous run's artifacts reproduces that run's pseudo-random choices.")
 }
 
 // RegisterClusterFlags registers flags specific to the cluster e2e test suite.
@@ -501,4 +511,8 @@ func AfterReadingAllFlags(t *TestContextType) {
 		}
 		os.Exit(1)
 	}
+
+	// Resolve and record the suite-level random seed up front, so that it ends
+	// up in the artifacts even when the run is interrupted.
+	RandomSeed()
 }
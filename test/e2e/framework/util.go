@@ -22,7 +22,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"net/url"
 	"os"
@@ -508,8 +507,9 @@ func LoadClientset() (*clientset.Clientset, error) {
 }
 
 // RandomSuffix provides a random sequence to append to pods,services,rcs.
+// It draws from the suite-level RNG, so a run is reproducible given its seed.
 func RandomSuffix() string {
-	return strconv.Itoa(rand.Intn(10000))
+	return strconv.Itoa(RandIntn(10000))
 }
 
 // LookForStringInPodExec looks for the given string in the output of a command
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	"k8s.io/kubernetes/test/e2e/network/common"
+)
+
+const (
+	externalIPServiceName = "external-ip-spoof-test"
+	externalIPBackendPod  = "external-ip-backend"
+	externalIPSpooferPod  = "external-ip-spoofer"
+	externalIPHTTPPort    = 8080
+)
+
+// documentationExternalIP returns an address from the ranges reserved for
+// documentation (RFC 5737 / RFC 3849), so that it is never assigned to a real
+// host and only kube-proxy rules make it reachable.
+func documentationExternalIP() string {
+	if framework.TestContext.ClusterIsIPv6() {
+		return "2001:DB8::cb00:71fb"
+	}
+	return "203.0.113.251"
+}
+
+// addHostAddressCmd and delHostAddressCmd assign and remove a service IP on
+// the loopback interface of the node a host-network pod runs on, which is the
+// spoofing primitive CVE-2020-8554 is built around.
+func addHostAddressCmd(ip string) string {
+	if framework.TestContext.ClusterIsIPv6() {
+		return fmt.Sprintf("ip -6 addr add %s/128 dev lo", ip)
+	}
+	return fmt.Sprintf("ip addr add %s/32 dev lo", ip)
+}
+
+func delHostAddressCmd(ip string) string {
+	if framework.TestContext.ClusterIsIPv6() {
+		return fmt.Sprintf("ip -6 addr del %s/128 dev lo", ip)
+	}
+	return fmt.Sprintf("ip addr del %s/32 dev lo", ip)
+}
+
+// A service externalIP is forwarded to the service's backends purely by
+// kube-proxy rules; the IP is not assigned to any interface. CVE-2020-8554
+// showed that a party who can claim such an IP locally may intercept traffic
+// meant for the service. These checks verify that the node's service rules
+// keep winning over a locally claimed service IP, so that spoofed listeners
+// never see the traffic.
+var _ = common.SIGDescribe("Service externalIPs spoofing [LinuxOnly]", func() {
+
+	fr := framework.NewDefaultFramework("external-ips")
+
+	type nodeInfo struct {
+		name string
+	}
+
+	var backendNode, spooferNode nodeInfo
+
+	ginkgo.BeforeEach(func() {
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(fr.ClientSet, 2)
+		framework.ExpectNoError(err)
+		if len(nodes.Items) < 2 {
+			e2eskipper.Skipf(
+				"Test requires >= 2 Ready nodes, but there are only %v nodes",
+				len(nodes.Items))
+		}
+		backendNode = nodeInfo{name: nodes.Items[0].Name}
+		spooferNode = nodeInfo{name: nodes.Items[1].Name}
+	})
+
+	ginkgo.It("should keep serving a service via its externalIP when a node claims the IP locally [Privileged]", func() {
+		cs := fr.ClientSet
+		ns := fr.Namespace.Name
+		externalIP := documentationExternalIP()
+
+		ginkgo.By("creating service " + externalIPServiceName + " with an externalIP in namespace " + ns)
+		jig := e2eservice.NewTestJig(cs, ns, externalIPServiceName)
+		_, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Type = v1.ServiceTypeClusterIP
+			svc.Spec.ExternalIPs = []string{externalIP}
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(externalIPHTTPPort)},
+			}
+		})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating a backend pod " + externalIPBackendPod + " on node " + backendNode.name)
+		backend := e2epod.NewAgnhostPod(ns, externalIPBackendPod, nil, nil, nil, "netexec", fmt.Sprintf("--http-port=%d", externalIPHTTPPort))
+		backend.Labels = jig.Labels
+		e2epod.SetNodeSelection(&backend.Spec, e2epod.NodeSelection{Name: backendNode.name})
+		fr.PodClient().CreateSync(backend)
+		validateEndpointsPortsOrFail(cs, ns, externalIPServiceName, portsByPodName{externalIPBackendPod: {externalIPHTTPPort}})
+
+		ginkgo.By("creating a client pod on node " + spooferNode.name)
+		clientPod := e2epod.CreateExecPodOrFail(cs, ns, "external-ip-client", func(pod *v1.Pod) {
+			e2epod.SetNodeSelection(&pod.Spec, e2epod.NodeSelection{Name: spooferNode.name})
+		})
+
+		curlExternalIP := fmt.Sprintf("curl -g -q -s --connect-timeout 5 http://%s/hostname", net.JoinHostPort(externalIP, "80"))
+		hostnameFromExternalIP := func() string {
+			var hostname string
+			err := wait.PollImmediate(2*time.Second, e2eservice.KubeProxyLagTimeout, func() (bool, error) {
+				output, err := framework.RunHostCmd(ns, clientPod.Name, curlExternalIP)
+				if err != nil {
+					return false, nil
+				}
+				hostname = strings.TrimSpace(output)
+				return hostname != "", nil
+			})
+			framework.ExpectNoError(err, "failed to reach service externalIP %s", externalIP)
+			return hostname
+		}
+
+		ginkgo.By("connecting to the externalIP and reaching the backend")
+		framework.ExpectEqual(hostnameFromExternalIP(), externalIPBackendPod)
+
+		// The spoofer shares the node's network namespace: claiming the
+		// externalIP there and answering on the service port is exactly what a
+		// spoofed host would do. Its netexec reports the node's hostname, so
+		// any answer from it is distinguishable from the backend pod's.
+		ginkgo.By("claiming the externalIP on node " + spooferNode.name + " and serving the service port there")
+		privileged := true
+		spoofer := e2epod.NewAgnhostPod(ns, externalIPSpooferPod, nil, nil, nil, "netexec", "--http-port=80")
+		spoofer.Spec.HostNetwork = true
+		spoofer.Spec.Containers[0].SecurityContext = &v1.SecurityContext{Privileged: &privileged}
+		e2epod.SetNodeSelection(&spoofer.Spec, e2epod.NodeSelection{Name: spooferNode.name})
+		fr.PodClient().CreateSync(spoofer)
+
+		_, err = framework.RunHostCmd(ns, externalIPSpooferPod, addHostAddressCmd(externalIP))
+		framework.ExpectNoError(err, "failed to assign %s on node %s", externalIP, spooferNode.name)
+		// The address was added to the node itself and outlives both the pod
+		// and the test namespace, so it must be removed unconditionally.
+		defer func() {
+			_, err := framework.RunHostCmd(ns, externalIPSpooferPod, delHostAddressCmd(externalIP))
+			framework.ExpectNoError(err, "failed to remove spoofed address %s from node %s", externalIP, spooferNode.name)
+		}()
+
+		ginkgo.By("verifying the spoofed listener answers on the node itself")
+		err = wait.PollImmediate(2*time.Second, time.Minute, func() (bool, error) {
+			output, err := framework.RunHostCmd(ns, externalIPSpooferPod, "curl -g -q -s --connect-timeout 5 http://127.0.0.1:80/hostname")
+			if err != nil {
+				return false, nil
+			}
+			return strings.TrimSpace(output) != "", nil
+		})
+		framework.ExpectNoError(err, "spoofed listener did not come up on node %s", spooferNode.name)
+
+		ginkgo.By("verifying pod traffic to the externalIP keeps reaching the backend")
+		for i := 0; i < 5; i++ {
+			hostname := hostnameFromExternalIP()
+			if hostname != externalIPBackendPod {
+				framework.Failf("traffic to externalIP %s was intercepted by the spoofed listener (got %q, expected %q)",
+					externalIP, hostname, externalIPBackendPod)
+			}
+			time.Sleep(1 * time.Second)
+		}
+	})
+})
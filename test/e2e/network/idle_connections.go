@@ -0,0 +1,248 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	"k8s.io/kubernetes/test/e2e/network/common"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+	netutils "k8s.io/utils/net"
+)
+
+const (
+	idleConnServerPort = 11304
+	// idleConnPeriod is how long the test connections stay idle. It is
+	// longer than the default UDP conntrack timeout (30s) and long enough
+	// for conntrack state transitions to be observed, while still being
+	// far below the default established TCP timeout (5 days), so a broken
+	// connection points at NAT or endpoint programming, not at expiry.
+	idleConnPeriod = 2 * time.Minute
+	// idleConnProbeTimeout bounds each probe of the idle connection. A
+	// connection whose conntrack entry was removed either gets a RST
+	// (reachable backend, unknown flow) or times out (blackholed flow);
+	// both surface within this timeout as a predictable client error.
+	idleConnProbeTimeout = 30 * time.Second
+)
+
+var _ = common.SIGDescribe("Idle connections [Slow]", func() {
+
+	fr := framework.NewDefaultFramework("idle-connections")
+
+	type nodeInfo struct {
+		name   string
+		nodeIP string
+	}
+
+	var clientNodeInfo, serverNodeInfo nodeInfo
+
+	ginkgo.BeforeEach(func() {
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(fr.ClientSet, 2)
+		framework.ExpectNoError(err)
+		if len(nodes.Items) < 2 {
+			e2eskipper.Skipf(
+				"Test requires >= 2 Ready nodes, but there are only %v nodes",
+				len(nodes.Items))
+		}
+
+		family := v1.IPv4Protocol
+		if framework.TestContext.ClusterIsIPv6() {
+			family = v1.IPv6Protocol
+		}
+
+		ips := e2enode.GetAddressesByTypeAndFamily(&nodes.Items[0], v1.NodeInternalIP, family)
+		framework.ExpectNotEqual(len(ips), 0)
+		clientNodeInfo = nodeInfo{name: nodes.Items[0].Name, nodeIP: ips[0]}
+
+		ips = e2enode.GetAddressesByTypeAndFamily(&nodes.Items[1], v1.NodeInternalIP, family)
+		framework.ExpectNotEqual(len(ips), 0)
+		serverNodeInfo = nodeInfo{name: nodes.Items[1].Name, nodeIP: ips[0]}
+	})
+
+	// startIdleConnServer creates the echo server pod behind the given
+	// service jig and waits until it is ready.
+	startIdleConnServer := func(jig *e2eservice.TestJig) {
+		serverPod := e2epod.NewAgnhostPod(fr.Namespace.Name, "idle-conn-server", nil, nil, nil,
+			"net",
+			"--runner", "nat-idleconn-server",
+			"--options", fmt.Sprintf(`{"LocalAddr":":%d"}`, idleConnServerPort))
+		serverPod.Labels = jig.Labels
+		e2epod.SetNodeSelection(&serverPod.Spec, e2epod.NodeSelection{Name: serverNodeInfo.name})
+		fr.PodClient().CreateSync(serverPod)
+	}
+
+	// runIdleConnClient holds an idle connection against the given address
+	// and returns whether the connection survived, together with the client
+	// pod logs. The client pod fails predictably (non-zero exit with the
+	// probe error in its logs) when the connection broke.
+	runIdleConnClient := func(address string, keepalive bool) (bool, string) {
+		options := fmt.Sprintf(`{"RemoteAddr":"%s", "IdleSeconds":%d, "EnableKeepAlive":%v, "KeepAlivePeriodSeconds":30, "TimeoutSeconds":%d}`,
+			address, int(idleConnPeriod.Seconds()), keepalive, int(idleConnProbeTimeout.Seconds()))
+		clientPod := e2epod.NewAgnhostPod(fr.Namespace.Name, "idle-conn-client", nil, nil, nil,
+			"net", "--runner", "nat-idleconn-client", "--options", options)
+		clientPod.Spec.RestartPolicy = v1.RestartPolicyNever
+		e2epod.SetNodeSelection(&clientPod.Spec, e2epod.NodeSelection{Name: clientNodeInfo.name})
+		fr.PodClient().Create(clientPod)
+
+		timeout := idleConnPeriod + 2*idleConnProbeTimeout + 2*time.Minute
+		err := e2epod.WaitTimeoutForPodNoLongerRunningInNamespace(fr.ClientSet, clientPod.Name, fr.Namespace.Name, timeout)
+		framework.ExpectNoError(err, "client pod did not finish within %v", timeout)
+
+		logs, err := e2epod.GetPodLogs(fr.ClientSet, fr.Namespace.Name, clientPod.Name, clientPod.Spec.Containers[0].Name)
+		framework.ExpectNoError(err)
+		framework.Logf("Idle connection client logs:\n%s", logs)
+
+		pod, err := fr.ClientSet.CoreV1().Pods(fr.Namespace.Name).Get(context.TODO(), clientPod.Name, metav1.GetOptions{})
+		framework.ExpectNoError(err)
+		return pod.Status.Phase == v1.PodSucceeded, logs
+	}
+
+	// watchConntrackStates samples the conntrack entry of the test
+	// connection on the client node until stop is closed and returns the
+	// sequence of states observed, e.g. [SYN_SENT ESTABLISHED TIME_WAIT].
+	watchConntrackStates := func(hostExecPod *v1.Pod, stop chan struct{}, states *[]string) {
+		ipFamily := "ipv4"
+		if netutils.IsIPv6String(serverNodeInfo.nodeIP) {
+			ipFamily = "ipv6"
+		}
+		cmd := fmt.Sprintf("conntrack -L -f %s -p tcp --dport %d 2>/dev/null | head -n 1", ipFamily, idleConnServerPort)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Second):
+			}
+			result, err := framework.RunHostCmd(fr.Namespace.Name, hostExecPod.Name, cmd)
+			if err != nil {
+				framework.Logf("failed to sample conntrack entry: %v", err)
+				continue
+			}
+			fields := strings.Fields(result)
+			// The TCP state is the fourth column of the entry.
+			if len(fields) < 4 {
+				continue
+			}
+			state := fields[3]
+			if len(*states) == 0 || (*states)[len(*states)-1] != state {
+				*states = append(*states, state)
+			}
+		}
+	}
+
+	// startConntrackWatcher creates a privileged host network pod on the
+	// client node and samples the conntrack state of the test connection in
+	// the background.
+	startConntrackWatcher := func() (chan struct{}, *[]string) {
+		privileged := true
+		hostExecPod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "idle-conn-host-exec",
+				Namespace: fr.Namespace.Name,
+			},
+			Spec: v1.PodSpec{
+				HostNetwork: true,
+				NodeName:    clientNodeInfo.name,
+				Containers: []v1.Container{
+					{
+						Name:            "idle-conn-host-exec",
+						Image:           imageutils.GetE2EImage(imageutils.DebianIptables),
+						ImagePullPolicy: v1.PullIfNotPresent,
+						Command:         []string{"sleep", "3600"},
+						SecurityContext: &v1.SecurityContext{
+							Privileged: &privileged,
+						},
+					},
+				},
+			},
+		}
+		fr.PodClient().CreateSync(hostExecPod)
+
+		stop := make(chan struct{})
+		states := &[]string{}
+		go watchConntrackStates(hostExecPod, stop, states)
+		return stop, states
+	}
+
+	ginkgo.It("should keep an idle TCP connection open through a ClusterIP service with keepalive enabled", func() {
+		jig := e2eservice.NewTestJig(fr.ClientSet, fr.Namespace.Name, "idle-conn-clusterip")
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Type = v1.ServiceTypeClusterIP
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: idleConnServerPort, Name: "tcp", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(idleConnServerPort)},
+			}
+		})
+		framework.ExpectNoError(err)
+		startIdleConnServer(jig)
+
+		stop, states := startConntrackWatcher()
+		defer close(stop)
+
+		address := net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(idleConnServerPort))
+		ginkgo.By(fmt.Sprintf("holding an idle connection to %v for %v with keepalive on", address, idleConnPeriod))
+		survived, logs := runIdleConnClient(address, true)
+
+		framework.Logf("conntrack states observed for the idle connection: %v", *states)
+		if !survived {
+			framework.Failf("idle connection with keepalive did not survive %v through the ClusterIP service: %s", idleConnPeriod, logs)
+		}
+	})
+
+	ginkgo.It("should either keep an idle TCP connection open through a NodePort service without keepalive or fail it predictably", func() {
+		jig := e2eservice.NewTestJig(fr.ClientSet, fr.Namespace.Name, "idle-conn-nodeport")
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Type = v1.ServiceTypeNodePort
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: idleConnServerPort, Name: "tcp", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(idleConnServerPort)},
+			}
+		})
+		framework.ExpectNoError(err)
+		startIdleConnServer(jig)
+
+		stop, states := startConntrackWatcher()
+		defer close(stop)
+
+		address := net.JoinHostPort(serverNodeInfo.nodeIP, strconv.Itoa(int(svc.Spec.Ports[0].NodePort)))
+		ginkgo.By(fmt.Sprintf("holding an idle connection to %v for %v with keepalive off", address, idleConnPeriod))
+		survived, logs := runIdleConnClient(address, false)
+
+		framework.Logf("conntrack states observed for the idle connection: %v", *states)
+		if survived {
+			return
+		}
+		// Without keepalive the connection is allowed to break, but it
+		// must break predictably: the second probe has to report the
+		// error, not the initial one, and the client must not hang.
+		if !strings.Contains(logs, "probe after") {
+			framework.Failf("idle connection through the NodePort service broke outside the idle period: %s", logs)
+		}
+		framework.Logf("idle connection broke predictably during the idle period")
+	})
+})
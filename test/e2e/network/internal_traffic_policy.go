@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/onsi/ginkgo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	"k8s.io/kubernetes/test/e2e/network/common"
+)
+
+// Pending change metrics exposed by kube-proxy. When both are zero the proxy
+// has programmed all known services and endpoints into the dataplane.
+var kubeProxyPendingChangeMetrics = []string{
+	"kubeproxy_sync_proxy_rules_endpoint_changes_pending",
+	"kubeproxy_sync_proxy_rules_service_changes_pending",
+}
+
+// grabKubeProxyMetricsOnNode scrapes the kube-proxy metrics endpoint on the
+// given node through a temporary host-network pod.
+func grabKubeProxyMetricsOnNode(f *framework.Framework, nodeName string) (testutil.Metrics, error) {
+	pod := e2epod.NewAgnhostPod(f.Namespace.Name, "kube-proxy-metrics-grabber", nil, nil, nil)
+	pod.Spec.HostNetwork = true
+	e2epod.SetNodeSelection(&pod.Spec, e2epod.NodeSelection{Name: nodeName})
+	f.PodClient().CreateSync(pod)
+	defer f.PodClient().DeleteSync(pod.Name, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
+
+	cmd := "curl -q -s --connect-timeout 5 http://localhost:10249/metrics"
+	stdout, err := framework.RunHostCmd(pod.Namespace, pod.Name, cmd)
+	if err != nil {
+		return nil, err
+	}
+	metrics := testutil.NewMetrics()
+	if err := testutil.ParseMetrics(stdout, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// expectKubeProxyConverged fails the test if kube-proxy on the given node
+// still has pending service or endpoint changes. Connectivity assertions made
+// after this call reflect traffic policy rather than programming lag.
+func expectKubeProxyConverged(f *framework.Framework, nodeName string) {
+	metrics, err := grabKubeProxyMetricsOnNode(f, nodeName)
+	framework.ExpectNoError(err, "failed to grab kube-proxy metrics on node %s", nodeName)
+
+	for _, name := range kubeProxyPendingChangeMetrics {
+		samples, found := metrics[name]
+		if !found {
+			framework.Logf("kube-proxy on node %s does not report %s, skipping the assertion", nodeName, name)
+			continue
+		}
+		for _, sample := range samples {
+			if sample.Value != 0 {
+				framework.Failf("kube-proxy on node %s reports %v pending changes in %s", nodeName, sample.Value, name)
+			}
+		}
+	}
+}
+
+var _ = common.SIGDescribe("Services with internalTrafficPolicy=Local [Feature:ServiceInternalTrafficPolicy]", func() {
+	f := framework.NewDefaultFramework("itp-local")
+
+	ginkgo.BeforeEach(func() {
+		// windows kube-proxy does not support this feature yet
+		e2eskipper.SkipIfNodeOSDistroIs("windows")
+
+		// This behavior is not supported if kube-proxy is in "userspace" mode.
+		if proxyMode, err := proxyMode(f); err == nil {
+			if proxyMode == "userspace" {
+				e2eskipper.Skipf("The test doesn't work with kube-proxy in userspace mode")
+			}
+		} else {
+			framework.Logf("Couldn't detect KubeProxy mode - test failure may be expected: %v", err)
+		}
+	})
+
+	ginkgo.It("should route traffic only to node-local endpoints when endpoints exist on multiple nodes", func() {
+		cs := f.ClientSet
+		ns := f.Namespace.Name
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(cs, 2)
+		framework.ExpectNoError(err)
+		if len(nodes.Items) < 2 {
+			e2eskipper.Skipf("The test requires at least two ready nodes on %s, but found %v", framework.TestContext.Provider, len(nodes.Items))
+		}
+		node0 := nodes.Items[0]
+		node1 := nodes.Items[1]
+
+		serviceName := "svc-itp-mirror"
+		servicePort := 80
+
+		ginkgo.By("creating a TCP service " + serviceName + " with type=ClusterIP and internalTrafficPolicy=Local in namespace " + ns)
+		local := v1.ServiceInternalTrafficPolicyLocal
+		jig := e2eservice.NewTestJig(cs, ns, serviceName)
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(80)},
+			}
+			svc.Spec.InternalTrafficPolicy = &local
+		})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating a webserver pod on each node")
+		webserverPods := make(map[string]*v1.Pod, 2)
+		endpointNames := portsByPodName{}
+		for i, node := range []v1.Node{node0, node1} {
+			pod := e2epod.NewAgnhostPod(ns, fmt.Sprintf("echo-hostname-%d", i), nil, nil, nil, "netexec", "--http-port", strconv.Itoa(servicePort))
+			pod.Labels = jig.Labels
+			e2epod.SetNodeSelection(&pod.Spec, e2epod.NodeSelection{Name: node.Name})
+			_, err = cs.CoreV1().Pods(ns).Create(context.TODO(), pod, metav1.CreateOptions{})
+			framework.ExpectNoError(err)
+			framework.ExpectNoError(e2epod.WaitTimeoutForPodReadyInNamespace(cs, pod.Name, ns, framework.PodStartTimeout))
+			webserverPods[node.Name] = pod
+			endpointNames[pod.Name] = []int{servicePort}
+		}
+		validateEndpointsPortsOrFail(cs, ns, serviceName, endpointNames)
+
+		ginkgo.By("creating a client pod on each node")
+		clientPods := make(map[string]*v1.Pod, 2)
+		for i, node := range []v1.Node{node0, node1} {
+			pod := e2epod.NewAgnhostPod(ns, fmt.Sprintf("pause-pod-%d", i), nil, nil, nil)
+			e2epod.SetNodeSelection(&pod.Spec, e2epod.NodeSelection{Name: node.Name})
+			pod, err = cs.CoreV1().Pods(ns).Create(context.TODO(), pod, metav1.CreateOptions{})
+			framework.ExpectNoError(err)
+			framework.ExpectNoError(e2epod.WaitTimeoutForPodReadyInNamespace(cs, pod.Name, ns, framework.PodStartTimeout))
+			clientPods[node.Name] = pod
+		}
+
+		ginkgo.By("checking kube-proxy has no pending changes before asserting traffic distribution")
+		expectKubeProxyConverged(f, node0.Name)
+		expectKubeProxyConverged(f, node1.Name)
+
+		// every request from a client must be answered by the webserver on the
+		// same node, even though endpoints exist on both nodes
+		serviceAddress := net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(servicePort))
+		for _, node := range []v1.Node{node0, node1} {
+			ginkgo.By("asserting traffic from node " + node.Name + " only reaches its local endpoint")
+			for i := 0; i < 5; i++ {
+				execHostnameTest(*clientPods[node.Name], serviceAddress, webserverPods[node.Name].Name)
+			}
+		}
+	})
+
+	ginkgo.It("should drop traffic from nodes without local endpoints", func() {
+		cs := f.ClientSet
+		ns := f.Namespace.Name
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(cs, 2)
+		framework.ExpectNoError(err)
+		if len(nodes.Items) < 2 {
+			e2eskipper.Skipf("The test requires at least two ready nodes on %s, but found %v", framework.TestContext.Provider, len(nodes.Items))
+		}
+		node0 := nodes.Items[0]
+		node1 := nodes.Items[1]
+
+		serviceName := "svc-itp-drop"
+		servicePort := 80
+
+		ginkgo.By("creating a TCP service " + serviceName + " with type=ClusterIP and internalTrafficPolicy=Local in namespace " + ns)
+		local := v1.ServiceInternalTrafficPolicyLocal
+		jig := e2eservice.NewTestJig(cs, ns, serviceName)
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(80)},
+			}
+			svc.Spec.InternalTrafficPolicy = &local
+		})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating a webserver pod on node " + node1.Name + " only")
+		webserverPod := e2epod.NewAgnhostPod(ns, "echo-hostname-remote", nil, nil, nil, "netexec", "--http-port", strconv.Itoa(servicePort))
+		webserverPod.Labels = jig.Labels
+		e2epod.SetNodeSelection(&webserverPod.Spec, e2epod.NodeSelection{Name: node1.Name})
+		_, err = cs.CoreV1().Pods(ns).Create(context.TODO(), webserverPod, metav1.CreateOptions{})
+		framework.ExpectNoError(err)
+		framework.ExpectNoError(e2epod.WaitTimeoutForPodReadyInNamespace(cs, webserverPod.Name, ns, framework.PodStartTimeout))
+		validateEndpointsPortsOrFail(cs, ns, serviceName, portsByPodName{webserverPod.Name: {servicePort}})
+
+		ginkgo.By("creating a client pod on node " + node0.Name + " without local endpoints")
+		pausePod := e2epod.NewAgnhostPod(ns, "pause-pod-no-local", nil, nil, nil)
+		e2epod.SetNodeSelection(&pausePod.Spec, e2epod.NodeSelection{Name: node0.Name})
+		pausePod, err = cs.CoreV1().Pods(ns).Create(context.TODO(), pausePod, metav1.CreateOptions{})
+		framework.ExpectNoError(err)
+		framework.ExpectNoError(e2epod.WaitTimeoutForPodReadyInNamespace(cs, pausePod.Name, ns, framework.PodStartTimeout))
+
+		ginkgo.By("checking kube-proxy has no pending changes before asserting the drop")
+		expectKubeProxyConverged(f, node0.Name)
+
+		// there is deliberately no fallback to remote endpoints: traffic from
+		// a node without local endpoints must fail instead of being rerouted
+		serviceAddress := net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(servicePort))
+		for i := 0; i < 5; i++ {
+			cmd := fmt.Sprintf(`curl -q -s --connect-timeout 5 %s/hostname`, serviceAddress)
+			_, err := framework.RunHostCmd(pausePod.Namespace, pausePod.Name, cmd)
+			framework.ExpectError(err, "expected error when trying to connect to cluster IP from a node without local endpoints")
+		}
+	})
+})
@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	e2essh "k8s.io/kubernetes/test/e2e/framework/ssh"
+	"k8s.io/kubernetes/test/e2e/network/common"
+
+	"github.com/onsi/ginkgo"
+)
+
+const (
+	// kubeProxyDaemonSetName is the name of the kube-proxy DaemonSet on
+	// clusters which manage kube-proxy that way.
+	kubeProxyDaemonSetName = "kube-proxy"
+	// kubeProxyRestartedAtAnnotation is bumped on the DaemonSet pod template
+	// to force a rolling update without changing the image, simulating an
+	// upgrade of the running version.
+	kubeProxyRestartedAtAnnotation = "e2e.kubernetes.io/restartedAt"
+	// kubeProxyRollTimeout bounds how long a full node-by-node roll of
+	// kube-proxy may take.
+	kubeProxyRollTimeout = 10 * time.Minute
+)
+
+// proxyDisruption captures what the traffic runner observed while kube-proxy
+// was rolled on a single node.
+type proxyDisruption struct {
+	Node       string
+	Duration   time.Duration
+	Reconnects int
+	Downtime   time.Duration
+}
+
+func (d proxyDisruption) String() string {
+	return fmt.Sprintf("node %s: rolled in %v, %d reconnects, downtime %v",
+		d.Node, d.Duration.Round(time.Millisecond), d.Reconnects, d.Downtime.Round(time.Millisecond))
+}
+
+// diffDisruption builds the per-node measurement from two runner snapshots
+// taken around the roll of that node.
+func diffDisruption(node string, before, after streamStats, took time.Duration) proxyDisruption {
+	return proxyDisruption{
+		Node:       node,
+		Duration:   took,
+		Reconnects: after.Reconnects - before.Reconnects,
+		Downtime:   after.Downtime - before.Downtime,
+	}
+}
+
+// rollKubeProxyByHost restarts kube-proxy on every host in sequence while the
+// given runner keeps streaming, and returns one disruption measurement per
+// node. This is the harness for clusters running kube-proxy as a static pod
+// or under systemd.
+func rollKubeProxyByHost(runner *wsStreamRunner, hosts []string) ([]proxyDisruption, error) {
+	var disruptions []proxyDisruption
+	for _, host := range hosts {
+		before := runner.Snapshot()
+		start := time.Now()
+		if err := restartKubeProxyOnHost(host); err != nil {
+			return disruptions, err
+		}
+		// Leave the stream a little time to surface damage caused on this
+		// node before moving to the next one.
+		time.Sleep(5 * time.Second)
+		disruptions = append(disruptions, diffDisruption(host, before, runner.Snapshot(), time.Since(start)))
+	}
+	return disruptions, nil
+}
+
+// rollKubeProxyDaemonSet triggers a rolling update of the kube-proxy
+// DaemonSet — swapping the image when one is given, otherwise only bumping a
+// restart annotation — and watches the updated pod come up on every node in
+// turn, returning one disruption measurement per node.
+func rollKubeProxyDaemonSet(cs clientset.Interface, runner *wsStreamRunner, image string) ([]proxyDisruption, error) {
+	ctx := context.TODO()
+	ds, err := cs.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(ctx, kubeProxyDaemonSetName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube-proxy does not run as a DaemonSet: %v", err)
+	}
+	restartedAt := time.Now().Format(time.RFC3339Nano)
+	if ds.Spec.Template.Annotations == nil {
+		ds.Spec.Template.Annotations = map[string]string{}
+	}
+	ds.Spec.Template.Annotations[kubeProxyRestartedAtAnnotation] = restartedAt
+	if image != "" {
+		ds.Spec.Template.Spec.Containers[0].Image = image
+	}
+	ds, err = cs.AppsV1().DaemonSets(metav1.NamespaceSystem).Update(ctx, ds, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update kube-proxy DaemonSet: %v", err)
+	}
+
+	// The default rolling update strategy replaces one pod at a time. Watch
+	// nodes complete one by one and attribute the runner stats observed in
+	// each window to the node that was rolled during it.
+	var disruptions []proxyDisruption
+	updated := map[string]bool{}
+	before := runner.Snapshot()
+	windowStart := time.Now()
+	selector := labels.SelectorFromSet(ds.Spec.Selector.MatchLabels).String()
+	err = wait.PollImmediate(2*time.Second, kubeProxyRollTimeout, func() (bool, error) {
+		pods, err := cs.CoreV1().Pods(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, nil
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Spec.NodeName == "" || updated[pod.Spec.NodeName] {
+				continue
+			}
+			if pod.Annotations[kubeProxyRestartedAtAnnotation] != restartedAt {
+				continue
+			}
+			if !podutil.IsPodReady(pod) {
+				continue
+			}
+			updated[pod.Spec.NodeName] = true
+			after := runner.Snapshot()
+			disruptions = append(disruptions, diffDisruption(pod.Spec.NodeName, before, after, time.Since(windowStart)))
+			before = after
+			windowStart = time.Now()
+		}
+		return len(updated) >= int(ds.Status.DesiredNumberScheduled), nil
+	})
+	if err != nil {
+		return disruptions, fmt.Errorf("kube-proxy DaemonSet did not finish rolling: %v", err)
+	}
+	return disruptions, nil
+}
+
+var _ = common.SIGDescribe("kube-proxy rolling restart", func() {
+	f := framework.NewDefaultFramework("kube-proxy-roll")
+
+	var config *restclient.Config
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		config, err = framework.LoadConfig()
+		framework.ExpectNoError(err, "unable to get base config")
+	})
+
+	// checkDisruptions logs the per-node measurements and fails the test if
+	// any node's roll broke the established stream.
+	checkDisruptions := func(disruptions []proxyDisruption, stats streamStats) {
+		for _, d := range disruptions {
+			framework.Logf("%v", d)
+			if d.Reconnects > 0 {
+				framework.Failf("established stream did not survive the kube-proxy roll: %v (last error %v)", d, stats.LastError)
+			}
+		}
+		if stats.FramesReceived == 0 {
+			framework.Failf("no frames were echoed back over the stream: %+v", stats)
+		}
+	}
+
+	ginkgo.It("should roll the kube-proxy DaemonSet node by node without disrupting established streams [Disruptive]", func() {
+		if _, err := f.ClientSet.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(context.TODO(), kubeProxyDaemonSetName, metav1.GetOptions{}); err != nil {
+			e2eskipper.Skipf("kube-proxy is not managed as a DaemonSet: %v", err)
+		}
+
+		url, pod := portForwardStreamURL(f)
+
+		ginkgo.By("starting a websocket stream to " + pod.Name)
+		runner := newWebsocketStreamRunner(config, url, []string{"v4.channel.k8s.io"}, time.Second)
+		framework.ExpectNoError(runner.Start())
+		// Let the stream settle before rolling the datapath.
+		time.Sleep(10 * time.Second)
+
+		ginkgo.By("rolling the kube-proxy DaemonSet node by node while the stream is active")
+		disruptions, err := rollKubeProxyDaemonSet(f.ClientSet, runner, "")
+
+		// Give the stream time to surface any damage the roll caused.
+		time.Sleep(10 * time.Second)
+		stats := runner.Stop()
+		framework.ExpectNoError(err)
+
+		checkDisruptions(disruptions, stats)
+	})
+
+	ginkgo.It("should restart kube-proxy node by node without disrupting established streams [Disruptive]", func() {
+		e2eskipper.SkipUnlessProviderIs(framework.ProvidersWithSSH...)
+		e2eskipper.SkipUnlessSSHKeyPresent()
+
+		hosts, err := e2essh.NodeSSHHosts(f.ClientSet)
+		framework.ExpectNoError(err, "failed to find external/internal IPs for every node")
+		if len(hosts) == 0 {
+			framework.Failf("No ssh-able nodes")
+		}
+
+		url, pod := portForwardStreamURL(f)
+
+		ginkgo.By("starting a websocket stream to " + pod.Name)
+		runner := newWebsocketStreamRunner(config, url, []string{"v4.channel.k8s.io"}, time.Second)
+		framework.ExpectNoError(runner.Start())
+		// Let the stream settle before rolling the datapath.
+		time.Sleep(10 * time.Second)
+
+		ginkgo.By("restarting kube-proxy node by node while the stream is active")
+		disruptions, err := rollKubeProxyByHost(runner, hosts)
+
+		// Give the stream time to surface any damage the restarts caused.
+		time.Sleep(10 * time.Second)
+		stats := runner.Stop()
+		framework.ExpectNoError(err)
+
+		checkDisruptions(disruptions, stats)
+	})
+})
@@ -283,8 +283,8 @@ var _ = common.SIGDescribe("LoadBalancers", func() {
 	})
 
 	ginkgo.It("should be able to change the type and ports of a UDP service [Slow]", func() {
-		// requires cloud load-balancer support
-		e2eskipper.SkipUnlessProviderIs("gce", "gke")
+		// requires cloud load-balancer support for UDP services
+		e2eskipper.RequireProviderCapability(framework.CapabilityUDPLoadBalancer)
 
 		loadBalancerLagTimeout := e2eservice.LoadBalancerLagTimeoutDefault
 		loadBalancerCreateTimeout := e2eservice.GetServiceLoadBalancerCreationTimeout(cs)
@@ -485,8 +485,7 @@ var _ = common.SIGDescribe("LoadBalancers", func() {
 	})
 
 	ginkgo.It("should only allow access from service loadbalancer source ranges [Slow]", func() {
-		// this feature currently supported only on GCE/GKE/AWS
-		e2eskipper.SkipUnlessProviderIs("gce", "gke", "aws")
+		e2eskipper.RequireProviderCapability(framework.CapabilityLoadBalancerSourceRanges)
 
 		loadBalancerCreateTimeout := e2eservice.GetServiceLoadBalancerCreationTimeout(cs)
 
@@ -569,7 +568,7 @@ var _ = common.SIGDescribe("LoadBalancers", func() {
 	})
 
 	ginkgo.It("should be able to create an internal type load balancer [Slow]", func() {
-		e2eskipper.SkipUnlessProviderIs("azure", "gke", "gce")
+		e2eskipper.RequireProviderCapability(framework.CapabilityInternalLoadBalancer)
 
 		createTimeout := e2eservice.GetServiceLoadBalancerCreationTimeout(cs)
 		pollInterval := framework.Poll * 10
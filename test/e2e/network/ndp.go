@@ -0,0 +1,250 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	"k8s.io/kubernetes/test/e2e/network/common"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/onsi/ginkgo"
+)
+
+const (
+	ndpServiceName = "svc-ndp"
+	ndpHostExecPod = "e2e-net-exec-ndp"
+	ndpBackend1    = "pod-ndp-backend-1"
+	ndpBackend2    = "pod-ndp-backend-2"
+)
+
+// neighborEntry is one row of a node's IPv6 neighbor table.
+type neighborEntry struct {
+	// IP is the neighbor address.
+	IP string
+	// Device is the interface the neighbor was learned on.
+	Device string
+	// LLAddr is the link-layer address, empty for failed entries.
+	LLAddr string
+	// State is the NUD state, e.g. REACHABLE, STALE or FAILED.
+	State string
+}
+
+// isValid reports whether the entry can currently be used to reach the IP.
+func (e neighborEntry) isValid() bool {
+	switch e.State {
+	case "REACHABLE", "STALE", "DELAY", "PROBE", "PERMANENT", "NOARP":
+		return e.LLAddr != ""
+	}
+	return false
+}
+
+// parseNeighborTable parses the output of `ip -6 neigh show`. Lines look like
+//
+//	fd00:10:244::5 dev eth0 lladdr 2e:15:95:00:00:05 router REACHABLE
+//	fd00:10:244::6 dev eth0 FAILED
+//
+// Lines that do not start with an address, e.g. continuation output of proxy
+// entries, are skipped.
+func parseNeighborTable(output string) []neighborEntry {
+	var entries []neighborEntry
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		entry := neighborEntry{IP: fields[0]}
+		for i := 1; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "dev":
+				entry.Device = fields[i+1]
+			case "lladdr":
+				entry.LLAddr = fields[i+1]
+			}
+		}
+		// The NUD state is the last field and is the only upper-case one;
+		// flags like "router" are lower case.
+		if state := fields[len(fields)-1]; state == strings.ToUpper(state) {
+			entry.State = state
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// getNeighborEntries returns the node's neighbor table entries for the given
+// IP, read through the host-network exec pod.
+func getNeighborEntries(fr *framework.Framework, hostExecPodName, ip string) []neighborEntry {
+	output, err := framework.RunHostCmd(fr.Namespace.Name, hostExecPodName, "ip -6 neigh show")
+	framework.ExpectNoError(err)
+	var entries []neighborEntry
+	for _, entry := range parseNeighborTable(output) {
+		if entry.IP == ip {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// waitForNeighborEntry waits until the node's neighbor table has an entry for
+// the IP whose validity matches wantValid.
+func waitForNeighborEntry(fr *framework.Framework, hostExecPodName, ip string, wantValid bool) error {
+	return wait.PollImmediate(2*time.Second, time.Minute, func() (bool, error) {
+		entries := getNeighborEntries(fr, hostExecPodName, ip)
+		if !wantValid {
+			// The entry may be garbage collected instead of failing.
+			for _, entry := range entries {
+				if entry.isValid() {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+		for _, entry := range entries {
+			if entry.isValid() {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// IPv6 neighbor discovery is the ARP equivalent of the service VIP data path:
+// once kube-proxy has translated the VIP to a backend pod IP, the node
+// resolves the pod's link-layer address through NDP and caches it in the
+// neighbor table. A stale cache entry that survives an endpoint move would
+// blackhole the service, which is why the IPv4 side has conntrack-flush
+// coverage; these checks mirror that for the IPv6 neighbor cache.
+var _ = common.SIGDescribe("IPv6 NDP [Feature:Networking-IPv6] [LinuxOnly]", func() {
+
+	fr := framework.NewDefaultFramework("ndp")
+
+	ginkgo.BeforeEach(func() {
+		if !framework.TestContext.ClusterIsIPv6() {
+			e2eskipper.Skipf("Test requires an IPv6 cluster")
+		}
+	})
+
+	ginkgo.It("should not serve a ClusterIP service from a poisoned neighbor cache after the endpoint moves", func() {
+		cs := fr.ClientSet
+		ns := fr.Namespace.Name
+
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(cs, 1)
+		framework.ExpectNoError(err)
+		nodeName := nodes.Items[0].Name
+
+		// The exec pod shares the node's network namespace so that it reads
+		// the node's neighbor table and exercises the node's VIP data path.
+		ginkgo.By("creating a host-network exec pod on node " + nodeName)
+		privileged := true
+		hostExecPod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ndpHostExecPod,
+				Namespace: ns,
+			},
+			Spec: v1.PodSpec{
+				HostNetwork: true,
+				NodeName:    nodeName,
+				Containers: []v1.Container{
+					{
+						Name:            ndpHostExecPod,
+						Image:           imageutils.GetE2EImage(imageutils.DebianIptables),
+						ImagePullPolicy: v1.PullIfNotPresent,
+						Command:         []string{"sleep", "600"},
+						SecurityContext: &v1.SecurityContext{
+							Privileged: &privileged,
+						},
+					},
+				},
+			},
+		}
+		fr.PodClient().CreateSync(hostExecPod)
+
+		ginkgo.By("creating a ClusterIP service " + ndpServiceName)
+		jig := e2eservice.NewTestJig(cs, ns, ndpServiceName)
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(8080)},
+			}
+		})
+		framework.ExpectNoError(err)
+
+		// The backend runs on the same node as the exec pod so that the pod
+		// IP is resolved over the veth pair and shows up in the node's
+		// neighbor table.
+		ginkgo.By("creating a backend pod " + ndpBackend1)
+		backend1 := e2epod.NewAgnhostPod(ns, ndpBackend1, nil, nil, nil, "netexec", "--http-port=8080")
+		backend1.Labels = jig.Labels
+		e2epod.SetNodeSelection(&backend1.Spec, e2epod.NodeSelection{Name: nodeName})
+		backend1 = fr.PodClient().CreateSync(backend1)
+		validateEndpointsPortsOrFail(cs, ns, ndpServiceName, portsByPodName{ndpBackend1: {8080}})
+
+		curlVIP := fmt.Sprintf("curl -g -q -s --connect-timeout 5 http://[%s]:80/hostname", svc.Spec.ClusterIP)
+		hostnameFromVIP := func() string {
+			var hostname string
+			err := wait.PollImmediate(2*time.Second, time.Minute, func() (bool, error) {
+				output, err := framework.RunHostCmd(ns, ndpHostExecPod, curlVIP)
+				if err != nil {
+					return false, nil
+				}
+				hostname = strings.TrimSpace(output)
+				return hostname != "", nil
+			})
+			framework.ExpectNoError(err, "failed to reach service VIP %s", svc.Spec.ClusterIP)
+			return hostname
+		}
+
+		ginkgo.By("connecting to the service VIP and resolving the first backend over NDP")
+		framework.ExpectEqual(hostnameFromVIP(), ndpBackend1)
+		framework.ExpectNoError(waitForNeighborEntry(fr, ndpHostExecPod, backend1.Status.PodIP, true),
+			"no valid neighbor entry for backend %s (%s)", ndpBackend1, backend1.Status.PodIP)
+
+		ginkgo.By("moving the endpoint to a new backend pod " + ndpBackend2)
+		backend2 := e2epod.NewAgnhostPod(ns, ndpBackend2, nil, nil, nil, "netexec", "--http-port=8080")
+		backend2.Labels = jig.Labels
+		e2epod.SetNodeSelection(&backend2.Spec, e2epod.NodeSelection{Name: nodeName})
+		backend2 = fr.PodClient().CreateSync(backend2)
+		fr.PodClient().DeleteSync(ndpBackend1, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
+		validateEndpointsPortsOrFail(cs, ns, ndpServiceName, portsByPodName{ndpBackend2: {8080}})
+
+		ginkgo.By("connecting to the service VIP again and resolving the new backend")
+		framework.ExpectEqual(hostnameFromVIP(), ndpBackend2)
+		framework.ExpectNoError(waitForNeighborEntry(fr, ndpHostExecPod, backend2.Status.PodIP, true),
+			"no valid neighbor entry for backend %s (%s)", ndpBackend2, backend2.Status.PodIP)
+
+		// The first backend's veth is gone, so a usable neighbor entry for
+		// its IP would be a poisoned cache. The entry may also be reused when
+		// the new pod inherited the IP, which the hostname check above
+		// already proved to be serving the right backend.
+		if backend1.Status.PodIP != backend2.Status.PodIP {
+			ginkgo.By("checking that the neighbor entry of the removed backend is gone")
+			framework.ExpectNoError(waitForNeighborEntry(fr, ndpHostExecPod, backend1.Status.PodIP, false),
+				"neighbor entry for removed backend %s (%s) is still valid", ndpBackend1, backend1.Status.PodIP)
+		}
+	})
+})
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	"k8s.io/kubernetes/test/e2e/network/common"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/onsi/ginkgo"
+)
+
+const (
+	policyRoutingServiceName = "svc-policy-routing"
+	policyRoutingHostExecPod = "e2e-net-exec-policy-routing"
+	policyRoutingBackendPod  = "pod-policy-routing-backend"
+
+	// secondaryRouteTableID is the routing table the tests populate. It is
+	// well above the tables CNI plugins typically claim and below the
+	// reserved main/local tables.
+	secondaryRouteTableID = 100
+	// secondaryRulePriority places the test's policy rule before the main
+	// table lookup (priority 32766), so the secondary table wins.
+	secondaryRulePriority = 512
+	// secondaryDefaultRouteMetric keeps the extra default route from
+	// preempting the primary one.
+	secondaryDefaultRouteMetric = 4096
+)
+
+// parseDefaultRoute extracts the gateway and device of the first default
+// route in `ip route show default` output, e.g.
+//
+//	default via 10.0.2.2 dev eth0 proto dhcp metric 100
+func parseDefaultRoute(output string) (gateway, device string, err error) {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 1 || fields[0] != "default" {
+			continue
+		}
+		for i := 1; i < len(fields)-1; i++ {
+			switch fields[i] {
+			case "via":
+				gateway = fields[i+1]
+			case "dev":
+				device = fields[i+1]
+			}
+		}
+		if device != "" {
+			return gateway, device, nil
+		}
+	}
+	return "", "", fmt.Errorf("no default route in %q", output)
+}
+
+// setupSecondaryRoutingTable copies the node's main routing table into the
+// secondary table and adds a policy rule that resolves all lookups through
+// it, bypassing the main table the way a multi-homed node with source-based
+// routing does. It returns a rollback func that restores the node; callers
+// must run it even when the test fails, the rule outlives the test namespace.
+func setupSecondaryRoutingTable(ns, hostExecPodName string) (rollback func(), err error) {
+	copyRoutes := fmt.Sprintf(
+		"ip route show table main | while read route; do ip route add $route table %d; done",
+		secondaryRouteTableID)
+	if _, err := framework.RunHostCmd(ns, hostExecPodName, copyRoutes); err != nil {
+		return nil, fmt.Errorf("copying the main routing table: %v", err)
+	}
+	addRule := fmt.Sprintf("ip rule add priority %d lookup %d", secondaryRulePriority, secondaryRouteTableID)
+	if _, err := framework.RunHostCmd(ns, hostExecPodName, addRule); err != nil {
+		return nil, fmt.Errorf("adding the policy rule: %v", err)
+	}
+	return func() {
+		delRule := fmt.Sprintf("ip rule del priority %d lookup %d", secondaryRulePriority, secondaryRouteTableID)
+		_, err := framework.RunHostCmd(ns, hostExecPodName, delRule)
+		framework.ExpectNoError(err, "failed to remove the test policy rule from the node")
+		flushTable := fmt.Sprintf("ip route flush table %d", secondaryRouteTableID)
+		_, err = framework.RunHostCmd(ns, hostExecPodName, flushTable)
+		framework.ExpectNoError(err, "failed to flush the test routing table on the node")
+	}, nil
+}
+
+// addSecondaryDefaultRoute adds a second default route to the node's main
+// table with a higher metric, the way a second uplink managed by DHCP shows
+// up. It returns a rollback func removing the route again.
+func addSecondaryDefaultRoute(ns, hostExecPodName string) (rollback func(), err error) {
+	output, err := framework.RunHostCmd(ns, hostExecPodName, "ip route show default")
+	if err != nil {
+		return nil, fmt.Errorf("reading the node's default routes: %v", err)
+	}
+	gateway, device, err := parseDefaultRoute(output)
+	if err != nil {
+		return nil, err
+	}
+	route := fmt.Sprintf("default dev %s metric %d", device, secondaryDefaultRouteMetric)
+	if gateway != "" {
+		route = fmt.Sprintf("default via %s dev %s metric %d", gateway, device, secondaryDefaultRouteMetric)
+	}
+	if _, err := framework.RunHostCmd(ns, hostExecPodName, "ip route add "+route); err != nil {
+		return nil, fmt.Errorf("adding a secondary default route: %v", err)
+	}
+	return func() {
+		_, err := framework.RunHostCmd(ns, hostExecPodName, "ip route del "+route)
+		framework.ExpectNoError(err, "failed to remove the secondary default route from the node")
+	}, nil
+}
+
+// Multi-homed bare-metal nodes commonly carry several default routes and
+// resolve them through policy rules instead of the main table. kube-proxy and
+// the kubelet's host networking must not assume that the main table is the
+// only source of routing decisions; these tests rewire a node's routing
+// through a secondary table and check that service VIPs keep working.
+var _ = common.SIGDescribe("PolicyRouting [LinuxOnly] [Disruptive]", func() {
+
+	fr := framework.NewDefaultFramework("policy-routing")
+
+	var nodeName string
+
+	ginkgo.BeforeEach(func() {
+		nodes, err := e2enode.GetBoundedReadySchedulableNodes(fr.ClientSet, 1)
+		framework.ExpectNoError(err)
+		if len(nodes.Items) < 1 {
+			e2eskipper.Skipf("Test requires at least 1 schedulable node")
+		}
+		nodeName = nodes.Items[0].Name
+
+		// The exec pod shares the node's network namespace so that it can
+		// rewrite the node's routing tables and exercise the node's VIP data
+		// path.
+		ginkgo.By("creating a host-network exec pod on node " + nodeName)
+		privileged := true
+		hostExecPod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      policyRoutingHostExecPod,
+				Namespace: fr.Namespace.Name,
+			},
+			Spec: v1.PodSpec{
+				HostNetwork: true,
+				NodeName:    nodeName,
+				Containers: []v1.Container{
+					{
+						Name:            policyRoutingHostExecPod,
+						Image:           imageutils.GetE2EImage(imageutils.DebianIptables),
+						ImagePullPolicy: v1.PullIfNotPresent,
+						Command:         []string{"sleep", "600"},
+						SecurityContext: &v1.SecurityContext{
+							Privileged: &privileged,
+						},
+					},
+				},
+			},
+		}
+		fr.PodClient().CreateSync(hostExecPod)
+	})
+
+	// setupService creates a ClusterIP service with one backend pod on the
+	// node and returns a func that polls the VIP until the backend answers.
+	setupService := func() func() {
+		cs := fr.ClientSet
+		ns := fr.Namespace.Name
+
+		ginkgo.By("creating a ClusterIP service " + policyRoutingServiceName)
+		jig := e2eservice.NewTestJig(cs, ns, policyRoutingServiceName)
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(8080)},
+			}
+		})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating a backend pod " + policyRoutingBackendPod + " on node " + nodeName)
+		backend := e2epod.NewAgnhostPod(ns, policyRoutingBackendPod, nil, nil, nil, "netexec", "--http-port=8080")
+		backend.Labels = jig.Labels
+		e2epod.SetNodeSelection(&backend.Spec, e2epod.NodeSelection{Name: nodeName})
+		fr.PodClient().CreateSync(backend)
+		validateEndpointsPortsOrFail(cs, ns, policyRoutingServiceName, portsByPodName{policyRoutingBackendPod: {8080}})
+
+		curlVIP := fmt.Sprintf("curl -g -q -s --connect-timeout 5 http://%s/hostname",
+			net.JoinHostPort(svc.Spec.ClusterIP, "80"))
+		return func() {
+			var hostname string
+			err := wait.PollImmediate(2*time.Second, e2eservice.KubeProxyLagTimeout, func() (bool, error) {
+				output, err := framework.RunHostCmd(ns, policyRoutingHostExecPod, curlVIP)
+				if err != nil {
+					return false, nil
+				}
+				hostname = strings.TrimSpace(output)
+				return hostname != "", nil
+			})
+			framework.ExpectNoError(err, "failed to reach service VIP %s", svc.Spec.ClusterIP)
+			framework.ExpectEqual(hostname, policyRoutingBackendPod)
+		}
+	}
+
+	ginkgo.It("should keep serving a ClusterIP service when node routing goes through a secondary table [Privileged]", func() {
+		reachBackendOrFail := setupService()
+
+		ginkgo.By("connecting to the service VIP before changing the routing setup")
+		reachBackendOrFail()
+
+		ginkgo.By("resolving all node routing through a secondary routing table")
+		rollback, err := setupSecondaryRoutingTable(fr.Namespace.Name, policyRoutingHostExecPod)
+		framework.ExpectNoError(err)
+		defer rollback()
+
+		ginkgo.By("connecting to the service VIP through the secondary routing table")
+		reachBackendOrFail()
+	})
+
+	ginkgo.It("should keep serving a ClusterIP service when the node has multiple default routes [Privileged]", func() {
+		reachBackendOrFail := setupService()
+
+		ginkgo.By("connecting to the service VIP before changing the routing setup")
+		reachBackendOrFail()
+
+		ginkgo.By("adding a second default route with a higher metric")
+		rollback, err := addSecondaryDefaultRoute(fr.Namespace.Name, policyRoutingHostExecPod)
+		framework.ExpectNoError(err)
+		defer rollback()
+
+		ginkgo.By("connecting to the service VIP with both default routes present")
+		reachBackendOrFail()
+	})
+})
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eservice "k8s.io/kubernetes/test/e2e/framework/service"
+	"k8s.io/kubernetes/test/e2e/network/common"
+	utilptr "k8s.io/utils/pointer"
+)
+
+const (
+	saTokenServiceName = "sa-token-identity"
+	saTokenBackendPod  = "sa-token-backend"
+	saTokenClientPod   = "sa-token-client"
+	saTokenHTTPPort    = 8080
+	saTokenAudience    = "e2e-sa-token-identity"
+	saTokenVolumeName  = "sa-token-identity"
+	saTokenMountPath   = "/var/run/secrets/tokens"
+	saTokenPath        = "identity-token"
+)
+
+// withProjectedServiceAccountToken mounts an audience-scoped projected service
+// account token into the pod's first container, the way workloads present a
+// network identity to service meshes.
+func withProjectedServiceAccountToken(pod *v1.Pod, audience string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: saTokenVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: []v1.VolumeProjection{
+					{
+						ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+							Path:              saTokenPath,
+							Audience:          audience,
+							ExpirationSeconds: utilptr.Int64Ptr(60 * 60),
+						},
+					},
+				},
+			},
+		},
+	})
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, v1.VolumeMount{
+		Name:      saTokenVolumeName,
+		MountPath: saTokenMountPath,
+		ReadOnly:  true,
+	})
+}
+
+// deliverTokenOrFail makes the client pod send its projected token through the
+// given address to the netexec backend and returns the token the backend saw.
+// The token is a JWT and therefore URL safe, so it can travel as the echo
+// message without escaping.
+func deliverTokenOrFail(ns, clientPod, address string) string {
+	cmd := fmt.Sprintf("curl -g -q -s --connect-timeout 5 'http://%s/echo?msg='$(cat %s/%s)", address, saTokenMountPath, saTokenPath)
+	var delivered string
+	err := wait.PollImmediate(2*time.Second, e2eservice.KubeProxyLagTimeout, func() (bool, error) {
+		output, err := framework.RunHostCmd(ns, clientPod, cmd)
+		if err != nil {
+			return false, nil
+		}
+		delivered = strings.TrimSpace(output)
+		return delivered != "", nil
+	})
+	framework.ExpectNoError(err, "failed to deliver the projected token through %s", address)
+	return delivered
+}
+
+// reviewToken asks the apiserver whether the token authenticates for the given
+// audiences, which is the check an in-cluster service performs on a presented
+// identity.
+func reviewToken(cs clientset.Interface, token string, audiences []string) *authenticationv1.TokenReview {
+	tokenReview := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: audiences,
+		},
+	}
+	tokenReview, err := cs.AuthenticationV1().TokenReviews().Create(context.TODO(), tokenReview, metav1.CreateOptions{})
+	framework.ExpectNoError(err, "failed to create TokenReview")
+	return tokenReview
+}
+
+// Workloads behind service meshes identify themselves to in-cluster services
+// by presenting an audience-scoped projected service account token over the
+// network. These checks verify that such a token survives the service data
+// path intact, that the receiving side can validate it for the intended
+// audience, and that the token is useless for any other audience.
+var _ = common.SIGDescribe("ServiceAccount token network identity", func() {
+
+	fr := framework.NewDefaultFramework("sa-token-identity")
+
+	ginkgo.It("should deliver a projected token through a ClusterIP service and validate it for its audience", func() {
+		cs := fr.ClientSet
+		ns := fr.Namespace.Name
+
+		ginkgo.By("creating service " + saTokenServiceName + " in namespace " + ns)
+		jig := e2eservice.NewTestJig(cs, ns, saTokenServiceName)
+		svc, err := jig.CreateTCPService(func(svc *v1.Service) {
+			svc.Spec.Ports = []v1.ServicePort{
+				{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(saTokenHTTPPort)},
+			}
+		})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating backend pod " + saTokenBackendPod)
+		backend := e2epod.NewAgnhostPod(ns, saTokenBackendPod, nil, nil, nil, "netexec", fmt.Sprintf("--http-port=%d", saTokenHTTPPort))
+		backend.Labels = jig.Labels
+		fr.PodClient().CreateSync(backend)
+		validateEndpointsPortsOrFail(cs, ns, saTokenServiceName, portsByPodName{saTokenBackendPod: {saTokenHTTPPort}})
+
+		ginkgo.By("creating client pod " + saTokenClientPod + " with a projected token for audience " + saTokenAudience)
+		e2epod.CreateExecPodOrFail(cs, ns, saTokenClientPod, func(pod *v1.Pod) {
+			withProjectedServiceAccountToken(pod, saTokenAudience)
+		})
+
+		ginkgo.By("presenting the token to the backend through the service")
+		delivered := deliverTokenOrFail(ns, saTokenClientPod, net.JoinHostPort(svc.Spec.ClusterIP, "80"))
+
+		ginkgo.By("checking the token was delivered intact")
+		mounted, err := framework.RunHostCmd(ns, saTokenClientPod, fmt.Sprintf("cat %s/%s", saTokenMountPath, saTokenPath))
+		framework.ExpectNoError(err, "failed to read the projected token in the client pod")
+		framework.ExpectEqual(delivered, strings.TrimSpace(mounted), "token received by the backend differs from the mounted token")
+
+		ginkgo.By("validating the delivered token for its audience")
+		tokenReview := reviewToken(cs, delivered, []string{saTokenAudience})
+		if !tokenReview.Status.Authenticated {
+			framework.Failf("delivered token did not authenticate for audience %q: %v", saTokenAudience, tokenReview.Status.Error)
+		}
+		expectedUser := fmt.Sprintf("system:serviceaccount:%s:default", ns)
+		framework.ExpectEqual(tokenReview.Status.User.Username, expectedUser)
+
+		ginkgo.By("checking the delivered token does not validate for a different audience")
+		tokenReview = reviewToken(cs, delivered, []string{"e2e-some-other-audience"})
+		if tokenReview.Status.Authenticated {
+			framework.Failf("token scoped to audience %q unexpectedly authenticated for a different audience", saTokenAudience)
+		}
+	})
+
+	ginkgo.It("should deliver a projected token through a service without selectors via a manual endpoint", func() {
+		cs := fr.ClientSet
+		ns := fr.Namespace.Name
+
+		// Meshes routinely point services at endpoints they manage themselves;
+		// the identity presented by the client must be independent of how the
+		// service finds its backends.
+		ginkgo.By("creating backend pod " + saTokenBackendPod)
+		backend := e2epod.NewAgnhostPod(ns, saTokenBackendPod, nil, nil, nil, "netexec", fmt.Sprintf("--http-port=%d", saTokenHTTPPort))
+		backend = fr.PodClient().CreateSync(backend)
+
+		ginkgo.By("creating service " + saTokenServiceName + " without selectors in namespace " + ns)
+		svc, err := cs.CoreV1().Services(ns).Create(context.TODO(), &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: saTokenServiceName},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{
+					{Port: 80, Name: "http", Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt(saTokenHTTPPort)},
+				},
+			},
+		}, metav1.CreateOptions{})
+		framework.ExpectNoError(err)
+
+		_, err = cs.CoreV1().Endpoints(ns).Create(context.TODO(), &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: saTokenServiceName},
+			Subsets: []v1.EndpointSubset{
+				{
+					Addresses: []v1.EndpointAddress{{IP: backend.Status.PodIP}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: saTokenHTTPPort, Protocol: v1.ProtocolTCP}},
+				},
+			},
+		}, metav1.CreateOptions{})
+		framework.ExpectNoError(err)
+
+		ginkgo.By("creating client pod " + saTokenClientPod + " with a projected token for audience " + saTokenAudience)
+		e2epod.CreateExecPodOrFail(cs, ns, saTokenClientPod, func(pod *v1.Pod) {
+			withProjectedServiceAccountToken(pod, saTokenAudience)
+		})
+
+		ginkgo.By("presenting the token to the backend through the service")
+		delivered := deliverTokenOrFail(ns, saTokenClientPod, net.JoinHostPort(svc.Spec.ClusterIP, "80"))
+
+		ginkgo.By("validating the delivered token for its audience")
+		tokenReview := reviewToken(cs, delivered, []string{saTokenAudience})
+		if !tokenReview.Status.Authenticated {
+			framework.Failf("delivered token did not authenticate for audience %q: %v", saTokenAudience, tokenReview.Status.Error)
+		}
+	})
+})
@@ -0,0 +1,297 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"golang.org/x/net/websocket"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	e2essh "k8s.io/kubernetes/test/e2e/framework/ssh"
+	e2ewebsocket "k8s.io/kubernetes/test/e2e/framework/websocket"
+	"k8s.io/kubernetes/test/e2e/network/common"
+)
+
+// streamStats records what a stream runner observed over its lifetime. A
+// healthy long-lived stream keeps FramesReceived growing and Reconnects at
+// zero; any disconnect is counted together with the time it took to
+// re-establish the stream so disruption tests can put a number on the outage.
+type streamStats struct {
+	FramesSent     int
+	FramesReceived int
+	Reconnects     int
+	Downtime       time.Duration
+	LastError      error
+}
+
+// wsStreamRunner keeps a long-lived websocket stream open, writing a payload
+// at a fixed interval and reading the echoed frames back. When the stream
+// breaks it redials and keeps going, recording the disconnect in its stats.
+// The zero value is not usable; construct with newWebsocketStreamRunner.
+type wsStreamRunner struct {
+	url       *url.URL
+	config    *restclient.Config
+	protocols []string
+	interval  time.Duration
+
+	mu     sync.Mutex
+	stats  streamStats
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// newWebsocketStreamRunner returns a runner that will stream over a websocket
+// connection to the given URL (typically a pod portforward or exec URL served
+// by the API server) once Start is called.
+func newWebsocketStreamRunner(config *restclient.Config, url *url.URL, protocols []string, interval time.Duration) *wsStreamRunner {
+	return &wsStreamRunner{
+		url:       url,
+		config:    config,
+		protocols: protocols,
+		interval:  interval,
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Start dials the websocket and begins streaming in a goroutine. It returns
+// once the initial connection is established so callers know traffic is
+// flowing before they start disrupting the datapath.
+func (r *wsStreamRunner) Start() error {
+	conn, err := e2ewebsocket.OpenWebSocketForURL(r.url, r.config, r.protocols)
+	if err != nil {
+		return fmt.Errorf("failed to establish initial websocket stream to %s: %v", r.url.String(), err)
+	}
+	go r.run(conn)
+	return nil
+}
+
+// Stop terminates the stream and returns the accumulated stats.
+func (r *wsStreamRunner) Stop() streamStats {
+	close(r.stopCh)
+	<-r.doneCh
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Snapshot returns the stats accumulated so far without stopping the stream,
+// so harnesses can attribute observations to a window of the run.
+func (r *wsStreamRunner) Snapshot() streamStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+func (r *wsStreamRunner) run(conn *websocket.Conn) {
+	defer close(r.doneCh)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		err := r.exchange(conn)
+		if err == nil {
+			continue
+		}
+
+		// The stream broke; keep redialing until we either reconnect or
+		// are told to stop, and account the downtime.
+		framework.Logf("websocket stream to %s broke: %v, reconnecting", r.url.String(), err)
+		conn.Close()
+		conn = nil
+		disconnected := time.Now()
+		for conn == nil {
+			select {
+			case <-r.stopCh:
+				r.recordDisconnect(err, time.Since(disconnected))
+				return
+			case <-time.After(r.interval):
+			}
+			conn, err = e2ewebsocket.OpenWebSocketForURL(r.url, r.config, r.protocols)
+			if err != nil {
+				conn = nil
+			}
+		}
+		r.recordDisconnect(err, time.Since(disconnected))
+	}
+}
+
+// exchange writes one frame on channel 0 and reads frames until one carrying
+// data arrives. Frames on other channels (e.g. the port numbers the
+// portforward protocol sends when a stream is created) are skipped.
+func (r *wsStreamRunner) exchange(conn *websocket.Conn) error {
+	frame := append([]byte{0}, []byte("keepalive")...)
+	if err := websocket.Message.Send(conn, frame); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.stats.FramesSent++
+	r.mu.Unlock()
+
+	conn.SetReadDeadline(time.Now().Add(r.interval * 4))
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(conn, &data); err != nil {
+			return err
+		}
+		if len(data) < 2 || data[0] != 0 {
+			continue
+		}
+		r.mu.Lock()
+		r.stats.FramesReceived++
+		r.mu.Unlock()
+		return nil
+	}
+}
+
+func (r *wsStreamRunner) recordDisconnect(err error, downtime time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Reconnects++
+	r.stats.Downtime += downtime
+	r.stats.LastError = err
+}
+
+// restartKubeProxyOnHost kills kube-proxy on the given ssh host and waits for
+// the supervisor (systemd or the static pod kubelet) to bring it back.
+func restartKubeProxyOnHost(host string) error {
+	result, err := e2essh.SSH("sudo pkill kube-proxy", host, framework.TestContext.Provider)
+	if err != nil || result.Code != 0 {
+		e2essh.LogResult(result)
+		return fmt.Errorf("couldn't restart kube-proxy on %s: %v", host, err)
+	}
+	// Wait for kube-proxy to come back up before declaring the restart done.
+	const restartTimeout = 2 * time.Minute
+	for start := time.Now(); time.Since(start) < restartTimeout; time.Sleep(5 * time.Second) {
+		result, err := e2essh.SSH("pgrep kube-proxy", host, framework.TestContext.Provider)
+		if err == nil && result.Code == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("kube-proxy did not come back on %s within %v", host, restartTimeout)
+}
+
+// portForwardStreamURL creates an agnhost netexec pod echoing on a TCP port
+// and returns the API server portforward URL for it, plus the pod.
+func portForwardStreamURL(f *framework.Framework) (*url.URL, *v1.Pod) {
+	ns := f.Namespace.Name
+	pod := e2epod.NewAgnhostPod(ns, "stream-backend", nil, nil, nil, "netexec", "--http-port=8080", "--tcp-port=8081")
+	pod, err := f.ClientSet.CoreV1().Pods(ns).Create(context.TODO(), pod, metav1.CreateOptions{})
+	framework.ExpectNoError(err, "failed to create streaming backend pod")
+	framework.ExpectNoError(e2epod.WaitTimeoutForPodReadyInNamespace(f.ClientSet, pod.Name, ns, framework.PodStartTimeout))
+	pod, err = f.ClientSet.CoreV1().Pods(ns).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+	framework.ExpectNoError(err)
+
+	req := f.ClientSet.CoreV1().RESTClient().Get().
+		Namespace(ns).
+		Resource("pods").
+		Name(pod.Name).
+		Suffix("portforward").
+		Param("ports", "8081")
+	return req.URL(), pod
+}
+
+var _ = common.SIGDescribe("Streaming connectivity", func() {
+	f := framework.NewDefaultFramework("streaming")
+
+	var config *restclient.Config
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		config, err = framework.LoadConfig()
+		framework.ExpectNoError(err, "unable to get base config")
+	})
+
+	ginkgo.It("should keep a long-lived websocket stream through the API server alive", func() {
+		url, _ := portForwardStreamURL(f)
+
+		ginkgo.By("streaming over a websocket portforward connection for 30 seconds")
+		runner := newWebsocketStreamRunner(config, url, []string{"v4.channel.k8s.io"}, time.Second)
+		framework.ExpectNoError(runner.Start())
+		time.Sleep(30 * time.Second)
+		stats := runner.Stop()
+
+		framework.Logf("stream stats: %+v", stats)
+		if stats.FramesReceived == 0 {
+			framework.Failf("no frames were echoed back over the stream: %+v", stats)
+		}
+		if stats.Reconnects != 0 {
+			framework.Failf("stream reconnected %d times (downtime %v, last error %v) with no disruption applied",
+				stats.Reconnects, stats.Downtime, stats.LastError)
+		}
+	})
+
+	ginkgo.It("should keep an established websocket stream alive across a kube-proxy restart [Disruptive]", func() {
+		e2eskipper.SkipUnlessProviderIs(framework.ProvidersWithSSH...)
+		e2eskipper.SkipUnlessSSHKeyPresent()
+
+		url, pod := portForwardStreamURL(f)
+
+		hosts, err := e2essh.NodeSSHHosts(f.ClientSet)
+		framework.ExpectNoError(err, "failed to find external/internal IPs for every node")
+		if len(hosts) == 0 {
+			framework.Failf("No ssh-able nodes")
+		}
+
+		ginkgo.By("starting a websocket stream to " + pod.Name)
+		runner := newWebsocketStreamRunner(config, url, []string{"v4.channel.k8s.io"}, time.Second)
+		framework.ExpectNoError(runner.Start())
+		// Let the stream settle before disrupting the node.
+		time.Sleep(10 * time.Second)
+
+		ginkgo.By("restarting kube-proxy on every ssh-able node while the stream is active")
+		for _, host := range hosts {
+			framework.ExpectNoError(restartKubeProxyOnHost(host))
+		}
+
+		// Give the stream time to surface any damage the restarts caused.
+		time.Sleep(10 * time.Second)
+		stats := runner.Stop()
+
+		framework.Logf("stream stats across kube-proxy restarts: %+v", stats)
+		if stats.Reconnects != 0 {
+			framework.Failf("established stream did not survive kube-proxy restart: reconnected %d times, downtime %v, last error %v",
+				stats.Reconnects, stats.Downtime, stats.LastError)
+		}
+		if stats.FramesReceived == 0 {
+			framework.Failf("no frames were echoed back over the stream: %+v", stats)
+		}
+	})
+})
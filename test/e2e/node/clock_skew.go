@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
+	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
+	e2essh "k8s.io/kubernetes/test/e2e/framework/ssh"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// clockSkewOffset is well below e2enode.MaxClockSkew but large enough to
+// exceed the tolerances kubelet heartbeating and certificate validation are
+// expected to have.
+const clockSkewOffset = 5 * time.Minute
+
+// A node clock that drifts away from the control plane is a recurring
+// bare-metal failure mode that is nearly impossible to reproduce after the
+// fact: leases are renewed with the node's time, and the kubelet's rotated
+// client certificate is validated against the apiserver's time. These tests
+// skew one node's clock deliberately and verify that the kubelet keeps its
+// heartbeats and its credentials working.
+var _ = SIGDescribe("ClockSkew [Serial] [Disruptive]", func() {
+
+	f := framework.NewDefaultFramework("clock-skew")
+
+	var node *v1.Node
+
+	ginkgo.BeforeEach(func() {
+		e2eskipper.SkipUnlessProviderIs(framework.ProvidersWithSSH...)
+		e2eskipper.SkipUnlessSSHKeyPresent()
+
+		var err error
+		node, err = e2enode.GetRandomReadySchedulableNode(f.ClientSet)
+		framework.ExpectNoError(err)
+	})
+
+	ginkgo.It("should keep renewing the node lease while the node clock is skewed", func() {
+		leaseClient := f.ClientSet.CoordinationV1().Leases(v1.NamespaceNodeLease)
+		lease, err := leaseClient.Get(context.TODO(), node.Name, metav1.GetOptions{})
+		framework.ExpectNoError(err)
+		lastRenewTime := (*lease.Spec.RenewTime).Time
+
+		ginkgo.By("skewing the node clock forward")
+		restore, err := e2enode.SkewClock(node, framework.TestContext.Provider, clockSkewOffset)
+		framework.ExpectNoError(err)
+		defer func() {
+			framework.ExpectNoError(restore(), "failed to restore the clock of node %s", node.Name)
+		}()
+
+		ginkgo.By("checking that the lease is still renewed")
+		gomega.Eventually(func() error {
+			lease, err := leaseClient.Get(context.TODO(), node.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !(*lease.Spec.RenewTime).Time.After(lastRenewTime) {
+				return fmt.Errorf("lease renew time did not advance past %v", lastRenewTime)
+			}
+			return nil
+		}, 2*time.Minute, 5*time.Second).Should(gomega.BeNil())
+
+		ginkgo.By("checking that the node stays Ready")
+		if !e2enode.IsNodeReady(node) {
+			framework.Failf("node %s became not ready under clock skew", node.Name)
+		}
+	})
+
+	ginkgo.It("should keep the kubelet's rotated client certificate working while the node clock is skewed", func() {
+		// The kubelet authenticates every heartbeat with its (rotated) client
+		// certificate, so heartbeats advancing under skew prove the
+		// certificate is still accepted and the kubelet did not wedge itself
+		// by rotating into a certificate it considers not yet valid.
+		fingerprintCmd := "sudo md5sum /var/lib/kubelet/pki/kubelet-client-current.pem 2>/dev/null || echo no-rotated-certificate"
+		result, err := e2essh.IssueSSHCommandWithResult(fingerprintCmd, framework.TestContext.Provider, node)
+		framework.ExpectNoError(err)
+		fingerprintBefore := strings.TrimSpace(result.Stdout)
+		if fingerprintBefore == "no-rotated-certificate" {
+			e2eskipper.Skipf("node %s does not use kubelet client certificate rotation", node.Name)
+		}
+
+		ginkgo.By("skewing the node clock backward")
+		restore, err := e2enode.SkewClock(node, framework.TestContext.Provider, -clockSkewOffset)
+		framework.ExpectNoError(err)
+		defer func() {
+			framework.ExpectNoError(restore(), "failed to restore the clock of node %s", node.Name)
+		}()
+
+		ginkgo.By("checking that authenticated heartbeats continue")
+		lastHeartbeat := nodeReadyHeartbeatTime(f.ClientSet, node.Name)
+		gomega.Eventually(func() error {
+			if !nodeReadyHeartbeatTime(f.ClientSet, node.Name).After(lastHeartbeat) {
+				return fmt.Errorf("node status heartbeat did not advance past %v", lastHeartbeat)
+			}
+			return nil
+		}, 2*time.Minute, 5*time.Second).Should(gomega.BeNil())
+
+		ginkgo.By("checking that the kubelet did not rotate into a spurious certificate")
+		result, err = e2essh.IssueSSHCommandWithResult(fingerprintCmd, framework.TestContext.Provider, node)
+		framework.ExpectNoError(err)
+		framework.ExpectEqual(strings.TrimSpace(result.Stdout), fingerprintBefore,
+			"the kubelet rotated its client certificate in response to clock skew")
+	})
+})
+
+// nodeReadyHeartbeatTime returns the LastHeartbeatTime of the node's Ready
+// condition.
+func nodeReadyHeartbeatTime(cs clientset.Interface, nodeName string) time.Time {
+	node, err := cs.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	framework.ExpectNoError(err)
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.LastHeartbeatTime.Time
+		}
+	}
+	framework.Failf("node %s has no Ready condition", nodeName)
+	return time.Time{}
+}
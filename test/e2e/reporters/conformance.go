@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reporters
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+	"k8s.io/klog/v2"
+)
+
+// ConformanceSpecResult is the outcome of one conformance spec in a schema
+// certification tooling can consume directly, without parsing junit XML.
+type ConformanceSpecResult struct {
+	// BehaviorID is a stable identifier for the behavior the spec covers,
+	// derived from the owning sig and the spec text.
+	BehaviorID string `json:"behaviorID"`
+	// Name is the full spec name as reported by ginkgo.
+	Name string `json:"name"`
+	// Status is one of "passed", "failed" or "skipped".
+	Status string `json:"status"`
+	// SkipReason carries the message given to the skip call, for skipped specs.
+	SkipReason string `json:"skipReason,omitempty"`
+	// FailureMessage carries the failure, for failed specs.
+	FailureMessage string `json:"failureMessage,omitempty"`
+	// DurationSeconds is how long the spec ran.
+	DurationSeconds float64 `json:"durationSeconds"`
+	// CodeLocation points at the innermost It of the spec.
+	CodeLocation string `json:"codeLocation,omitempty"`
+}
+
+// ConformanceSuiteResult wraps the per-spec outcomes of one suite run.
+type ConformanceSuiteResult struct {
+	Total           int                     `json:"total"`
+	Passed          int                     `json:"passed"`
+	Failed          int                     `json:"failed"`
+	Skipped         int                     `json:"skipped"`
+	DurationSeconds float64                 `json:"durationSeconds"`
+	Results         []ConformanceSpecResult `json:"results"`
+}
+
+// ConformanceReporter is a ginkgo reporter which collects the outcomes of all
+// conformance specs of a run and writes them out as one machine-readable JSON
+// document when the suite ends.
+type ConformanceReporter struct {
+	Writer io.Writer
+	suite  ConformanceSuiteResult
+}
+
+// NewConformanceReporterWithWriter returns a reporter writing the conformance
+// results document to the given writer when the suite ends.
+func NewConformanceReporterWithWriter(w io.Writer) *ConformanceReporter {
+	return &ConformanceReporter{Writer: w}
+}
+
+// NewConformanceReporterFile returns a reporter writing the conformance
+// results document to the given file when the suite ends.
+func NewConformanceReporterFile(filename string) *ConformanceReporter {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		klog.Errorf("%#v\n", err)
+		panic(err)
+	}
+	f, err := os.Create(absPath)
+	if err != nil {
+		klog.Errorf("%#v\n", err)
+		panic(err)
+	}
+	return NewConformanceReporterWithWriter(f)
+}
+
+var (
+	sigRegex    = regexp.MustCompile(`\[sig-[a-z-]+\]`)
+	tagRegex    = regexp.MustCompile(`\[[^]]+\]`)
+	nonIDRegex  = regexp.MustCompile(`[^a-z0-9]+`)
+	conformance = "[Conformance]"
+)
+
+// behaviorID derives a stable identifier for the behavior a spec covers:
+// the owning sig followed by a slug of the spec text with all tags removed,
+// e.g. "sig-network/should-serve-a-basic-endpoint-from-pods". It stays usable
+// as a join key until specs carry explicit behavior annotations.
+func behaviorID(specName string) string {
+	sig := strings.Trim(sigRegex.FindString(specName), "[]")
+	name := strings.TrimSpace(tagRegex.ReplaceAllString(specName, ""))
+	slug := strings.Trim(nonIDRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if sig == "" {
+		return slug
+	}
+	return sig + "/" + slug
+}
+
+// SpecSuiteWillBegin is implemented as a noop to satisfy the reporter interface for ginkgo.
+func (reporter *ConformanceReporter) SpecSuiteWillBegin(cfg config.GinkgoConfigType, summary *types.SuiteSummary) {
+}
+
+// SpecWillRun is implemented as a noop to satisfy the reporter interface for ginkgo.
+func (reporter *ConformanceReporter) SpecWillRun(specSummary *types.SpecSummary) {}
+
+// BeforeSuiteDidRun is implemented as a noop to satisfy the reporter interface for ginkgo.
+func (reporter *ConformanceReporter) BeforeSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+// AfterSuiteDidRun is implemented as a noop to satisfy the reporter interface for ginkgo.
+func (reporter *ConformanceReporter) AfterSuiteDidRun(setupSummary *types.SetupSummary) {}
+
+// SpecDidComplete collects the outcome of every completed conformance spec.
+func (reporter *ConformanceReporter) SpecDidComplete(specSummary *types.SpecSummary) {
+	name := strings.Join(specSummary.ComponentTexts[1:], " ")
+	if !strings.Contains(name, conformance) {
+		return
+	}
+
+	result := ConformanceSpecResult{
+		BehaviorID:      behaviorID(name),
+		Name:            name,
+		DurationSeconds: specSummary.RunTime.Seconds(),
+	}
+	if locations := specSummary.ComponentCodeLocations; len(locations) > 0 {
+		result.CodeLocation = locations[len(locations)-1].String()
+	}
+
+	reporter.suite.Total++
+	reporter.suite.DurationSeconds += specSummary.RunTime.Seconds()
+	switch specSummary.State {
+	case types.SpecStatePassed:
+		result.Status = "passed"
+		reporter.suite.Passed++
+	case types.SpecStateSkipped, types.SpecStatePending:
+		result.Status = "skipped"
+		result.SkipReason = specSummary.Failure.Message
+		reporter.suite.Skipped++
+	default:
+		result.Status = "failed"
+		result.FailureMessage = specSummary.Failure.Message
+		reporter.suite.Failed++
+	}
+
+	reporter.suite.Results = append(reporter.suite.Results, result)
+}
+
+// SpecSuiteDidEnd writes the collected results as one JSON document.
+func (reporter *ConformanceReporter) SpecSuiteDidEnd(summary *types.SuiteSummary) {
+	encoder := json.NewEncoder(reporter.Writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reporter.suite); err != nil {
+		klog.Errorf("Error writing conformance results: %v", err)
+	}
+	if c, ok := reporter.Writer.(io.Closer); ok {
+		c.Close()
+	}
+}
@@ -87,5 +87,18 @@ func gatherTestSuiteMetrics() error {
 		framework.Logf("\n\nTest Suite Metrics:\n%s\n", metricsJSON)
 	}
 
+	errorBudgetReport := e2emetrics.ComputeErrorBudgetReport(received, e2emetrics.DefaultErrorBudgets)
+	if exceeded := errorBudgetReport.ExceededComponents(); len(exceeded) > 0 {
+		framework.Logf("Error budget exceeded for components: %v", exceeded)
+	}
+	if framework.TestContext.ReportDir != "" {
+		filePath := path.Join(framework.TestContext.ReportDir, "ErrorBudgetReport_"+time.Now().Format(time.RFC3339)+".json")
+		if err := ioutil.WriteFile(filePath, []byte(errorBudgetReport.PrintJSON()), 0644); err != nil {
+			return fmt.Errorf("error writing to %q: %v", filePath, err)
+		}
+	} else {
+		framework.Logf("\n\nError Budget Report:\n%s\n", errorBudgetReport.PrintHumanReadable())
+	}
+
 	return nil
 }
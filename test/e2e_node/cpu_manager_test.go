@@ -31,6 +31,7 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager"
 	cpumanagerstate "k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	kubeletmetrics "k8s.io/kubernetes/pkg/kubelet/metrics"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	"k8s.io/kubernetes/test/e2e/framework"
 	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
@@ -288,8 +289,13 @@ func runGuPodTest(f *framework.Framework) {
 		},
 	}
 	pod = makeCPUManagerPod("gu-pod", ctnAttrs)
+	counters := getResourceManagerCounters()
 	pod = f.PodClient().CreateSync(pod)
 
+	ginkgo.By("checking the cpu manager pinning metrics")
+	expectCounterDelta(counters, kubeletmetrics.CPUManagerPinningRequestsTotalKey, gomega.BeNumerically(">=", 1), "expected the cpu manager to pin cpus for the Gu pod")
+	expectCounterDelta(counters, kubeletmetrics.CPUManagerPinningErrorsTotalKey, gomega.BeZero(), "expected no cpu pinning failure for the Gu pod")
+
 	ginkgo.By("checking if the expected cpuset was assigned")
 	cpu1 = 1
 	if isHTEnabled() {
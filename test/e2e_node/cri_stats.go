@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2emetrics "k8s.io/kubernetes/test/e2e/framework/metrics"
+)
+
+// containerStatsSample is one point-in-time resource usage observation for a
+// container, comparable across the CRI and cadvisor stats sources.
+type containerStatsSample struct {
+	cpuUsageCoreNanoSeconds uint64
+	memoryWorkingSetBytes   uint64
+}
+
+// containerStatsKey identifies a container across stats sources.
+func containerStatsKey(namespace, podName, containerName string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, podName, containerName)
+}
+
+// getCRIContainerStats returns the resource usage of all running containers
+// as reported by the container runtime through CRI, keyed by
+// namespace/pod/container.
+func getCRIContainerStats() (map[string]containerStatsSample, error) {
+	rs, _, err := getCRIClient()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := rs.ListContainerStats(&runtimeapi.ContainerStatsFilter{})
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]containerStatsSample{}
+	for _, stat := range stats {
+		labels := stat.GetAttributes().GetLabels()
+		key := containerStatsKey(
+			kubelettypes.GetPodNamespace(labels),
+			kubelettypes.GetPodName(labels),
+			kubelettypes.GetContainerName(labels),
+		)
+		result[key] = containerStatsSample{
+			cpuUsageCoreNanoSeconds: stat.GetCpu().GetUsageCoreNanoSeconds().GetValue(),
+			memoryWorkingSetBytes:   stat.GetMemory().GetWorkingSetBytes().GetValue(),
+		}
+	}
+	return result, nil
+}
+
+// getCadvisorContainerStats returns the resource usage of all containers as
+// reported by cadvisor through the kubelet's /metrics/cadvisor endpoint,
+// keyed by namespace/pod/container. When cadvisor reports more than one
+// series for a container (for example around a restart), the largest value
+// wins: usage counters are cumulative and the newest incarnation reports the
+// highest one.
+func getCadvisorContainerStats() (map[string]containerStatsSample, error) {
+	ms, err := e2emetrics.GrabKubeletMetricsWithoutProxy(framework.TestContext.NodeName+":"+kubeletReadOnlyPort, "/metrics/cadvisor")
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]containerStatsSample{}
+	for _, sample := range ms["container_cpu_usage_seconds_total"] {
+		key, ok := cadvisorSampleKey(sample)
+		if !ok {
+			continue
+		}
+		entry := result[key]
+		if value := uint64(float64(sample.Value) * 1e9); value > entry.cpuUsageCoreNanoSeconds {
+			entry.cpuUsageCoreNanoSeconds = value
+		}
+		result[key] = entry
+	}
+	for _, sample := range ms["container_memory_working_set_bytes"] {
+		key, ok := cadvisorSampleKey(sample)
+		if !ok {
+			continue
+		}
+		entry := result[key]
+		if value := uint64(sample.Value); value > entry.memoryWorkingSetBytes {
+			entry.memoryWorkingSetBytes = value
+		}
+		result[key] = entry
+	}
+	return result, nil
+}
+
+// cadvisorSampleKey derives the namespace/pod/container key from a cadvisor
+// sample. Samples for pod and node level cgroups carry no container label and
+// are skipped.
+func cadvisorSampleKey(sample *model.Sample) (string, bool) {
+	namespace := string(sample.Metric["namespace"])
+	podName := string(sample.Metric["pod"])
+	containerName := string(sample.Metric["container"])
+	if namespace == "" || podName == "" || containerName == "" {
+		return "", false
+	}
+	return containerStatsKey(namespace, podName, containerName), true
+}
+
+// checkStatsAgreement returns an error unless the two observations of the
+// same cumulative or gauge quantity agree within the relative tolerance or
+// the absolute slack, whichever allows the larger difference. The slack
+// absorbs the sampling time skew between the two sources.
+func checkStatsAgreement(what string, criValue, cadvisorValue uint64, relativeTolerance float64, absoluteSlack uint64) error {
+	diff := criValue - cadvisorValue
+	if cadvisorValue > criValue {
+		diff = cadvisorValue - criValue
+	}
+	larger := criValue
+	if cadvisorValue > larger {
+		larger = cadvisorValue
+	}
+	allowed := uint64(relativeTolerance * float64(larger))
+	if absoluteSlack > allowed {
+		allowed = absoluteSlack
+	}
+	if diff > allowed {
+		return fmt.Errorf("%s differs beyond tolerance: CRI reports %d, cadvisor reports %d, difference %d exceeds allowed %d", what, criValue, cadvisorValue, diff, allowed)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const (
+	// criStatsCPURelativeTolerance and criStatsCPUSlackNanoSeconds bound how
+	// far the cumulative CPU usage reported by CRI and cadvisor may drift
+	// apart. The slack absorbs up to 20 CPU seconds of sampling time skew
+	// between the two sources; as usage accumulates the relative tolerance
+	// takes over.
+	criStatsCPURelativeTolerance = 0.25
+	criStatsCPUSlackNanoSeconds  = 20e9
+	// criStatsMemoryRelativeTolerance and criStatsMemorySlackBytes bound how
+	// far the memory working set reported by CRI and cadvisor may drift
+	// apart. The slack absorbs page accounting differences for small
+	// containers.
+	criStatsMemoryRelativeTolerance = 0.25
+	criStatsMemorySlackBytes        = 8 * 1024 * 1024
+)
+
+// Compares container stats as reported by the container runtime through CRI
+// against the same containers as reported by cadvisor, to catch regressions
+// in runtime stats implementations ahead of the migration to CRI-only stats
+// collection.
+var _ = SIGDescribe("CRI stats consistency [NodeFeature:CRIStatsConsistency]", func() {
+	f := framework.NewDefaultFramework("cri-stats-consistency")
+
+	ginkgo.It("should report container CPU and memory usage consistent with cadvisor", func() {
+		const podName = "cri-stats-consistency-pod"
+		const containerName = "busy"
+
+		ginkgo.By("Creating a pod that keeps one container busy")
+		f.PodClient().CreateSync(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName},
+			Spec: v1.PodSpec{
+				RestartPolicy: v1.RestartPolicyNever,
+				Containers: []v1.Container{
+					{
+						Name:    containerName,
+						Image:   imageutils.GetE2EImage(imageutils.BusyBox),
+						Command: []string{"sh", "-c", "i=0; while true; do i=$((i+1)); done"},
+					},
+				},
+			},
+		})
+
+		ginkgo.By("Waiting for both stats sources to agree on the container usage")
+		key := containerStatsKey(f.Namespace.Name, podName, containerName)
+		gomega.Eventually(func() error {
+			criStats, err := getCRIContainerStats()
+			if err != nil {
+				return err
+			}
+			cadvisorStats, err := getCadvisorContainerStats()
+			if err != nil {
+				return err
+			}
+			criSample, found := criStats[key]
+			if !found {
+				return fmt.Errorf("container %q not found in CRI stats", key)
+			}
+			cadvisorSample, found := cadvisorStats[key]
+			if !found {
+				return fmt.Errorf("container %q not found in cadvisor stats", key)
+			}
+			if criSample.cpuUsageCoreNanoSeconds == 0 || cadvisorSample.cpuUsageCoreNanoSeconds == 0 {
+				return fmt.Errorf("container %q has not accumulated CPU usage in both sources yet", key)
+			}
+			if err := checkStatsAgreement("cpu usage core nano seconds", criSample.cpuUsageCoreNanoSeconds, cadvisorSample.cpuUsageCoreNanoSeconds, criStatsCPURelativeTolerance, criStatsCPUSlackNanoSeconds); err != nil {
+				return err
+			}
+			return checkStatsAgreement("memory working set bytes", criSample.memoryWorkingSetBytes, cadvisorSample.memoryWorkingSetBytes, criStatsMemoryRelativeTolerance, criStatsMemorySlackBytes)
+		}, 3*time.Minute, 10*time.Second).Should(gomega.Succeed())
+	})
+})
@@ -18,6 +18,7 @@ package e2enode
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -55,6 +56,11 @@ const (
 	// fake resource name
 	resourceName            = "example.com/resource"
 	envVarNamePluginSockDir = "PLUGIN_SOCK_DIR"
+
+	// healthControlDir is the host directory watched by the sample device
+	// plugin for device health control files. A file named after a device ID
+	// marks that device unhealthy; removing it marks the device healthy again.
+	healthControlDir = "/var/run/sample-device-plugin"
 )
 
 var (
@@ -321,9 +327,153 @@ func testDevicePlugin(f *framework.Framework, pluginSockDir string) {
 			f.PodClient().DeleteSync(pod1.Name, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
 			f.PodClient().DeleteSync(pod2.Name, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
 		})
+
+		ginkgo.It("Keeps pods off unhealthy devices and reallocates when they recover.", func() {
+			ginkgo.By("Wait for node is ready to start with")
+			e2enode.WaitForNodeToBeReady(f.ClientSet, framework.TestContext.NodeName, 5*time.Minute)
+			dp := getSampleDevicePluginPod()
+			for i := range dp.Spec.Containers[0].Env {
+				if dp.Spec.Containers[0].Env[i].Name == envVarNamePluginSockDir {
+					dp.Spec.Containers[0].Env[i].Value = pluginSockDir
+				}
+			}
+			dp.Spec.NodeName = framework.TestContext.NodeName
+			ginkgo.By("Create sample device plugin pod")
+			devicePluginPod, err := f.ClientSet.CoreV1().Pods(metav1.NamespaceSystem).Create(context.TODO(), dp, metav1.CreateOptions{})
+			framework.ExpectNoError(err)
+
+			gp := int64(0)
+			deleteOptions := metav1.DeleteOptions{
+				GracePeriodSeconds: &gp,
+			}
+			defer func() {
+				ginkgo.By("Cleaning up the health control files and the device plugin pod")
+				framework.ExpectNoError(markDeviceHealthy("Dev-1"))
+				framework.ExpectNoError(markDeviceHealthy("Dev-2"))
+				err := f.ClientSet.CoreV1().Pods(metav1.NamespaceSystem).Delete(context.TODO(), dp.Name, deleteOptions)
+				framework.ExpectNoError(err)
+				waitForContainerRemoval(devicePluginPod.Spec.Containers[0].Name, devicePluginPod.Name, devicePluginPod.Namespace)
+			}()
+
+			ginkgo.By("Waiting for the resource exported by the sample device plugin to become available on the local node")
+			devsLen := int64(2)
+			gomega.Eventually(func() bool {
+				node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+				framework.ExpectNoError(err)
+				return numberOfDevicesCapacity(node, resourceName) == devsLen &&
+					numberOfDevicesAllocatable(node, resourceName) == devsLen
+			}, 5*time.Minute, framework.Poll).Should(gomega.BeTrue())
+
+			ginkgo.By("Marking one device unhealthy")
+			framework.ExpectNoError(markDeviceUnhealthy("Dev-1"))
+
+			ginkgo.By("Waiting for the unhealthy device to be removed from the node allocatable")
+			gomega.Eventually(func() int64 {
+				node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+				framework.ExpectNoError(err)
+				return numberOfDevicesAllocatable(node, resourceName)
+			}, 30*time.Second, framework.Poll).Should(gomega.Equal(devsLen - 1))
+
+			ginkgo.By("Checking that the unhealthy device is still part of the node capacity")
+			node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+			framework.ExpectNoError(err)
+			framework.ExpectEqual(numberOfDevicesCapacity(node, resourceName), devsLen)
+
+			ginkgo.By("Creating a pod and checking it avoids the unhealthy device")
+			podRECMD := "devs=$(ls /tmp/ | egrep '^Dev-[0-9]+$') && echo stub devices: $devs"
+			deviceIDRE := "stub devices: (Dev-[0-9]+)"
+			pod1 := f.PodClient().CreateSync(makeBusyboxPod(resourceName, podRECMD))
+			devID1 := parseLog(f, pod1.Name, pod1.Name, deviceIDRE)
+			framework.ExpectEqual(devID1, "Dev-2")
+
+			ginkgo.By("Checking the allocated device reports its NUMA topology")
+			topology1 := getSampleDeviceTopology(pod1.Name)
+			gomega.Expect(topology1).NotTo(gomega.BeNil())
+			framework.ExpectEqual(len(topology1.Nodes), 1)
+
+			ginkgo.By("Deleting the pod and swapping the healthy and unhealthy devices")
+			f.PodClient().DeleteSync(pod1.Name, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
+			framework.ExpectNoError(markDeviceHealthy("Dev-1"))
+			gomega.Eventually(func() int64 {
+				node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+				framework.ExpectNoError(err)
+				return numberOfDevicesAllocatable(node, resourceName)
+			}, 30*time.Second, framework.Poll).Should(gomega.Equal(devsLen))
+			framework.ExpectNoError(markDeviceUnhealthy("Dev-2"))
+			gomega.Eventually(func() int64 {
+				node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+				framework.ExpectNoError(err)
+				return numberOfDevicesAllocatable(node, resourceName)
+			}, 30*time.Second, framework.Poll).Should(gomega.Equal(devsLen - 1))
+
+			ginkgo.By("Creating a replacement pod and checking it gets the recovered device")
+			pod2 := f.PodClient().CreateSync(makeBusyboxPod(resourceName, podRECMD))
+			devID2 := parseLog(f, pod2.Name, pod2.Name, deviceIDRE)
+			framework.ExpectEqual(devID2, "Dev-1")
+
+			ginkgo.By("Re-verifying NUMA alignment for the replacement allocation")
+			topology2 := getSampleDeviceTopology(pod2.Name)
+			gomega.Expect(topology2).NotTo(gomega.BeNil())
+			framework.ExpectEqual(len(topology2.Nodes), 1)
+			framework.ExpectEqual(topology2.Nodes[0].ID, topology1.Nodes[0].ID)
+
+			ginkgo.By("Marking all devices healthy again and waiting for the node allocatable to recover")
+			framework.ExpectNoError(markDeviceHealthy("Dev-2"))
+			gomega.Eventually(func() int64 {
+				node, err := f.ClientSet.CoreV1().Nodes().Get(context.TODO(), framework.TestContext.NodeName, metav1.GetOptions{})
+				framework.ExpectNoError(err)
+				return numberOfDevicesAllocatable(node, resourceName)
+			}, 30*time.Second, framework.Poll).Should(gomega.Equal(devsLen))
+
+			// Cleanup
+			f.PodClient().DeleteSync(pod2.Name, metav1.DeleteOptions{}, framework.DefaultPodDeletionTimeout)
+		})
 	})
 }
 
+// markDeviceUnhealthy asks the sample device plugin to mark the given device
+// unhealthy by creating its health control file.
+func markDeviceUnhealthy(devID string) error {
+	if err := os.MkdirAll(healthControlDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(healthControlDir, devID))
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// markDeviceHealthy asks the sample device plugin to mark the given device
+// healthy again by removing its health control file.
+func markDeviceHealthy(devID string) error {
+	err := os.Remove(filepath.Join(healthControlDir, devID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// getSampleDeviceTopology returns the NUMA topology the podresources API
+// reports for the sample device assigned to the given pod.
+func getSampleDeviceTopology(podName string) *kubeletpodresourcesv1.TopologyInfo {
+	v1PodResources, err := getV1NodeDevices()
+	framework.ExpectNoError(err)
+	for _, res := range v1PodResources.GetPodResources() {
+		if res.Name != podName {
+			continue
+		}
+		for _, cnt := range res.Containers {
+			for _, dev := range cnt.Devices {
+				if dev.ResourceName == resourceName {
+					return dev.Topology
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // makeBusyboxPod returns a simple Pod spec with a busybox container
 // that requests resourceName and runs the specified command.
 func makeBusyboxPod(resourceName, cmd string) *v1.Pod {
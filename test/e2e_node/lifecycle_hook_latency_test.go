@@ -0,0 +1,219 @@
+// +build linux
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2emetrics "k8s.io/kubernetes/test/e2e/framework/metrics"
+	e2eperf "k8s.io/kubernetes/test/e2e/framework/perf"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	"k8s.io/kubernetes/test/e2e/perftype"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/onsi/ginkgo"
+)
+
+// hookLatencyTest describes one lifecycle hook latency measurement: podsNr
+// pods are created in a batch, each with a postStart hook sleeping for
+// postStartSleep, and later deleted with gracePeriod while a preStop hook
+// sleeps for preStopSleep.
+type hookLatencyTest struct {
+	podsNr         int
+	postStartSleep time.Duration
+	preStopSleep   time.Duration
+	gracePeriod    int64
+	// podRunningTimeout bounds how long a pod may take until it is observed running.
+	podRunningTimeout time.Duration
+}
+
+func (h hookLatencyTest) getTestName() string {
+	return fmt.Sprintf("lifecycle_hook_latency_%dpods_%dgrace", h.podsNr, h.gracePeriod)
+}
+
+var _ = SIGDescribe("LifecycleHookLatency [Serial] [Slow]", func() {
+	f := framework.NewDefaultFramework("lifecycle-hook-latency-test")
+
+	ginkgo.Context("measuring hook execution latency", func() {
+		dTests := []hookLatencyTest{
+			{
+				podsNr:            10,
+				postStartSleep:    time.Second,
+				preStopSleep:      2 * time.Second,
+				gracePeriod:       30,
+				podRunningTimeout: 5 * time.Minute,
+			},
+		}
+
+		for _, testArg := range dTests {
+			itArg := testArg
+			desc := fmt.Sprintf("latency of postStart and preStop hooks of %d pods [Benchmark]", itArg.podsNr)
+			ginkgo.It(desc, func() {
+				testInfo := getTestNodeInfo(f, itArg.getTestName(), desc)
+
+				runHookLatencyTest(f, itArg, testInfo)
+			})
+		}
+	})
+})
+
+// runHookLatencyTest creates the pods of the test in one batch, measures how
+// long each of them takes until it is observed running with its postStart
+// hook finished, then deletes them and measures how long each of them takes
+// until it is gone. Both distributions are exported in the perf data format,
+// and terminations exceeding the grace period are reported as test failures.
+func runHookLatencyTest(f *framework.Framework, testArg hookLatencyTest, testInfo map[string]string) {
+	pods := newHookPods(testArg.podsNr, testArg.postStartSleep, testArg.preStopSleep)
+
+	ginkgo.By(fmt.Sprintf("Creating a batch of %d pods with lifecycle hooks", testArg.podsNr))
+	createTimes := make(map[string]time.Time, testArg.podsNr)
+	for _, pod := range pods {
+		createTimes[pod.Name] = time.Now()
+		f.PodClient().Create(pod)
+	}
+
+	ginkgo.By("Waiting for all pods to be running with their postStart hook finished")
+	startupLags := measurePodLatencies(pods, func(pod *v1.Pod) error {
+		return e2epod.WaitTimeoutForPodRunningInNamespace(f.ClientSet, pod.Name, f.Namespace.Name, testArg.podRunningTimeout)
+	}, createTimes)
+	startupLatency := extractLatencyMetrics(startupLags)
+	framework.Logf("postStart hook startup latency: %s", framework.PrettyPrintJSON(startupLatency))
+	logPerfData(getHookLatencyPerfData(startupLatency, "poststart-pod-running", testInfo), "lifecycle-hook")
+
+	ginkgo.By(fmt.Sprintf("Deleting all pods with a grace period of %d seconds", testArg.gracePeriod))
+	deleteTimes := make(map[string]time.Time, testArg.podsNr)
+	for _, pod := range pods {
+		deleteTimes[pod.Name] = time.Now()
+		err := f.ClientSet.CoreV1().Pods(f.Namespace.Name).Delete(context.TODO(), pod.Name, *metav1.NewDeleteOptions(testArg.gracePeriod))
+		framework.ExpectNoError(err)
+	}
+
+	ginkgo.By("Waiting for all pods to be gone with their preStop hook finished")
+	terminationTimeout := time.Duration(testArg.gracePeriod)*time.Second + testArg.podRunningTimeout
+	terminationLags := measurePodLatencies(pods, func(pod *v1.Pod) error {
+		return e2epod.WaitForPodNotFoundInNamespace(f.ClientSet, pod.Name, f.Namespace.Name, terminationTimeout)
+	}, deleteTimes)
+	terminationLatency := extractLatencyMetrics(terminationLags)
+	framework.Logf("preStop hook termination latency: %s", framework.PrettyPrintJSON(terminationLatency))
+	logPerfData(getHookLatencyPerfData(terminationLatency, "prestop-pod-termination", testInfo), "lifecycle-hook")
+
+	// The preStop hook must have been given time to run, and no pod may
+	// outlive its grace period by more than the kubelet's reaction time.
+	gracePeriod := time.Duration(testArg.gracePeriod) * time.Second
+	for _, lag := range terminationLags {
+		if lag.Latency < testArg.preStopSleep {
+			framework.Failf("pod %s terminated after %v, before its preStop hook could finish (%v)", lag.Name, lag.Latency, testArg.preStopSleep)
+		}
+		if lag.Latency > gracePeriod+2*time.Minute {
+			framework.Failf("pod %s took %v to terminate, exceeding its grace period of %v", lag.Name, lag.Latency, gracePeriod)
+		}
+	}
+}
+
+// newHookPods returns podsNr pods whose single container has a postStart hook
+// sleeping for postStartSleep and a preStop hook sleeping for preStopSleep.
+func newHookPods(podsNr int, postStartSleep, preStopSleep time.Duration) []*v1.Pod {
+	pods := make([]*v1.Pod, 0, podsNr)
+	for i := 0; i < podsNr; i++ {
+		pods = append(pods, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("hook-pod-%d", i),
+			},
+			Spec: v1.PodSpec{
+				RestartPolicy: v1.RestartPolicyNever,
+				Containers: []v1.Container{
+					{
+						Name:    "hook-container",
+						Image:   imageutils.GetE2EImage(imageutils.BusyBox),
+						Command: []string{"sh", "-c", "sleep 1000000"},
+						Lifecycle: &v1.Lifecycle{
+							PostStart: &v1.Handler{
+								Exec: &v1.ExecAction{
+									Command: []string{"sh", "-c", fmt.Sprintf("sleep %d", int(postStartSleep.Seconds()))},
+								},
+							},
+							PreStop: &v1.Handler{
+								Exec: &v1.ExecAction{
+									Command: []string{"sh", "-c", fmt.Sprintf("sleep %d", int(preStopSleep.Seconds()))},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+	return pods
+}
+
+// measurePodLatencies waits for the given condition on all pods in parallel
+// and returns how long each pod took from its recorded start time, sorted by
+// latency.
+func measurePodLatencies(pods []*v1.Pod, waitFn func(pod *v1.Pod) error, startTimes map[string]time.Time) []e2emetrics.PodLatencyData {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	lags := make([]e2emetrics.PodLatencyData, 0, len(pods))
+
+	wg.Add(len(pods))
+	for _, pod := range pods {
+		go func(pod *v1.Pod) {
+			defer ginkgo.GinkgoRecover()
+			defer wg.Done()
+			framework.ExpectNoError(waitFn(pod))
+			latency := time.Since(startTimes[pod.Name])
+			mutex.Lock()
+			defer mutex.Unlock()
+			lags = append(lags, e2emetrics.PodLatencyData{Name: pod.Name, Latency: latency})
+		}(pod)
+	}
+	wg.Wait()
+
+	sort.Sort(e2emetrics.LatencySlice(lags))
+	return lags
+}
+
+// getHookLatencyPerfData returns perf data of lifecycle hook latency.
+func getHookLatencyPerfData(latency e2emetrics.LatencyMetric, latencyType string, testInfo map[string]string) *perftype.PerfData {
+	return &perftype.PerfData{
+		Version: e2eperf.CurrentKubeletPerfMetricsVersion,
+		DataItems: []perftype.DataItem{
+			{
+				Data: map[string]float64{
+					"Perc50":  float64(latency.Perc50) / 1000000,
+					"Perc90":  float64(latency.Perc90) / 1000000,
+					"Perc99":  float64(latency.Perc99) / 1000000,
+					"Perc100": float64(latency.Perc100) / 1000000,
+				},
+				Unit: "ms",
+				Labels: map[string]string{
+					"datatype":    "latency",
+					"latencytype": latencyType,
+				},
+			},
+		},
+		Labels: testInfo,
+	}
+}
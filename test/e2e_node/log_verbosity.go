@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e_node/services"
+)
+
+// verboseWindowsLogName is the artifact the verbose log windows are appended
+// to, next to the kubelet log, so that the matching log sections can be found.
+const verboseWindowsLogName = "kubelet-verbose-windows.txt"
+
+// setKubeletLogVerbosity adjusts the kubelet's log verbosity at runtime
+// through the /debug/flags/v endpoint, which is wired to the logs subsystem
+// GlogSetter. It requires the debug flags handler to be enabled.
+func setKubeletLogVerbosity(verbosity int32) error {
+	kubeletConfig, err := getCurrentKubeletConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get current kubelet config: %v", err)
+	}
+	url := fmt.Sprintf("https://127.0.0.1:%d/debug/flags/v", kubeletConfig.Port)
+
+	req, err := http.NewRequest("PUT", url, strings.NewReader(strconv.Itoa(int(verbosity))))
+	if err != nil {
+		return fmt.Errorf("failed to build http request: %v", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", framework.TestContext.BearerToken))
+
+	insecureTransport := http.DefaultTransport.(*http.Transport).Clone()
+	insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	insecureHTTPClient := &http.Client{
+		Transport: insecureTransport,
+	}
+	resp, err := insecureHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to set kubelet log verbosity to %d: status=%d, body=%q", verbosity, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// defaultKubeletLogVerbosity is the verbosity the kubelet under test was
+// started with.
+func defaultKubeletLogVerbosity() int32 {
+	verbosity, err := strconv.Atoi(services.LogVerbosityLevel)
+	if err != nil {
+		// LogVerbosityLevel is a constant, this cannot happen.
+		return 4
+	}
+	return int32(verbosity)
+}
+
+// recordVerboseLogWindow appends the time window during which the kubelet ran
+// with raised verbosity to an artifact in the report directory, so that the
+// verbose section of the kubelet log can be correlated with the test that
+// requested it.
+func recordVerboseLogWindow(testName string, verbosity int32, start, end time.Time) {
+	entry := fmt.Sprintf("test=%q verbosity=%d start=%s end=%s\n",
+		testName, verbosity, start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano))
+	if framework.TestContext.ReportDir == "" {
+		framework.Logf("No report directory, logging verbose window instead: %s", entry)
+		return
+	}
+	fileName := path.Join(framework.TestContext.ReportDir, verboseWindowsLogName)
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		framework.Logf("Failed to open %s: %v", fileName, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(entry); err != nil {
+		framework.Logf("Failed to record verbose window in %s: %v", fileName, err)
+	}
+}
+
+// runWithHighKubeletLogVerbosity runs fn with the kubelet log verbosity
+// raised to the given level and restores the default verbosity afterwards.
+// It is intended to wrap the retry of a failing test so that the retry is
+// captured with verbose kubelet logs; the verbose window is recorded in the
+// test artifacts.
+func runWithHighKubeletLogVerbosity(testName string, verbosity int32, fn func()) {
+	if err := setKubeletLogVerbosity(verbosity); err != nil {
+		framework.Logf("Could not raise kubelet log verbosity, continuing with default: %v", err)
+		fn()
+		return
+	}
+	start := time.Now()
+	defer func() {
+		end := time.Now()
+		if err := setKubeletLogVerbosity(defaultKubeletLogVerbosity()); err != nil {
+			framework.Logf("Could not restore kubelet log verbosity: %v", err)
+		}
+		recordVerboseLogWindow(testName, verbosity, start, end)
+	}()
+	fn()
+}
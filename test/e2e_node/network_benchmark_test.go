@@ -0,0 +1,196 @@
+// +build linux
+
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
+	e2eperf "k8s.io/kubernetes/test/e2e/framework/perf"
+	"k8s.io/kubernetes/test/e2e/perftype"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const (
+	// pingPacketCount is the number of probes each benchmark sends.
+	pingPacketCount = 200
+	// pingIntervalSeconds is the interval between probes.
+	pingIntervalSeconds = "0.01"
+	// pingPodTimeout bounds how long one benchmark pod may take.
+	pingPodTimeout = 3 * time.Minute
+)
+
+var (
+	pingPacketsRE = regexp.MustCompile(`(\d+) packets transmitted, (\d+) packets received`)
+	pingRTTRE     = regexp.MustCompile(`min/avg/max[^=]*= ([\d.]+)/([\d.]+)/([\d.]+)`)
+)
+
+// pingResults holds the latency and packet drop results of one ping benchmark.
+type pingResults struct {
+	transmitted int
+	received    int
+	minMs       float64
+	avgMs       float64
+	maxMs       float64
+}
+
+func (p pingResults) lossPercent() float64 {
+	if p.transmitted == 0 {
+		return 100
+	}
+	return float64(p.transmitted-p.received) / float64(p.transmitted) * 100
+}
+
+// parsePingOutput extracts the packet counters and round-trip times from the
+// summary that busybox ping prints on exit.
+func parsePingOutput(output string) (pingResults, error) {
+	results := pingResults{}
+	matches := pingPacketsRE.FindStringSubmatch(output)
+	if matches == nil {
+		return results, fmt.Errorf("no packet statistics found in ping output:\n%s", output)
+	}
+	results.transmitted, _ = strconv.Atoi(matches[1])
+	results.received, _ = strconv.Atoi(matches[2])
+
+	// The round-trip line is only printed when at least one reply arrived.
+	if matches := pingRTTRE.FindStringSubmatch(output); matches != nil {
+		results.minMs, _ = strconv.ParseFloat(matches[1], 64)
+		results.avgMs, _ = strconv.ParseFloat(matches[2], 64)
+		results.maxMs, _ = strconv.ParseFloat(matches[3], 64)
+	}
+	return results, nil
+}
+
+// runPingBenchmarkPod pings the target from a freshly created pod and returns
+// the parsed results. Ping keeps going on lost probes, so packet drops show up
+// in the counters instead of failing the pod.
+func runPingBenchmarkPod(f *framework.Framework, name, target string) pingResults {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{
+				{
+					Image:   busyboxImage,
+					Name:    name,
+					Command: []string{"sh", "-c", fmt.Sprintf("ping -c %d -i %s %s", pingPacketCount, pingIntervalSeconds, target)},
+				},
+			},
+		},
+	}
+	pod = f.PodClient().Create(pod)
+	f.PodClient().WaitForSuccess(pod.Name, pingPodTimeout)
+	podLogs, err := e2epod.GetPodLogs(f.ClientSet, f.Namespace.Name, pod.Name, pod.Spec.Containers[0].Name)
+	framework.ExpectNoError(err)
+	results, err := parsePingOutput(podLogs)
+	framework.ExpectNoError(err)
+	return results
+}
+
+// getNetworkPerfData converts ping results into the perf data format consumed
+// by the perf dashboards.
+func getNetworkPerfData(results pingResults, linkType string, testInfo map[string]string) *perftype.PerfData {
+	return &perftype.PerfData{
+		Version: e2eperf.CurrentKubeletPerfMetricsVersion,
+		DataItems: []perftype.DataItem{
+			{
+				Data: map[string]float64{
+					"min": results.minMs,
+					"avg": results.avgMs,
+					"max": results.maxMs,
+				},
+				Unit: "ms",
+				Labels: map[string]string{
+					"datatype": "latency",
+					"linktype": linkType,
+				},
+			},
+			{
+				Data: map[string]float64{
+					"loss": results.lossPercent(),
+				},
+				Unit: "percent",
+				Labels: map[string]string{
+					"datatype": "packetdrop",
+					"linktype": linkType,
+				},
+			},
+		},
+		Labels: testInfo,
+	}
+}
+
+// Serial because the benchmarks are sensitive to other pods on the node.
+// Slow by design.
+var _ = SIGDescribe("Node Network Performance [Serial] [Slow] [Benchmark]", func() {
+	f := framework.NewDefaultFramework("node-network-benchmark")
+
+	ginkgo.It("should measure pod to host latency and packet drops over the veth pair", func() {
+		node := getLocalNode(f)
+		hostIP := ""
+		for _, address := range node.Status.Addresses {
+			if address.Type == v1.NodeInternalIP {
+				hostIP = address.Address
+				break
+			}
+		}
+		gomega.Expect(hostIP).NotTo(gomega.BeEmpty(), "the test node has no internal IP")
+
+		results := runPingBenchmarkPod(f, "ping-pod-to-host", hostIP)
+		testInfo := getTestNodeInfo(f, "pod-to-host-network", "latency and packet drops from a pod to its node")
+		logPerfData(getNetworkPerfData(results, "pod-to-host", testInfo), "network")
+
+		gomega.Expect(results.received).To(gomega.BeNumerically(">", 0), "no ping replies received from the host")
+	})
+
+	ginkgo.It("should measure pod to pod latency and packet drops on the same node", func() {
+		ginkgo.By("creating the target pod")
+		target := f.PodClient().CreateSync(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ping-target",
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Image: imageutils.GetPauseImageName(),
+						Name:  "ping-target",
+					},
+				},
+			},
+		})
+		gomega.Expect(target.Status.PodIP).NotTo(gomega.BeEmpty(), "the target pod has no IP")
+
+		results := runPingBenchmarkPod(f, "ping-pod-to-pod", target.Status.PodIP)
+		testInfo := getTestNodeInfo(f, "pod-to-pod-network", "latency and packet drops between two pods on the same node")
+		logPerfData(getNetworkPerfData(results, "pod-to-pod", testInfo), "network")
+
+		gomega.Expect(results.received).To(gomega.BeNumerically(">", 0), "no ping replies received from the target pod")
+	})
+})
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// oomKillRecord is a kernel OOM kill event parsed from the dmesg ring buffer.
+type oomKillRecord struct {
+	// Process is the comm of the killed task.
+	Process string
+	// Pid is the pid of the killed task.
+	Pid int
+	// Cgroup is the memory cgroup of the killed task. It is empty when the
+	// kernel did not report one, e.g. for global (non-memcg) OOM kills on
+	// older kernels.
+	Cgroup string
+}
+
+var (
+	// oomKilledProcessRegexp matches the kill record common to cgroup v1
+	// ("Memory cgroup out of memory: Killed process ..."), cgroup v2 and
+	// global OOM kills ("Out of memory: Killed process ...").
+	oomKilledProcessRegexp = regexp.MustCompile(`Killed process (\d+) \(([^)]+)\)`)
+	// oomKillTaskRegexp matches the separate oom-kill record that newer
+	// kernels emit with the memory cgroup of the killed task.
+	oomKillTaskRegexp = regexp.MustCompile(`oom-kill:.*task_memcg=([^,]+),task=([^,]+),pid=(\d+)`)
+)
+
+// parseOOMKillRecords parses kernel OOM kill records out of the given kernel
+// log. Records are returned in the order they were logged.
+func parseOOMKillRecords(log string) []oomKillRecord {
+	memcgByPid := map[int]string{}
+	for _, match := range oomKillTaskRegexp.FindAllStringSubmatch(log, -1) {
+		pid, err := strconv.Atoi(match[3])
+		if err != nil {
+			continue
+		}
+		memcgByPid[pid] = match[1]
+	}
+
+	var records []oomKillRecord
+	for _, match := range oomKilledProcessRegexp.FindAllStringSubmatch(log, -1) {
+		pid, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		records = append(records, oomKillRecord{
+			Process: match[2],
+			Pid:     pid,
+			Cgroup:  memcgByPid[pid],
+		})
+	}
+	return records
+}
+
+// getOOMKillRecords returns the OOM kill records currently held in the kernel
+// ring buffer. The node e2e suite runs as root, so dmesg can be invoked
+// directly.
+func getOOMKillRecords() ([]oomKillRecord, error) {
+	out, err := runCommand("dmesg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel log: %v", err)
+	}
+	return parseOOMKillRecords(out), nil
+}
+
+// findOOMKillRecord returns the most recent OOM kill record for the given
+// process name.
+func findOOMKillRecord(records []oomKillRecord, process string) (oomKillRecord, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Process == process {
+			return records[i], true
+		}
+	}
+	return oomKillRecord{}, false
+}
+
+// verifyOOMKilledContainerStatus returns an error unless the container status
+// reports a terminated container that was killed by the OOM killer. Both the
+// OOMKilled reason and the SIGKILL exit code are checked so that runtimes
+// which only propagate one of the two are still caught.
+func verifyOOMKilledContainerStatus(status v1.ContainerStatus) error {
+	terminated := status.State.Terminated
+	if terminated == nil {
+		return fmt.Errorf("container %q is not terminated", status.Name)
+	}
+	if terminated.Reason != "OOMKilled" {
+		return fmt.Errorf("container %q terminated with reason %q, expected OOMKilled", status.Name, terminated.Reason)
+	}
+	if terminated.ExitCode != 137 {
+		return fmt.Errorf("container %q terminated with exit code %d, expected 137 (128+SIGKILL)", status.Name, terminated.ExitCode)
+	}
+	return nil
+}
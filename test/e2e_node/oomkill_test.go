@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// oomKilledProcessName is the comm of the memory hog started below. dd
+// allocates its block-size buffer up front, so a block size above the pod
+// memory limit makes the kernel OOM killer terminate it reliably.
+const oomKilledProcessName = "dd"
+
+var _ = SIGDescribe("OOMKiller [LinuxOnly] [NodeFeature:OOMKiller]", func() {
+	f := framework.NewDefaultFramework("oomkiller-test")
+
+	ginkgo.It("should report OOMKilled status for a container exceeding its memory limit", func() {
+		if IsCgroup2UnifiedMode() {
+			ginkgo.By("running on cgroup v2, expecting plain OOM kill records")
+		} else {
+			ginkgo.By("running on cgroup v1, expecting memory cgroup OOM kill records")
+		}
+
+		pod := f.PodClient().Create(getOOMTargetPod("oomkill-target-pod"))
+
+		ginkgo.By("waiting for the container to be OOM killed")
+		gomega.Eventually(func() error {
+			p, err := f.PodClient().Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if len(p.Status.ContainerStatuses) != 1 {
+				return fmt.Errorf("no container status for pod %q yet", p.Name)
+			}
+			return verifyOOMKilledContainerStatus(p.Status.ContainerStatuses[0])
+		}, 3*time.Minute, 10*time.Second).Should(gomega.Succeed())
+
+		ginkgo.By("verifying the kernel logged a matching OOM kill record")
+		records, err := getOOMKillRecords()
+		framework.ExpectNoError(err, "failed to collect kernel OOM kill records")
+		record, found := findOOMKillRecord(records, oomKilledProcessName)
+		if !found {
+			framework.Failf("no kernel OOM kill record found for process %q, records: %+v", oomKilledProcessName, records)
+		}
+		framework.Logf("found OOM kill record: %+v", record)
+
+		// On kernels which report the task memcg, the killed task must live in
+		// the pod's cgroup rather than anywhere else on the node.
+		if record.Cgroup != "" && !strings.Contains(record.Cgroup, "kubepods") {
+			framework.Failf("OOM killed process %q is not in a kubepods cgroup: %q", record.Process, record.Cgroup)
+		}
+	})
+})
+
+// getOOMTargetPod returns a pod with a single container exceeding its memory
+// limit shortly after start.
+func getOOMTargetPod(podName string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: podName,
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{
+				{
+					Name:  "oomkill-target-container",
+					Image: busyboxImage,
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							v1.ResourceMemory: resource.MustParse("15Mi"),
+						},
+					},
+					Command: []string{
+						"sh",
+						"-c",
+						// Allocate a buffer well above the memory limit.
+						"sleep 5 && dd if=/dev/zero of=/dev/null bs=20M",
+					},
+				},
+			},
+		},
+	}
+}
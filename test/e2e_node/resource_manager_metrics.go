@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeletmetrics "k8s.io/kubernetes/pkg/kubelet/metrics"
+	"k8s.io/kubernetes/test/e2e/framework"
+
+	"github.com/onsi/gomega"
+)
+
+// resourceManagerCounters holds the value of the kubelet counters which track the
+// resource manager (cpu, memory, topology) pinning and admission activity.
+// The map is indexed by the full metric name, including the kubelet subsystem prefix.
+type resourceManagerCounters map[string]float64
+
+var resourceManagerCounterNames = []string{
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.CPUManagerPinningRequestsTotalKey,
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.CPUManagerPinningErrorsTotalKey,
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.MemoryManagerPinningRequestsTotalKey,
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.MemoryManagerPinningErrorsTotalKey,
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.TopologyManagerAdmissionRequestsTotalKey,
+	kubeletmetrics.KubeletSubsystem + "_" + kubeletmetrics.TopologyManagerAdmissionErrorsTotalKey,
+}
+
+// getResourceManagerCounters returns the current value of the resource manager
+// counters from the local kubelet. Counters not (yet) exposed report zero, so
+// deltas against older kubelets resolve to the observed value.
+func getResourceManagerCounters() resourceManagerCounters {
+	counters := make(resourceManagerCounters, len(resourceManagerCounterNames))
+	for _, name := range resourceManagerCounterNames {
+		counters[name] = 0
+	}
+
+	ms, err := getKubeletMetrics(sets.NewString(resourceManagerCounterNames...))
+	framework.ExpectNoError(err, "failed to get the resource manager metrics from the local kubelet")
+
+	for name, samples := range ms {
+		var value float64
+		for _, sample := range samples {
+			value += float64(sample.Value)
+		}
+		counters[name] = value
+	}
+	return counters
+}
+
+// delta returns how much the counter identified by metricName (without the kubelet
+// subsystem prefix) grew since the older snapshot was taken.
+func (c resourceManagerCounters) delta(older resourceManagerCounters, metricName string) float64 {
+	fullName := kubeletmetrics.KubeletSubsystem + "_" + metricName
+	return c[fullName] - older[fullName]
+}
+
+// expectCounterDelta asserts how much a resource manager counter grew since the
+// older snapshot was taken. Use it right after the pod (or pods) under test got
+// admitted, so unrelated pods cannot skew the counters.
+func expectCounterDelta(older resourceManagerCounters, metricName string, matcher gomega.OmegaMatcher, explain string) {
+	newer := getResourceManagerCounters()
+	gomega.ExpectWithOffset(1, newer.delta(older, metricName)).To(matcher, explain)
+}
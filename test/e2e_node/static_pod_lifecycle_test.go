@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/kubernetes/test/e2e/framework"
+)
+
+var _ = SIGDescribe("StaticPodLifecycle", func() {
+	f := framework.NewDefaultFramework("static-pod-lifecycle")
+	ginkgo.Context("when a static pod manifest changes on disk", func() {
+		var ns, podPath, staticPodName, mirrorPodName string
+		ginkgo.BeforeEach(func() {
+			ns = f.Namespace.Name
+			staticPodName = "lifecycle-pod-" + string(uuid.NewUUID())
+			mirrorPodName = staticPodName + "-" + framework.TestContext.NodeName
+
+			podPath = framework.TestContext.KubeletConfig.StaticPodPath
+
+			ginkgo.By("create the static pod")
+			err := writeStaticPod(podPath, newStaticPod(staticPodName, ns, nil))
+			framework.ExpectNoError(err)
+
+			ginkgo.By("wait for the mirror pod to be running")
+			gomega.Eventually(func() error {
+				return checkMirrorPodRunning(f.ClientSet, mirrorPodName, ns)
+			}, 2*time.Minute, time.Second*4).Should(gomega.BeNil())
+		})
+
+		ginkgo.It("should converge the mirror pod after the manifest is modified [NodeConformance]", func() {
+			ginkgo.By("get mirror pod uid")
+			pod, err := f.ClientSet.CoreV1().Pods(ns).Get(context.TODO(), mirrorPodName, metav1.GetOptions{})
+			framework.ExpectNoError(err)
+			uid := pod.UID
+
+			ginkgo.By("modify the static pod container command")
+			command := []string{"/bin/sh", "-c", "sleep 200000"}
+			err = writeStaticPod(podPath, newStaticPod(staticPodName, ns, func(pod *v1.Pod) {
+				pod.Spec.Containers[0].Command = command
+			}))
+			framework.ExpectNoError(err)
+
+			ginkgo.By("wait for the mirror pod to be recreated")
+			gomega.Eventually(func() error {
+				return checkMirrorPodRecreatedAndRunning(f.ClientSet, mirrorPodName, ns, uid)
+			}, 2*time.Minute, time.Second*4).Should(gomega.BeNil())
+
+			ginkgo.By("check the mirror pod container command is updated")
+			pod, err = f.ClientSet.CoreV1().Pods(ns).Get(context.TODO(), mirrorPodName, metav1.GetOptions{})
+			framework.ExpectNoError(err)
+			framework.ExpectEqual(len(pod.Spec.Containers), 1)
+			framework.ExpectEqual(pod.Spec.Containers[0].Command, command)
+		})
+
+		ginkgo.It("should report container restarts of a crashing static pod [NodeConformance]", func() {
+			ginkgo.By("modify the static pod so that its container keeps exiting")
+			err := writeStaticPod(podPath, newStaticPod(staticPodName, ns, func(pod *v1.Pod) {
+				pod.Spec.Containers[0].Command = []string{"/bin/sh", "-c", "sleep 5; exit 1"}
+			}))
+			framework.ExpectNoError(err)
+
+			ginkgo.By("wait for the mirror pod to report a container restart")
+			gomega.Eventually(func() error {
+				return checkMirrorPodRestartCount(f.ClientSet, mirrorPodName, ns, 1)
+			}, 3*time.Minute, time.Second*4).Should(gomega.BeNil())
+		})
+
+		ginkgo.It("should terminate gracefully when the manifest is removed [NodeConformance]", func() {
+			ginkgo.By("modify the static pod so that it exits promptly on SIGTERM")
+			gracePeriod := int64(60)
+			err := writeStaticPod(podPath, newStaticPod(staticPodName, ns, func(pod *v1.Pod) {
+				pod.Spec.TerminationGracePeriodSeconds = &gracePeriod
+				pod.Spec.Containers[0].Command = []string{"/bin/sh", "-c",
+					`trap 'echo "Caught SIGTERM signal!"; exit 0' TERM; sleep 100000 & wait`}
+			}))
+			framework.ExpectNoError(err)
+			gomega.Eventually(func() error {
+				return checkMirrorPodRunning(f.ClientSet, mirrorPodName, ns)
+			}, 2*time.Minute, time.Second*4).Should(gomega.BeNil())
+
+			ginkgo.By("delete the static pod")
+			err = deleteStaticPod(podPath, staticPodName, ns)
+			framework.ExpectNoError(err)
+
+			// The container handles SIGTERM, so the kubelet must tear the pod
+			// down well before the grace period runs out; waiting for the full
+			// grace period would indicate the pod was killed, not terminated.
+			ginkgo.By("wait for the mirror pod to disappear before the grace period runs out")
+			gomega.Eventually(func() error {
+				return checkMirrorPodDisappear(f.ClientSet, mirrorPodName, ns)
+			}, 30*time.Second, time.Second).Should(gomega.BeNil())
+		})
+
+		ginkgo.AfterEach(func() {
+			ginkgo.By("delete the static pod")
+			err := deleteStaticPod(podPath, staticPodName, ns)
+			if err != nil && !os.IsNotExist(err) {
+				framework.ExpectNoError(err)
+			}
+
+			ginkgo.By("wait for the mirror pod to disappear")
+			gomega.Eventually(func() error {
+				return checkMirrorPodDisappear(f.ClientSet, mirrorPodName, ns)
+			}, 2*time.Minute, time.Second*4).Should(gomega.BeNil())
+		})
+	})
+})
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2enode
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	imageutils "k8s.io/kubernetes/test/utils/image"
+	"sigs.k8s.io/yaml"
+)
+
+// Helpers for tests that drive the kubelet through static pod manifests on
+// disk. Unlike the YAML templates used by the older mirror pod tests, these
+// work on v1.Pod structs, so tests can modify arbitrary fields of a manifest
+// between writes and assert how the mirror pod converges.
+
+// newStaticPod returns a minimal static pod manifest running a sleeping
+// busybox container. Tests adjust it through the mutate func before writing
+// it to the manifest directory.
+func newStaticPod(name, namespace string, mutate func(*v1.Pod)) *v1.Pod {
+	pod := &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyAlways,
+			Containers: []v1.Container{
+				{
+					Name:    "test",
+					Image:   imageutils.GetE2EImage(imageutils.BusyBox),
+					Command: []string{"/bin/sh", "-c", "sleep 100000"},
+				},
+			},
+		},
+	}
+	if mutate != nil {
+		mutate(pod)
+	}
+	return pod
+}
+
+// writeStaticPod writes (or overwrites) the manifest of the pod in the given
+// static pod directory. The kubelet picks the change up through its file
+// watch, so no restart is needed.
+func writeStaticPod(dir string, pod *v1.Pod) error {
+	podYaml, err := yaml.Marshal(pod)
+	if err != nil {
+		return err
+	}
+
+	file := staticPodPath(dir, pod.Name, pod.Namespace)
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(podYaml)
+	return err
+}
+
+// mirrorPodRestartCount returns the restart count of the first container of
+// the mirror pod.
+func mirrorPodRestartCount(cl clientset.Interface, name, namespace string) (int32, error) {
+	pod, err := cl.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("expected the mirror pod %q to appear: %v", name, err)
+	}
+	if len(pod.Status.ContainerStatuses) < 1 {
+		return 0, fmt.Errorf("expected the mirror pod %q to report a container status", name)
+	}
+	return pod.Status.ContainerStatuses[0].RestartCount, nil
+}
+
+// checkMirrorPodRestartCount returns nil once the mirror pod reports at least
+// minRestarts container restarts.
+func checkMirrorPodRestartCount(cl clientset.Interface, name, namespace string, minRestarts int32) error {
+	restarts, err := mirrorPodRestartCount(cl, name, namespace)
+	if err != nil {
+		return err
+	}
+	if restarts < minRestarts {
+		return fmt.Errorf("expected the mirror pod %q to report at least %d restart(s), got %d", name, minRestarts, restarts)
+	}
+	return nil
+}
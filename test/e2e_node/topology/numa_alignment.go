@@ -14,7 +14,11 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package e2enode
+// Package topology verifies the NUMA alignment of the resources assigned to
+// containers. It is used by the topology manager node e2e tests and is kept
+// reusable so that device plugin and operator vendors can run exactly the
+// same verification in their own e2e suites.
+package topology
 
 import (
 	"fmt"
@@ -31,12 +35,16 @@ import (
 	"k8s.io/kubernetes/test/e2e/framework"
 )
 
-type numaPodResources struct {
+// NUMAPodResources maps the resources assigned to a container onto the NUMA
+// nodes they were taken from.
+type NUMAPodResources struct {
 	CPUToNUMANode     map[int]int
 	PCIDevsToNUMANode map[string]int
 }
 
-func (R *numaPodResources) CheckAlignment() bool {
+// CheckAlignment returns true when all the assigned resources live on the
+// same NUMA node.
+func (R *NUMAPodResources) CheckAlignment() bool {
 	nodeNum := -1 // not set
 	for _, cpuNode := range R.CPUToNUMANode {
 		if nodeNum == -1 {
@@ -53,7 +61,9 @@ func (R *numaPodResources) CheckAlignment() bool {
 	return true
 }
 
-func (R *numaPodResources) String() string {
+// Report returns a stable human-readable description of the resource to NUMA
+// node assignment, one resource per line.
+func (R *NUMAPodResources) Report() string {
 	var b strings.Builder
 	// To store the keys in slice in sorted order
 	var cpuKeys []int
@@ -77,7 +87,13 @@ func (R *numaPodResources) String() string {
 	return b.String()
 }
 
-func getCPUsPerNUMANode(nodeNum int) ([]int, error) {
+func (R *NUMAPodResources) String() string {
+	return R.Report()
+}
+
+// GetCPUsPerNUMANode returns the CPU IDs attached to the NUMA node, as read
+// from the sysfs of the node running the test.
+func GetCPUsPerNUMANode(nodeNum int) ([]int, error) {
 	nodeCPUList, err := ioutil.ReadFile(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", nodeNum))
 	if err != nil {
 		return nil, err
@@ -89,7 +105,10 @@ func getCPUsPerNUMANode(nodeNum int) ([]int, error) {
 	return cpus.ToSlice(), nil
 }
 
-func getCPUToNUMANodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, environ map[string]string, numaNodes int) (map[int]int, error) {
+// GetCPUToNUMANodeMapFromEnv maps the CPUs allowed to the container, as
+// published in its CPULIST_ALLOWED environment variable, onto the NUMA nodes
+// they belong to, as seen from inside the container.
+func GetCPUToNUMANodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, environ map[string]string, numaNodes int) (map[int]int, error) {
 	var cpuIDs []int
 	cpuListAllowedEnvVar := "CPULIST_ALLOWED"
 
@@ -138,7 +157,10 @@ func getCPUToNUMANodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *v1.Con
 	return CPUMap, nil
 }
 
-func getPCIDeviceToNumaNodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, environ map[string]string) (map[string]int, error) {
+// GetPCIDeviceToNumaNodeMapFromEnv maps the PCI devices assigned to the
+// container, as published in its PCIDEVICE_* environment variables, onto the
+// NUMA nodes they are attached to, as seen from inside the container.
+func GetPCIDeviceToNumaNodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, environ map[string]string) (map[string]int, error) {
 	pciDevPrefix := "PCIDEVICE_"
 	// at this point we don't care which plugin selected the device,
 	// we only need to know which devices were assigned to the POD.
@@ -155,13 +177,19 @@ func getPCIDeviceToNumaNodeMapFromEnv(f *framework.Framework, pod *v1.Pod, cnt *
 		for _, pciDev := range pciDevs {
 			pciDevNUMANode := f.ExecCommandInContainer(pod.Name, cnt.Name,
 				"/bin/cat", fmt.Sprintf("/sys/bus/pci/devices/%s/numa_node", pciDev))
-			NUMAPerDev[pciDev] = numaNodeFromSysFsEntry(pciDevNUMANode)
+			nodeNum, err := numaNodeFromSysFsEntry(pciDevNUMANode)
+			if err != nil {
+				return nil, err
+			}
+			NUMAPerDev[pciDev] = nodeNum
 		}
 	}
 	return NUMAPerDev, nil
 }
 
-func makeEnvMap(logs string) (map[string]string, error) {
+// MakeEnvMap parses the container environment, as dumped by `env` in the
+// container logs, into a map.
+func MakeEnvMap(logs string) (map[string]string, error) {
 	podEnv := strings.Split(logs, "\n")
 	envMap := make(map[string]string)
 	for _, envVar := range podEnv {
@@ -177,39 +205,46 @@ func makeEnvMap(logs string) (map[string]string, error) {
 	return envMap, nil
 }
 
-type testEnvInfo struct {
-	numaNodes         int
-	sriovResourceName string
-	policy            string
-	scope             string
+// Environment describes the node the alignment is verified on.
+type Environment struct {
+	// NUMANodes is the number of NUMA nodes of the node.
+	NUMANodes int
+	// DeviceResourceName is the resource name of the devices whose alignment
+	// is verified, for example the SRIOV device plugin resource.
+	DeviceResourceName string
 }
 
-func containerWantsDevices(cnt *v1.Container, envInfo *testEnvInfo) bool {
-	_, found := cnt.Resources.Requests[v1.ResourceName(envInfo.sriovResourceName)]
+// ContainerWantsDevices returns true when the container requests the devices
+// of the environment.
+func ContainerWantsDevices(cnt *v1.Container, env *Environment) bool {
+	_, found := cnt.Resources.Requests[v1.ResourceName(env.DeviceResourceName)]
 	return found
 }
 
-func checkNUMAAlignment(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, logs string, envInfo *testEnvInfo) (*numaPodResources, error) {
-	var err error
-	podEnv, err := makeEnvMap(logs)
+// CheckPodAlignment verifies that the CPUs and devices assigned to the
+// container all live on the same NUMA node. The container is expected to
+// have dumped its environment into its logs with `env`. The resource
+// assignment is returned for reporting even when the check fails.
+func CheckPodAlignment(f *framework.Framework, pod *v1.Pod, cnt *v1.Container, logs string, env *Environment) (*NUMAPodResources, error) {
+	podEnv, err := MakeEnvMap(logs)
 	if err != nil {
 		return nil, err
 	}
 
-	CPUToNUMANode, err := getCPUToNUMANodeMapFromEnv(f, pod, cnt, podEnv, envInfo.numaNodes)
+	CPUToNUMANode, err := GetCPUToNUMANodeMapFromEnv(f, pod, cnt, podEnv, env.NUMANodes)
 	if err != nil {
 		return nil, err
 	}
 
-	PCIDevsToNUMANode, err := getPCIDeviceToNumaNodeMapFromEnv(f, pod, cnt, podEnv)
+	PCIDevsToNUMANode, err := GetPCIDeviceToNumaNodeMapFromEnv(f, pod, cnt, podEnv)
 	if err != nil {
 		return nil, err
 	}
 
-	if containerWantsDevices(cnt, envInfo) && len(PCIDevsToNUMANode) == 0 {
+	if ContainerWantsDevices(cnt, env) && len(PCIDevsToNUMANode) == 0 {
 		return nil, fmt.Errorf("no PCI devices found in environ")
 	}
-	numaRes := numaPodResources{
+	numaRes := NUMAPodResources{
 		CPUToNUMANode:     CPUToNUMANode,
 		PCIDevsToNUMANode: PCIDevsToNUMANode,
 	}
@@ -220,15 +255,19 @@ func checkNUMAAlignment(f *framework.Framework, pod *v1.Pod, cnt *v1.Container,
 	return &numaRes, err
 }
 
-type pciDeviceInfo struct {
+// PCIDeviceInfo describes a PCI device and its NUMA placement.
+type PCIDeviceInfo struct {
 	Address  string
 	NUMANode int
 	IsPhysFn bool
 	IsVFn    bool
 }
 
-func getPCIDeviceInfo(sysPCIDir string) ([]pciDeviceInfo, error) {
-	var pciDevs []pciDeviceInfo
+// Discover reads the PCI devices of the node from the given sysfs directory,
+// usually /sys/bus/pci/devices, together with their NUMA placement and
+// SRIOV role.
+func Discover(sysPCIDir string) ([]PCIDeviceInfo, error) {
+	var pciDevs []PCIDeviceInfo
 
 	entries, err := ioutil.ReadDir(sysPCIDir)
 	if err != nil {
@@ -256,9 +295,13 @@ func getPCIDeviceInfo(sysPCIDir string) ([]pciDeviceInfo, error) {
 			return nil, err
 		}
 
-		pciDevs = append(pciDevs, pciDeviceInfo{
+		nodeNum, err := numaNodeFromSysFsEntry(string(content))
+		if err != nil {
+			return nil, err
+		}
+		pciDevs = append(pciDevs, PCIDeviceInfo{
 			Address:  entry.Name(),
-			NUMANode: numaNodeFromSysFsEntry(string(content)),
+			NUMANode: nodeNum,
 			IsPhysFn: isPhysFn,
 			IsVFn:    isVFn,
 		})
@@ -267,8 +310,10 @@ func getPCIDeviceInfo(sysPCIDir string) ([]pciDeviceInfo, error) {
 	return pciDevs, nil
 }
 
-func numaNodeFromSysFsEntry(content string) int {
+func numaNodeFromSysFsEntry(content string) (int, error) {
 	nodeNum, err := strconv.Atoi(strings.TrimSpace(content))
-	framework.ExpectNoError(err, "error detecting the device numa_node from sysfs: %v", err)
-	return nodeNum
+	if err != nil {
+		return 0, fmt.Errorf("error detecting the device numa_node from sysfs: %v", err)
+	}
+	return nodeNum, nil
 }
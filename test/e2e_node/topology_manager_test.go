@@ -38,12 +38,14 @@ import (
 	kubeletconfig "k8s.io/kubernetes/pkg/kubelet/apis/config"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpumanager"
 	"k8s.io/kubernetes/pkg/kubelet/cm/topologymanager"
+	kubeletmetrics "k8s.io/kubernetes/pkg/kubelet/metrics"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	"k8s.io/kubernetes/test/e2e/framework"
 	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
 	e2epod "k8s.io/kubernetes/test/e2e/framework/pod"
 	e2eskipper "k8s.io/kubernetes/test/e2e/framework/skipper"
 	e2etestfiles "k8s.io/kubernetes/test/e2e/framework/testfiles"
+	"k8s.io/kubernetes/test/e2e_node/topology"
 
 	"github.com/onsi/ginkgo"
 	"github.com/onsi/gomega"
@@ -60,6 +62,22 @@ const (
 	minSriovResource = 7 // This is the min number of SRIOV VFs needed on the system under test.
 )
 
+type testEnvInfo struct {
+	numaNodes         int
+	sriovResourceName string
+	policy            string
+	scope             string
+}
+
+// alignmentEnv exposes the parts of the test environment the topology
+// alignment library needs.
+func (e *testEnvInfo) alignmentEnv() *topology.Environment {
+	return &topology.Environment{
+		NUMANodes:          e.numaNodes,
+		DeviceResourceName: e.sriovResourceName,
+	}
+}
+
 // Helper for makeTopologyManagerPod().
 type tmCtnAttribute struct {
 	ctnName       string
@@ -170,7 +188,7 @@ func findNUMANodeWithoutSRIOVDevicesFromConfigMap(configMap *v1.ConfigMap, numaN
 }
 
 func findNUMANodeWithoutSRIOVDevicesFromSysfs(numaNodes int) (int, bool) {
-	pciDevs, err := getPCIDeviceInfo("/sys/bus/pci/devices")
+	pciDevs, err := topology.Discover("/sys/bus/pci/devices")
 	if err != nil {
 		framework.Failf("error detecting the PCI device NUMA node: %v", err)
 	}
@@ -232,7 +250,7 @@ func configureTopologyManagerInKubelet(f *framework.Framework, oldCfg *kubeletco
 	newCfg.CPUManagerReconcilePeriod = metav1.Duration{Duration: 1 * time.Second}
 
 	if nodeNum, ok := findNUMANodeWithoutSRIOVDevices(configMap, numaNodes); ok {
-		cpus, err := getCPUsPerNUMANode(nodeNum)
+		cpus, err := topology.GetCPUsPerNUMANode(nodeNum)
 		framework.Logf("NUMA Node %d doesn't seem to have attached SRIOV devices and has cpus=%v", nodeNum, cpus)
 		framework.ExpectNoError(err)
 		newCfg.ReservedSystemCPUs = fmt.Sprintf("%d", cpus[len(cpus)-1])
@@ -325,7 +343,7 @@ func validatePodAlignment(f *framework.Framework, pod *v1.Pod, envInfo *testEnvI
 		framework.ExpectNoError(err, "expected log not found in container [%s] of pod [%s]", cnt.Name, pod.Name)
 
 		framework.Logf("got pod logs: %v", logs)
-		numaRes, err := checkNUMAAlignment(f, pod, &cnt, logs, envInfo)
+		numaRes, err := topology.CheckPodAlignment(f, pod, &cnt, logs, envInfo.alignmentEnv())
 		framework.ExpectNoError(err, "NUMA Alignment check failed for [%s] of pod [%s]", cnt.Name, pod.Name)
 		if numaRes != nil {
 			framework.Logf("NUMA resources for %s/%s: %s", pod.Name, cnt.Name, numaRes.String())
@@ -342,16 +360,16 @@ func validatePodAlignmentWithPodScope(f *framework.Framework, pod *v1.Pod, envIn
 	for _, cnt := range pod.Spec.Containers {
 		logs, err := e2epod.GetPodLogs(f.ClientSet, f.Namespace.Name, pod.Name, cnt.Name)
 		framework.ExpectNoError(err, "NUMA alignment failed for container [%s] of pod [%s]", cnt.Name, pod.Name)
-		envMap, err := makeEnvMap(logs)
+		envMap, err := topology.MakeEnvMap(logs)
 		framework.ExpectNoError(err, "NUMA alignment failed for container [%s] of pod [%s]", cnt.Name, pod.Name)
-		cpuToNUMA, err := getCPUToNUMANodeMapFromEnv(f, pod, &cnt, envMap, envInfo.numaNodes)
+		cpuToNUMA, err := topology.GetCPUToNUMANodeMapFromEnv(f, pod, &cnt, envMap, envInfo.numaNodes)
 		framework.ExpectNoError(err, "NUMA alignment failed for container [%s] of pod [%s]", cnt.Name, pod.Name)
 		for cpuID, numaID := range cpuToNUMA {
 			podsNUMA[cpuID] = numaID
 		}
 	}
 
-	numaRes := numaPodResources{
+	numaRes := topology.NUMAPodResources{
 		CPUToNUMANode: podsNUMA,
 	}
 	aligned := numaRes.CheckAlignment()
@@ -417,6 +435,7 @@ func waitForAllContainerRemoval(podName, podNS string) {
 func runTopologyManagerPositiveTest(f *framework.Framework, numPods int, ctnAttrs, initCtnAttrs []tmCtnAttribute, envInfo *testEnvInfo) {
 	podMap := make(map[string]*v1.Pod)
 
+	counters := getResourceManagerCounters()
 	for podID := 0; podID < numPods; podID++ {
 		podName := fmt.Sprintf("gu-pod-%d", podID)
 		framework.Logf("creating pod %s attrs %v", podName, ctnAttrs)
@@ -426,6 +445,10 @@ func runTopologyManagerPositiveTest(f *framework.Framework, numPods int, ctnAttr
 		podMap[podName] = pod
 	}
 
+	ginkgo.By("checking the topology manager admission metrics")
+	expectCounterDelta(counters, kubeletmetrics.TopologyManagerAdmissionRequestsTotalKey, gomega.BeNumerically(">=", numPods), "expected an admission request per created pod")
+	expectCounterDelta(counters, kubeletmetrics.TopologyManagerAdmissionErrorsTotalKey, gomega.BeZero(), "expected no admission rejection for the created pods")
+
 	// per https://github.com/kubernetes/enhancements/blob/master/keps/sig-node/693-topology-manager/README.md#multi-numa-systems-tests
 	// we can do a menaingful validation only when using the single-numa node policy
 	if envInfo.policy == topologymanager.PolicySingleNumaNode {
@@ -463,6 +486,7 @@ func runTopologyManagerNegativeTest(f *framework.Framework, ctnAttrs, initCtnAtt
 	framework.Logf("creating pod %s attrs %v", podName, ctnAttrs)
 	pod := makeTopologyManagerTestPod(podName, ctnAttrs, initCtnAttrs)
 
+	counters := getResourceManagerCounters()
 	pod = f.PodClient().Create(pod)
 	err := e2epod.WaitForPodCondition(f.ClientSet, f.Namespace.Name, pod.Name, "Failed", 30*time.Second, func(pod *v1.Pod) (bool, error) {
 		if pod.Status.Phase != v1.PodPending {
@@ -481,6 +505,9 @@ func runTopologyManagerNegativeTest(f *framework.Framework, ctnAttrs, initCtnAtt
 		framework.Failf("pod %s failed for wrong reason: %q", pod.Name, pod.Status.Reason)
 	}
 
+	ginkgo.By("checking the topology manager admission metrics")
+	expectCounterDelta(counters, kubeletmetrics.TopologyManagerAdmissionErrorsTotalKey, gomega.BeNumerically(">=", 1), "expected the admission rejection to be accounted")
+
 	deletePodSyncByName(f, pod.Name)
 }
 
@@ -110,6 +110,8 @@ func makeRunnerMap() runnerMap {
 	return runnerMap{
 		"nat-closewait-client": nat.NewCloseWaitClient(),
 		"nat-closewait-server": nat.NewCloseWaitServer(),
+		"nat-idleconn-client":  nat.NewIdleConnClient(),
+		"nat-idleconn-server":  nat.NewIdleConnServer(),
 	}
 }
 
@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nat
+
+/*
+client/server for testing how NAT handles long-lived idle TCP connections.
+
+client              server
+  |<--tcp handshake-->|
+  |<---probe/echo---->| verify the path works
+  |        ...        | client stays idle (keepalive on or off)
+  |<---probe/echo---->| verify the conntrack entry survived the idle period
+*/
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"k8s.io/kubernetes/test/images/agnhost/net/common"
+)
+
+// IdleConnServerOptions holds server JSON options.
+type IdleConnServerOptions struct {
+	// Address to bind for the test.
+	LocalAddr string
+}
+
+type idleConnServer struct {
+	options *IdleConnServerOptions
+}
+
+// NewIdleConnServer returns a new Runner.
+func NewIdleConnServer() common.Runner {
+	return &idleConnServer{}
+}
+
+// NewOptions allocates new options structure.
+func (server *idleConnServer) NewOptions() interface{} {
+	return &IdleConnServerOptions{}
+}
+
+// Run the server-side of the test. It echoes every byte it receives and
+// serves until the process is killed.
+func (server *idleConnServer) Run(logger *log.Logger, rawOptions interface{}) error {
+	if options, ok := rawOptions.(*IdleConnServerOptions); ok {
+		server.options = options
+	} else {
+		return errors.New("invalid type")
+	}
+
+	logger.Printf("Run %v", server.options)
+
+	addr, err := net.ResolveTCPAddr("tcp", server.options.LocalAddr)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	logger.Printf("Server listening on %v", addr)
+
+	for {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return err
+		}
+		logger.Printf("Client %v connected", conn.RemoteAddr())
+		go echoUntilClosed(logger, conn)
+	}
+}
+
+func echoUntilClosed(logger *log.Logger, conn *net.TCPConn) {
+	defer conn.Close()
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			logger.Printf("Client %v disconnected: %v", conn.RemoteAddr(), err)
+			return
+		}
+		if _, err := conn.Write(buf); err != nil {
+			logger.Printf("Failed to echo to client %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// IdleConnClientOptions holds client JSON options.
+type IdleConnClientOptions struct {
+	// RemoteAddr of the server to connect to.
+	RemoteAddr string
+	// IdleSeconds the connection stays idle between the two probes.
+	IdleSeconds int
+	// EnableKeepAlive turns TCP keepalive on or off for the connection.
+	EnableKeepAlive bool
+	// KeepAlivePeriodSeconds between keepalive probes. Only used when
+	// EnableKeepAlive is true; zero keeps the operating system default.
+	KeepAlivePeriodSeconds int
+	// TimeoutSeconds on I/O with the server.
+	TimeoutSeconds int
+}
+
+type idleConnClient struct {
+	options *IdleConnClientOptions
+}
+
+// NewIdleConnClient creates a new runner.
+func NewIdleConnClient() common.Runner {
+	return &idleConnClient{}
+}
+
+// NewOptions allocates new options structure.
+func (client *idleConnClient) NewOptions() interface{} {
+	return &IdleConnClientOptions{}
+}
+
+// Run the client. It probes the server, stays idle for the configured
+// period and probes again, so that a conntrack entry dropped during the
+// idle period surfaces as an I/O error on the second probe.
+func (client *idleConnClient) Run(logger *log.Logger, rawOptions interface{}) error {
+	if options, ok := rawOptions.(*IdleConnClientOptions); ok {
+		client.options = options
+	} else {
+		return errors.New("invalid type")
+	}
+
+	logger.Printf("Run %v", client.options)
+
+	addr, err := net.ResolveTCPAddr("tcp", client.options.RemoteAddr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTCP("tcp", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetKeepAlive(client.options.EnableKeepAlive); err != nil {
+		return err
+	}
+	if client.options.EnableKeepAlive && client.options.KeepAlivePeriodSeconds > 0 {
+		period := time.Duration(client.options.KeepAlivePeriodSeconds) * time.Second
+		if err := conn.SetKeepAlivePeriod(period); err != nil {
+			return err
+		}
+	}
+
+	logger.Printf("Connected to server, keepalive enabled: %v",
+		client.options.EnableKeepAlive)
+
+	if err := probe(conn, client.options.TimeoutSeconds); err != nil {
+		return fmt.Errorf("initial probe failed: %v", err)
+	}
+	logger.Printf("Initial probe succeeded, staying idle for %v seconds",
+		client.options.IdleSeconds)
+
+	<-time.After(time.Duration(client.options.IdleSeconds) * time.Second)
+
+	if err := probe(conn, client.options.TimeoutSeconds); err != nil {
+		return fmt.Errorf("probe after %v seconds idle failed: %v",
+			client.options.IdleSeconds, err)
+	}
+	logger.Printf("Connection survived %v seconds idle",
+		client.options.IdleSeconds)
+
+	return nil
+}
+
+// probe writes a single byte and reads it back.
+func probe(conn *net.TCPConn, timeoutSeconds int) error {
+	if timeoutSeconds > 0 {
+		delay := time.Duration(timeoutSeconds) * time.Second
+		if err := conn.SetDeadline(time.Now().Add(delay)); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.Write([]byte("p")); err != nil {
+		return err
+	}
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return err
+	}
+	return nil
+}
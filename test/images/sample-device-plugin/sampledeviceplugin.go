@@ -29,6 +29,15 @@ import (
 
 const (
 	resourceName = "example.com/resource"
+
+	// envVarNameControlDir names the directory watched for device health
+	// control files. A file named after a device ID marks that device
+	// unhealthy; removing the file marks it healthy again. The watcher is
+	// disabled when the variable is empty.
+	envVarNameControlDir = "HEALTH_CONTROL_DIR"
+	// healthPollInterval is how often the control directory is scanned for
+	// device health changes.
+	healthPollInterval = 1 * time.Second
 )
 
 // stubAllocFunc creates and returns allocation response for the input allocate request
@@ -72,10 +81,35 @@ func stubAllocFunc(r *pluginapi.AllocateRequest, devs map[string]pluginapi.Devic
 	return &responses, nil
 }
 
+// watchDeviceHealth scans the control directory and flips the health of the
+// devices accordingly, pushing every change to the kubelet through the stub.
+func watchDeviceHealth(dp *dm.Stub, devs []*pluginapi.Device, controlDir string) {
+	for range time.Tick(healthPollInterval) {
+		changed := false
+		for _, dev := range devs {
+			health := pluginapi.Healthy
+			if _, err := os.Stat(filepath.Join(controlDir, dev.ID)); err == nil {
+				health = pluginapi.Unhealthy
+			}
+			if dev.Health != health {
+				klog.Infof("marking device %s %s", dev.ID, health)
+				dev.Health = health
+				changed = true
+			}
+		}
+		if changed {
+			dp.Update(devs)
+		}
+	}
+}
+
 func main() {
+	// All devices are reported on NUMA node 0 so that allocations can be
+	// checked for topology alignment regardless of the hardware the plugin
+	// runs on.
 	devs := []*pluginapi.Device{
-		{ID: "Dev-1", Health: pluginapi.Healthy},
-		{ID: "Dev-2", Health: pluginapi.Healthy},
+		{ID: "Dev-1", Health: pluginapi.Healthy, Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 0}}}},
+		{ID: "Dev-2", Health: pluginapi.Healthy, Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 0}}}},
 	}
 
 	pluginSocksDir := os.Getenv("PLUGIN_SOCK_DIR")
@@ -95,5 +129,9 @@ func main() {
 	if err := dp1.Register(pluginapi.KubeletSocket, resourceName, pluginapi.DevicePluginPath); err != nil {
 		panic(err)
 	}
+	if controlDir := os.Getenv(envVarNameControlDir); controlDir != "" {
+		klog.Infof("watching %s for device health changes", controlDir)
+		go watchDeviceHealth(dp1, devs, controlDir)
+	}
 	select {}
 }